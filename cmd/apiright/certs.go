@@ -0,0 +1,62 @@
+package apiright
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bata94/apiright/pkg/certs"
+	"github.com/spf13/cobra"
+)
+
+func NewCertsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "TLS certificate commands",
+		Long:  `Create certificates for serving HTTP and gRPC over TLS.`,
+		Example: `  apiright certs dev
+  apiright certs dev --hosts localhost,api.local`,
+	}
+
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Create a local development CA and certificates",
+		Long: `Creates a self-signed CA plus a server and a client certificate signed by it.
+An existing CA in the output directory is reused. The certificates are meant
+for local development only; never use them in production.`,
+		Example: `  apiright certs dev
+  apiright certs dev --dir certs --hosts localhost,127.0.0.1`,
+		RunE: runCertsDev,
+	}
+	devCmd.Flags().String("dir", "certs", "Output directory, relative to the project directory")
+	devCmd.Flags().StringSlice("hosts", certs.DefaultDevHosts, "DNS names and IP addresses for the server certificate")
+	cmd.AddCommand(devCmd)
+
+	return cmd
+}
+
+func runCertsDev(cmd *cobra.Command, args []string) error {
+	projectDir, err := GetProjectDir(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get project directory: %w", err)
+	}
+
+	dir, _ := cmd.Flags().GetString("dir")
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(projectDir, dir)
+	}
+	hosts, _ := cmd.Flags().GetStringSlice("hosts")
+
+	if err := certs.GenerateDev(dir, hosts); err != nil {
+		return fmt.Errorf("failed to create development certificates: %w", err)
+	}
+
+	fmt.Printf("Development certificates written to %s\n", dir)
+	fmt.Println("Enable them in apiright.yaml:")
+	fmt.Println("  server:")
+	fmt.Println("    tls:")
+	fmt.Println("      enabled: true")
+	fmt.Printf("      cert_file: %s\n", filepath.Join(dir, certs.ServerFile))
+	fmt.Printf("      key_file: %s\n", filepath.Join(dir, certs.ServerKeyFile))
+	fmt.Printf("      client_ca_file: %s  # optional, enables mTLS\n", filepath.Join(dir, certs.CAFile))
+	return nil
+}
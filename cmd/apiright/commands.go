@@ -28,7 +28,8 @@ func NewGenCommand() *cobra.Command {
   apiright gen --sql-only
   apiright gen --force
   apiright gen --verbose
-  apiright gen --go-only`,
+  apiright gen --go-only
+  apiright gen --freeze v1`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := runGenerate(cmd, options); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -43,12 +44,19 @@ func NewGenCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&options.Force, "force", false, "force regeneration bypassing cache")
 	cmd.Flags().BoolVar(&options.Verbose, "verbose", false, "detailed output with progress")
 	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "show what would be generated without writing files")
+	cmd.Flags().StringVar(&options.Freeze, "freeze", "", "snapshot the schema as this API version in versions/<name>")
 
 	return cmd
 }
 
 // runGenerate executes the generation process
 func runGenerate(cmd *cobra.Command, options generator.GenerateOptions) error {
+	if options.Freeze != "" {
+		if err := config.ValidateVersionName(options.Freeze); err != nil {
+			return err
+		}
+	}
+
 	// Determine project directory
 	projectDir, err := GetProjectDir(cmd)
 	if err != nil {
@@ -82,6 +90,7 @@ func runGenerate(cmd *cobra.Command, options generator.GenerateOptions) error {
 			GRPCPort:   cfg.Server.GRPCPort,
 			APIVersion: cfg.Server.APIVersion,
 			BasePath:   cfg.Server.BasePath,
+			DocsPath:   cfg.Server.DocsPath,
 		})
 
 	// Create plugin registry
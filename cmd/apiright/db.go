@@ -1,9 +1,13 @@
 package apiright
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/bata94/apiright/pkg/encryption"
+	"github.com/bata94/apiright/pkg/generator"
 	"github.com/spf13/cobra"
 )
 
@@ -15,7 +19,8 @@ func NewDBCCommand() *cobra.Command {
 		Example: `  apiright db ping
   apiright db create
   apiright db reset
-  apiright db seed`,
+  apiright db seed
+  apiright db reencrypt`,
 	}
 
 	cmd.AddCommand(&cobra.Command{
@@ -58,6 +63,19 @@ func NewDBCCommand() *cobra.Command {
 		RunE:    runDBPing,
 	})
 
+	reencryptCmd := &cobra.Command{
+		Use:   "reencrypt",
+		Short: "Re-encrypt encrypted columns under the primary key",
+		Long: `Rewrites every @encrypted column value that is not yet encrypted with the
+keyring's primary key. Run it after adding a new primary key to the keyring,
+before removing the old one.`,
+		Example: `  apiright db reencrypt
+  apiright db reencrypt --dry-run`,
+		RunE: runDBReencrypt,
+	}
+	reencryptCmd.Flags().Bool("dry-run", false, "Count rows that would be rewritten without changing them")
+	cmd.AddCommand(reencryptCmd)
+
 	return cmd
 }
 
@@ -157,3 +175,64 @@ func runDBPing(cmd *cobra.Command, args []string) error {
 	pc.Logger.Info("Database ping successful", "type", pc.Config.Database.Type)
 	return nil
 }
+
+func runDBReencrypt(cmd *cobra.Command, args []string) error {
+	pc, err := NewProjectContext(cmd)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	keyringFile := pc.Config.Encryption.KeyringFile
+	if path := os.Getenv("APIRIGHT_KEYRING_FILE"); path != "" {
+		keyringFile = path
+	}
+	if !filepath.IsAbs(keyringFile) {
+		keyringFile = filepath.Join(pc.Dir, keyringFile)
+	}
+	keyring, err := encryption.LoadKeyring(keyringFile)
+	if err != nil {
+		return err
+	}
+
+	parser := generator.NewSchemaParser(pc.Config.Database.Type, pc.Logger)
+	schema, err := parser.ParseMigrations(filepath.Join(pc.Dir, "migrations"))
+	if err != nil {
+		return err
+	}
+
+	db, err := ConnectDatabase(&pc.Config.Database, pc.Logger)
+	if err != nil {
+		return err
+	}
+	pc.Database = db
+
+	var total int64
+	for _, table := range schema.Tables {
+		tableConfig := pc.Config.Tables[table.Name]
+		target := encryption.Target{Table: table.Name}
+		if len(table.PrimaryKey) > 0 {
+			target.PrimaryKey = table.PrimaryKey[0]
+		}
+		for _, col := range table.Columns {
+			if col.Encrypted || tableConfig.IsEncrypted(col.Name) {
+				target.Columns = append(target.Columns, col.Name)
+			}
+		}
+		if len(target.Columns) == 0 {
+			continue
+		}
+
+		count, err := encryption.ReencryptTable(context.Background(), db.GetDB(), db.Dialect(), keyring, target, dryRun)
+		if err != nil {
+			return err
+		}
+		pc.Logger.Info("Re-encrypted table", "table", table.Name, "rows", count, "dry_run", dryRun)
+		total += count
+	}
+
+	pc.Logger.Info("Re-encryption complete", "rows", total, "primary_key", keyring.Primary(), "dry_run", dryRun)
+	return nil
+}
@@ -267,6 +267,9 @@ Thumbs.db
 .env
 .env.local
 .env.*.local
+
+# Encryption keys
+keyring.yaml
 `
 
 	// Main application file
@@ -284,17 +287,29 @@ import (
 	"github.com/bata94/apiright/pkg/core"
 	"github.com/bata94/apiright/pkg/database"
 	"github.com/bata94/apiright/pkg/server"
+	"github.com/bata94/apiright/pkg/telemetry"
 )
 
 func main() {
 	log.Printf("Starting %s application...", projectName)
 
 	// Load configuration
-	cfg, err := config.Load("apiright.yaml")
+	cfg, err := config.LoadConfig(".")
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %%v", err)
 	}
 
+	// Export traces and metrics if telemetry is enabled
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), cfg.Telemetry, cfg.Project.Version)
+	if err != nil {
+		log.Fatalf("Failed to set up telemetry: %%v", err)
+	}
+	defer func() {
+		if err := shutdownTelemetry(context.Background()); err != nil {
+			log.Printf("Failed to flush telemetry: %%v", err)
+		}
+	}()
+
 	// Create logger
 	logger := &simpleLogger{verbose: false}
 
@@ -315,7 +330,12 @@ func main() {
 	}
 
 	// Initialize server
-	srv := server.NewServer(&cfg.Server, db, logger)
+	srv := server.NewServer(&cfg.Server, ".", db, logger)
+
+	// Add the middleware configured under server.middleware
+	if err := srv.ConfigureMiddleware(cfg); err != nil {
+		log.Fatalf("Failed to configure middleware: %%v", err)
+	}
 
 	// Register generated services
 	if err := srv.RegisterGeneratedServices("."); err != nil {
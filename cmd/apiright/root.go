@@ -39,6 +39,7 @@ func init() {
 	rootCmd.AddCommand(NewMigrateCommand())
 	rootCmd.AddCommand(NewDBCCommand())
 	rootCmd.AddCommand(NewCacheCommand())
+	rootCmd.AddCommand(NewCertsCommand())
 	rootCmd.AddCommand(NewDoctorCommand())
 	rootCmd.AddCommand(NewVersionCommand())
 }
@@ -0,0 +1,371 @@
+// Package audit records and queries the mutation history of generated tables.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+)
+
+// DefaultTable is the default name of the audit log table
+const DefaultTable = "apiright_audit"
+
+// DefaultRole is the role that may read the audit log unless Options.Roles is set
+const DefaultRole = "admin"
+
+// MaxHistoryLimit bounds the entries returned by one History call
+const MaxHistoryLimit = 1000
+
+// Options configures an audit Recorder
+type Options struct {
+	Table     string        // Audit table name (default: apiright_audit)
+	Tables    []string      // Tables to audit (empty = all)
+	Retention time.Duration // How long to keep entries (0 = forever)
+	Roles     []string      // Roles that may read the audit log (default: admin)
+}
+
+// Recorder implements core.Auditor on top of a SQL database
+type Recorder struct {
+	db      *sql.DB
+	dialect string
+	options Options
+	logger  core.Logger
+}
+
+// NewRecorder creates a new audit recorder
+func NewRecorder(db *sql.DB, dialect string, options Options, logger core.Logger) *Recorder {
+	if options.Table == "" {
+		options.Table = DefaultTable
+	}
+	if len(options.Roles) == 0 {
+		options.Roles = []string{DefaultRole}
+	}
+	return &Recorder{
+		db:      db,
+		dialect: dialect,
+		options: options,
+		logger:  logger,
+	}
+}
+
+// Record stores an audit entry for the given event through conn, or through
+// the recorder's database if conn is nil
+func (r *Recorder) Record(ctx context.Context, conn core.Execer, event core.AuditEvent) error {
+	if !r.isAudited(event.Table) {
+		return nil
+	}
+
+	changes, err := Diff(event.Before, event.After)
+	if err != nil {
+		return fmt.Errorf("failed to compute audit changes for %s: %w", event.Table, err)
+	}
+	redact(changes, event.Redacted)
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit changes: %w", err)
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (actor, table_name, primary_key, operation, changes, request_id, created_at) VALUES (%s)",
+		r.options.Table, database.Placeholders(r.dialect, 7),
+	)
+
+	if conn == nil {
+		conn = r.db
+	}
+	_, err = conn.ExecContext(ctx, query,
+		core.ActorFromContext(ctx),
+		event.Table,
+		event.PrimaryKey,
+		string(event.Operation),
+		string(changesJSON),
+		core.RequestIDFromContext(ctx),
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// CanRead reports whether principal may read the audit log; the role "*"
+// allows any authenticated caller
+func (r *Recorder) CanRead(principal *core.Principal) bool {
+	for _, role := range r.options.Roles {
+		if role == "*" || principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// History returns the audit entries for a table, optionally filtered by primary key
+func (r *Recorder) History(ctx context.Context, table, primaryKey string, limit int) ([]core.AuditEntry, error) {
+	return r.HistoryBefore(ctx, table, primaryKey, 0, limit)
+}
+
+// HistoryBefore returns the audit entries older than the entry with ID before,
+// newest first; before 0 starts at the newest entry. limit is capped at MaxHistoryLimit.
+func (r *Recorder) HistoryBefore(ctx context.Context, table, primaryKey string, before int64, limit int) ([]core.AuditEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	limit = min(limit, MaxHistoryLimit)
+
+	var conditions []string
+	var args []any
+	if before > 0 {
+		args = append(args, before)
+		conditions = append(conditions, "id < "+database.Placeholder(r.dialect, len(args)))
+	}
+	if table != "" {
+		args = append(args, table)
+		conditions = append(conditions, "table_name = "+database.Placeholder(r.dialect, len(args)))
+	}
+	if primaryKey != "" {
+		args = append(args, primaryKey)
+		conditions = append(conditions, "primary_key = "+database.Placeholder(r.dialect, len(args)))
+	}
+
+	query := fmt.Sprintf(
+		"SELECT id, actor, table_name, primary_key, operation, changes, request_id, created_at FROM %s",
+		r.options.Table,
+	)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d", limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer core.Close("audit rows", rows, r.logger)
+
+	entries := []core.AuditEntry{}
+	for rows.Next() {
+		var entry core.AuditEntry
+		var operation, changes string
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Table, &entry.PrimaryKey,
+			&operation, &changes, &entry.RequestID, &entry.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Operation = core.AuditOperation(operation)
+		if changes != "" {
+			if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+				return nil, fmt.Errorf("failed to decode audit changes for entry %d: %w", entry.ID, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Prune deletes entries older than the configured retention period
+func (r *Recorder) Prune(ctx context.Context) (int64, error) {
+	if r.options.Retention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().UTC().Add(-r.options.Retention)
+	query := fmt.Sprintf("DELETE FROM %s WHERE created_at < %s", r.options.Table, database.Placeholder(r.dialect, 1))
+
+	result, err := r.db.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune audit log: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RunRetention prunes expired entries periodically until ctx is cancelled
+func (r *Recorder) RunRetention(ctx context.Context, interval time.Duration) {
+	if r.options.Retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := r.Prune(ctx); err != nil {
+			r.logger.Warn("Audit retention failed", "error", err)
+		} else if deleted > 0 {
+			r.logger.Info("Pruned audit log", "deleted", deleted, "retention", r.options.Retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isAudited reports whether a table is covered by this recorder
+func (r *Recorder) isAudited(table string) bool {
+	if len(r.options.Tables) == 0 {
+		return true
+	}
+	for _, t := range r.options.Tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff computes the field-level changes between two record states.
+// Either side may be nil (create or delete).
+func Diff(before, after any) ([]core.FieldChange, error) {
+	beforeMap, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMap, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]bool)
+	for k := range beforeMap {
+		fields[k] = true
+	}
+	for k := range afterMap {
+		fields[k] = true
+	}
+
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	changes := []core.FieldChange{}
+	for _, name := range names {
+		oldValue, newValue := beforeMap[name], afterMap[name]
+		if jsonEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, core.FieldChange{
+			Field:    name,
+			OldValue: oldValue,
+			NewValue: newValue,
+		})
+	}
+
+	return changes, nil
+}
+
+// RedactedValue replaces the values of redacted fields in audit entries
+const RedactedValue = "[redacted]"
+
+// redact masks the old and new values of the given fields, keeping the fact that they changed
+func redact(changes []core.FieldChange, fields []string) {
+	for i := range changes {
+		if !slices.Contains(fields, changes[i].Field) {
+			continue
+		}
+		if changes[i].OldValue != nil {
+			changes[i].OldValue = RedactedValue
+		}
+		if changes[i].NewValue != nil {
+			changes[i].NewValue = RedactedValue
+		}
+	}
+}
+
+// toMap converts a record (struct, map or pointer) into a generic map via JSON
+func toMap(v any) (map[string]any, error) {
+	if v == nil {
+		return map[string]any{}, nil
+	}
+	if m, ok := v.(map[string]any); ok {
+		return m, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	result := map[string]any{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("record is not an object: %w", err)
+	}
+	return result, nil
+}
+
+// jsonEqual compares two values by their JSON representation
+func jsonEqual(a, b any) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aj) == string(bj)
+}
+
+// CreateTableSQL returns the DDL for the audit table in the given dialect
+func CreateTableSQL(dialect, table string) string {
+	if table == "" {
+		table = DefaultTable
+	}
+
+	switch dialect {
+	case "postgres", "postgresql":
+		return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL DEFAULT '',
+    table_name TEXT NOT NULL,
+    primary_key TEXT NOT NULL DEFAULT '',
+    operation TEXT NOT NULL,
+    changes TEXT NOT NULL DEFAULT '[]',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_%[1]s_record ON %[1]s (table_name, primary_key);
+CREATE INDEX IF NOT EXISTS idx_%[1]s_created_at ON %[1]s (created_at);
+`, table)
+	case "mysql":
+		return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    table_name VARCHAR(255) NOT NULL,
+    primary_key VARCHAR(255) NOT NULL DEFAULT '',
+    operation VARCHAR(16) NOT NULL,
+    changes TEXT NOT NULL,
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_%[1]s_record (table_name, primary_key),
+    INDEX idx_%[1]s_created_at (created_at)
+);
+`, table)
+	default:
+		return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor TEXT NOT NULL DEFAULT '',
+    table_name TEXT NOT NULL,
+    primary_key TEXT NOT NULL DEFAULT '',
+    operation TEXT NOT NULL,
+    changes TEXT NOT NULL DEFAULT '[]',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_%[1]s_record ON %[1]s (table_name, primary_key);
+CREATE INDEX IF NOT EXISTS idx_%[1]s_created_at ON %[1]s (created_at);
+`, table)
+	}
+}
+
+// Ensure Recorder implements core.Auditor
+var _ core.Auditor = (*Recorder)(nil)
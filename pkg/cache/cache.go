@@ -0,0 +1,102 @@
+// Package cache caches serialized Get and List responses of generated tables.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// keySeparator joins key parts; it cannot appear in paths or header values
+const keySeparator = "\x00"
+
+// Options configures a ResponseCache
+type Options struct {
+	Store core.CacheStore          // Defaults to an LRU with DefaultMaxEntries
+	TTLs  map[string]time.Duration // Per table; tables without a TTL are not cached
+}
+
+// ResponseCache stores responses per table and implements core.CacheInvalidator,
+// so generated adapters can drop a table's entries after writes
+type ResponseCache struct {
+	store  core.CacheStore
+	ttls   map[string]time.Duration
+	logger core.Logger
+}
+
+// New creates a new response cache
+func New(options Options, logger core.Logger) *ResponseCache {
+	if options.Store == nil {
+		options.Store = NewLRU(DefaultMaxEntries)
+	}
+	return &ResponseCache{
+		store:  options.Store,
+		ttls:   options.TTLs,
+		logger: logger,
+	}
+}
+
+// NewFromConfig creates a response cache from the cache section and the
+// cache_ttl of each table in apiright.yaml
+func NewFromConfig(cfg *config.Config, logger core.Logger) (*ResponseCache, error) {
+	ttls, err := cfg.CacheTTLs()
+	if err != nil {
+		return nil, err
+	}
+
+	var store core.CacheStore
+	switch cfg.Cache.Store {
+	case "", "memory":
+		store = NewLRU(cfg.Cache.MaxEntries)
+	default:
+		return nil, fmt.Errorf("unknown cache store: %s", cfg.Cache.Store)
+	}
+
+	return New(Options{Store: store, TTLs: ttls}, logger), nil
+}
+
+// TTL returns how long responses of table are cached, or false if they are not
+func (c *ResponseCache) TTL(table string) (time.Duration, bool) {
+	ttl, ok := c.ttls[table]
+	return ttl, ok && ttl > 0
+}
+
+// Key builds the cache key of a response of table from the parts that select it,
+// such as the route, query, content type and auth scope
+func (c *ResponseCache) Key(table string, parts ...string) string {
+	return table + keySeparator + strings.Join(parts, keySeparator)
+}
+
+// Get returns a cached response. Store errors are logged and count as a miss.
+func (c *ResponseCache) Get(ctx context.Context, key string) (core.CachedResponse, bool) {
+	response, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		core.LoggerFromContext(ctx, c.logger).Warn("Response cache lookup failed", "error", err)
+		return core.CachedResponse{}, false
+	}
+	return response, ok
+}
+
+// Set caches a response of table for the table's TTL
+func (c *ResponseCache) Set(ctx context.Context, table, key string, response core.CachedResponse) {
+	ttl, ok := c.TTL(table)
+	if !ok {
+		return
+	}
+	if err := c.store.Set(ctx, key, response, ttl); err != nil {
+		core.LoggerFromContext(ctx, c.logger).Warn("Failed to cache response", "table", table, "error", err)
+	}
+}
+
+// InvalidateTable removes all cached responses of table
+func (c *ResponseCache) InvalidateTable(ctx context.Context, table string) {
+	if err := c.store.DeletePrefix(ctx, table+keySeparator); err != nil {
+		core.LoggerFromContext(ctx, c.logger).Error("Failed to invalidate response cache", "table", table, "error", err)
+	}
+}
+
+var _ core.CacheInvalidator = (*ResponseCache)(nil)
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// DefaultMaxEntries is the capacity of an LRU created without one
+const DefaultMaxEntries = 10000
+
+// LRU is an in-memory core.CacheStore that evicts the least recently used
+// response once it holds maxEntries
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Front is the most recently used
+	entries    map[string]*list.Element
+	timeSource func() time.Time
+}
+
+// lruEntry is a stored response with its expiry
+type lruEntry struct {
+	key       string
+	response  core.CachedResponse
+	expiresAt time.Time
+}
+
+// NewLRU creates a new in-memory LRU store
+func NewLRU(maxEntries int) *LRU {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &LRU{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		timeSource: time.Now,
+	}
+}
+
+// Get returns the response stored under key, if present and not expired
+func (l *LRU) Get(ctx context.Context, key string) (core.CachedResponse, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return core.CachedResponse{}, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if l.timeSource().After(entry.expiresAt) {
+		l.remove(element)
+		return core.CachedResponse{}, false, nil
+	}
+	l.order.MoveToFront(element)
+	return entry.response, true, nil
+}
+
+// Set stores a response under key for ttl
+func (l *LRU) Set(ctx context.Context, key string, response core.CachedResponse, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := l.timeSource().Add(ttl)
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.response = response
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(element)
+		return nil
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, response: response, expiresAt: expiresAt})
+	for l.order.Len() > l.maxEntries {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+// DeletePrefix removes every response whose key starts with prefix
+func (l *LRU) DeletePrefix(ctx context.Context, prefix string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, element := range l.entries {
+		if strings.HasPrefix(key, prefix) {
+			l.remove(element)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of stored responses, including expired ones not yet evicted
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// remove drops an element (caller must hold lock)
+func (l *LRU) remove(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.entries, element.Value.(*lruEntry).key)
+}
+
+var _ core.CacheStore = (*LRU)(nil)
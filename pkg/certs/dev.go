@@ -0,0 +1,146 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File names written by GenerateDev
+const (
+	CAFile        = "ca.pem"
+	CAKeyFile     = "ca-key.pem"
+	ServerFile    = "server.pem"
+	ServerKeyFile = "server-key.pem"
+	ClientFile    = "client.pem"
+	ClientKeyFile = "client-key.pem"
+)
+
+const (
+	devCAValidity   = 10 * 365 * 24 * time.Hour
+	devCertValidity = 365 * 24 * time.Hour
+)
+
+// DefaultDevHosts are the server certificate names when none are given
+var DefaultDevHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// GenerateDev writes a self-signed development CA plus a server certificate
+// for hosts and a client certificate signed by it into dir. An existing CA in
+// dir is reused, so clients that already trust it keep working.
+func GenerateDev(dir string, hosts []string) error {
+	if len(hosts) == 0 {
+		hosts = DefaultDevHosts
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+
+	ca, caKey, err := loadOrCreateCA(dir)
+	if err != nil {
+		return err
+	}
+
+	server := &x509.Certificate{
+		Subject:     pkix.Name{Organization: []string{"apiright development"}, CommonName: hosts[0]},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			server.IPAddresses = append(server.IPAddresses, ip)
+		} else {
+			server.DNSNames = append(server.DNSNames, host)
+		}
+	}
+	if err := issue(dir, ServerFile, ServerKeyFile, server, ca, caKey); err != nil {
+		return err
+	}
+
+	client := &x509.Certificate{
+		Subject:     pkix.Name{Organization: []string{"apiright development"}, CommonName: "apiright-dev-client"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	return issue(dir, ClientFile, ClientKeyFile, client, ca, caKey)
+}
+
+// loadOrCreateCA reads the CA pair from dir, creating it when missing
+func loadOrCreateCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, CAFile), filepath.Join(dir, CAKeyFile))
+	if err == nil {
+		key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("existing CA key in %s is not an ECDSA key", dir)
+		}
+		return pair.Leaf, key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("failed to load existing CA: %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"apiright development"}, CommonName: "apiright development CA"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	if err := issue(dir, CAFile, CAKeyFile, template, nil, nil); err != nil {
+		return nil, nil, err
+	}
+	return loadOrCreateCA(dir)
+}
+
+// issue creates a key and a certificate from template signed by parent (self-signed when nil)
+func issue(dir, certFile, keyFile string, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(devCertValidity)
+	if parent == nil {
+		template.NotAfter = time.Now().Add(devCAValidity)
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate %s: %w", certFile, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key %s: %w", keyFile, err)
+	}
+
+	if err := writePEM(filepath.Join(dir, certFile), "CERTIFICATE", der, 0644); err != nil {
+		return err
+	}
+	return writePEM(filepath.Join(dir, keyFile), "EC PRIVATE KEY", keyDER, 0600)
+}
+
+// writePEM writes a single PEM block to path
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// defaultReloadInterval applies when server.tls.reload_interval is not set
+const defaultReloadInterval = 10 * time.Second
+
+// Reloader serves a certificate and key pair from disk and reloads it when
+// either file changes. Files are checked during handshakes, at most once per
+// interval; a pair that fails to load keeps the previous certificate in use.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   core.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// NewReloader loads the certificate pair and returns a reloader for it
+func NewReloader(certFile, keyFile string, interval time.Duration, logger core.Logger) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		logger:   logger,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate; it fits tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload reads the certificate pair from disk
+func (r *Reloader) Reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.checked = time.Now()
+	return nil
+}
+
+// maybeReload reloads the pair if the interval has passed and a file changed
+func (r *Reloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.checked) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	r.checked = time.Now()
+	current := r.modTime
+	r.mu.Unlock()
+
+	modTime, err := r.latestModTime()
+	if err != nil || !modTime.After(current) {
+		return
+	}
+	if err := r.Reload(); err != nil {
+		if r.logger != nil {
+			r.logger.Error("Failed to reload TLS certificate, keeping the previous one", "cert_file", r.certFile, "error", err)
+		}
+		return
+	}
+	if r.logger != nil {
+		r.logger.Info("Reloaded TLS certificate", "cert_file", r.certFile)
+	}
+}
+
+// latestModTime returns the newer modification time of the two files
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read TLS certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
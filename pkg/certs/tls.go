@@ -0,0 +1,130 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// NewTLSConfig builds the server TLS configuration shared by the HTTP and gRPC
+// servers. The certificate is served through a Reloader, so replacing the
+// files on disk takes effect without a restart.
+func NewTLSConfig(cfg config.TLSConfig, logger core.Logger) (*tls.Config, error) {
+	interval := defaultReloadInterval
+	if cfg.ReloadInterval != "" {
+		d, err := config.ParseDuration(cfg.ReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls reload_interval: %w", err)
+		}
+		interval = d
+	}
+
+	reloader, err := NewReloader(cfg.CertFile, cfg.KeyFile, interval, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	minVersion, err := parseMinVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := parseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := parseClientAuth(cfg.ClientAuth, cfg.ClientCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pool, err := loadCertPool(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// loadCertPool reads a PEM bundle of CA certificates
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", path)
+	}
+	return pool, nil
+}
+
+// parseMinVersion maps min_version to a TLS version, defaulting to 1.2
+func parseMinVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid tls min_version: %s (must be 1.2 or 1.3)", version)
+	}
+}
+
+// parseCipherSuites maps cipher suite names to IDs; only Go's secure suites are accepted
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported or insecure tls cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseClientAuth maps client_auth to a tls.ClientAuthType. Without an
+// explicit setting, a client CA enables mandatory client certificates.
+func parseClientAuth(mode string, hasClientCA bool) (tls.ClientAuthType, error) {
+	switch mode {
+	case "":
+		if hasClientCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require_and_verify":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("invalid tls client_auth: %s", mode)
+	}
+}
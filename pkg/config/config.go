@@ -2,19 +2,31 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
 
+	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the complete APIRight configuration
 type Config struct {
-	Project    ProjectConfig    `yaml:"project"`
-	Database   DatabaseConfig   `yaml:"database"`
-	Server     ServerConfig     `yaml:"server"`
-	Generation GenerationConfig `yaml:"generation"`
-	Plugins    []PluginConfig   `yaml:"plugins"`
+	Project    ProjectConfig          `yaml:"project"`
+	Database   DatabaseConfig         `yaml:"database"`
+	Server     ServerConfig           `yaml:"server"`
+	Generation GenerationConfig       `yaml:"generation"`
+	Audit      AuditConfig            `yaml:"audit"`
+	Cache      CacheConfig            `yaml:"cache"`
+	Auth       AuthConfig             `yaml:"auth"`
+	Policies   PolicyConfig           `yaml:"policies"`
+	Tables     map[string]TableConfig `yaml:"tables"`
+	Encryption EncryptionConfig       `yaml:"encryption"`
+	Telemetry  TelemetryConfig        `yaml:"telemetry"`
+	Plugins    []PluginConfig         `yaml:"plugins"`
 }
 
 // ProjectConfig holds project-specific configuration
@@ -40,24 +52,145 @@ type DatabaseConfig struct {
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	EnableHTTP bool      `yaml:"enable_http"`
-	EnableGRPC bool      `yaml:"enable_grpc"`
-	EnableDocs *bool     `yaml:"enable_docs"`
-	DocsPath   string    `yaml:"docs_path"`
-	APIVersion string    `yaml:"api_version"`
-	BasePath   string    `yaml:"base_path"`
-	HTTPPort   int       `yaml:"http_port"`
-	GRPCPort   int       `yaml:"grpc_port"`
-	Host       string    `yaml:"host"`
-	Timeout    int       `yaml:"timeout"`
-	TLS        TLSConfig `yaml:"tls"`
+	EnableHTTP    bool             `yaml:"enable_http"`
+	EnableGRPC    bool             `yaml:"enable_grpc"`
+	EnableDocs    *bool            `yaml:"enable_docs"`
+	DocsPath      string           `yaml:"docs_path"`
+	EnableGraphQL *bool            `yaml:"enable_graphql"`
+	GraphQLPath   string           `yaml:"graphql_path"`
+	JSONMapping   string           `yaml:"json_mapping"` // sqlc (default) or protojson: render JSON from the generated protobuf messages
+	APIVersion    string           `yaml:"api_version"`
+	BasePath      string           `yaml:"base_path"`
+	Versions      []VersionConfig  `yaml:"versions"` // Older API versions served next to api_version
+	HTTPPort      int              `yaml:"http_port"`
+	GRPCPort      int              `yaml:"grpc_port"`
+	SinglePort    bool             `yaml:"single_port"` // Serve HTTP, gRPC (h2c) and gRPC-Web on http_port
+	Host          string           `yaml:"host"`
+	Timeout       int              `yaml:"timeout"`
+	Shutdown      ShutdownConfig   `yaml:"shutdown"`
+	TLS           TLSConfig        `yaml:"tls"`
+	Metrics       MetricsConfig    `yaml:"metrics"`
+	Middleware    MiddlewareConfig `yaml:"middleware"`
+}
+
+// VersionConfig declares an older API version; its routes serve the schema
+// frozen in versions/<name>/schema.json by "apiright gen --freeze <name>"
+type VersionConfig struct {
+	Name       string `yaml:"name"`       // Path segment and Accept-Version value (e.g. v1)
+	Deprecated string `yaml:"deprecated"` // Date (2006-01-02 or RFC 3339) sent in the Deprecation header
+	Sunset     string `yaml:"sunset"`     // Date after which the version may be removed, sent in the Sunset header
+
+	// Renames maps, per table, frozen column names to the current columns they became
+	Renames map[string]map[string]string `yaml:"renames"`
+}
+
+// ShutdownConfig holds graceful shutdown settings
+type ShutdownConfig struct {
+	Timeout    string `yaml:"timeout"`     // Go duration to drain in-flight requests before forcing close (default: 30s)
+	DrainDelay string `yaml:"drain_delay"` // Go duration to keep serving after readiness flips, so load balancers notice (default: none)
+}
+
+// MetricsConfig holds the Prometheus metrics endpoint configuration
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"` // Default: /metrics
 }
 
 // TLSConfig holds TLS configuration
 type TLSConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	CertFile string `yaml:"cert_file"`
-	KeyFile  string `yaml:"key_file"`
+	Enabled        bool     `yaml:"enabled"`
+	CertFile       string   `yaml:"cert_file"`
+	KeyFile        string   `yaml:"key_file"`
+	ClientCAFile   string   `yaml:"client_ca_file"`  // PEM bundle to verify client certificates against (mTLS)
+	ClientAuth     string   `yaml:"client_auth"`     // none, request, require, verify_if_given or require_and_verify (default: require_and_verify with client_ca_file, otherwise none)
+	MinVersion     string   `yaml:"min_version"`     // 1.2 or 1.3 (default: 1.2)
+	CipherSuites   []string `yaml:"cipher_suites"`   // TLS 1.2 cipher suite names (default: Go's secure defaults)
+	ReloadInterval string   `yaml:"reload_interval"` // Go duration between checks for changed certificate files (default: 10s)
+}
+
+// MiddlewareConfig holds the middleware pipeline built at server startup
+type MiddlewareConfig struct {
+	RequestID   RequestIDConfig           `yaml:"request_id"`
+	Logging     MiddlewareToggle          `yaml:"logging"`
+	CORS        CORSMiddlewareConfig      `yaml:"cors"`
+	RateLimit   RateLimitMiddlewareConfig `yaml:"rate_limit"`
+	Telemetry   MiddlewareToggle          `yaml:"telemetry"` // Exporters are configured under telemetry
+	Auth        MiddlewareToggle          `yaml:"auth"`      // Methods are configured under auth
+	Compression CompressionConfig         `yaml:"compression"`
+	BodyLimit   BodyLimitConfig           `yaml:"body_limit"`
+	Idempotency IdempotencyConfig         `yaml:"idempotency"`
+}
+
+// MiddlewareToggle holds the settings shared by every middleware
+type MiddlewareToggle struct {
+	Enabled  *bool `yaml:"enabled"`
+	Priority *int  `yaml:"priority"` // Overrides the built-in priority
+}
+
+// RequestIDConfig holds request ID and trace context settings. The middleware
+// always wraps all others, so it has no priority.
+type RequestIDConfig struct {
+	Enabled *bool  `yaml:"enabled"` // Default: true
+	Header  string `yaml:"header"`  // Default: X-Request-ID
+}
+
+// CORSMiddlewareConfig holds CORS settings; empty lists use the middleware defaults
+type CORSMiddlewareConfig struct {
+	MiddlewareToggle `yaml:",inline"`
+	AllowOrigins     []string `yaml:"allow_origins"`
+	AllowMethods     []string `yaml:"allow_methods"`
+	AllowHeaders     []string `yaml:"allow_headers"`
+	ExposeHeaders    []string `yaml:"expose_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age"` // Seconds
+}
+
+// RateLimitMiddlewareConfig holds rate limiting settings
+type RateLimitMiddlewareConfig struct {
+	MiddlewareToggle `yaml:",inline"`
+	Requests         int                   `yaml:"requests"` // Requests per window, -1 = unlimited
+	Window           string                `yaml:"window"`   // Go duration, e.g. 1m
+	Store            string                `yaml:"store"`    // memory or sql
+	Table            string                `yaml:"table"`    // Bucket table for the sql store
+	APIKeyHeader     string                `yaml:"api_key_header"`
+	Rules            []RateLimitRuleConfig `yaml:"rules"`           // First matching rule wins
+	TrustedProxies   []string              `yaml:"trusted_proxies"` // IPs or CIDRs whose X-Forwarded-For is honoured
+}
+
+// RateLimitRuleConfig overrides the limit for matching requests
+type RateLimitRuleConfig struct {
+	Path     string `yaml:"path"` // HTTP path or gRPC method; trailing * matches a prefix
+	Method   string `yaml:"method"`
+	APIKey   string `yaml:"api_key"` // Name of a key under auth.api_keys.keys
+	Requests int    `yaml:"requests"`
+	Window   string `yaml:"window"` // Defaults to the rate_limit window
+}
+
+// CompressionConfig holds response compression and request decompression settings
+type CompressionConfig struct {
+	MiddlewareToggle `yaml:",inline"`
+	Encodings        []string `yaml:"encodings"`         // Preference order; defaults to zstd, gzip, deflate
+	Level            int      `yaml:"level"`             // 0 = encoder default
+	MinSize          int      `yaml:"min_size"`          // Smaller responses are sent uncompressed
+	ContentTypes     []string `yaml:"content_types"`     // Allowlist; a trailing /* matches a type prefix
+	MaxDecodedBytes  int64    `yaml:"max_decoded_bytes"` // Cap on decompressed request bodies (default: 10 MiB)
+}
+
+// BodyLimitConfig holds request size limit settings
+type BodyLimitConfig struct {
+	MiddlewareToggle `yaml:",inline"`
+	MaxBytes         int64 `yaml:"max_bytes"`
+}
+
+// IdempotencyConfig holds Idempotency-Key replay settings
+type IdempotencyConfig struct {
+	MiddlewareToggle `yaml:",inline"`
+	Header           string   `yaml:"header"`  // Default: Idempotency-Key (gRPC metadata: idempotency-key)
+	TTL              string   `yaml:"ttl"`     // Go duration responses are kept for replay (default: 24h)
+	Lease            string   `yaml:"lease"`   // Go duration a key stays reserved by an unfinished request (default: 1m)
+	Methods          []string `yaml:"methods"` // HTTP methods honoring the header (default: POST)
+	Store            string   `yaml:"store"`   // sql or memory (default: sql)
+	Table            string   `yaml:"table"`   // Response table for the sql store (default: apiright_idempotency)
 }
 
 // GenerationConfig holds generation configuration
@@ -68,7 +201,116 @@ type GenerationConfig struct {
 	GenerateTests bool     `yaml:"generate_tests"`
 	GenerateDocs  bool     `yaml:"generate_docs"`
 	Validation    bool     `yaml:"validation"`
-	Middleware    []string `yaml:"middleware"`
+	Middleware    []string `yaml:"middleware"` // Deprecated: use server.middleware
+}
+
+// AuditConfig holds audit log configuration
+type AuditConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	Table         string   `yaml:"table"`
+	Tables        []string `yaml:"tables"`         // Tables to audit (empty = all)
+	RetentionDays int      `yaml:"retention_days"` // 0 = keep forever
+	Roles         []string `yaml:"roles"`          // Roles that may read /_audit (default: admin)
+}
+
+// CacheConfig holds HTTP response cache configuration. Only tables with a
+// cache_ttl under tables are cached.
+type CacheConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Store      string `yaml:"store"`       // memory (default)
+	MaxEntries int    `yaml:"max_entries"` // LRU capacity of the memory store (default: 10000)
+}
+
+// EncryptionConfig holds field-level encryption configuration
+type EncryptionConfig struct {
+	KeyringFile string `yaml:"keyring_file"` // Keyring for @encrypted columns (default: keyring.yaml)
+}
+
+// TelemetryConfig holds OpenTelemetry tracing and metrics configuration
+type TelemetryConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	ServiceName     string   `yaml:"service_name"`     // Default: project name
+	Exporter        string   `yaml:"exporter"`         // otlp or stdout
+	Protocol        string   `yaml:"protocol"`         // OTLP transport: http or grpc
+	Endpoint        string   `yaml:"endpoint"`         // Default: localhost:4318 (http) or localhost:4317 (grpc)
+	Insecure        *bool    `yaml:"insecure"`         // Disable TLS to the collector (default: true)
+	SampleRatio     *float64 `yaml:"sample_ratio"`     // Fraction of new traces to sample (default: 1)
+	MetricsInterval string   `yaml:"metrics_interval"` // Go duration between metric exports (default: 15s)
+}
+
+// AuthConfig holds authentication configuration
+type AuthConfig struct {
+	Enabled     bool            `yaml:"enabled"`
+	PublicPaths []string        `yaml:"public_paths"` // HTTP paths or gRPC methods; trailing * matches a prefix
+	RolesClaim  string          `yaml:"roles_claim"`
+	JWT         JWTConfig       `yaml:"jwt"`
+	APIKeys     APIKeyConfig    `yaml:"api_keys"`
+	Basic       BasicAuthConfig `yaml:"basic"`
+}
+
+// JWTConfig holds JWT bearer token configuration
+type JWTConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Algorithm string `yaml:"algorithm"` // HS256 or RS256
+	Secret    string `yaml:"secret"`
+	KeyFile   string `yaml:"key_file"`
+	JWKSFile  string `yaml:"jwks_file"`
+	Issuer    string `yaml:"issuer"`
+	Audience  string `yaml:"audience"`
+	Leeway    int    `yaml:"leeway"` // Clock skew tolerance in seconds
+}
+
+// APIKeyConfig holds API key configuration
+type APIKeyConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Header  string        `yaml:"header"`
+	Keys    []APIKeyEntry `yaml:"keys"`
+	Table   string        `yaml:"table"` // Optional table with name, key_hash and roles columns
+}
+
+// APIKeyEntry holds a single API key, stored as a hex SHA-256 hash
+type APIKeyEntry struct {
+	Name  string   `yaml:"name"`
+	Hash  string   `yaml:"hash"`
+	Roles []string `yaml:"roles"`
+}
+
+// BasicAuthConfig holds HTTP basic auth configuration
+type BasicAuthConfig struct {
+	Enabled bool            `yaml:"enabled"`
+	Realm   string          `yaml:"realm"`
+	Users   []BasicAuthUser `yaml:"users"`
+}
+
+// BasicAuthUser holds a basic auth user, with the password stored as a bcrypt hash
+type BasicAuthUser struct {
+	Username     string   `yaml:"username"`
+	PasswordHash string   `yaml:"password_hash"`
+	Roles        []string `yaml:"roles"`
+}
+
+// PolicyConfig maps table names to their authorization policies
+type PolicyConfig map[string]TablePolicy
+
+// TablePolicy holds per-operation role requirements and row-ownership rules for a table.
+// An operation without roles is unrestricted; the role "*" allows any authenticated caller.
+type TablePolicy struct {
+	Get         []string `yaml:"get"`
+	List        []string `yaml:"list"`
+	Create      []string `yaml:"create"`
+	Update      []string `yaml:"update"`
+	Delete      []string `yaml:"delete"`
+	OwnerColumn string   `yaml:"owner_column"` // Restrict rows to those owned by the caller
+	OwnerBypass []string `yaml:"owner_bypass"` // Roles that may access all rows
+}
+
+// TableConfig holds per-table column settings
+type TableConfig struct {
+	Hidden    []string `yaml:"hidden"`    // Columns never exposed through the API
+	WriteOnly []string `yaml:"writeonly"` // Columns accepted on input but never returned
+	ReadOnly  []string `yaml:"readonly"`  // Columns returned but never set by clients
+	Encrypted []string `yaml:"encrypted"` // Columns encrypted at rest
+	CacheTTL  string   `yaml:"cache_ttl"` // Go duration Get/List responses are cached (empty = not cached)
 }
 
 // PluginConfig holds plugin configuration
@@ -148,19 +390,52 @@ func DefaultConfig() *Config {
 			SSLMode: "disable",
 		},
 		Server: ServerConfig{
-			EnableHTTP: true,
-			EnableGRPC: true,
-			EnableDocs: func() *bool { v := true; return &v }(),
-			DocsPath:   "/docs",
-			APIVersion: "v0",
-			BasePath:   "/api",
-			HTTPPort:   8080,
-			GRPCPort:   9090,
-			Host:       "localhost",
-			Timeout:    30,
+			EnableHTTP:    true,
+			EnableGRPC:    true,
+			EnableDocs:    func() *bool { v := true; return &v }(),
+			DocsPath:      "/docs",
+			EnableGraphQL: func() *bool { v := true; return &v }(),
+			GraphQLPath:   "/graphql",
+			APIVersion:    "v0",
+			BasePath:      "/api",
+			HTTPPort:      8080,
+			GRPCPort:      9090,
+			Host:          "localhost",
+			Timeout:       30,
+			Shutdown: ShutdownConfig{
+				Timeout: "30s",
+			},
 			TLS: TLSConfig{
 				Enabled: false,
 			},
+			Metrics: MetricsConfig{
+				Path: "/metrics",
+			},
+			Middleware: MiddlewareConfig{
+				RequestID: RequestIDConfig{
+					Header: "X-Request-ID",
+				},
+				RateLimit: RateLimitMiddlewareConfig{
+					Requests: 100,
+					Window:   "1m",
+					Store:    "memory",
+				},
+				Compression: CompressionConfig{
+					MinSize:         1024,
+					MaxDecodedBytes: 10 << 20,
+				},
+				BodyLimit: BodyLimitConfig{
+					MaxBytes: 1 << 20,
+				},
+				Idempotency: IdempotencyConfig{
+					Header:  "Idempotency-Key",
+					TTL:     "24h",
+					Lease:   "1m",
+					Methods: []string{"POST"},
+					Store:   "sql",
+					Table:   "apiright_idempotency",
+				},
+			},
 		},
 		Generation: GenerationConfig{
 			OutputDir: "gen",
@@ -177,6 +452,37 @@ func DefaultConfig() *Config {
 			Validation:    true,
 			Middleware:    []string{},
 		},
+		Audit: AuditConfig{
+			Enabled: false,
+			Table:   "apiright_audit",
+			Roles:   []string{"admin"},
+		},
+		Cache: CacheConfig{
+			Store:      "memory",
+			MaxEntries: 10000,
+		},
+		Encryption: EncryptionConfig{
+			KeyringFile: "keyring.yaml",
+		},
+		Telemetry: TelemetryConfig{
+			Exporter:        "otlp",
+			Protocol:        "http",
+			MetricsInterval: "15s",
+		},
+		Auth: AuthConfig{
+			Enabled:     false,
+			PublicPaths: defaultPublicPaths("/docs"),
+			RolesClaim:  "roles",
+			JWT: JWTConfig{
+				Algorithm: "HS256",
+			},
+			APIKeys: APIKeyConfig{
+				Header: "X-API-Key",
+			},
+			Basic: BasicAuthConfig{
+				Realm: "apiright",
+			},
+		},
 		Plugins: []PluginConfig{},
 	}
 }
@@ -219,6 +525,16 @@ func applyDefaults(config *Config) {
 	if config.Server.DocsPath == "" {
 		config.Server.DocsPath = "/docs"
 	}
+	if config.Server.EnableGraphQL == nil {
+		config.Server.EnableGraphQL = new(bool)
+		*config.Server.EnableGraphQL = true
+	}
+	if config.Server.GraphQLPath == "" {
+		config.Server.GraphQLPath = "/graphql"
+	}
+	if config.Server.Metrics.Path == "" {
+		config.Server.Metrics.Path = "/metrics"
+	}
 	if config.Server.APIVersion == "" {
 		config.Server.APIVersion = "v0"
 	}
@@ -237,6 +553,58 @@ func applyDefaults(config *Config) {
 	if config.Server.Timeout == 0 {
 		config.Server.Timeout = 30
 	}
+	if config.Server.Shutdown.Timeout == "" {
+		config.Server.Shutdown.Timeout = "30s"
+	}
+
+	// Middleware defaults
+	mw := &config.Server.Middleware
+	if mw.RequestID.Header == "" {
+		mw.RequestID.Header = "X-Request-ID"
+	}
+	if mw.RateLimit.Requests == 0 {
+		mw.RateLimit.Requests = 100
+	}
+	if mw.RateLimit.Window == "" {
+		mw.RateLimit.Window = "1m"
+	}
+	if mw.RateLimit.Store == "" {
+		mw.RateLimit.Store = "memory"
+	}
+	if mw.Compression.MinSize == 0 {
+		mw.Compression.MinSize = 1024
+	}
+	if mw.Compression.MaxDecodedBytes == 0 {
+		mw.Compression.MaxDecodedBytes = 10 << 20
+	}
+	if mw.BodyLimit.MaxBytes == 0 {
+		mw.BodyLimit.MaxBytes = 1 << 20
+	}
+	if mw.Idempotency.Header == "" {
+		mw.Idempotency.Header = "Idempotency-Key"
+	}
+	if mw.Idempotency.TTL == "" {
+		mw.Idempotency.TTL = "24h"
+	}
+	if mw.Idempotency.Lease == "" {
+		mw.Idempotency.Lease = "1m"
+	}
+	if len(mw.Idempotency.Methods) == 0 {
+		mw.Idempotency.Methods = []string{"POST"}
+	}
+	if mw.Idempotency.Store == "" {
+		mw.Idempotency.Store = "sql"
+	}
+	if mw.Idempotency.Table == "" {
+		mw.Idempotency.Table = "apiright_idempotency"
+	}
+	// Names listed under the deprecated generation.middleware enable the matching middleware
+	for _, name := range config.Generation.Middleware {
+		if toggle := mw.Toggle(name); toggle != nil && toggle.Enabled == nil {
+			enabled := true
+			toggle.Enabled = &enabled
+		}
+	}
 
 	// Generation defaults
 	if config.Generation.OutputDir == "" {
@@ -254,6 +622,69 @@ func applyDefaults(config *Config) {
 			"text/plain",
 		}
 	}
+
+	// Audit defaults
+	if config.Audit.Table == "" {
+		config.Audit.Table = "apiright_audit"
+	}
+	if len(config.Audit.Roles) == 0 {
+		config.Audit.Roles = []string{"admin"}
+	}
+
+	// Cache defaults
+	if config.Cache.Store == "" {
+		config.Cache.Store = "memory"
+	}
+	if config.Cache.MaxEntries == 0 {
+		config.Cache.MaxEntries = 10000
+	}
+
+	// Encryption defaults
+	if config.Encryption.KeyringFile == "" {
+		config.Encryption.KeyringFile = "keyring.yaml"
+	}
+
+	// Telemetry defaults
+	if config.Telemetry.ServiceName == "" {
+		config.Telemetry.ServiceName = config.Project.Name
+	}
+	if config.Telemetry.Exporter == "" {
+		config.Telemetry.Exporter = "otlp"
+	}
+	if config.Telemetry.Protocol == "" {
+		config.Telemetry.Protocol = "http"
+	}
+	if config.Telemetry.MetricsInterval == "" {
+		config.Telemetry.MetricsInterval = "15s"
+	}
+
+	// Auth defaults
+	if config.Auth.PublicPaths == nil {
+		config.Auth.PublicPaths = defaultPublicPaths(config.Server.DocsPath)
+	}
+	if config.Auth.RolesClaim == "" {
+		config.Auth.RolesClaim = "roles"
+	}
+	if config.Auth.JWT.Algorithm == "" {
+		config.Auth.JWT.Algorithm = "HS256"
+	}
+	if config.Auth.APIKeys.Header == "" {
+		config.Auth.APIKeys.Header = "X-API-Key"
+	}
+	if config.Auth.Basic.Realm == "" {
+		config.Auth.Basic.Realm = "apiright"
+	}
+}
+
+// defaultPublicPaths returns the routes that skip authentication by default
+func defaultPublicPaths(docsPath string) []string {
+	return []string{
+		"/health",
+		"/health/*",
+		docsPath + "*",
+		"/grpc.health.v1.Health/*",
+		"/grpc.reflection.*",
+	}
 }
 
 // ValidateConfig validates the configuration
@@ -286,15 +717,40 @@ func ValidateConfig(config *Config) error {
 	if config.Server.EnableHTTP && (config.Server.HTTPPort <= 0 || config.Server.HTTPPort > 65535) {
 		return fmt.Errorf("invalid HTTP port: %d", config.Server.HTTPPort)
 	}
-	if config.Server.EnableGRPC && (config.Server.GRPCPort <= 0 || config.Server.GRPCPort > 65535) {
+	if config.Server.SinglePort && (!config.Server.EnableHTTP || !config.Server.EnableGRPC) {
+		return fmt.Errorf("single_port requires both enable_http and enable_grpc")
+	}
+	if config.Server.EnableGRPC && !config.Server.SinglePort && (config.Server.GRPCPort <= 0 || config.Server.GRPCPort > 65535) {
 		return fmt.Errorf("invalid gRPC port: %d", config.Server.GRPCPort)
 	}
-	if config.Server.EnableHTTP && config.Server.EnableGRPC && config.Server.HTTPPort == config.Server.GRPCPort {
+	if config.Server.EnableHTTP && config.Server.EnableGRPC && !config.Server.SinglePort && config.Server.HTTPPort == config.Server.GRPCPort {
 		return fmt.Errorf("HTTP and gRPC ports cannot be the same: %d", config.Server.HTTPPort)
 	}
 	if config.Server.APIVersion == "" {
 		return fmt.Errorf("api_version cannot be empty")
 	}
+	if err := validateVersions(&config.Server); err != nil {
+		return err
+	}
+	if config.Server.JSONMapping != "" && config.Server.JSONMapping != "sqlc" && config.Server.JSONMapping != "protojson" {
+		return fmt.Errorf("invalid json_mapping: %s (must be sqlc or protojson)", config.Server.JSONMapping)
+	}
+	if config.Server.Shutdown.Timeout != "" {
+		if _, err := ParseDuration(config.Server.Shutdown.Timeout); err != nil {
+			return fmt.Errorf("invalid shutdown timeout: %w", err)
+		}
+	}
+	if config.Server.Shutdown.DrainDelay != "" {
+		if _, err := ParseDuration(config.Server.Shutdown.DrainDelay); err != nil {
+			return fmt.Errorf("invalid shutdown drain_delay: %w", err)
+		}
+	}
+	if err := validateTLS(&config.Server.TLS); err != nil {
+		return err
+	}
+	if err := validateMiddleware(&config.Server.Middleware); err != nil {
+		return err
+	}
 
 	// Validate generation config
 	if config.Generation.OutputDir == "" {
@@ -307,9 +763,261 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("content types cannot be empty")
 	}
 
+	// Validate audit config
+	if config.Audit.RetentionDays < 0 {
+		return fmt.Errorf("audit retention_days cannot be negative: %d", config.Audit.RetentionDays)
+	}
+
+	// Validate cache config
+	if config.Cache.Enabled {
+		if config.Cache.Store != "memory" {
+			return fmt.Errorf("invalid cache store: %s (must be memory)", config.Cache.Store)
+		}
+		if config.Cache.MaxEntries < 0 {
+			return fmt.Errorf("cache max_entries cannot be negative: %d", config.Cache.MaxEntries)
+		}
+	}
+	for name, table := range config.Tables {
+		if table.CacheTTL != "" {
+			if _, err := ParseDuration(table.CacheTTL); err != nil {
+				return fmt.Errorf("invalid cache_ttl for table %s: %w", name, err)
+			}
+		}
+	}
+
+	// Validate telemetry config
+	if config.Telemetry.Enabled {
+		if config.Telemetry.Exporter != "otlp" && config.Telemetry.Exporter != "stdout" {
+			return fmt.Errorf("invalid telemetry exporter: %s (must be otlp or stdout)", config.Telemetry.Exporter)
+		}
+		if config.Telemetry.Protocol != "http" && config.Telemetry.Protocol != "grpc" {
+			return fmt.Errorf("invalid telemetry protocol: %s (must be http or grpc)", config.Telemetry.Protocol)
+		}
+		if ratio := config.Telemetry.SampleRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+			return fmt.Errorf("telemetry sample_ratio must be between 0 and 1: %v", *ratio)
+		}
+		if _, err := ParseDuration(config.Telemetry.MetricsInterval); err != nil {
+			return fmt.Errorf("invalid telemetry metrics_interval: %w", err)
+		}
+	}
+
+	// Validate auth config
+	if config.Auth.Enabled {
+		if !config.Auth.JWT.Enabled && !config.Auth.APIKeys.Enabled && !config.Auth.Basic.Enabled {
+			return fmt.Errorf("auth is enabled but no jwt, api_keys or basic method is enabled")
+		}
+		if config.Auth.JWT.Enabled {
+			switch config.Auth.JWT.Algorithm {
+			case "HS256":
+				if config.Auth.JWT.Secret == "" && config.Auth.JWT.KeyFile == "" && config.Auth.JWT.JWKSFile == "" {
+					return fmt.Errorf("auth jwt HS256 requires secret, key_file or jwks_file")
+				}
+			case "RS256":
+				if config.Auth.JWT.KeyFile == "" && config.Auth.JWT.JWKSFile == "" {
+					return fmt.Errorf("auth jwt RS256 requires key_file or jwks_file")
+				}
+			default:
+				return fmt.Errorf("invalid auth jwt algorithm: %s (must be HS256 or RS256)", config.Auth.JWT.Algorithm)
+			}
+		}
+		if config.Auth.Basic.Enabled {
+			for _, user := range config.Auth.Basic.Users {
+				if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+					return fmt.Errorf("invalid password_hash for basic auth user %s: must be a bcrypt hash", user.Username)
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
+// validateMiddleware validates the server.middleware block
+// versionNamePattern matches API version names usable as a path segment and Go identifier suffix
+var versionNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// ValidateVersionName checks that name can be used as an API version
+func ValidateVersionName(name string) error {
+	if !versionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid API version name: %q (must start with a letter and contain only letters, digits and _)", name)
+	}
+	return nil
+}
+
+// validateVersions checks that older API versions have distinct names and valid dates
+func validateVersions(server *ServerConfig) error {
+	seen := map[string]bool{server.APIVersion: true}
+	for _, version := range server.Versions {
+		if err := ValidateVersionName(version.Name); err != nil {
+			return err
+		}
+		if seen[version.Name] {
+			return fmt.Errorf("duplicate API version: %s", version.Name)
+		}
+		seen[version.Name] = true
+		if version.Deprecated != "" {
+			if _, err := ParseDate(version.Deprecated); err != nil {
+				return fmt.Errorf("invalid deprecated date for API version %s: %w", version.Name, err)
+			}
+		}
+		if version.Sunset != "" {
+			if _, err := ParseDate(version.Sunset); err != nil {
+				return fmt.Errorf("invalid sunset date for API version %s: %w", version.Name, err)
+			}
+		}
+		for table, renames := range version.Renames {
+			for frozen, current := range renames {
+				if frozen == "" || current == "" {
+					return fmt.Errorf("invalid rename %q -> %q of %s in API version %s", frozen, current, table, version.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateTLS(t *TLSConfig) error {
+	if !t.Enabled {
+		return nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return fmt.Errorf("tls requires cert_file and key_file")
+	}
+	switch t.ClientAuth {
+	case "", "none", "request", "require":
+	case "verify_if_given", "require_and_verify":
+		if t.ClientCAFile == "" {
+			return fmt.Errorf("tls client_auth %q requires client_ca_file", t.ClientAuth)
+		}
+	default:
+		return fmt.Errorf("invalid tls client_auth: %s (must be one of: none, request, require, verify_if_given, require_and_verify)", t.ClientAuth)
+	}
+	switch t.MinVersion {
+	case "", "1.2", "1.3":
+	default:
+		return fmt.Errorf("invalid tls min_version: %s (must be 1.2 or 1.3)", t.MinVersion)
+	}
+	if t.ReloadInterval != "" {
+		if _, err := ParseDuration(t.ReloadInterval); err != nil {
+			return fmt.Errorf("invalid tls reload_interval: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateMiddleware(mw *MiddlewareConfig) error {
+	cors := mw.CORS
+	if cors.IsEnabled(false) && cors.AllowCredentials && slices.Contains(cors.AllowOrigins, "*") {
+		return fmt.Errorf("middleware cors cannot allow credentials for origin \"*\"")
+	}
+
+	rl := mw.RateLimit
+	if rl.IsEnabled(false) {
+		if rl.Requests == 0 {
+			return fmt.Errorf("middleware rate_limit requests cannot be 0")
+		}
+		if _, err := ParseDuration(rl.Window); err != nil {
+			return fmt.Errorf("invalid middleware rate_limit window: %w", err)
+		}
+		if rl.Store != "memory" && rl.Store != "sql" {
+			return fmt.Errorf("invalid middleware rate_limit store: %s (must be memory or sql)", rl.Store)
+		}
+		for _, proxy := range rl.TrustedProxies {
+			if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+				return fmt.Errorf("invalid middleware rate_limit trusted proxy: %s", proxy)
+			}
+		}
+		for i, rule := range rl.Rules {
+			if rule.Requests == 0 {
+				return fmt.Errorf("middleware rate_limit rule %d: requests cannot be 0", i+1)
+			}
+			if rule.Window != "" {
+				if _, err := ParseDuration(rule.Window); err != nil {
+					return fmt.Errorf("middleware rate_limit rule %d: invalid window: %w", i+1, err)
+				}
+			}
+		}
+	}
+
+	if mw.Compression.IsEnabled(false) && mw.Compression.MinSize < 0 {
+		return fmt.Errorf("middleware compression min_size cannot be negative: %d", mw.Compression.MinSize)
+	}
+	if mw.Compression.IsEnabled(false) && mw.Compression.MaxDecodedBytes < 0 {
+		return fmt.Errorf("middleware compression max_decoded_bytes cannot be negative: %d", mw.Compression.MaxDecodedBytes)
+	}
+
+	if mw.BodyLimit.IsEnabled(false) && mw.BodyLimit.MaxBytes < 0 {
+		return fmt.Errorf("middleware body_limit max_bytes cannot be negative: %d", mw.BodyLimit.MaxBytes)
+	}
+
+	idem := mw.Idempotency
+	if idem.IsEnabled(false) {
+		if _, err := ParseDuration(idem.TTL); err != nil {
+			return fmt.Errorf("invalid middleware idempotency ttl: %w", err)
+		}
+		if _, err := ParseDuration(idem.Lease); err != nil {
+			return fmt.Errorf("invalid middleware idempotency lease: %w", err)
+		}
+		if idem.Store != "memory" && idem.Store != "sql" {
+			return fmt.Errorf("invalid middleware idempotency store: %s (must be memory or sql)", idem.Store)
+		}
+	}
+
+	return nil
+}
+
+// Toggle returns the shared settings of the named middleware, or nil if the name is unknown
+func (m *MiddlewareConfig) Toggle(name string) *MiddlewareToggle {
+	switch name {
+	case "logging":
+		return &m.Logging
+	case "cors":
+		return &m.CORS.MiddlewareToggle
+	case "rate_limit":
+		return &m.RateLimit.MiddlewareToggle
+	case "telemetry":
+		return &m.Telemetry
+	case "auth":
+		return &m.Auth
+	case "compression":
+		return &m.Compression.MiddlewareToggle
+	case "body_limit":
+		return &m.BodyLimit.MiddlewareToggle
+	case "idempotency":
+		return &m.Idempotency.MiddlewareToggle
+	default:
+		return nil
+	}
+}
+
+// IsEnabled reports whether the middleware is enabled, falling back to def when unset
+func (t MiddlewareToggle) IsEnabled(def bool) bool {
+	if t.Enabled == nil {
+		return def
+	}
+	return *t.Enabled
+}
+
+// ParseDuration parses a Go duration, rejecting values that are not positive
+func ParseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive: %s", value)
+	}
+	return d, nil
+}
+
+// ParseDate parses a date (2006-01-02, midnight UTC) or an RFC 3339 timestamp
+func ParseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
 // GetDatabaseURL returns the database connection URL
 func (c *DatabaseConfig) GetDatabaseURL() string {
 	if c.URL != "" {
@@ -351,6 +1059,83 @@ func (c *ServerConfig) GetGRPCAddress() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.GRPCPort)
 }
 
+// CacheTTLs returns the response cache TTL of every table with a cache_ttl
+func (c *Config) CacheTTLs() (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for name, table := range c.Tables {
+		if table.CacheTTL == "" {
+			continue
+		}
+		ttl, err := ParseDuration(table.CacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache_ttl for table %s: %w", name, err)
+		}
+		ttls[name] = ttl
+	}
+	return ttls, nil
+}
+
+// IsAudited reports whether mutations on the given table should be audited
+func (c *AuditConfig) IsAudited(table string) bool {
+	if !c.Enabled {
+		return false
+	}
+	if len(c.Tables) == 0 {
+		return true
+	}
+	for _, t := range c.Tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles returns the roles required for an operation (get, list, create, update or delete)
+func (p *TablePolicy) Roles(operation string) []string {
+	switch operation {
+	case "get":
+		return p.Get
+	case "list":
+		return p.List
+	case "create":
+		return p.Create
+	case "update":
+		return p.Update
+	case "delete":
+		return p.Delete
+	default:
+		return nil
+	}
+}
+
+// OwnerColumn returns the ownership column configured for a table, or ""
+func (p PolicyConfig) OwnerColumn(table string) string {
+	if policy, ok := p[table]; ok {
+		return policy.OwnerColumn
+	}
+	return ""
+}
+
+// ColumnVisibility returns the configured visibility for a column, or ""
+func (t TableConfig) ColumnVisibility(column string) string {
+	switch {
+	case slices.Contains(t.Hidden, column):
+		return "hidden"
+	case slices.Contains(t.WriteOnly, column):
+		return "writeonly"
+	case slices.Contains(t.ReadOnly, column):
+		return "readonly"
+	default:
+		return ""
+	}
+}
+
+// IsEncrypted reports whether a column is configured to be encrypted at rest
+func (t TableConfig) IsEncrypted(column string) bool {
+	return slices.Contains(t.Encrypted, column)
+}
+
 // expandEnv expands environment variables in configuration values
 // Supports $VAR and ${VAR} syntax
 func expandEnv(config *Config) {
@@ -362,8 +1147,15 @@ func expandEnv(config *Config) {
 	config.Database.URL = os.ExpandEnv(config.Database.URL)
 	config.Server.Host = os.ExpandEnv(config.Server.Host)
 	config.Server.DocsPath = os.ExpandEnv(config.Server.DocsPath)
+	config.Server.GraphQLPath = os.ExpandEnv(config.Server.GraphQLPath)
+	config.Server.Metrics.Path = os.ExpandEnv(config.Server.Metrics.Path)
 	config.Server.TLS.CertFile = os.ExpandEnv(config.Server.TLS.CertFile)
 	config.Server.TLS.KeyFile = os.ExpandEnv(config.Server.TLS.KeyFile)
+	config.Server.TLS.ClientCAFile = os.ExpandEnv(config.Server.TLS.ClientCAFile)
+	config.Auth.JWT.Secret = os.ExpandEnv(config.Auth.JWT.Secret)
+	config.Auth.JWT.KeyFile = os.ExpandEnv(config.Auth.JWT.KeyFile)
+	config.Auth.JWT.JWKSFile = os.ExpandEnv(config.Auth.JWT.JWKSFile)
+	config.Encryption.KeyringFile = os.ExpandEnv(config.Encryption.KeyringFile)
 }
 
 // MergePluginConfigs merges plugin configurations
@@ -1,21 +1,21 @@
 package core
 
 import (
-	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"gopkg.in/yaml.v3"
-	"reflect"
+	"io"
+	"mime"
+	"sort"
 	"strconv"
 	"strings"
-
-	"google.golang.org/protobuf/proto"
 )
 
-// ContentNegotiatorImpl implements the ContentNegotiator interface
+// ContentNegotiatorImpl implements the ContentNegotiator interface with a
+// registry of serializers keyed by media type
 type ContentNegotiatorImpl struct {
 	supportedTypes []string
 	defaultType    string
+	serializers    map[string]Serializer
 }
 
 // XMLResponse represents a generic XML response with proper structure
@@ -32,18 +32,66 @@ type XMLElement struct {
 	Attrs    []xml.Attr   `xml:",attr"`
 }
 
-// NewContentNegotiator creates a new content negotiator
+// NewContentNegotiator creates a content negotiator with the built-in serializers
 func NewContentNegotiator() *ContentNegotiatorImpl {
-	return &ContentNegotiatorImpl{
-		supportedTypes: []string{
-			"application/json",
-			"application/xml",
-			"application/yaml",
-			"application/protobuf",
-			"text/plain",
-		},
+	cn := &ContentNegotiatorImpl{
 		defaultType: "application/json",
+		serializers: make(map[string]Serializer),
+	}
+	for _, serializer := range []Serializer{
+		jsonSerializer{},
+		xmlSerializer{},
+		yamlSerializer{},
+		protobufSerializer{},
+		textSerializer{},
+		jsonAPISerializer{},
+		halSerializer{},
+		csvSerializer{},
+		ndjsonSerializer{},
+		msgpackSerializer{},
+	} {
+		cn.Register(serializer)
+	}
+	return cn
+}
+
+// Register adds a serializer for its media types, replacing any serializer
+// registered for the same type; new types become negotiable
+func (cn *ContentNegotiatorImpl) Register(serializer Serializer) {
+	for _, mediaType := range serializer.MediaTypes() {
+		mediaType = strings.ToLower(mediaType)
+		if _, exists := cn.serializers[mediaType]; !exists && !cn.IsContentTypeSupported(mediaType) {
+			cn.supportedTypes = append(cn.supportedTypes, mediaType)
+		}
+		cn.serializers[mediaType] = serializer
+	}
+}
+
+// Serializer returns the serializer for contentType. Types with a structured
+// suffix fall back to the suffix's serializer (application/vnd.x+json -> application/json).
+func (cn *ContentNegotiatorImpl) Serializer(contentType string) (Serializer, bool) {
+	contentType = strings.ToLower(contentType)
+	if serializer, ok := cn.serializers[contentType]; ok {
+		return serializer, true
+	}
+	if suffixType := structuredSuffixType(contentType); suffixType != "" {
+		serializer, ok := cn.serializers[suffixType]
+		return serializer, ok
+	}
+	return nil, false
+}
+
+// structuredSuffixType returns the media type named by an RFC 6839 structured
+// suffix (application/hal+json -> application/json), or ""
+func structuredSuffixType(contentType string) string {
+	_, subtype, ok := strings.Cut(contentType, "/")
+	if !ok {
+		return ""
+	}
+	if i := strings.LastIndex(subtype, "+"); i >= 0 && i < len(subtype)-1 {
+		return "application/" + subtype[i+1:]
 	}
+	return ""
 }
 
 // SupportedTypes returns the list of supported content types
@@ -53,126 +101,131 @@ func (cn *ContentNegotiatorImpl) SupportedTypes() []string {
 
 // SerializeResponse serializes data to the specified content type
 func (cn *ContentNegotiatorImpl) SerializeResponse(data any, contentType string) ([]byte, error) {
-	switch contentType {
-	case "application/json":
-		return json.Marshal(data)
-	case "application/xml":
-		return cn.serializeToXML(data)
-	case "application/yaml":
-		return yaml.Marshal(data)
-	case "text/plain":
-		return []byte(fmt.Sprintf("%v", data)), nil
-	case "application/protobuf":
-		return cn.serializeProtobuf(data)
-	default:
+	serializer, ok := cn.Serializer(contentType)
+	if !ok {
 		return nil, fmt.Errorf("unsupported content type: %s", contentType)
 	}
+	return serializer.Marshal(data)
 }
 
 // DeserializeRequest deserializes data from the specified content type
 func (cn *ContentNegotiatorImpl) DeserializeRequest(data []byte, contentType string, target any) error {
-	switch contentType {
-	case "application/json":
-		return json.Unmarshal(data, target)
-	case "application/xml":
-		return xml.Unmarshal(data, target)
-	case "application/yaml":
-		return yaml.Unmarshal(data, target)
-	case "text/plain":
-		// For plain text, try to convert to string and set if target is string pointer
-		if strPtr, ok := target.(*string); ok {
-			*strPtr = string(data)
-			return nil
-		}
-		return fmt.Errorf("plain text deserialization only supports *string target")
-	case "application/protobuf":
-		return cn.deserializeProtobuf(data, target)
-	default:
+	serializer, ok := cn.Serializer(contentType)
+	if !ok {
 		return fmt.Errorf("unsupported content type: %s", contentType)
 	}
+	return serializer.Unmarshal(data, target)
+}
+
+// NewRowWriter returns a writer streaming rows to w in contentType
+func (cn *ContentNegotiatorImpl) NewRowWriter(contentType string, w io.Writer) (RowWriter, error) {
+	if serializer, ok := cn.Serializer(contentType); ok {
+		if streaming, ok := serializer.(StreamingSerializer); ok {
+			return streaming.NewRowWriter(w), nil
+		}
+	}
+	return nil, fmt.Errorf("content type %s cannot be streamed", contentType)
+}
+
+// NewRowReader returns a reader for the rows of a bulk import body in contentType
+func (cn *ContentNegotiatorImpl) NewRowReader(contentType string, r io.Reader) (RowReader, error) {
+	if serializer, ok := cn.Serializer(contentType); ok {
+		if bulk, ok := serializer.(BulkSerializer); ok {
+			return bulk.NewRowReader(r), nil
+		}
+	}
+	return nil, fmt.Errorf("content type %s cannot be imported", contentType)
+}
+
+// IsStreamable reports whether List responses in contentType can be written row by row
+func (cn *ContentNegotiatorImpl) IsStreamable(contentType string) bool {
+	serializer, ok := cn.Serializer(contentType)
+	_, streaming := serializer.(StreamingSerializer)
+	return ok && streaming
+}
+
+// IsBulkImport reports whether a request body in contentType holds rows to import
+func (cn *ContentNegotiatorImpl) IsBulkImport(contentType string) bool {
+	serializer, ok := cn.Serializer(contentType)
+	_, bulk := serializer.(BulkSerializer)
+	return ok && bulk
+}
+
+// MediaType is a negotiated content type with the parameters of the accepted
+// media range, such as charset or version
+type MediaType struct {
+	Type   string
+	Params map[string]string
 }
 
 // DetectContentType detects content type from Accept header
 func (cn *ContentNegotiatorImpl) DetectContentType(header string) string {
-	if header == "" {
-		return cn.defaultType
-	}
+	return cn.NegotiateMediaType(header).Type
+}
 
-	// Parse Accept header with q-value support
+// NegotiateMediaType picks the supported type the Accept header prefers. Media
+// ranges are ordered by q-value, then by position; parameters other than q
+// are kept. A type with a +json or +xml suffix is accepted as itself when
+// its suffix type is supported.
+func (cn *ContentNegotiatorImpl) NegotiateMediaType(header string) MediaType {
 	type acceptOption struct {
 		typeName string
+		params   map[string]string
 		quality  float64
 	}
 
 	var options []acceptOption
+	for _, part := range strings.Split(header, ",") {
+		typeName, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
 
-	// Split by comma to handle multiple types
-	parts := strings.Split(header, ",")
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-
-		// Extract type and q-value
-		typeAndParams := strings.SplitN(part, ";", 2)
-		typeName := strings.TrimSpace(typeAndParams[0])
-
-		// Default quality is 1.0
+		// Default quality is 1.0; q=0 options are not acceptable
 		quality := 1.0
-
-		// Parse q-value if present
-		if len(typeAndParams) > 1 {
-			qPart := strings.TrimSpace(typeAndParams[1])
-			if strings.HasPrefix(qPart, "q=") {
-				qValue := strings.TrimPrefix(qPart, "q=")
-				if parsedQ, err := strconv.ParseFloat(qValue, 64); err == nil {
-					quality = parsedQ
-				}
+		if q, ok := params["q"]; ok {
+			if parsedQ, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsedQ
 			}
+			delete(params, "q")
 		}
-
-		// Skip q=0 options (not acceptable)
 		if quality > 0 {
-			options = append(options, acceptOption{typeName: typeName, quality: quality})
+			options = append(options, acceptOption{typeName: typeName, params: params, quality: quality})
 		}
 	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].quality > options[j].quality
+	})
 
-	// Sort by quality (highest first)
-	for i := 0; i < len(options)-1; i++ {
-		for j := i + 1; j < len(options); j++ {
-			if options[j].quality > options[i].quality {
-				options[i], options[j] = options[j], options[i]
-			}
-		}
-	}
-
-	// Find best match
 	for _, opt := range options {
 		acceptedType := opt.typeName
-
-		// Check for wildcards
 		if acceptedType == "*/*" {
-			return cn.defaultType
+			return MediaType{Type: cn.defaultType, Params: opt.params}
+		}
+
+		// Exact match
+		if cn.IsContentTypeSupported(acceptedType) {
+			return MediaType{Type: acceptedType, Params: opt.params}
 		}
 
-		// Check for exact match
-		for _, supportedType := range cn.supportedTypes {
-			if acceptedType == supportedType {
-				return supportedType
+		// Structured suffix, e.g. application/vnd.acme+json
+		if suffixType := structuredSuffixType(acceptedType); suffixType != "" && cn.IsContentTypeSupported(suffixType) {
+			if _, ok := cn.Serializer(acceptedType); ok {
+				return MediaType{Type: acceptedType, Params: opt.params}
 			}
 		}
 
-		// Check for type wildcard (e.g., "application/*")
-		if strings.HasSuffix(acceptedType, "/*") {
-			prefix := strings.TrimSuffix(acceptedType, "/*")
+		// Type wildcard, e.g. application/*
+		if prefix, ok := strings.CutSuffix(acceptedType, "/*"); ok {
 			for _, supportedType := range cn.supportedTypes {
 				if strings.HasPrefix(supportedType, prefix+"/") {
-					return supportedType
+					return MediaType{Type: supportedType, Params: opt.params}
 				}
 			}
 		}
 	}
 
-	// Fallback to default
-	return cn.defaultType
+	return MediaType{Type: cn.defaultType}
 }
 
 // Constants for content types
@@ -182,6 +235,11 @@ const (
 	ContentTypeYAML     = "application/yaml"
 	ContentTypeProtobuf = "application/protobuf"
 	ContentTypeText     = "text/plain"
+	ContentTypeJSONAPI  = "application/vnd.api+json"
+	ContentTypeHAL      = "application/hal+json"
+	ContentTypeCSV      = "text/csv"
+	ContentTypeNDJSON   = "application/x-ndjson"
+	ContentTypeMsgpack  = "application/msgpack"
 	ContentTypeHTML     = "text/html"
 	ContentTypeForm     = "application/x-www-form-urlencoded"
 )
@@ -192,6 +250,7 @@ type ContentInfo struct {
 	ContentLength int
 	Charset       string
 	Encoding      string
+	Version       string // Media type version parameter (e.g. application/json; version=2)
 }
 
 // ParseContentHeader parses a content-related header (Content-Type or Accept)
@@ -205,7 +264,7 @@ func ParseContentHeader(header string) *ContentInfo {
 
 	// Main content type
 	if len(parts) > 0 {
-		info.ContentType = strings.TrimSpace(parts[0])
+		info.ContentType = strings.ToLower(strings.TrimSpace(parts[0]))
 	}
 
 	// Parameters
@@ -215,6 +274,8 @@ func ParseContentHeader(header string) *ContentInfo {
 			info.Charset = strings.TrimPrefix(part, "charset=")
 		} else if strings.HasPrefix(part, "encoding=") {
 			info.Encoding = strings.TrimPrefix(part, "encoding=")
+		} else if strings.HasPrefix(part, "version=") {
+			info.Version = strings.TrimPrefix(part, "version=")
 		}
 	}
 
@@ -240,214 +301,3 @@ func (cn *ContentNegotiatorImpl) SetSupportedTypes(types []string) {
 func (cn *ContentNegotiatorImpl) SetDefaultType(defaultType string) {
 	cn.defaultType = defaultType
 }
-
-// serializeProtobuf serializes data to protobuf format
-func (cn *ContentNegotiatorImpl) serializeProtobuf(data any) ([]byte, error) {
-	// Check if data is already a proto.Message
-	if protoMsg, ok := data.(proto.Message); ok {
-		return proto.Marshal(protoMsg)
-	}
-
-	// Try to handle common cases where we need to convert to protobuf
-	// This is a simple implementation - in a real scenario, you'd want to
-	// have proper mapping between Go types and protobuf messages
-
-	// For now, fall back to JSON if we can't handle it as protobuf
-	return nil, fmt.Errorf("data is not a protobuf message and no conversion available")
-}
-
-// deserializeProtobuf deserializes data from protobuf format
-func (cn *ContentNegotiatorImpl) deserializeProtobuf(data []byte, target any) error {
-	// Check if target is a pointer to a proto.Message
-	targetValue := reflect.ValueOf(target)
-	if targetValue.Kind() != reflect.Ptr {
-		return fmt.Errorf("target must be a pointer")
-	}
-
-	// Try to cast to proto.Message
-	if protoMsg, ok := target.(proto.Message); ok {
-		return proto.Unmarshal(data, protoMsg)
-	}
-
-	return fmt.Errorf("target is not a protobuf message pointer")
-}
-
-// serializeToXML converts map[string]any to XML-serializable format
-func (cn *ContentNegotiatorImpl) serializeToXML(data any) ([]byte, error) {
-	// If data is already XML-serializable, use it directly
-	switch v := data.(type) {
-	case map[string]any:
-		// Convert map to struct-based approach for XML compatibility
-		return cn.mapToXML(v)
-	default:
-		// Try to marshal directly
-		return xml.Marshal(data)
-	}
-}
-
-// mapToXML converts map[string]any to proper XML with full feature support
-func (cn *ContentNegotiatorImpl) mapToXML(data map[string]any) ([]byte, error) {
-	if data == nil {
-		return []byte("<response/>"), nil
-	}
-
-	// Convert map to XML-serializable structure
-	xmlData := cn.convertToXMLStructure(data)
-
-	// Wrap in response element
-	response := XMLResponse{
-		Data: xmlData,
-	}
-
-	// Marshal to XML
-	return xml.Marshal(response)
-}
-
-// convertToXMLStructure recursively converts data to XML-serializable format
-func (cn *ContentNegotiatorImpl) convertToXMLStructure(data any) any {
-	if data == nil {
-		return ""
-	}
-
-	v := reflect.ValueOf(data)
-
-	switch v.Kind() {
-	case reflect.Map:
-		if v.Len() == 0 {
-			return ""
-		}
-
-		// Convert map to slice of XMLElements
-		elements := make([]XMLElement, 0, v.Len())
-		for _, key := range v.MapKeys() {
-			keyStr := fmt.Sprintf("%v", key.Interface())
-			value := v.MapIndex(key).Interface()
-
-			// Skip nil values
-			if value == nil {
-				continue
-			}
-
-			convertedValue := cn.convertToXMLStructure(value)
-
-			element := XMLElement{
-				XMLName: xml.Name{Local: cn.sanitizeXMLName(keyStr)},
-			}
-
-			// Handle the converted value based on its type
-			switch val := convertedValue.(type) {
-			case []XMLElement:
-				// Nested structure
-				element.Elements = val
-			case string:
-				// Simple text content
-				element.Value = val
-			default:
-				// Other types, convert to string
-				element.Value = fmt.Sprintf("%v", val)
-			}
-
-			elements = append(elements, element)
-		}
-		return elements
-
-	case reflect.Slice, reflect.Array:
-		if v.Len() == 0 {
-			return ""
-		}
-
-		elements := make([]XMLElement, 0, v.Len())
-		for i := 0; i < v.Len(); i++ {
-			value := v.Index(i).Interface()
-			if value == nil {
-				continue
-			}
-
-			convertedValue := cn.convertToXMLStructure(value)
-
-			element := XMLElement{
-				XMLName: xml.Name{Local: "item"},
-			}
-
-			switch val := convertedValue.(type) {
-			case []XMLElement:
-				element.Elements = val
-			case string:
-				element.Value = val
-			default:
-				element.Value = fmt.Sprintf("%v", val)
-			}
-
-			elements = append(elements, element)
-		}
-		return elements
-
-	case reflect.String:
-		return cn.escapeXMLContent(v.String())
-
-	case reflect.Bool:
-		if v.Bool() {
-			return "true"
-		}
-		return "false"
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Float32, reflect.Float64:
-		return fmt.Sprintf("%v", data)
-
-	default:
-		// For other types, try to convert to string
-		return cn.escapeXMLContent(fmt.Sprintf("%v", data))
-	}
-}
-
-// sanitizeXMLName ensures XML element names are valid
-func (cn *ContentNegotiatorImpl) sanitizeXMLName(name string) string {
-	// XML names must start with letter or underscore, and can contain letters, digits, hyphens, underscores, and periods
-	var sanitized strings.Builder
-
-	// Replace invalid characters with underscore
-	for i, r := range name {
-		var valid bool
-		if i == 0 {
-			valid = (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
-		} else {
-			valid = (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
-				r == '-' || r == '_' || r == '.'
-		}
-		if !valid {
-			sanitized.WriteRune('_')
-			continue
-		}
-		sanitized.WriteRune(r)
-	}
-
-	result := sanitized.String()
-	if result == "" {
-		return "_empty"
-	}
-	return result
-}
-
-// escapeXMLContent properly escapes XML character data
-func (cn *ContentNegotiatorImpl) escapeXMLContent(content string) string {
-	var buf strings.Builder
-	for _, r := range content {
-		switch r {
-		case '&':
-			buf.WriteString("&amp;")
-		case '<':
-			buf.WriteString("&lt;")
-		case '>':
-			buf.WriteString("&gt;")
-		case '"':
-			buf.WriteString("&quot;")
-		case '\'':
-			buf.WriteString("&apos;")
-		default:
-			buf.WriteRune(r)
-		}
-	}
-	return buf.String()
-}
@@ -30,6 +30,7 @@ type ServerConfig struct {
 	GRPCPort   int
 	APIVersion string
 	BasePath   string
+	DocsPath   string // Where the server serves the docs and gen/xml schemas
 }
 
 // NewGenerationContext creates a new generation context
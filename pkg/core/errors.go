@@ -0,0 +1,359 @@
+package core
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain identifies apiright in gRPC ErrorInfo details
+const ErrorDomain = "apiright"
+
+// Sentinel errors for errors.Is; any *APIError with the same code matches
+var (
+	ErrNotFound             = &APIError{Code: StatusNotFound}
+	ErrConflict             = &APIError{Code: StatusConflict}
+	ErrValidation           = &APIError{Code: StatusUnprocessableEntity}
+	ErrUnauthorized         = &APIError{Code: StatusUnauthorized}
+	ErrForbidden            = &APIError{Code: StatusForbidden}
+	ErrPreconditionFailed   = &APIError{Code: StatusPreconditionFailed}
+	ErrRateLimited          = &APIError{Code: StatusTooManyRequests}
+	ErrPayloadTooLarge      = &APIError{Code: StatusPayloadTooLarge}
+	ErrUnsupportedMediaType = &APIError{Code: StatusUnsupportedMediaType}
+)
+
+// APIError is a typed error shared by both transports: HTTP renders it as an
+// RFC 7807 problem, gRPC as a status with error details
+type APIError struct {
+	Code       StatusCode
+	Message    string
+	Violations ValidationErrors // Field violations of a validation error
+	RetryAfter time.Duration    // When a rate-limited request may be retried
+	Err        error            // Underlying cause, never shown to clients
+}
+
+// NotFound returns an error for a missing resource
+func NotFound(format string, args ...any) *APIError {
+	return &APIError{Code: StatusNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// BadRequest returns an error for a malformed request
+func BadRequest(format string, args ...any) *APIError {
+	return &APIError{Code: StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// ReadBodyFailed returns the error for a request body that could not be read:
+// too large if a size limit stopped it, bad request otherwise
+func ReadBodyFailed(err error) *APIError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return PayloadTooLarge("Request body exceeds %d bytes", tooLarge.Limit).Wrap(err)
+	}
+	return BadRequest("failed to read request body").Wrap(err)
+}
+
+// Conflict returns an error for a request that conflicts with existing data
+func Conflict(format string, args ...any) *APIError {
+	return &APIError{Code: StatusConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validation returns an error listing the fields that failed validation
+func Validation(violations ...ValidationError) *APIError {
+	return &APIError{Code: StatusUnprocessableEntity, Message: "Validation failed", Violations: violations}
+}
+
+// Unauthorized returns an error for a missing or invalid identity
+func Unauthorized(format string, args ...any) *APIError {
+	return &APIError{Code: StatusUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden returns an error for an identity that lacks permission
+func Forbidden(format string, args ...any) *APIError {
+	return &APIError{Code: StatusForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// PreconditionFailed returns an error for a failed conditional request
+func PreconditionFailed(format string, args ...any) *APIError {
+	return &APIError{Code: StatusPreconditionFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+// RateLimited returns an error for a rejected request that may be retried after retryAfter
+func RateLimited(retryAfter time.Duration, format string, args ...any) *APIError {
+	return &APIError{Code: StatusTooManyRequests, Message: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
+
+// PayloadTooLarge returns an error for a request body over the size limit
+func PayloadTooLarge(format string, args ...any) *APIError {
+	return &APIError{Code: StatusPayloadTooLarge, Message: fmt.Sprintf(format, args...)}
+}
+
+// UnsupportedMediaType returns an error for a request body in an unsupported format or encoding
+func UnsupportedMediaType(format string, args ...any) *APIError {
+	return &APIError{Code: StatusUnsupportedMediaType, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap returns a copy of e with err as the underlying cause
+func (e *APIError) Wrap(err error) *APIError {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Code.HTTPStatus())
+	}
+	if len(e.Violations) > 0 {
+		msg += ": " + e.Violations.Error()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *APIError with the same code, so the sentinels match
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// AsAPIError returns the typed error in err's chain. Validation errors from the
+// validators are converted; other errors report false.
+func AsAPIError(err error) (*APIError, bool) {
+	var typed *APIError
+	if errors.As(err, &typed) {
+		return typed, true
+	}
+	var violations ValidationErrors
+	if errors.As(err, &violations) {
+		return Validation(violations...), true
+	}
+	var violation ValidationError
+	if errors.As(err, &violation) {
+		return Validation(violation), true
+	}
+	return nil, false
+}
+
+// ToAPIError returns the typed error in err's chain, or an internal error that
+// hides err's message from clients
+func ToAPIError(err error) *APIError {
+	if typed, ok := AsAPIError(err); ok {
+		return typed
+	}
+	return &APIError{Code: StatusInternalServerError, Message: "Internal server error", Err: err}
+}
+
+// HTTPStatus maps the code to an HTTP status
+func (c StatusCode) HTTPStatus() int {
+	switch c {
+	case StatusOK:
+		return http.StatusOK
+	case StatusNotFound:
+		return http.StatusNotFound
+	case StatusBadRequest:
+		return http.StatusBadRequest
+	case StatusUnauthorized:
+		return http.StatusUnauthorized
+	case StatusForbidden:
+		return http.StatusForbidden
+	case StatusConflict:
+		return http.StatusConflict
+	case StatusUnprocessableEntity:
+		return http.StatusUnprocessableEntity
+	case StatusPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case StatusTooManyRequests:
+		return http.StatusTooManyRequests
+	case StatusPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case StatusUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode maps the code to a gRPC status code
+func (c StatusCode) GRPCCode() codes.Code {
+	switch c {
+	case StatusOK:
+		return codes.OK
+	case StatusNotFound:
+		return codes.NotFound
+	case StatusBadRequest, StatusUnprocessableEntity, StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case StatusUnauthorized:
+		return codes.Unauthenticated
+	case StatusForbidden:
+		return codes.PermissionDenied
+	case StatusConflict:
+		return codes.AlreadyExists
+	case StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case StatusTooManyRequests, StatusPayloadTooLarge:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// String returns the machine-readable name used in problem and ErrorInfo details
+func (c StatusCode) String() string {
+	switch c {
+	case StatusOK:
+		return "ok"
+	case StatusNotFound:
+		return "not_found"
+	case StatusBadRequest:
+		return "bad_request"
+	case StatusUnauthorized:
+		return "unauthorized"
+	case StatusForbidden:
+		return "forbidden"
+	case StatusConflict:
+		return "conflict"
+	case StatusUnprocessableEntity:
+		return "validation_failed"
+	case StatusPreconditionFailed:
+		return "precondition_failed"
+	case StatusTooManyRequests:
+		return "rate_limited"
+	case StatusPayloadTooLarge:
+		return "payload_too_large"
+	case StatusUnsupportedMediaType:
+		return "unsupported_media_type"
+	default:
+		return "internal"
+	}
+}
+
+// GRPCStatus returns the gRPC status with ErrorInfo plus BadRequest,
+// PreconditionFailure or RetryInfo details; grpc-go uses it to encode e
+func (e *APIError) GRPCStatus() *status.Status {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Code.HTTPStatus())
+	}
+	st := status.New(e.Code.GRPCCode(), msg)
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: e.Code.String(), Domain: ErrorDomain}}
+	switch e.Code {
+	case StatusBadRequest, StatusUnprocessableEntity:
+		if len(e.Violations) > 0 {
+			badRequest := &errdetails.BadRequest{}
+			for _, v := range e.Violations {
+				badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       v.Field,
+					Description: v.Message,
+				})
+			}
+			details = append(details, badRequest)
+		}
+	case StatusPreconditionFailed:
+		details = append(details, &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{Type: "precondition", Description: msg}},
+		})
+	case StatusTooManyRequests:
+		if e.RetryAfter > 0 {
+			details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+		}
+	}
+
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// Problem is an RFC 7807 problem details document. Code and Errors are
+// extension members carrying the error name and field violations.
+type Problem struct {
+	XMLName   xml.Name          `json:"-" yaml:"-" xml:"urn:ietf:rfc:7807 problem"`
+	Type      string            `json:"type" yaml:"type" xml:"type"`
+	Title     string            `json:"title" yaml:"title" xml:"title"`
+	Status    int               `json:"status" yaml:"status" xml:"status"`
+	Detail    string            `json:"detail,omitempty" yaml:"detail,omitempty" xml:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty" yaml:"instance,omitempty" xml:"instance,omitempty"`
+	Code      string            `json:"code" yaml:"code" xml:"code"`
+	Errors    []ValidationError `json:"errors,omitempty" yaml:"errors,omitempty" xml:"errors>error,omitempty"`
+	RequestID string            `json:"request_id,omitempty" yaml:"request_id,omitempty" xml:"request_id,omitempty"`
+}
+
+// Problem returns the problem document for e; instance is the request path
+func (e *APIError) Problem(instance string) Problem {
+	httpStatus := e.Code.HTTPStatus()
+	detail := e.Message
+	if detail == "" {
+		detail = http.StatusText(httpStatus)
+	}
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(httpStatus),
+		Status:   httpStatus,
+		Detail:   detail,
+		Instance: instance,
+		Code:     e.Code.String(),
+		Errors:   e.Violations,
+	}
+}
+
+// String renders the problem for text/plain responses
+func (p Problem) String() string {
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+}
+
+// WriteError writes err as a problem document: application/problem+json by
+// default and for +json types, CSV and NDJSON, application/problem+xml for
+// XML and +xml types, a JSON:API error document for JSON:API, or another
+// negotiated format
+func WriteError(w http.ResponseWriter, r *http.Request, cn *ContentNegotiatorImpl, err error) {
+	typed := ToAPIError(err)
+	problem := typed.Problem(r.URL.Path)
+	problem.RequestID = RequestIDFromContext(r.Context())
+
+	contentType := cn.DetectContentType(r.Header.Get("Accept"))
+	var body any = problem
+	if contentType == ContentTypeJSONAPI {
+		body = problem.JSONAPIErrors()
+	}
+	data, serializeErr := cn.SerializeResponse(body, contentType)
+	jsonProblem := contentType == "application/json" || cn.IsStreamable(contentType) ||
+		strings.HasSuffix(contentType, "+json") && contentType != ContentTypeJSONAPI
+	if serializeErr != nil || jsonProblem {
+		contentType = "application/json"
+		data, _ = cn.SerializeResponse(problem, contentType)
+	}
+
+	switch {
+	case contentType == "application/json":
+		contentType = "application/problem+json"
+	case contentType == "application/xml" || strings.HasSuffix(contentType, "+xml"):
+		contentType = "application/problem+xml"
+	}
+
+	if typed.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((typed.RetryAfter+time.Second-1)/time.Second)))
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(problem.Status)
+	_, _ = w.Write(data)
+}
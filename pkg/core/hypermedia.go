@@ -0,0 +1,207 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Resource describes a table for the JSON:API and HAL formats and bulk imports
+type Resource struct {
+	Type        string // Table name, used as JSON:API type and in resource URLs
+	PrimaryKey  string
+	Relations   []Relation
+	ColumnTypes map[string]string // OpenAPI type of each column; CSV cells are parsed with it
+	Element     string            // XML element of a row (e.g. "post"); lists are a Type element
+	Columns     []Column          // Columns in table order, mapped onto XML elements
+}
+
+// Relation is a to-one relationship through a foreign key column
+type Relation struct {
+	Name   string // Relationship name (e.g. "author" for author_id)
+	Column string // Foreign key column
+	Type   string // Referenced table
+}
+
+// Hypermedia holds the URLs hypermedia documents link to
+type Hypermedia struct {
+	BasePath string // Prefix of resource URLs (e.g. "/api/v0")
+	Self     string // URL of the current request
+	Page     *Page  // Set for list responses
+}
+
+// Page is the limit and offset a list was read with
+type Page struct {
+	Limit  int32
+	Offset int32
+}
+
+// JSONAPIDocument wraps a row (map[string]any) or rows ([]map[string]any) in
+// a JSON:API document with relationships and pagination links
+func JSONAPIDocument(res Resource, data any, hm Hypermedia) map[string]any {
+	switch rows := data.(type) {
+	case map[string]any:
+		return map[string]any{
+			"data":  jsonAPIResource(res, rows, hm.BasePath),
+			"links": map[string]any{"self": hm.Self},
+		}
+	case []map[string]any:
+		resources := make([]any, 0, len(rows))
+		for _, row := range rows {
+			resources = append(resources, jsonAPIResource(res, row, hm.BasePath))
+		}
+		return map[string]any{
+			"data":  resources,
+			"links": hm.pageLinks(len(rows), func(href string) any { return href }),
+			"meta":  map[string]any{"count": len(rows)},
+		}
+	default:
+		return map[string]any{"data": nil, "links": map[string]any{"self": hm.Self}}
+	}
+}
+
+// jsonAPIResource renders a row as a resource object; foreign key columns
+// become relationships instead of attributes
+func jsonAPIResource(res Resource, row map[string]any, basePath string) map[string]any {
+	foreignKeys := make(map[string]bool, len(res.Relations))
+	relationships := make(map[string]any, len(res.Relations))
+	for _, rel := range res.Relations {
+		foreignKeys[rel.Column] = true
+		value := row[rel.Column]
+		if value == nil {
+			relationships[rel.Name] = map[string]any{"data": nil}
+			continue
+		}
+		id := fmt.Sprint(value)
+		relationships[rel.Name] = map[string]any{
+			"data":  map[string]any{"type": rel.Type, "id": id},
+			"links": map[string]any{"related": resourceURL(basePath, rel.Type, id)},
+		}
+	}
+
+	attributes := make(map[string]any, len(row))
+	for column, value := range row {
+		if column != res.PrimaryKey && !foreignKeys[column] {
+			attributes[column] = value
+		}
+	}
+
+	id := ""
+	if value, ok := row[res.PrimaryKey]; ok && value != nil {
+		id = fmt.Sprint(value)
+	}
+	resource := map[string]any{
+		"type":       res.Type,
+		"id":         id,
+		"attributes": attributes,
+		"links":      map[string]any{"self": resourceURL(basePath, res.Type, id)},
+	}
+	if len(relationships) > 0 {
+		resource["relationships"] = relationships
+	}
+	return resource
+}
+
+// HALDocument renders a row as a HAL resource with _links, or rows as a
+// collection with the rows under _embedded and pagination links
+func HALDocument(res Resource, data any, hm Hypermedia) map[string]any {
+	switch rows := data.(type) {
+	case map[string]any:
+		return halResource(res, rows, hm.BasePath)
+	case []map[string]any:
+		embedded := make([]any, 0, len(rows))
+		for _, row := range rows {
+			embedded = append(embedded, halResource(res, row, hm.BasePath))
+		}
+		return map[string]any{
+			"_links":    hm.pageLinks(len(rows), func(href string) any { return map[string]any{"href": href} }),
+			"_embedded": map[string]any{res.Type: embedded},
+			"count":     len(rows),
+		}
+	default:
+		return map[string]any{"_links": map[string]any{"self": map[string]any{"href": hm.Self}}}
+	}
+}
+
+// halResource adds self and relation links to a row
+func halResource(res Resource, row map[string]any, basePath string) map[string]any {
+	resource := make(map[string]any, len(row)+1)
+	for column, value := range row {
+		resource[column] = value
+	}
+
+	links := map[string]any{}
+	if value, ok := row[res.PrimaryKey]; ok && value != nil {
+		links["self"] = map[string]any{"href": resourceURL(basePath, res.Type, fmt.Sprint(value))}
+	}
+	for _, rel := range res.Relations {
+		if value := row[rel.Column]; value != nil {
+			links[rel.Name] = map[string]any{"href": resourceURL(basePath, rel.Type, fmt.Sprint(value))}
+		}
+	}
+	resource["_links"] = links
+	return resource
+}
+
+// pageLinks returns self, first, prev and next links of a list page; next is
+// left out once a page comes back short
+func (hm Hypermedia) pageLinks(count int, link func(href string) any) map[string]any {
+	links := map[string]any{"self": link(hm.Self)}
+	if hm.Page == nil || hm.Page.Limit <= 0 {
+		return links
+	}
+
+	limit, offset := hm.Page.Limit, hm.Page.Offset
+	links["first"] = link(hm.pageURL(limit, 0))
+	if offset > 0 {
+		links["prev"] = link(hm.pageURL(limit, max(offset-limit, 0)))
+	}
+	if count >= int(limit) {
+		links["next"] = link(hm.pageURL(limit, offset+limit))
+	}
+	return links
+}
+
+// pageURL returns the request URL with its limit and offset replaced
+func (hm Hypermedia) pageURL(limit, offset int32) string {
+	u, err := url.Parse(hm.Self)
+	if err != nil {
+		return hm.Self
+	}
+	query := u.Query()
+	query.Set("limit", strconv.Itoa(int(limit)))
+	query.Set("offset", strconv.Itoa(int(offset)))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// resourceURL returns the URL of a row (e.g. /api/v0/users/1)
+func resourceURL(basePath, table, id string) string {
+	return basePath + "/" + table + "/" + url.PathEscape(id)
+}
+
+// JSONAPIErrors renders the problem as a JSON:API error document, with one
+// error per field violation pointing at its attribute
+func (p Problem) JSONAPIErrors() map[string]any {
+	status := strconv.Itoa(p.Status)
+	if len(p.Errors) == 0 {
+		return map[string]any{"errors": []any{map[string]any{
+			"status": status,
+			"code":   p.Code,
+			"title":  p.Title,
+			"detail": p.Detail,
+		}}}
+	}
+
+	errs := make([]any, 0, len(p.Errors))
+	for _, v := range p.Errors {
+		errs = append(errs, map[string]any{
+			"status": status,
+			"code":   p.Code,
+			"title":  p.Title,
+			"detail": v.Message,
+			"source": map[string]any{"pointer": "/data/attributes/" + v.Field},
+		})
+	}
+	return map[string]any{"errors": errs}
+}
@@ -2,6 +2,11 @@ package core
 
 import (
 	"context"
+	"database/sql"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 )
 
 // Server defines the interface for both HTTP and gRPC servers
@@ -78,6 +83,36 @@ type ContentNegotiator interface {
 	DetectContentType(header string) string
 }
 
+// Serializer encodes and decodes one format for content negotiation
+type Serializer interface {
+	// MediaTypes returns the media types the serializer handles
+	MediaTypes() []string
+
+	// Marshal encodes a response
+	Marshal(data any) ([]byte, error)
+
+	// Unmarshal decodes a request body into target
+	Unmarshal(data []byte, target any) error
+}
+
+// StreamingSerializer is a Serializer that can write List responses row by row
+type StreamingSerializer interface {
+	Serializer
+	NewRowWriter(w io.Writer) RowWriter
+}
+
+// BulkSerializer is a Serializer whose request bodies hold rows to import
+type BulkSerializer interface {
+	Serializer
+	NewRowReader(r io.Reader) RowReader
+}
+
+// SerializerProvider defines the interface for plugins that add formats to content negotiation
+type SerializerProvider interface {
+	// Serializers returns the serializers to register; they replace built-ins for the same media types
+	Serializers() []Serializer
+}
+
 // Validator defines the interface for data validation
 type Validator interface {
 	// Validate validates the given data
@@ -120,6 +155,7 @@ type Table struct {
 	PrimaryKey  []string     `json:"primary_key"`
 	Indexes     []Index      `json:"indexes"`
 	ForeignKeys []ForeignKey `json:"foreign_keys"`
+	OwnerColumn string       `json:"owner_column,omitempty"` // Set from policies; rows are scoped to their owner
 }
 
 // Column represents a database column
@@ -129,6 +165,25 @@ type Column struct {
 	Nullable      bool   `json:"nullable"`
 	Default       string `json:"default"`
 	AutoIncrement bool   `json:"auto_increment"`
+	Visibility    string `json:"visibility,omitempty"` // "", hidden, writeonly or readonly
+	Encrypted     bool   `json:"encrypted,omitempty"`  // Encrypted at rest by generated adapters
+}
+
+// Column visibility values
+const (
+	VisibilityHidden    = "hidden"    // Never read or written through the API
+	VisibilityWriteOnly = "writeonly" // Accepted on input, never returned
+	VisibilityReadOnly  = "readonly"  // Returned, but ignored on input
+)
+
+// IsReadable reports whether the column may be returned to clients
+func (c Column) IsReadable() bool {
+	return c.Visibility != VisibilityHidden && c.Visibility != VisibilityWriteOnly
+}
+
+// IsWritable reports whether clients may set the column
+func (c Column) IsWritable() bool {
+	return c.Visibility != VisibilityHidden && c.Visibility != VisibilityReadOnly
 }
 
 // Index represents a database index
@@ -207,6 +262,10 @@ const (
 	StatusInternalServerError
 	StatusConflict
 	StatusUnprocessableEntity
+	StatusPreconditionFailed
+	StatusTooManyRequests
+	StatusPayloadTooLarge
+	StatusUnsupportedMediaType
 )
 
 // ProtoExtension defines the interface for protobuf extensions
@@ -221,8 +280,208 @@ type ProtoExtension interface {
 	Imports() []string
 }
 
+// Execer executes statements, such as *sql.DB, *sql.Tx or a generated adapter's connection
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Auditor defines the interface for recording data mutations
+type Auditor interface {
+	// Record stores an audit event for a single mutation through conn, the
+	// mutation's transaction, so the entry commits or rolls back with it; a nil
+	// conn records on the auditor's own connection
+	Record(ctx context.Context, conn Execer, event AuditEvent) error
+}
+
+// FieldCipher defines the interface for encrypting columns at rest
+type FieldCipher interface {
+	// EncryptFields encrypts the named values of a request body in place, bound
+	// to the row of table with the given primary key
+	EncryptFields(values map[string]any, table string, primaryKey any, columns ...string) error
+
+	// DecryptFields decrypts the named fields of a record or slice of records
+	// read from table in place; primaryKey names the column identifying each row
+	DecryptFields(record any, table, primaryKey string, columns ...string) error
+}
+
+// Finder is implemented by generated adapters to list rows matching a filter;
+// GraphQL uses it for filters and batched relationship loads
+type Finder interface {
+	// Find returns the readable columns of the matching rows, ordered by primary key
+	Find(ctx context.Context, filter Filter) ([]map[string]any, error)
+}
+
+// Streamer is implemented by generated adapters to stream rows matching a
+// filter straight from the database; CSV and NDJSON exports use it
+type Streamer interface {
+	// Stream writes the readable column names, then each row in primary key order
+	Stream(ctx context.Context, filter Filter, w RowWriter) error
+}
+
+// RowWriter receives rows one at a time as they are read
+type RowWriter interface {
+	WriteHeader(columns []string) error
+
+	// WriteRow writes the values of one row, in header order; values is reused between rows
+	WriteRow(values []any) error
+}
+
+// RowReader reads the rows of a bulk import body one at a time
+type RowReader interface {
+	// Next returns the next row, or io.EOF after the last one
+	Next() (map[string]any, error)
+}
+
+// ResourceDescriber is implemented by generated adapters to describe their
+// table's key, foreign keys and column types for hypermedia formats and imports
+type ResourceDescriber interface {
+	Resource() Resource
+}
+
+// ProtoConverter is implemented by generated adapters to convert their rows
+// and requests to and from the table's generated protobuf messages
+type ProtoConverter interface {
+	// ProtoRequest returns an empty request message of operation (e.g. CreatePostRequest)
+	ProtoRequest(operation string) proto.Message
+	// ProtoResponse wraps the result of operation in its response message (e.g. GetPostResponse)
+	ProtoResponse(operation string, result any) (proto.Message, error)
+}
+
+// Filter selects rows by column values; a row matches if every column equals
+// one of its values
+type Filter struct {
+	Equal  map[string][]any
+	Limit  int32 // 0 = no limit
+	Offset int32
+	PerKey string // Column whose every value gets its own Limit and Offset, for batched relationship loads
+}
+
+// CacheStore defines the interface for storing cached HTTP responses
+type CacheStore interface {
+	// Get returns the response stored under key, if present and not expired
+	Get(ctx context.Context, key string) (CachedResponse, bool, error)
+
+	// Set stores a response under key for ttl
+	Set(ctx context.Context, key string, response CachedResponse, ttl time.Duration) error
+
+	// DeletePrefix removes every response whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// CacheInvalidator defines the interface adapters use to drop cached responses after writes
+type CacheInvalidator interface {
+	// InvalidateTable removes all cached responses of a table
+	InvalidateTable(ctx context.Context, table string)
+}
+
+// CachedResponse is a serialized response held by a CacheStore
+type CachedResponse struct {
+	ContentType string
+	Body        []byte
+	StoredAt    time.Time
+}
+
+// AuditOperation identifies the kind of mutation being audited
+type AuditOperation string
+
+const (
+	AuditCreate AuditOperation = "create"
+	AuditUpdate AuditOperation = "update"
+	AuditDelete AuditOperation = "delete"
+)
+
+// AuditEvent describes a mutation as seen by a generated adapter
+type AuditEvent struct {
+	Table      string
+	PrimaryKey string
+	Operation  AuditOperation
+	Before     any      // Record state before the mutation (nil for create)
+	After      any      // Record state after the mutation (nil for delete)
+	Redacted   []string // Fields whose values are masked in the log (e.g. encrypted columns)
+}
+
+// FieldChange represents a single changed field in an audit entry
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue any    `json:"old_value"`
+	NewValue any    `json:"new_value"`
+}
+
+// AuditEntry represents a stored audit log record
+type AuditEntry struct {
+	ID         int64          `json:"id"`
+	Actor      string         `json:"actor"`
+	Table      string         `json:"table"`
+	PrimaryKey string         `json:"primary_key"`
+	Operation  AuditOperation `json:"operation"`
+	Changes    []FieldChange  `json:"changes"`
+	RequestID  string         `json:"request_id"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
 // MiddlewareProvider defines the interface for plugins that provide middleware
 type MiddlewareProvider interface {
 	// Middleware returns a list of middleware instances
 	Middleware() []Middleware
 }
+
+// HealthStatus is the outcome of a health check
+type HealthStatus string
+
+const (
+	HealthOK       HealthStatus = "ok"       // Fully functional
+	HealthDegraded HealthStatus = "degraded" // Serving, but with reduced functionality
+	HealthDown     HealthStatus = "down"     // Not able to serve requests
+)
+
+// HealthCheckResult is the result of a single health check
+type HealthCheckResult struct {
+	Status  HealthStatus `json:"status"`
+	Message string       `json:"message,omitempty"`
+}
+
+// HealthChecker reports the health of a dependency for readiness probes
+type HealthChecker interface {
+	// Name identifies the check in health reports
+	Name() string
+	// Check returns the current status. ctx carries the check timeout.
+	Check(ctx context.Context) HealthCheckResult
+}
+
+// HealthCheckProvider defines the interface for plugins that provide health checks
+type HealthCheckProvider interface {
+	// HealthChecks returns the checks to add to the server's readiness probe
+	HealthChecks() []HealthChecker
+}
+
+// healthCheckFunc adapts a function to the HealthChecker interface
+type healthCheckFunc struct {
+	name  string
+	check func(ctx context.Context) HealthCheckResult
+}
+
+// NewHealthCheck returns a HealthChecker that runs check
+func NewHealthCheck(name string, check func(ctx context.Context) HealthCheckResult) HealthChecker {
+	return &healthCheckFunc{name: name, check: check}
+}
+
+// Name returns the check name
+func (hc *healthCheckFunc) Name() string {
+	return hc.name
+}
+
+// Check runs the check function
+func (hc *healthCheckFunc) Check(ctx context.Context) HealthCheckResult {
+	return hc.check(ctx)
+}
+
+// ShutdownHook runs during graceful shutdown. ctx carries the shutdown deadline.
+type ShutdownHook func(ctx context.Context) error
+
+// ShutdownHookProvider defines the interface for plugins that run code during shutdown
+type ShutdownHookProvider interface {
+	// PreShutdown runs once readiness reports not-ready, before connections are drained
+	PreShutdown(ctx context.Context) error
+	// PostShutdown runs after the HTTP and gRPC servers have stopped
+	PostShutdown(ctx context.Context) error
+}
@@ -0,0 +1,430 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ProtoMessageSpec describes a message of the generated .proto files
+type ProtoMessageSpec struct {
+	Name   string // Full name (e.g. "db.Post")
+	Fields []ProtoFieldSpec
+}
+
+// ProtoFieldSpec describes a field of a generated message
+type ProtoFieldSpec struct {
+	Name     string // Field name in the .proto file
+	Number   int32
+	Type     string // Type as written in the .proto file (e.g. "int64", "repeated db.Post")
+	JSONName string // Column the field maps to
+}
+
+// ProtoMethodSpec describes the request and response messages of one operation
+type ProtoMethodSpec struct {
+	Operation    string // get, list, create, update or delete
+	Request      ProtoMessageSpec
+	Response     string // Full name of the response message
+	ResponseType string // Type of its data field (e.g. "db.Post", "repeated db.Post", "bool")
+}
+
+// ProtoSchema holds the protobuf messages of one table. The descriptors are
+// built at runtime from the same definitions as gen/proto, so messages are
+// wire compatible with code compiled from those files.
+type ProtoSchema struct {
+	model    protoreflect.MessageDescriptor
+	requests map[string]protoreflect.MessageDescriptor
+	replies  map[string]protoreflect.MessageDescriptor
+}
+
+// NewProtoSchema builds the descriptors of a table's model message and the
+// request and response messages of its operations
+func NewProtoSchema(model ProtoMessageSpec, methods ...ProtoMethodSpec) (*ProtoSchema, error) {
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(timestamppb.File_google_protobuf_timestamp_proto); err != nil {
+		return nil, err
+	}
+
+	// Messages are grouped into one file per package, as in gen/proto
+	specs := []ProtoMessageSpec{model}
+	for _, method := range methods {
+		specs = append(specs, method.Request, ProtoMessageSpec{
+			Name:   method.Response,
+			Fields: []ProtoFieldSpec{{Name: "data", Number: 1, Type: method.ResponseType, JSONName: "data"}},
+		})
+	}
+	var packages []string
+	byPackage := map[string][]ProtoMessageSpec{}
+	for _, spec := range specs {
+		pkg, _ := splitProtoName(spec.Name)
+		if _, ok := byPackage[pkg]; !ok {
+			packages = append(packages, pkg)
+		}
+		byPackage[pkg] = append(byPackage[pkg], spec)
+	}
+
+	_, modelName := splitProtoName(model.Name)
+	var dependencies []string
+	for _, pkg := range packages {
+		file := &descriptorpb.FileDescriptorProto{
+			Name:       proto.String(pkg + "/" + strings.ToLower(modelName) + ".proto"),
+			Package:    proto.String(pkg),
+			Syntax:     proto.String("proto3"),
+			Dependency: append([]string{"google/protobuf/timestamp.proto"}, dependencies...),
+		}
+		for _, spec := range byPackage[pkg] {
+			message, err := messageDescriptor(spec)
+			if err != nil {
+				return nil, err
+			}
+			file.MessageType = append(file.MessageType, message)
+		}
+		fd, err := protodesc.NewFile(file, files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build protobuf messages of %s: %w", model.Name, err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, file.GetName())
+	}
+
+	find := func(name string) (protoreflect.MessageDescriptor, error) {
+		desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("protobuf message %s: %w", name, err)
+		}
+		return desc.(protoreflect.MessageDescriptor), nil
+	}
+	schema := &ProtoSchema{
+		requests: make(map[string]protoreflect.MessageDescriptor, len(methods)),
+		replies:  make(map[string]protoreflect.MessageDescriptor, len(methods)),
+	}
+	var err error
+	if schema.model, err = find(model.Name); err != nil {
+		return nil, err
+	}
+	for _, method := range methods {
+		if schema.requests[method.Operation], err = find(method.Request.Name); err != nil {
+			return nil, err
+		}
+		if schema.replies[method.Operation], err = find(method.Response); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// MustProtoSchema is like NewProtoSchema but panics on invalid definitions;
+// generated code uses it for package-level schemas
+func MustProtoSchema(model ProtoMessageSpec, methods ...ProtoMethodSpec) *ProtoSchema {
+	schema, err := NewProtoSchema(model, methods...)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// Model converts a row (a sqlc model or column map) to the table's message
+func (ps *ProtoSchema) Model(row any) (proto.Message, error) {
+	msg := dynamicpb.NewMessage(ps.model)
+	if err := RowToProto(row, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Request returns an empty request message of operation, or nil if the table has none
+func (ps *ProtoSchema) Request(operation string) proto.Message {
+	desc, ok := ps.requests[operation]
+	if !ok {
+		return nil
+	}
+	return dynamicpb.NewMessage(desc)
+}
+
+// Response wraps the result of operation (a row, rows or a deletion flag) in
+// the data field of its response message
+func (ps *ProtoSchema) Response(operation string, result any) (proto.Message, error) {
+	desc, ok := ps.replies[operation]
+	if !ok {
+		return nil, fmt.Errorf("no protobuf response message for %s", operation)
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if err := RowToProto(map[string]any{"data": result}, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// RowToProto sets the fields of msg from a row through its JSON form, matching
+// columns by JSON name. NULL columns are left unset.
+func RowToProto(row any, msg proto.Message) error {
+	raw, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	var values map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return fmt.Errorf("protobuf rows must be objects: %w", err)
+	}
+	return setProtoFields(msg.ProtoReflect(), values)
+}
+
+// ProtoToRow returns the populated fields of msg keyed by JSON name, with
+// timestamps as time.Time and nested messages as maps
+func ProtoToRow(msg proto.Message) map[string]any {
+	return protoRow(msg.ProtoReflect())
+}
+
+// setProtoFields sets each field of m from values
+func setProtoFields(m protoreflect.Message, values map[string]any) error {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		value := unwrapNull(values[fd.JSONName()])
+		if value == nil {
+			continue
+		}
+
+		if fd.IsList() {
+			items, ok := value.([]any)
+			if !ok {
+				return fmt.Errorf("field %s: expected a list, got %T", fd.JSONName(), value)
+			}
+			list := m.Mutable(fd).List()
+			for _, item := range items {
+				v, err := protoValue(fd, list.NewElement, unwrapNull(item))
+				if err != nil {
+					return err
+				}
+				list.Append(v)
+			}
+			continue
+		}
+
+		v, err := protoValue(fd, func() protoreflect.Value { return m.NewField(fd) }, value)
+		if err != nil {
+			return err
+		}
+		m.Set(fd, v)
+	}
+	return nil
+}
+
+// protoValue converts a JSON value to the kind of fd; newMessage returns an
+// empty message for message fields
+func protoValue(fd protoreflect.FieldDescriptor, newMessage func() protoreflect.Value, value any) (protoreflect.Value, error) {
+	invalid := func(err error) (protoreflect.Value, error) {
+		if err == nil {
+			err = fmt.Errorf("unexpected %T", value)
+		}
+		return protoreflect.Value{}, fmt.Errorf("field %s: %w", fd.JSONName(), err)
+	}
+	text := fmt.Sprint(value)
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if b, ok := value.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(text, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt64(n), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(text, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint64(n), nil
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(text, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfFloat32(float32(f)), nil
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+	case protoreflect.StringKind:
+		switch v := value.(type) {
+		case string:
+			return protoreflect.ValueOfString(v), nil
+		case json.Number:
+			return protoreflect.ValueOfString(v.String()), nil
+		default:
+			// JSON columns decoded into objects or lists go back to their JSON text
+			data, err := json.Marshal(v)
+			if err != nil {
+				return invalid(err)
+			}
+			return protoreflect.ValueOfString(string(data)), nil
+		}
+	case protoreflect.BytesKind:
+		s, ok := value.(string)
+		if !ok {
+			return invalid(nil)
+		}
+		// []byte columns marshal as base64; text read from the database does not
+		if data, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return protoreflect.ValueOfBytes(data), nil
+		}
+		return protoreflect.ValueOfBytes([]byte(s)), nil
+	case protoreflect.MessageKind:
+		nested := newMessage()
+		if fd.Message().FullName() == "google.protobuf.Timestamp" {
+			t, err := time.Parse(time.RFC3339Nano, text)
+			if err != nil {
+				return invalid(err)
+			}
+			setTimestamp(nested.Message(), t)
+			return nested, nil
+		}
+		row, ok := value.(map[string]any)
+		if !ok {
+			return invalid(nil)
+		}
+		if err := setProtoFields(nested.Message(), row); err != nil {
+			return invalid(err)
+		}
+		return nested, nil
+	}
+	return invalid(fmt.Errorf("unsupported kind %s", fd.Kind()))
+}
+
+// protoRow converts the populated fields of m to a column map
+func protoRow(m protoreflect.Message) map[string]any {
+	row := map[string]any{}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsList() {
+			list := v.List()
+			items := make([]any, 0, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				items = append(items, protoGoValue(fd, list.Get(i)))
+			}
+			row[fd.JSONName()] = items
+			return true
+		}
+		row[fd.JSONName()] = protoGoValue(fd, v)
+		return true
+	})
+	return row
+}
+
+// protoGoValue returns a field value as the Go value its column takes
+func protoGoValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	if fd.Kind() != protoreflect.MessageKind {
+		return v.Interface()
+	}
+	if fd.Message().FullName() == "google.protobuf.Timestamp" {
+		return timestampTime(v.Message())
+	}
+	return protoRow(v.Message())
+}
+
+// setTimestamp fills a google.protobuf.Timestamp, which may be a dynamic message
+func setTimestamp(m protoreflect.Message, t time.Time) {
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+	m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+}
+
+// timestampTime reads a google.protobuf.Timestamp, which may be a dynamic message
+func timestampTime(m protoreflect.Message) time.Time {
+	fields := m.Descriptor().Fields()
+	seconds := m.Get(fields.ByName("seconds")).Int()
+	nanos := m.Get(fields.ByName("nanos")).Int()
+	return time.Unix(seconds, nanos).UTC()
+}
+
+// messageDescriptor converts a message spec to its descriptor
+func messageDescriptor(spec ProtoMessageSpec) (*descriptorpb.DescriptorProto, error) {
+	_, name := splitProtoName(spec.Name)
+	message := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	for _, field := range spec.Fields {
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		typeName, repeated := strings.CutPrefix(field.Type, "repeated ")
+		if repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(field.Name),
+			Number:   proto.Int32(field.Number),
+			Label:    label.Enum(),
+			JsonName: proto.String(field.JSONName),
+		}
+		if kind, ok := protoScalarTypes[typeName]; ok {
+			fd.Type = kind.Enum()
+		} else if strings.Contains(typeName, ".") {
+			fd.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			fd.TypeName = proto.String("." + typeName)
+		} else {
+			return nil, fmt.Errorf("field %s.%s: unknown protobuf type %q", spec.Name, field.Name, field.Type)
+		}
+		message.Field = append(message.Field, fd)
+	}
+	return message, nil
+}
+
+// splitProtoName splits a full message name into its package and name
+func splitProtoName(fullName string) (string, string) {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i], fullName[i+1:]
+	}
+	return "", fullName
+}
+
+var protoScalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"double":   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"float":    descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"int64":    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint64":   descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	"int32":    descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"fixed64":  descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	"fixed32":  descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	"bool":     descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"string":   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":    descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	"uint32":   descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	"sfixed32": descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	"sfixed64": descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	"sint32":   descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64":   descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+}
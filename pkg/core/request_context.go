@@ -0,0 +1,116 @@
+package core
+
+import "context"
+
+type contextKey string
+
+const (
+	actorContextKey     contextKey = "apiright.actor"
+	requestIDContextKey contextKey = "apiright.request_id"
+	principalContextKey contextKey = "apiright.principal"
+	ownerContextKey     contextKey = "apiright.owner_scope"
+	routeContextKey     contextKey = "apiright.route"
+)
+
+// WithActor returns a copy of ctx carrying the authenticated actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey, actor)
+}
+
+// ActorFromContext returns the authenticated actor, or "" if unknown
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorContextKey).(string); ok {
+		return actor
+	}
+	return ""
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID, or "" if none was set
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDContextKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// Principal describes an authenticated caller
+type Principal struct {
+	Subject string         `json:"subject"`
+	Method  string         `json:"method"` // jwt, api_key or basic
+	Roles   []string       `json:"roles,omitempty"`
+	Claims  map[string]any `json:"claims,omitempty"`
+}
+
+// HasRole reports whether the principal carries the given role
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal.
+// The principal's subject is also stored as the actor.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	ctx = context.WithValue(ctx, principalContextKey, principal)
+	return WithActor(ctx, principal.Subject)
+}
+
+// PrincipalFromContext returns the authenticated principal, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// OwnerScope restricts data access on a table to rows owned by the caller
+type OwnerScope struct {
+	Table  string
+	Column string
+	Owner  string
+}
+
+// WithOwnerScope returns a copy of ctx restricted to the given owner scope
+func WithOwnerScope(ctx context.Context, scope OwnerScope) context.Context {
+	return context.WithValue(ctx, ownerContextKey, scope)
+}
+
+// OwnerScopeFromContext returns the owner scope for a table, if the request is restricted
+func OwnerScopeFromContext(ctx context.Context, table string) (OwnerScope, bool) {
+	scope, ok := ctx.Value(ownerContextKey).(OwnerScope)
+	if !ok || scope.Table != table {
+		return OwnerScope{}, false
+	}
+	return scope, true
+}
+
+// RouteInfo identifies the table and operation served by a request
+type RouteInfo struct {
+	Table     string
+	Operation string
+}
+
+// WithRouteInfo returns a copy of ctx with an empty RouteInfo that handlers
+// further down fill in through SetRoute. A RouteInfo already in ctx is reused,
+// so every middleware observes the same values.
+func WithRouteInfo(ctx context.Context) (context.Context, *RouteInfo) {
+	if info, ok := ctx.Value(routeContextKey).(*RouteInfo); ok {
+		return ctx, info
+	}
+	info := &RouteInfo{}
+	return context.WithValue(ctx, routeContextKey, info), info
+}
+
+// SetRoute records the table and operation of the request, keeping the first value set
+func SetRoute(ctx context.Context, table, operation string) {
+	if info, ok := ctx.Value(routeContextKey).(*RouteInfo); ok && info.Table == "" {
+		info.Table = table
+		info.Operation = operation
+	}
+}
@@ -0,0 +1,334 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSerializer encodes application/json; protobuf messages use the protojson mapping
+type jsonSerializer struct{}
+
+func (jsonSerializer) MediaTypes() []string { return []string{ContentTypeJSON} }
+
+func (jsonSerializer) Marshal(data any) ([]byte, error) {
+	if msg, ok := data.(proto.Message); ok {
+		return protojson.Marshal(msg)
+	}
+	return json.Marshal(data)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, target any) error {
+	if msg, ok := target.(proto.Message); ok {
+		return protojson.Unmarshal(data, msg)
+	}
+	return json.Unmarshal(data, target)
+}
+
+// xmlSerializer encodes application/xml; maps and slices become elements under
+// a <response> root, and schema-mapped resources (ResourceXML) encode themselves
+type xmlSerializer struct{}
+
+func (xmlSerializer) MediaTypes() []string { return []string{ContentTypeXML} }
+
+func (s xmlSerializer) Marshal(data any) ([]byte, error) { return s.serializeToXML(data) }
+
+// Unmarshal decodes the children of the root element into a *map[string]any,
+// and anything else with encoding/xml
+func (xmlSerializer) Unmarshal(data []byte, target any) error {
+	if row, ok := target.(*map[string]any); ok {
+		return unmarshalXMLMap(data, row)
+	}
+	return xml.Unmarshal(data, target)
+}
+
+// yamlSerializer encodes application/yaml
+type yamlSerializer struct{}
+
+func (yamlSerializer) MediaTypes() []string { return []string{ContentTypeYAML} }
+
+func (yamlSerializer) Marshal(data any) ([]byte, error) { return yaml.Marshal(data) }
+
+func (yamlSerializer) Unmarshal(data []byte, target any) error { return yaml.Unmarshal(data, target) }
+
+// protobufSerializer encodes proto.Message values as application/protobuf
+type protobufSerializer struct{}
+
+func (protobufSerializer) MediaTypes() []string { return []string{ContentTypeProtobuf} }
+
+func (s protobufSerializer) Marshal(data any) ([]byte, error) { return s.serializeProtobuf(data) }
+
+func (s protobufSerializer) Unmarshal(data []byte, target any) error {
+	return s.deserializeProtobuf(data, target)
+}
+
+// textSerializer renders values with fmt for text/plain
+type textSerializer struct{}
+
+func (textSerializer) MediaTypes() []string { return []string{ContentTypeText} }
+
+func (textSerializer) Marshal(data any) ([]byte, error) { return []byte(fmt.Sprintf("%v", data)), nil }
+
+// Unmarshal only supports *string targets
+func (textSerializer) Unmarshal(data []byte, target any) error {
+	if strPtr, ok := target.(*string); ok {
+		*strPtr = string(data)
+		return nil
+	}
+	return fmt.Errorf("plain text deserialization only supports *string target")
+}
+
+// jsonAPISerializer encodes JSON:API documents built by the server
+type jsonAPISerializer struct{}
+
+func (jsonAPISerializer) MediaTypes() []string { return []string{ContentTypeJSONAPI} }
+
+func (jsonAPISerializer) Marshal(data any) ([]byte, error) { return json.Marshal(data) }
+
+func (s jsonAPISerializer) Unmarshal(data []byte, target any) error {
+	return s.deserializeJSONAPI(data, target)
+}
+
+// halSerializer encodes HAL documents built by the server
+type halSerializer struct{}
+
+func (halSerializer) MediaTypes() []string { return []string{ContentTypeHAL} }
+
+func (halSerializer) Marshal(data any) ([]byte, error) { return json.Marshal(data) }
+
+func (s halSerializer) Unmarshal(data []byte, target any) error {
+	return s.deserializeHAL(data, target)
+}
+
+// serializeProtobuf serializes data to protobuf format
+func (s protobufSerializer) serializeProtobuf(data any) ([]byte, error) {
+	// Check if data is already a proto.Message
+	if protoMsg, ok := data.(proto.Message); ok {
+		return proto.Marshal(protoMsg)
+	}
+
+	// Generated adapters convert their rows with ProtoConverter before serialization
+	return nil, fmt.Errorf("data is not a protobuf message and no conversion available")
+}
+
+// deserializeProtobuf deserializes data from protobuf format
+func (s protobufSerializer) deserializeProtobuf(data []byte, target any) error {
+	// Check if target is a pointer to a proto.Message
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr {
+		return fmt.Errorf("target must be a pointer")
+	}
+
+	// Try to cast to proto.Message
+	if protoMsg, ok := target.(proto.Message); ok {
+		return proto.Unmarshal(data, protoMsg)
+	}
+
+	return fmt.Errorf("target is not a protobuf message pointer")
+}
+
+// deserializeJSONAPI decodes the attributes of a JSON:API resource document
+// into target; relationships are sent as their foreign key attributes
+func (s jsonAPISerializer) deserializeJSONAPI(data []byte, target any) error {
+	var document struct {
+		Data *struct {
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	if document.Data == nil || len(document.Data.Attributes) == 0 {
+		return fmt.Errorf("JSON:API document has no data.attributes")
+	}
+	return json.Unmarshal(document.Data.Attributes, target)
+}
+
+// deserializeHAL decodes a HAL resource into target without its _links and _embedded
+func (s halSerializer) deserializeHAL(data []byte, target any) error {
+	var resource map[string]json.RawMessage
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return err
+	}
+	delete(resource, "_links")
+	delete(resource, "_embedded")
+	stripped, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(stripped, target)
+}
+
+// serializeToXML converts map[string]any to XML-serializable format
+func (s xmlSerializer) serializeToXML(data any) ([]byte, error) {
+	// If data is already XML-serializable, use it directly
+	switch v := data.(type) {
+	case map[string]any:
+		// Convert map to struct-based approach for XML compatibility
+		return s.mapToXML(v)
+	case xml.Marshaler:
+		return xml.Marshal(v)
+	default:
+		// Slices have no root element of their own
+		if kind := reflect.ValueOf(data).Kind(); kind == reflect.Slice || kind == reflect.Array {
+			return xml.Marshal(XMLResponse{Data: s.convertToXMLStructure(data)})
+		}
+		// Try to marshal directly
+		return xml.Marshal(data)
+	}
+}
+
+// mapToXML converts map[string]any to proper XML with full feature support
+func (s xmlSerializer) mapToXML(data map[string]any) ([]byte, error) {
+	if data == nil {
+		return []byte("<response/>"), nil
+	}
+
+	// Convert map to XML-serializable structure
+	xmlData := s.convertToXMLStructure(data)
+
+	// Wrap in response element
+	response := XMLResponse{
+		Data: xmlData,
+	}
+
+	// Marshal to XML
+	return xml.Marshal(response)
+}
+
+// convertToXMLStructure recursively converts data to XML-serializable format
+func (s xmlSerializer) convertToXMLStructure(data any) any {
+	if data == nil {
+		return ""
+	}
+
+	v := reflect.ValueOf(data)
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Len() == 0 {
+			return ""
+		}
+
+		// Convert map to slice of XMLElements, in key order
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		elements := make([]XMLElement, 0, v.Len())
+		for _, key := range keys {
+			keyStr := fmt.Sprintf("%v", key.Interface())
+			value := v.MapIndex(key).Interface()
+
+			// Skip nil values
+			if value == nil {
+				continue
+			}
+
+			convertedValue := s.convertToXMLStructure(value)
+
+			element := XMLElement{
+				XMLName: xml.Name{Local: s.sanitizeXMLName(keyStr)},
+			}
+
+			// Handle the converted value based on its type
+			switch val := convertedValue.(type) {
+			case []XMLElement:
+				// Nested structure
+				element.Elements = val
+			case string:
+				// Simple text content
+				element.Value = val
+			default:
+				// Other types, convert to string
+				element.Value = fmt.Sprintf("%v", val)
+			}
+
+			elements = append(elements, element)
+		}
+		return elements
+
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return ""
+		}
+
+		elements := make([]XMLElement, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value := v.Index(i).Interface()
+			if value == nil {
+				continue
+			}
+
+			convertedValue := s.convertToXMLStructure(value)
+
+			element := XMLElement{
+				XMLName: xml.Name{Local: "item"},
+			}
+
+			switch val := convertedValue.(type) {
+			case []XMLElement:
+				element.Elements = val
+			case string:
+				element.Value = val
+			default:
+				element.Value = fmt.Sprintf("%v", val)
+			}
+
+			elements = append(elements, element)
+		}
+		return elements
+
+	// Character data is escaped by encoding/xml
+	case reflect.String:
+		return v.String()
+
+	case reflect.Bool:
+		if v.Bool() {
+			return "true"
+		}
+		return "false"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("%v", data)
+
+	default:
+		// For other types, try to convert to string
+		return fmt.Sprintf("%v", data)
+	}
+}
+
+// sanitizeXMLName ensures XML element names are valid
+func (s xmlSerializer) sanitizeXMLName(name string) string {
+	// XML names must start with letter or underscore, and can contain letters, digits, hyphens, underscores, and periods
+	var sanitized strings.Builder
+
+	// Replace invalid characters with underscore
+	for i, r := range name {
+		var valid bool
+		if i == 0 {
+			valid = (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		} else {
+			valid = (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+				r == '-' || r == '_' || r == '.'
+		}
+		if !valid {
+			sanitized.WriteRune('_')
+			continue
+		}
+		sanitized.WriteRune(r)
+	}
+
+	result := sanitized.String()
+	if result == "" {
+		return "_empty"
+	}
+	return result
+}
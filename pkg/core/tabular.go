@@ -0,0 +1,401 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// csvSerializer encodes text/csv; lists stream and bodies import row by row
+type csvSerializer struct{}
+
+func (csvSerializer) MediaTypes() []string { return []string{ContentTypeCSV} }
+
+func (csvSerializer) NewRowWriter(w io.Writer) RowWriter { return &csvRowWriter{w: csv.NewWriter(w)} }
+
+// NewRowReader reads rows keyed by the header line; cells are strings that
+// ParseCell converts to column types
+func (csvSerializer) NewRowReader(r io.Reader) RowReader {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	return &csvRowReader{r: reader}
+}
+
+// ndjsonSerializer encodes application/x-ndjson; lists stream and bodies import row by row
+type ndjsonSerializer struct{}
+
+func (ndjsonSerializer) MediaTypes() []string { return []string{ContentTypeNDJSON} }
+
+func (ndjsonSerializer) NewRowWriter(w io.Writer) RowWriter { return &ndjsonRowWriter{w: w} }
+
+func (ndjsonSerializer) NewRowReader(r io.Reader) RowReader {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return &ndjsonRowReader{d: decoder}
+}
+
+// msgpackSerializer encodes application/msgpack, naming struct fields by their JSON tags
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) MediaTypes() []string { return []string{ContentTypeMsgpack} }
+
+func (msgpackSerializer) NewRowReader(r io.Reader) RowReader {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag("json")
+	return &msgpackRowReader{d: decoder}
+}
+
+// csvRowWriter writes a header line and one line per row
+type csvRowWriter struct {
+	w     *csv.Writer
+	cells []string
+}
+
+func (c *csvRowWriter) WriteHeader(columns []string) error {
+	c.cells = make([]string, len(columns))
+	if err := c.w.Write(columns); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) WriteRow(values []any) error {
+	for i, value := range values {
+		c.cells[i] = csvCell(value)
+	}
+	if err := c.w.Write(c.cells); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvCell formats a value for CSV; NULL is an empty cell
+func csvCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ndjsonRowWriter writes each row as a JSON object on its own line
+type ndjsonRowWriter struct {
+	w       io.Writer
+	columns []string
+	buf     bytes.Buffer
+}
+
+func (n *ndjsonRowWriter) WriteHeader(columns []string) error {
+	n.columns = columns
+	return nil
+}
+
+func (n *ndjsonRowWriter) WriteRow(values []any) error {
+	// Objects are written by hand to keep the column order
+	n.buf.Reset()
+	n.buf.WriteByte('{')
+	for i, column := range n.columns {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", column, err)
+		}
+		n.buf.Write(key)
+		n.buf.WriteByte(':')
+		n.buf.Write(value)
+	}
+	n.buf.WriteString("}\n")
+	_, err := n.w.Write(n.buf.Bytes())
+	return err
+}
+
+// csvRowReader reads rows keyed by the header line
+type csvRowReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func (c *csvRowReader) Next() (map[string]any, error) {
+	if c.header == nil {
+		header, err := c.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		c.header = append([]string(nil), header...)
+	}
+	record, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]any, len(c.header))
+	for i, column := range c.header {
+		row[column] = record[i]
+	}
+	return row, nil
+}
+
+// ndjsonRowReader reads one JSON object per line
+type ndjsonRowReader struct {
+	d *json.Decoder
+}
+
+func (n *ndjsonRowReader) Next() (map[string]any, error) {
+	var row map[string]any
+	if err := n.d.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// msgpackRowReader reads an array of maps, or a sequence of maps
+type msgpackRowReader struct {
+	d         *msgpack.Decoder
+	started   bool
+	remaining int // Maps left in the array, -1 for a sequence
+}
+
+func (m *msgpackRowReader) Next() (map[string]any, error) {
+	if !m.started {
+		m.started = true
+		m.remaining = -1
+		code, err := m.d.PeekCode()
+		if err != nil {
+			return nil, err
+		}
+		if msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32 {
+			if m.remaining, err = m.d.DecodeArrayLen(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.remaining == 0 {
+		return nil, io.EOF
+	}
+
+	var row map[string]any
+	if err := m.d.Decode(&row); err != nil {
+		return nil, err
+	}
+	if m.remaining > 0 {
+		m.remaining--
+	}
+	return row, nil
+}
+
+// ParseCell converts a CSV cell to the column's OpenAPI type. Empty cells are
+// NULL except in string columns; cells that don't parse are kept as text.
+func ParseCell(cell, openAPIType string) any {
+	if cell == "" && openAPIType != "string" {
+		return nil
+	}
+	switch openAPIType {
+	case "integer":
+		if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(cell, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(cell); err == nil {
+			return b
+		}
+	case "object":
+		var v any
+		if err := json.Unmarshal([]byte(cell), &v); err == nil {
+			return v
+		}
+	}
+	return cell
+}
+
+// Marshal writes a row or slice of rows as CSV, with the columns of the first
+// row in field order
+func (s csvSerializer) Marshal(data any) ([]byte, error) {
+	rows, columns, err := tabularRows(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := s.NewRowWriter(&buf)
+	if err := w.WriteHeader(columns); err != nil {
+		return nil, err
+	}
+	values := make([]any, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			values[i] = unwrapNull(row[column])
+		}
+		if err := w.WriteRow(values); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Marshal writes each element of a slice, or a single value, as a JSON line
+func (ndjsonSerializer) Marshal(data any) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(raw, &items) != nil {
+		items = []json.RawMessage{raw}
+	}
+
+	var buf bytes.Buffer
+	for _, item := range items {
+		buf.Write(item)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackSerializer) Marshal(data any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackSerializer) Unmarshal(data []byte, target any) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(target)
+}
+
+// Unmarshal decodes CSV rows into a slice target (e.g. *[]map[string]any)
+func (s csvSerializer) Unmarshal(data []byte, target any) error {
+	return readRows(s.NewRowReader(bytes.NewReader(data)), target)
+}
+
+// Unmarshal decodes NDJSON rows into a slice target (e.g. *[]map[string]any)
+func (s ndjsonSerializer) Unmarshal(data []byte, target any) error {
+	return readRows(s.NewRowReader(bytes.NewReader(data)), target)
+}
+
+// readRows reads all rows into target, converting them through JSON unless
+// target is a *[]map[string]any
+func readRows(reader RowReader, target any) error {
+	rows := []map[string]any{}
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	if target, ok := target.(*[]map[string]any); ok {
+		*target = rows
+		return nil
+	}
+
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// tabularRows converts a struct, map or slice of them to rows through their
+// JSON form, returning the keys of the first row in the order they appear
+func tabularRows(data any) ([]map[string]any, []string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(raw, &items) != nil {
+		items = []json.RawMessage{raw}
+	}
+	if len(items) == 0 {
+		return nil, nil, nil
+	}
+
+	columns, err := objectKeys(items[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		var row map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(item))
+		decoder.UseNumber()
+		if err := decoder.Decode(&row); err != nil {
+			return nil, nil, fmt.Errorf("CSV rows must be objects: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, columns, nil
+}
+
+// objectKeys returns the keys of a JSON object in document order
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("CSV rows must be objects")
+	}
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// unwrapNull returns the value of an encoded sql.Null* ({"String": "x", "Valid": true}), or NULL
+func unwrapNull(value any) any {
+	nullable, ok := value.(map[string]any)
+	if !ok || len(nullable) != 2 {
+		return value
+	}
+	valid, ok := nullable["Valid"].(bool)
+	if !ok {
+		return value
+	}
+	for key, inner := range nullable {
+		if key != "Valid" && valid {
+			return inner
+		}
+	}
+	return nil
+}
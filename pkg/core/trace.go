@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	traceContextKey  contextKey = "apiright.trace_context"
+	loggerContextKey contextKey = "apiright.logger"
+)
+
+// TraceContext holds the W3C trace context (traceparent) of a request
+type TraceContext struct {
+	TraceID  string // 32 lowercase hex characters
+	SpanID   string // 16 lowercase hex characters
+	ParentID string // Span ID of the caller, empty for a new trace
+	Flags    string // 2 hex characters, "01" = sampled
+}
+
+// NewTraceContext starts a new sampled trace
+func NewTraceContext() TraceContext {
+	return TraceContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   "01",
+	}
+}
+
+// ParseTraceparent parses a W3C traceparent header. The returned SpanID is the caller's span.
+func ParseTraceparent(header string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, fmt.Errorf("invalid traceparent: %q", header)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// Version ff is forbidden; version 00 has exactly four fields
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceContext{}, fmt.Errorf("unsupported traceparent version: %q", version)
+	}
+	if !isHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent trace id: %q", traceID)
+	}
+	if !isHex(spanID, 16) || spanID == strings.Repeat("0", 16) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent parent id: %q", spanID)
+	}
+	if !isHex(flags, 2) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent flags: %q", flags)
+	}
+
+	return TraceContext{TraceID: traceID, SpanID: spanID, Flags: flags}, nil
+}
+
+// Child returns a new span in the same trace whose parent is tc
+func (tc TraceContext) Child() TraceContext {
+	return TraceContext{
+		TraceID:  tc.TraceID,
+		SpanID:   randomHex(8),
+		ParentID: tc.SpanID,
+		Flags:    tc.Flags,
+	}
+}
+
+// Traceparent formats tc as a W3C traceparent header value
+func (tc TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%s", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// Sampled reports whether the sampled flag is set
+func (tc TraceContext) Sampled() bool {
+	b, err := hex.DecodeString(tc.Flags)
+	return err == nil && len(b) == 1 && b[0]&0x01 == 1
+}
+
+// IsValid reports whether tc carries a trace and span ID
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != "" && tc.SpanID != ""
+}
+
+// WithTraceContext returns a copy of ctx carrying the trace context
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey, tc)
+}
+
+// TraceContextFromContext returns the trace context, if one was set
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey).(TraceContext)
+	return tc, ok
+}
+
+// WithLogger returns a copy of ctx carrying a request-scoped logger
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// LoggerFromContext returns the request-scoped logger, or fallback if none was set
+func LoggerFromContext(ctx context.Context, fallback Logger) Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(Logger); ok && logger != nil {
+		return logger
+	}
+	return fallback
+}
+
+// NewRequestID returns a random UUIDv4 string
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// randomHex returns n random bytes as lowercase hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isHex reports whether s is exactly n lowercase hex characters
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -84,6 +84,54 @@ func SQLTypeToOpenAPI(sqlType string) string {
 	}
 }
 
+// SQLTypeToGraphQL maps a SQL column type onto a GraphQL scalar; Time and JSON
+// are custom scalars declared by the generated schema
+func SQLTypeToGraphQL(sqlType string) string {
+	sqlType = strings.ToLower(sqlType)
+
+	switch {
+	case strings.Contains(sqlType, "int"), strings.Contains(sqlType, "serial"):
+		return "Int"
+	case strings.Contains(sqlType, "float"), strings.Contains(sqlType, "decimal"), strings.Contains(sqlType, "numeric"), strings.Contains(sqlType, "real"), strings.Contains(sqlType, "double"):
+		return "Float"
+	case strings.Contains(sqlType, "bool"):
+		return "Boolean"
+	case strings.Contains(sqlType, "text"), strings.Contains(sqlType, "char"), strings.Contains(sqlType, "string"):
+		return "String"
+	case strings.Contains(sqlType, "date"), strings.Contains(sqlType, "time"):
+		return "Time"
+	case strings.Contains(sqlType, "json"):
+		return "JSON"
+	default:
+		return "String"
+	}
+}
+
+// SQLTypeToXSD maps a SQL column type onto the XML Schema type of its element.
+// Times are encoded as RFC 3339, binary columns as base64 and JSON columns as text.
+func SQLTypeToXSD(sqlType string) string {
+	sqlType = strings.ToLower(sqlType)
+
+	switch {
+	case strings.Contains(sqlType, "int"), strings.Contains(sqlType, "serial"):
+		return "xs:long"
+	case strings.Contains(sqlType, "decimal"), strings.Contains(sqlType, "numeric"):
+		return "xs:decimal"
+	case strings.Contains(sqlType, "float"), strings.Contains(sqlType, "real"), strings.Contains(sqlType, "double"):
+		return "xs:double"
+	case strings.Contains(sqlType, "bool"):
+		return "xs:boolean"
+	case strings.Contains(sqlType, "text"), strings.Contains(sqlType, "char"), strings.Contains(sqlType, "string"):
+		return "xs:string"
+	case strings.Contains(sqlType, "date"), strings.Contains(sqlType, "time"):
+		return "xs:dateTime"
+	case strings.Contains(sqlType, "blob"), strings.Contains(sqlType, "binary"), strings.Contains(sqlType, "bytes"):
+		return "xs:base64Binary"
+	default:
+		return "xs:string"
+	}
+}
+
 func GetExampleValue(sqlType string) any {
 	sqlType = strings.ToLower(sqlType)
 
@@ -116,3 +164,11 @@ func ToPascalCase(s string) string {
 	}
 	return strings.Join(parts, "")
 }
+
+// InternalTablePrefix marks framework-managed tables that never get generated CRUD
+const InternalTablePrefix = "apiright_"
+
+// IsInternalTable reports whether a table is managed by the framework itself
+func IsInternalTable(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), InternalTablePrefix)
+}
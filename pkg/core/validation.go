@@ -8,8 +8,8 @@ import (
 )
 
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field" yaml:"field" xml:"field"`
+	Message string `json:"message" yaml:"message" xml:"message"`
 }
 
 func (e ValidationError) Error() string {
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// XMLSchemaInstance is the namespace of the xsi:nil attribute marking NULL columns
+const XMLSchemaInstance = "http://www.w3.org/2001/XMLSchema-instance"
+
+// XMLNamespace returns the target namespace of a table's generated XSD
+func XMLNamespace(table string) string {
+	return "urn:apiright:" + table
+}
+
+// ResourceXML is a row or list of rows mapped onto the elements declared by
+// the table's XSD in gen/xml
+type ResourceXML struct {
+	resource Resource
+	data     any
+}
+
+// XMLDocument maps a row (map[string]any) or rows ([]map[string]any) onto the
+// resource's XML schema: a row is a <post> element, a list a <posts> element
+// of them. Columns follow table order, and NULL columns are xsi:nil.
+func XMLDocument(res Resource, data any) ResourceXML {
+	return ResourceXML{resource: res, data: data}
+}
+
+// MarshalXML writes the document root in the table's namespace
+func (d ResourceXML) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "xmlns"}, Value: XMLNamespace(d.resource.Type)},
+		{Name: xml.Name{Local: "xmlns:xsi"}, Value: XMLSchemaInstance},
+	}
+
+	rows, ok := d.data.([]map[string]any)
+	if !ok {
+		row, _ := d.data.(map[string]any)
+		return d.writeRow(e, row, attrs)
+	}
+
+	collection := xml.StartElement{Name: xml.Name{Local: d.resource.Type}, Attr: attrs}
+	if err := e.EncodeToken(collection); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := d.writeRow(e, row, nil); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(collection.End())
+}
+
+// writeRow writes a row element with one child per column present in the row
+func (d ResourceXML) writeRow(e *xml.Encoder, row map[string]any, attrs []xml.Attr) error {
+	start := xml.StartElement{Name: xml.Name{Local: d.resource.Element}, Attr: attrs}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, col := range d.resource.Columns {
+		value, ok := row[col.Name]
+		if !ok {
+			continue
+		}
+		element := xml.StartElement{Name: xml.Name{Local: col.Name}}
+		if value == nil {
+			element.Attr = []xml.Attr{{Name: xml.Name{Local: "xsi:nil"}, Value: "true"}}
+		}
+		if err := e.EncodeToken(element); err != nil {
+			return err
+		}
+		if value != nil {
+			text, err := xmlText(value)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", col.Name, err)
+			}
+			if err := e.EncodeToken(xml.CharData(text)); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(element.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xmlText formats a column value as its XSD lexical form; JSON columns are
+// written as JSON text
+func xmlText(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// decodeXMLElement decodes the children of start: text-only elements become
+// strings, elements with children maps (repeated names a list), and xsi:nil
+// elements nil
+func decodeXMLElement(d *xml.Decoder, start xml.StartElement) (any, error) {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == XMLSchemaInstance && attr.Name.Local == "nil" && attr.Value == "true" {
+			return nil, d.Skip()
+		}
+	}
+
+	var text []byte
+	var children map[string]any
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := decodeXMLElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = map[string]any{}
+			}
+			name := t.Name.Local
+			switch existing := children[name].(type) {
+			case nil:
+				if _, repeated := children[name]; repeated {
+					children[name] = []any{nil, value}
+				} else {
+					children[name] = value
+				}
+			case []any:
+				children[name] = append(existing, value)
+			default:
+				children[name] = []any{existing, value}
+			}
+		case xml.CharData:
+			text = append(text, t...)
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+			return string(text), nil
+		}
+	}
+}
+
+// unmarshalXMLMap decodes the children of the document root into target
+func unmarshalXMLMap(data []byte, target *map[string]any) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := decodeXMLElement(d, start)
+			if err != nil {
+				return err
+			}
+			row, ok := value.(map[string]any)
+			if !ok {
+				row = map[string]any{}
+			}
+			*target = row
+			return nil
+		}
+	}
+}
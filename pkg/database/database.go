@@ -174,6 +174,14 @@ func (d *Database) GetDB() *sql.DB {
 	return d.db
 }
 
+// Dialect returns the normalized database type (sqlite, postgres, mysql)
+func (d *Database) Dialect() string {
+	if d.config.Type == "postgresql" {
+		return "postgres"
+	}
+	return d.config.Type
+}
+
 // loadMigrations loads migration files from the migrations directory
 func (d *Database) loadMigrations() ([]Migration, error) {
 	migrationsDir := "migrations"
@@ -386,6 +394,19 @@ func (d *Database) GetMigrationStatus() (*MigrationResult, error) {
 	return result, nil
 }
 
+// CurrentVersion returns the highest applied migration version, or 0 if none were applied
+func (d *Database) CurrentVersion() (int, error) {
+	if d.db == nil {
+		return 0, fmt.Errorf("database not connected")
+	}
+
+	var version sql.NullInt64
+	if err := d.db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
 // CreateMigration creates a new migration file
 func (d *Database) CreateMigration(name string) error {
 	migrationsDir := "migrations"
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// constraintKind classifies a driver constraint violation
+type constraintKind int
+
+const (
+	constraintNone constraintKind = iota
+	constraintUnique
+	constraintForeignKey
+	constraintNotNull
+	constraintCheck
+)
+
+// mysqlColumn extracts the column from MySQL "Column 'x' cannot be null" messages
+var mysqlColumn = regexp.MustCompile(`[Cc]olumn '([^']+)'`)
+
+// TranslateError maps sql.ErrNoRows and unique, foreign key, not-null and
+// check constraint violations of the SQLite, PostgreSQL and MySQL drivers onto
+// typed core errors. Other errors are returned unchanged.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := core.AsAPIError(err); ok {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return core.NotFound("Resource not found").Wrap(err)
+	}
+
+	kind, column := classifyConstraint(err)
+	switch kind {
+	case constraintUnique:
+		return core.Conflict("A record with the same unique value already exists").Wrap(err)
+	case constraintForeignKey:
+		return core.Conflict("The record references, or is referenced by, another record").Wrap(err)
+	case constraintNotNull:
+		if column == "" {
+			column = "unknown"
+		}
+		return core.Validation(core.ValidationError{Field: column, Message: "is required"}).Wrap(err)
+	case constraintCheck:
+		return core.Validation(core.ValidationError{Field: column, Message: "violates a check constraint"}).Wrap(err)
+	}
+	return err
+}
+
+// classifyConstraint returns the constraint violation in err and the column involved, if known
+func classifyConstraint(err error) (constraintKind, string) {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return constraintUnique, ""
+		case sqlite3.ErrConstraintForeignKey:
+			return constraintForeignKey, ""
+		case sqlite3.ErrConstraintNotNull:
+			return constraintNotNull, sqliteColumn(sqliteErr.Error())
+		case sqlite3.ErrConstraintCheck:
+			return constraintCheck, ""
+		}
+		return constraintNone, ""
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return constraintUnique, pqErr.Column
+		case "23503":
+			return constraintForeignKey, pqErr.Column
+		case "23502":
+			return constraintNotNull, pqErr.Column
+		case "23514":
+			return constraintCheck, pqErr.Column
+		}
+		return constraintNone, ""
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		column := ""
+		if m := mysqlColumn.FindStringSubmatch(mysqlErr.Message); m != nil {
+			column = m[1]
+		}
+		switch mysqlErr.Number {
+		case 1062:
+			return constraintUnique, ""
+		case 1451, 1452:
+			return constraintForeignKey, ""
+		case 1048, 1364:
+			return constraintNotNull, column
+		case 3819:
+			return constraintCheck, ""
+		}
+	}
+	return constraintNone, ""
+}
+
+// sqliteColumn extracts the column from "NOT NULL constraint failed: table.column"
+func sqliteColumn(message string) string {
+	_, target, ok := strings.Cut(message, "constraint failed: ")
+	if !ok {
+		return ""
+	}
+	target, _, _ = strings.Cut(target, ",")
+	if i := strings.LastIndex(target, "."); i >= 0 {
+		target = target[i+1:]
+	}
+	return strings.TrimSpace(target)
+}
@@ -0,0 +1,300 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// Queryer runs queries; *sql.DB, *sql.Tx and sqlc's DBTX satisfy it
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Execer runs statements; *sql.DB, *sql.Tx and sqlc's DBTX satisfy it
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// UpdateColumns sets columns of the row of table whose primaryKey column is id
+func UpdateColumns(ctx context.Context, conn Execer, dialect, table, primaryKey string, id any, values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	// Sort the columns so equal updates produce equal statements
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	sets := make([]string, len(columns))
+	args := make([]any, 0, len(columns)+1)
+	for i, column := range columns {
+		args = append(args, values[column])
+		sets[i] = column + " = " + Placeholder(dialect, len(args))
+	}
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", table, strings.Join(sets, ", "), primaryKey, Placeholder(dialect, len(args)))
+	if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	return nil
+}
+
+// FindSpec describes the table a Find query reads. Names come from generated
+// code, never from clients, so they are not quoted.
+type FindSpec struct {
+	Table      string
+	Columns    []string       // Columns returned for each row
+	Filterable []string       // Columns a filter may match on
+	OrderBy    string         // Column that keeps pages stable
+	Scope      map[string]any // Conditions always applied, such as row ownership
+}
+
+// Find lists the rows of spec.Table that match filter as column maps. Filters on
+// columns outside spec.Filterable are rejected with a bad request error.
+func Find(ctx context.Context, conn Queryer, dialect string, spec FindSpec, filter core.Filter) ([]map[string]any, error) {
+	collector := &rowCollector{columns: spec.Columns, rows: []map[string]any{}}
+	if err := Stream(ctx, conn, dialect, spec, filter, collector); err != nil {
+		return nil, err
+	}
+	return collector.rows, nil
+}
+
+// Stream writes the header and then each row matching filter to w as it is
+// read, so exports never hold more than one row in memory
+func Stream(ctx context.Context, conn Queryer, dialect string, spec FindSpec, filter core.Filter, w core.RowWriter) error {
+	query, args, err := findQuery(dialect, spec, filter)
+	if err == errNoMatch {
+		return w.WriteHeader(spec.Columns)
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", spec.Table, err)
+	}
+	defer rows.Close()
+
+	if err := w.WriteHeader(spec.Columns); err != nil {
+		return err
+	}
+	values := make([]any, len(spec.Columns))
+	targets := make([]any, len(spec.Columns))
+	for rows.Next() {
+		for i := range values {
+			values[i] = nil
+			targets[i] = &values[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", spec.Table, err)
+		}
+		for i := range values {
+			// Drivers return text as []byte; copy it out of the reused buffer
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := w.WriteRow(values); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", spec.Table, err)
+	}
+	return nil
+}
+
+// Placeholder returns the n-th (1-based) bind parameter for the dialect
+func Placeholder(dialect string, n int) string {
+	if dialect == "postgres" || dialect == "postgresql" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// Placeholders returns a comma-separated list of n bind parameters
+func Placeholders(dialect string, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = Placeholder(dialect, i+1)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// errNoMatch reports a filter no row can match, such as an empty IN list
+var errNoMatch = errors.New("filter matches no rows")
+
+// findQuery builds the SELECT statement and arguments for spec and filter
+func findQuery(dialect string, spec FindSpec, filter core.Filter) (string, []any, error) {
+	var conditions []string
+	var args []any
+	placeholder := func() string { return Placeholder(dialect, len(args)) }
+
+	// Sort the columns so equal filters produce equal statements
+	columns := make([]string, 0, len(filter.Equal))
+	for column := range filter.Equal {
+		if !slices.Contains(spec.Filterable, column) {
+			return "", nil, core.BadRequest("cannot filter %s by %s", spec.Table, column)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		values := filter.Equal[column]
+		if len(values) == 0 {
+			return "", nil, errNoMatch
+		}
+		marks := make([]string, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			marks[i] = placeholder()
+		}
+		if len(marks) == 1 {
+			conditions = append(conditions, column+" = "+marks[0])
+		} else {
+			conditions = append(conditions, column+" IN ("+strings.Join(marks, ", ")+")")
+		}
+	}
+
+	scoped := make([]string, 0, len(spec.Scope))
+	for column := range spec.Scope {
+		scoped = append(scoped, column)
+	}
+	sort.Strings(scoped)
+	for _, column := range scoped {
+		args = append(args, spec.Scope[column])
+		conditions = append(conditions, column+" = "+placeholder())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if filter.PerKey != "" {
+		if !slices.Contains(spec.Filterable, filter.PerKey) {
+			return "", nil, core.BadRequest("cannot page %s by %s", spec.Table, filter.PerKey)
+		}
+		return perKeyQuery(spec, filter, where), args, nil
+	}
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "SELECT %s FROM %s%s", strings.Join(spec.Columns, ", "), spec.Table, where)
+	if spec.OrderBy != "" {
+		query.WriteString(" ORDER BY " + spec.OrderBy)
+	}
+	if filter.Limit > 0 {
+		fmt.Fprintf(&query, " LIMIT %d OFFSET %d", filter.Limit, max(filter.Offset, 0))
+	} else if filter.Offset > 0 {
+		// SQLite and MySQL only accept OFFSET after a LIMIT
+		limit := "ALL"
+		if dialect != "postgres" {
+			limit = "-1"
+			if dialect == "mysql" {
+				limit = "18446744073709551615"
+			}
+		}
+		fmt.Fprintf(&query, " LIMIT %s OFFSET %d", limit, filter.Offset)
+	}
+
+	return query.String(), args, nil
+}
+
+// perKeyQuery numbers the matching rows per value of filter.PerKey with a
+// window function and keeps the page of each value
+func perKeyQuery(spec FindSpec, filter core.Filter, where string) string {
+	columns := strings.Join(spec.Columns, ", ")
+	window := "PARTITION BY " + filter.PerKey
+	if spec.OrderBy != "" {
+		window += " ORDER BY " + spec.OrderBy
+	}
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "SELECT %s FROM (SELECT %s, ROW_NUMBER() OVER (%s) AS apiright_row FROM %s%s) AS ranked WHERE apiright_row > %d",
+		columns, columns, window, spec.Table, where, max(filter.Offset, 0))
+	if filter.Limit > 0 {
+		fmt.Fprintf(&query, " AND apiright_row <= %d", int64(max(filter.Offset, 0))+int64(filter.Limit))
+	}
+	if spec.OrderBy != "" {
+		query.WriteString(" ORDER BY " + spec.OrderBy)
+	}
+	return query.String()
+}
+
+// rowCollector is a RowWriter that keeps rows as column maps
+type rowCollector struct {
+	columns []string
+	rows    []map[string]any
+}
+
+func (c *rowCollector) WriteHeader(columns []string) error {
+	c.columns = columns
+	return nil
+}
+
+func (c *rowCollector) WriteRow(values []any) error {
+	row := make(map[string]any, len(c.columns))
+	for i, column := range c.columns {
+		row[column] = values[i]
+	}
+	c.rows = append(c.rows, row)
+	return nil
+}
+
+// DecryptRows returns a RowWriter that decrypts the encrypted columns of each
+// row of table before passing it on to w; primaryKey names the column the
+// values are bound to
+func DecryptRows(w core.RowWriter, cipher core.FieldCipher, table, primaryKey string, columns ...string) core.RowWriter {
+	return &decryptingWriter{w: w, cipher: cipher, table: table, primaryKey: primaryKey, encrypted: columns}
+}
+
+// decryptingWriter decrypts rows on their way to another RowWriter
+type decryptingWriter struct {
+	w          core.RowWriter
+	cipher     core.FieldCipher
+	table      string
+	primaryKey string
+	encrypted  []string
+	positions  map[string]int
+	keyAt      int // Position of the primary key, -1 if not selected
+}
+
+func (d *decryptingWriter) WriteHeader(columns []string) error {
+	d.positions = make(map[string]int, len(d.encrypted))
+	d.keyAt = slices.Index(columns, d.primaryKey)
+	for i, column := range columns {
+		if slices.Contains(d.encrypted, column) {
+			d.positions[column] = i
+		}
+	}
+	return d.w.WriteHeader(columns)
+}
+
+func (d *decryptingWriter) WriteRow(values []any) error {
+	sealed := make(map[string]any, len(d.positions)+1)
+	for column, i := range d.positions {
+		sealed[column] = values[i]
+	}
+	if d.keyAt >= 0 {
+		sealed[d.primaryKey] = values[d.keyAt]
+	}
+	if err := d.cipher.DecryptFields(&sealed, d.table, d.primaryKey, d.encrypted...); err != nil {
+		return err
+	}
+	for column, i := range d.positions {
+		values[i] = sealed[column]
+	}
+	return d.w.WriteRow(values)
+}
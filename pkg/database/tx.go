@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Tx is a transaction generated adapters write through; *sql.Tx satisfies it,
+// as does sqlc's DBTX plus Commit and Rollback
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	Commit() error
+	Rollback() error
+}
+
+// TxBeginner is a connection that begins transactions, such as telemetry.DB
+type TxBeginner interface {
+	Begin(ctx context.Context) (Tx, error)
+}
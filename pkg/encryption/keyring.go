@@ -0,0 +1,351 @@
+// Package encryption provides AES-GCM field encryption with rotatable keys.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix marks encrypted values: "enc:<key id>:<base64 nonce+ciphertext>"
+const Prefix = "enc:"
+
+// ErrUnknownKey is returned when a value was encrypted with a key missing from the keyring
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Binding is the table, column and row a value is stored in. It is sealed as
+// AES-GCM additional data, so a value copied to another row or column does not
+// decrypt.
+type Binding struct {
+	Table      string
+	Column     string
+	PrimaryKey string
+}
+
+// NewBinding binds column of the row of table with the given primary key value
+func NewBinding(table, column string, primaryKey any) Binding {
+	return Binding{Table: table, Column: column, PrimaryKey: formatKey(primaryKey)}
+}
+
+// additionalData encodes the binding; the parts are length-prefixed so no two
+// bindings share an encoding
+func (b Binding) additionalData() []byte {
+	var data []byte
+	for _, part := range []string{b.Table, b.Column, b.PrimaryKey} {
+		data = strconv.AppendInt(data, int64(len(part)), 10)
+		data = append(data, ':')
+		data = append(data, part...)
+	}
+	return data
+}
+
+// formatKey formats a primary key value the same way whether it was read from
+// a row, a path or a JSON body
+func formatKey(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// keyringFile is the on-disk keyring format
+type keyringFile struct {
+	Primary string            `yaml:"primary"` // Key ID used for new values
+	Keys    map[string]string `yaml:"keys"`    // Base64 encoded AES keys by ID
+}
+
+// Keyring holds AES-GCM keys by ID and encrypts new values with the primary key
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring from raw AES keys (16, 24 or 32 bytes)
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q not found in keyring", primary)
+	}
+
+	k := &Keyring{
+		primary: primary,
+		aeads:   make(map[string]cipher.AEAD, len(keys)),
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+
+	return k, nil
+}
+
+// LoadKeyring loads a keyring from a YAML file
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring file: %w", err)
+	}
+
+	var file keyringFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring file %s: %w", path, err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 for key %s: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return NewKeyring(file.Primary, keys)
+}
+
+// GenerateKey returns a new random 256-bit key, base64 encoded for the keyring file
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Primary returns the ID of the key used for new values
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Encrypt encrypts plaintext with the primary key, bound to binding
+func (k *Keyring) Encrypt(plaintext string, binding Binding) (string, error) {
+	aead := k.aeads[k.primary]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), binding.additionalData())
+	return Prefix + k.primary + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt with the same binding. Values
+// without the prefix are returned unchanged so rows written before encryption
+// was enabled stay readable.
+func (k *Keyring) Decrypt(value string, binding Binding) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	id, payload, ok := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], binding.additionalData())
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value with key %s: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// Reencrypt re-encrypts a value under the primary key. It reports false if the
+// value already uses the primary key.
+func (k *Keyring) Reencrypt(value string, binding Binding) (string, bool, error) {
+	if KeyID(value) == k.primary {
+		return value, false, nil
+	}
+	plaintext, err := k.Decrypt(value, binding)
+	if err != nil {
+		return "", false, err
+	}
+	encrypted, err := k.Encrypt(plaintext, binding)
+	if err != nil {
+		return "", false, err
+	}
+	return encrypted, true, nil
+}
+
+// EncryptFields encrypts the named values of a request body in place, bound to
+// the row of table with the given primary key. Every string is encrypted, even
+// one that looks encrypted, so clients cannot store values of their own making.
+func (k *Keyring) EncryptFields(values map[string]any, table string, primaryKey any, columns ...string) error {
+	for _, column := range columns {
+		raw, ok := values[column]
+		if !ok || raw == nil {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return core.BadRequest("%s must be a string", column)
+		}
+		encrypted, err := k.Encrypt(value, NewBinding(table, column, primaryKey))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", column, err)
+		}
+		values[column] = encrypted
+	}
+	return nil
+}
+
+// DecryptFields decrypts the named fields of a struct, map, or slice of them,
+// read from table, in place. Fields are matched by their JSON tag and may be
+// string, *string or sql.NullString; each record's primaryKey field names the
+// row its values are bound to.
+func (k *Keyring) DecryptFields(record any, table, primaryKey string, columns ...string) error {
+	v := reflect.ValueOf(record)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("decrypt target must be a non-nil pointer, got %T", record)
+	}
+	return k.decryptValue(v.Elem(), table, primaryKey, columns)
+}
+
+// decryptValue walks slices, structs and maps decrypting matching fields
+func (k *Keyring) decryptValue(v reflect.Value, table, primaryKey string, columns []string) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return k.decryptValue(v.Elem(), table, primaryKey, columns)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := k.decryptValue(v.Index(i), table, primaryKey, columns); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		var key any
+		for i := 0; i < t.NumField(); i++ {
+			if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name == primaryKey {
+				key = v.Field(i).Interface()
+			}
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if !slices.Contains(columns, name) {
+				continue
+			}
+			if err := k.decryptField(v.Field(i), NewBinding(table, name, key)); err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", name, err)
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		var key any
+		if id := v.MapIndex(reflect.ValueOf(primaryKey).Convert(v.Type().Key())); id.IsValid() {
+			key = id.Interface()
+		}
+		for _, name := range v.MapKeys() {
+			if name.Kind() != reflect.String || !slices.Contains(columns, name.String()) {
+				continue
+			}
+			value, ok := v.MapIndex(name).Interface().(string)
+			if !ok {
+				continue
+			}
+			plaintext, err := k.Decrypt(value, NewBinding(table, name.String(), key))
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", name.String(), err)
+			}
+			v.SetMapIndex(name, reflect.ValueOf(plaintext))
+		}
+	}
+	return nil
+}
+
+// decryptField decrypts a string, *string or sql.NullString field
+func (k *Keyring) decryptField(field reflect.Value, binding Binding) error {
+	if !field.CanSet() {
+		return nil
+	}
+
+	switch value := field.Interface().(type) {
+	case string:
+		plaintext, err := k.Decrypt(value, binding)
+		if err != nil {
+			return err
+		}
+		field.SetString(plaintext)
+	case *string:
+		if value == nil {
+			return nil
+		}
+		plaintext, err := k.Decrypt(*value, binding)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(&plaintext))
+	case sql.NullString:
+		if !value.Valid {
+			return nil
+		}
+		plaintext, err := k.Decrypt(value.String, binding)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(sql.NullString{String: plaintext, Valid: true}))
+	}
+	return nil
+}
+
+// IsEncrypted reports whether a value carries the encryption prefix
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// KeyID returns the ID of the key a value was encrypted with, or "" if it is plaintext
+func KeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	return id
+}
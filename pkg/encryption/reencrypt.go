@@ -0,0 +1,102 @@
+package encryption
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/database"
+)
+
+// Target identifies the encrypted columns of a table
+type Target struct {
+	Table      string
+	PrimaryKey string
+	Columns    []string
+}
+
+// ReencryptTable rewrites every value in the target columns that is not yet
+// encrypted under the primary key, including plaintext values. It returns the
+// number of rows updated. With dryRun set, rows are counted but not written.
+func ReencryptTable(ctx context.Context, db *sql.DB, dialect string, keyring *Keyring, target Target, dryRun bool) (int64, error) {
+	if target.PrimaryKey == "" || len(target.Columns) == 0 {
+		return 0, fmt.Errorf("table %s needs a primary key and at least one encrypted column", target.Table)
+	}
+
+	type update struct {
+		id     any
+		values []any
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s FROM %s", target.PrimaryKey, strings.Join(target.Columns, ", "), target.Table)
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", target.Table, err)
+	}
+
+	var updates []update
+	for rows.Next() {
+		var id any
+		values := make([]sql.NullString, len(target.Columns))
+		dest := []any{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan %s: %w", target.Table, err)
+		}
+
+		changed := false
+		args := make([]any, len(values))
+		for i, value := range values {
+			if !value.Valid {
+				args[i] = nil
+				continue
+			}
+			encrypted, rewritten, err := keyring.Reencrypt(value.String, NewBinding(target.Table, target.Columns[i], id))
+			if err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("failed to re-encrypt %s.%s (id %v): %w", target.Table, target.Columns[i], id, err)
+			}
+			args[i] = encrypted
+			changed = changed || rewritten
+		}
+		if changed {
+			updates = append(updates, update{id: id, values: args})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("failed to read %s: %w", target.Table, err)
+	}
+	rows.Close()
+
+	if dryRun || len(updates) == 0 {
+		return int64(len(updates)), nil
+	}
+
+	sets := make([]string, len(target.Columns))
+	for i, column := range target.Columns {
+		sets[i] = fmt.Sprintf("%s = %s", column, database.Placeholder(dialect, i+1))
+	}
+	stmt := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
+		target.Table, strings.Join(sets, ", "), target.PrimaryKey, database.Placeholder(dialect, len(target.Columns)+1))
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, stmt, append(u.values, u.id)...); err != nil {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf("failed to update %s (id %v): %w", target.Table, u.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit re-encryption of %s: %w", target.Table, err)
+	}
+
+	return int64(len(updates)), nil
+}
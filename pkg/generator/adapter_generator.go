@@ -5,6 +5,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/bata94/apiright/pkg/config"
 	"github.com/bata94/apiright/pkg/core"
 )
 
@@ -13,6 +14,7 @@ import (
 type AdapterGenerator struct {
 	templates *template.Template
 	genSuffix string
+	config    *config.Config
 	logger    core.Logger
 }
 
@@ -25,12 +27,41 @@ type AdapterData struct {
 	ServiceName string // Full service name (e.g., "PostService")
 	PrimaryKey  ColumnData
 	ModulePath  string // Full module path from apiright.yaml
+	OwnerColumn string // Row ownership column from policies (empty = none)
+	OwnerIsInt  bool   // Whether the owner column holds an integer ID
+
+	InsertReturnsKey bool // Create returns the new key via RETURNING instead of a sql.Result
+
+	UnwritableList string // Quoted columns clients may not set (e.g. "created_at", "role")
+	UnreadableList string // Quoted columns never returned to clients (e.g. "password_hash")
+	EncryptedList  string // Quoted columns encrypted at rest (e.g. "phone")
+	ReadableList   string // Quoted columns returned by Find
+	FilterableList string // Quoted columns Find may filter on (readable and not encrypted)
+	ColumnTypeList string // Quoted column: OpenAPI type pairs of writable columns (e.g. "id": "integer")
+	Relations      []RelationData
+	XMLElement     string        // Row element of gen/xml/<table>.xsd (e.g. "post")
+	XMLColumns     []core.Column // Elements of the row, in table order
+
+	ProtoVar     string        // Variable holding the table's protobuf messages (e.g. "postProto")
+	ProtoModel   ProtoMessage  // Message of gen/proto/db_ar_gen.proto for the table
+	ProtoMethods []ProtoMethod // Request and response messages of gen/proto/api_ar_gen.proto
+}
+
+// RelationData represents a to-one relationship through a single-column foreign key
+type RelationData struct {
+	Name   string // Relationship name (e.g. "author" for author_id)
+	Column string // Foreign key column
+	Table  string // Referenced table
 }
 
 // NewAdapterGenerator creates a new adapter generator
-func NewAdapterGenerator(genSuffix string, logger core.Logger) *AdapterGenerator {
+func NewAdapterGenerator(genSuffix string, cfg *config.Config, logger core.Logger) *AdapterGenerator {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
 	return &AdapterGenerator{
 		genSuffix: genSuffix,
+		config:    cfg,
 		logger:    logger,
 	}
 }
@@ -49,8 +80,14 @@ func (ag *AdapterGenerator) GenerateAdapters(schema *core.Schema, ctx *core.Gene
 		}
 	}
 
+	// Older API versions frozen in versions/
+	versions, err := ag.generateVersions(schema, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to generate API versions: %w", err)
+	}
+
 	// Generate the init.go file that registers all adapters
-	if err := ag.generateInitFile(schema.Tables, ctx); err != nil {
+	if err := ag.generateInitFile(schema.Tables, versions, ctx); err != nil {
 		return fmt.Errorf("failed to generate init file: %w", err)
 	}
 
@@ -63,6 +100,7 @@ func (ag *AdapterGenerator) parseTemplates() error {
 	templates := map[string]string{
 		"adapter": adapterTemplate,
 		"init":    initTemplate,
+		"version": versionTemplate,
 	}
 
 	ag.templates = template.New("adapter").Option("missingkey=error")
@@ -95,21 +133,35 @@ func (ag *AdapterGenerator) generateTableAdapter(table core.Table, ctx *core.Gen
 }
 
 // generateInitFile generates the init.go file that registers all adapters
-func (ag *AdapterGenerator) generateInitFile(tables []core.Table, ctx *core.GenerationContext) error {
+func (ag *AdapterGenerator) generateInitFile(tables []core.Table, versions []VersionData, ctx *core.GenerationContext) error {
 	// Build table registration data
 	var tableRegs []TableRegistration
+	encryptionEnabled := false
 	for _, table := range tables {
 		title := ag.toTitleCase(singularize(table.Name))
 		tableRegs = append(tableRegs, TableRegistration{
 			TableName:   table.Name,
 			ServiceName: title + "Service",
+			VarName:     ag.toVarName(title) + "Adapter",
+			Audited:     ag.config.Audit.IsAudited(table.Name),
+			Encrypted:   hasEncryptedColumns(table),
 		})
+		if hasEncryptedColumns(table) {
+			encryptionEnabled = true
+		}
 	}
 
 	initData := InitData{
-		PackageName: "adapters",
-		ModulePath:  ctx.ModulePath,
-		Tables:      tableRegs,
+		PackageName:        "adapters",
+		ModulePath:         ctx.ModulePath,
+		Tables:             tableRegs,
+		Versions:           versions,
+		AuditEnabled:       ag.config.Audit.Enabled,
+		AuditTable:         ag.config.Audit.Table,
+		AuditRetentionDays: ag.config.Audit.RetentionDays,
+		AuditRoles:         quoteList(ag.config.Audit.Roles),
+		EncryptionEnabled:  encryptionEnabled,
+		KeyringFile:        ag.config.Encryption.KeyringFile,
 	}
 
 	// Generate init code
@@ -129,13 +181,23 @@ func (ag *AdapterGenerator) generateInitFile(tables []core.Table, ctx *core.Gene
 type TableRegistration struct {
 	TableName   string
 	ServiceName string // e.g., "PostService", "UserService"
+	VarName     string // e.g., "postAdapter"
+	Audited     bool   // Whether mutations are recorded in the audit log
+	Encrypted   bool   // Whether the table has encrypted columns
 }
 
 // InitData represents data for init template generation
 type InitData struct {
-	PackageName string
-	ModulePath  string
-	Tables      []TableRegistration
+	PackageName        string
+	ModulePath         string
+	Tables             []TableRegistration
+	Versions           []VersionData // API versions mounted on top of the adapters
+	AuditEnabled       bool
+	AuditTable         string
+	AuditRetentionDays int
+	AuditRoles         string // Quoted roles that may read /_audit
+	EncryptionEnabled  bool
+	KeyringFile        string
 }
 
 // prepareAdapterData converts core.Table to AdapterData for template execution
@@ -157,10 +219,61 @@ func (ag *AdapterGenerator) prepareAdapterData(table core.Table, ctx *core.Gener
 		}
 	}
 
+	// Row ownership column and its type
+	ownerIsInt := false
+	for _, col := range table.Columns {
+		if col.Name == table.OwnerColumn {
+			ownerIsInt = core.SQLToGoType(col.Type) == "int64"
+			break
+		}
+	}
+
+	// Columns filtered out of client input and output
+	var unwritable, unreadable, encrypted, readable, filterable, columnTypes []string
+	for _, col := range table.Columns {
+		if col.IsWritable() {
+			columnTypes = append(columnTypes, fmt.Sprintf("%q: %q", col.Name, core.SQLTypeToOpenAPI(col.Type)))
+		}
+		if col.Encrypted {
+			encrypted = append(encrypted, fmt.Sprintf("%q", col.Name))
+		}
+		if col.IsReadable() {
+			readable = append(readable, fmt.Sprintf("%q", col.Name))
+			if !col.Encrypted {
+				filterable = append(filterable, fmt.Sprintf("%q", col.Name))
+			}
+		}
+		if col.Name == primaryKey.Name {
+			continue
+		}
+		if !col.IsWritable() && col.Name != table.OwnerColumn {
+			unwritable = append(unwritable, fmt.Sprintf("%q", col.Name))
+		}
+		if !col.IsReadable() {
+			unreadable = append(unreadable, fmt.Sprintf("%q", col.Name))
+		}
+	}
+
+	// Foreign keys linked from JSON:API and HAL documents
+	var relations []RelationData
+	for _, fk := range table.ForeignKeys {
+		if len(fk.Columns) != 1 || !isReadableColumn(table, fk.Columns[0]) {
+			continue
+		}
+		relations = append(relations, RelationData{
+			Name:   relationName(fk.Columns[0], fk.RefTable),
+			Column: fk.Columns[0],
+			Table:  fk.RefTable,
+		})
+	}
+
 	// Convert table name to singular title case
 	singularTable := singularize(table.Name)
 	titleName := ag.toTitleCase(singularTable)
 
+	// Protobuf messages as declared in gen/proto
+	protoGen := NewProtoGenerator(ag.genSuffix, ag.logger)
+
 	return AdapterData{
 		TableName:   table.Name,
 		Title:       titleName,
@@ -169,7 +282,44 @@ func (ag *AdapterGenerator) prepareAdapterData(table core.Table, ctx *core.Gener
 		ServiceName: titleName + "Service",
 		PrimaryKey:  primaryKey,
 		ModulePath:  ctx.ModulePath,
+		OwnerColumn: table.OwnerColumn,
+		OwnerIsInt:  ownerIsInt,
+
+		InsertReturnsKey: DialectFor(ag.config.Database.Type) == DialectPostgres,
+
+		UnwritableList: strings.Join(unwritable, ", "),
+		UnreadableList: strings.Join(unreadable, ", "),
+		EncryptedList:  strings.Join(encrypted, ", "),
+		ReadableList:   strings.Join(readable, ", "),
+		FilterableList: strings.Join(filterable, ", "),
+		ColumnTypeList: strings.Join(columnTypes, ", "),
+		Relations:      relations,
+		XMLElement:     xmlElementName(table.Name),
+		XMLColumns:     xmlColumns(table),
+
+		ProtoVar:     ag.toVarName(titleName) + "Proto",
+		ProtoModel:   protoGen.createMessageFromTable(table),
+		ProtoMethods: protoGen.createServiceFromTable(table).Methods,
+	}
+}
+
+// hasEncryptedColumns reports whether any column of the table is encrypted at rest
+func hasEncryptedColumns(table core.Table) bool {
+	for _, col := range table.Columns {
+		if col.Encrypted {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteList joins values as quoted Go string literals (e.g. "admin", "auditor")
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
 	}
+	return strings.Join(quoted, ", ")
 }
 
 // executeTemplate executes a template with adapter data
@@ -183,6 +333,15 @@ func (ag *AdapterGenerator) executeTemplate(templateName string, data interface{
 	return buf.String()
 }
 
+// toVarName converts a TitleCase name to a lowerCamelCase Go identifier
+func (ag *AdapterGenerator) toVarName(title string) string {
+	name := strings.ReplaceAll(title, " ", "")
+	if name == "" {
+		return "table"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
 // toTitleCase converts a string to TitleCase for naming
 func (ag *AdapterGenerator) toTitleCase(s string) string {
 	if s == "" {
@@ -224,13 +383,43 @@ import (
 	"strconv"
 
 	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+	"github.com/bata94/apiright/pkg/server"
+	"github.com/bata94/apiright/pkg/telemetry"
+	"google.golang.org/protobuf/proto"
 	db "{{.ModulePath}}/gen/go"
 )
 
+// {{.ProtoVar}} holds the protobuf messages of {{.TableName}} declared in gen/proto
+var {{.ProtoVar}} = core.MustProtoSchema(
+	core.ProtoMessageSpec{Name: "db.{{.ProtoModel.Name}}", Fields: []core.ProtoFieldSpec{
+{{- range .ProtoModel.Fields}}
+		{Name: "{{.Name}}", Number: {{.Number}}, Type: "{{.Type}}", JSONName: "{{.JSONName}}"},
+{{- end}}
+	}},
+{{- range .ProtoMethods}}
+	core.ProtoMethodSpec{
+		Operation: "{{.Operation}}",
+		Request: core.ProtoMessageSpec{Name: "api.{{.Request}}", Fields: []core.ProtoFieldSpec{
+{{- range .RequestFields}}
+			{Name: "{{.Name}}", Number: {{.Number}}, Type: "{{.Type}}", JSONName: "{{.JSONName}}"},
+{{- end}}
+		}},
+		Response:     "api.{{.Response}}",
+		ResponseType: "{{.ResponseType}}",
+	},
+{{- end}}
+)
+
 // {{.ServiceName}}Adapter provides CRUD operations and implements ServiceInterface
 type {{.ServiceName}}Adapter struct {
 	querier db.Querier
 	logger  core.Logger
+	auditor core.Auditor
+	cipher  core.FieldCipher
+	cache   core.CacheInvalidator
+	conn    db.DBTX // Used by Find
+	dialect string
 }
 
 // New{{.ServiceName}}Adapter creates a new {{.ServiceName}}Adapter
@@ -241,8 +430,191 @@ func New{{.ServiceName}}Adapter(querier db.Querier, logger core.Logger) *{{.Serv
 	}
 }
 
+// SetAuditor enables audit logging of all mutations made through this adapter
+func (a *{{.ServiceName}}Adapter) SetAuditor(auditor core.Auditor) {
+	a.auditor = auditor
+}
+
+// SetDB sets the connection and dialect Find builds its queries for
+func (a *{{.ServiceName}}Adapter) SetDB(conn db.DBTX, dialect string) {
+	a.conn = conn
+	a.dialect = dialect
+}
+
+// SetCache sets the response cache invalidated after every write through this adapter
+func (a *{{.ServiceName}}Adapter) SetCache(cache core.CacheInvalidator) {
+	a.cache = cache
+}
+
+// SetCipher sets the cipher used for columns encrypted at rest
+func (a *{{.ServiceName}}Adapter) SetCipher(cipher core.FieldCipher) {
+	a.cipher = cipher
+}
+
+// recordAudit records a mutation through conn, the mutation's transaction, if
+// an auditor is configured; a failed entry fails the mutation
+func (a *{{.ServiceName}}Adapter) recordAudit(ctx context.Context, conn db.DBTX, op core.AuditOperation, id any, before, after any) error {
+	if a.auditor == nil {
+		return nil
+	}
+	event := core.AuditEvent{
+		Table:      "{{.TableName}}",
+		PrimaryKey: fmt.Sprintf("%v", id),
+		Operation:  op,
+		Before:     before,
+		After:      after,
+{{- if .EncryptedList}}
+		Redacted:   []string{ {{- .EncryptedList -}} },
+{{- end}}
+	}
+	if err := a.auditor.Record(ctx, conn, event); err != nil {
+		return a.queryFailed(ctx, string(op), fmt.Errorf("failed to record audit entry for {{.TableName}}: %w", err))
+	}
+	return nil
+}
+
+// invalidateCache drops the cached responses of {{.TableName}} after a write
+func (a *{{.ServiceName}}Adapter) invalidateCache(ctx context.Context) {
+	if a.cache != nil {
+		a.cache.InvalidateTable(ctx, "{{.TableName}}")
+	}
+}
+
+// log returns the request-scoped logger so entries carry the request and trace IDs
+func (a *{{.ServiceName}}Adapter) log(ctx context.Context) core.Logger {
+	return core.LoggerFromContext(ctx, a.logger)
+}
+
+// queryFailed maps constraint violations onto typed errors, logs the error with
+// the request's IDs, marks the span as failed and returns the error
+func (a *{{.ServiceName}}Adapter) queryFailed(ctx context.Context, op string, err error) error {
+	err = database.TranslateError(err)
+	telemetry.RecordError(ctx, err)
+	if _, ok := core.AsAPIError(err); ok {
+		a.log(ctx).Warn("Database constraint violated", "table", "{{.TableName}}", "operation", op, "error", err)
+		return err
+	}
+	a.log(ctx).Error("Database query failed", "table", "{{.TableName}}", "operation", op, "error", err)
+	return err
+}
+
+// inTx runs fn with a querier and connection bound to one transaction, which
+// is committed if fn succeeds and rolled back otherwise. Connections that
+// cannot begin transactions run fn on the adapter's querier and connection.
+func (a *{{.ServiceName}}Adapter) inTx(ctx context.Context, op string, fn func(q db.Querier, conn db.DBTX) error) error {
+	beginner, ok := a.conn.(database.TxBeginner)
+	if !ok {
+		return fn(a.querier, a.conn)
+	}
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return a.queryFailed(ctx, op, fmt.Errorf("failed to begin {{.TableName}} transaction: %w", err))
+	}
+	if err := fn(db.New(tx), tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			a.log(ctx).Error("Failed to roll back transaction", "table", "{{.TableName}}", "operation", op, "error", rollbackErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return a.queryFailed(ctx, op, fmt.Errorf("failed to commit {{.TableName}} transaction: %w", err))
+	}
+	return nil
+}
+{{- if .EncryptedList}}
+
+// encryptParams returns a copy of values with the encrypted columns sealed for
+// the row with primary key id
+func (a *{{.ServiceName}}Adapter) encryptParams(values map[string]any, id any) (map[string]any, error) {
+	if a.cipher == nil {
+		return nil, fmt.Errorf("encryption keyring not configured for {{.TableName}}")
+	}
+	sealed := make(map[string]any, len(values))
+	for k, v := range values {
+		sealed[k] = v
+	}
+	if err := a.cipher.EncryptFields(sealed, "{{.TableName}}", id, {{.EncryptedList}}); err != nil {
+		return nil, fmt.Errorf("failed to encrypt {{.TableName}}: %w", err)
+	}
+	return sealed, nil
+}
+
+// sealCreated re-encrypts the encrypted columns of a new row for its primary
+// key, which was unknown when the row was inserted; conn is the transaction
+// the row was inserted in
+func (a *{{.ServiceName}}Adapter) sealCreated(ctx context.Context, conn db.DBTX, values map[string]any, id any) error {
+	if conn == nil {
+		return fmt.Errorf("database connection not set for {{.TableName}}")
+	}
+	columns := map[string]any{}
+	for _, column := range []string{ {{- .EncryptedList -}} } {
+		if value, ok := values[column]; ok {
+			columns[column] = value
+		}
+	}
+	sealed, err := a.encryptParams(columns, id)
+	if err != nil {
+		return err
+	}
+	if err := database.UpdateColumns(ctx, conn, a.dialect, "{{.TableName}}", "{{.PrimaryKey.Name}}", id, sealed); err != nil {
+		return a.queryFailed(ctx, "create", err)
+	}
+	return nil
+}
+
+// decryptResult decrypts the encrypted columns of a row or slice of rows in place
+func (a *{{.ServiceName}}Adapter) decryptResult(result any) error {
+	if a.cipher == nil {
+		return fmt.Errorf("encryption keyring not configured for {{.TableName}}")
+	}
+	if err := a.cipher.DecryptFields(result, "{{.TableName}}", "{{.PrimaryKey.Name}}", {{.EncryptedList}}); err != nil {
+		return fmt.Errorf("failed to decrypt {{.TableName}}: %w", err)
+	}
+	return nil
+}
+{{- end}}
+{{- if .OwnerColumn}}
+
+// ownerScope returns the caller's owner ID if the request is restricted to its own rows
+func (a *{{.ServiceName}}Adapter) ownerScope(ctx context.Context) (any, bool, error) {
+	scope, ok := core.OwnerScopeFromContext(ctx, "{{.TableName}}")
+	if !ok {
+		return nil, false, nil
+	}
+{{- if .OwnerIsInt}}
+	owner, err := strconv.ParseInt(scope.Owner, 10, 64)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid owner id for {{.TableName}}: %s", scope.Owner)
+	}
+	return owner, true, nil
+{{- else}}
+	return scope.Owner, true, nil
+{{- end}}
+}
+
+// bindParams converts values into a sqlc params struct through its JSON tags
+func (a *{{.ServiceName}}Adapter) bindParams(values map[string]any, target any) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal params for {{.TableName}}: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to unmarshal params for {{.TableName}}: %w", err)
+	}
+	return nil
+}
+{{- end}}
+
 // Get retrieves a single {{.ModelName}} by id (supports int64 and string IDs)
 func (a *{{.ServiceName}}Adapter) Get(ctx context.Context, id any) (any, error) {
+	ctx, span := telemetry.StartOperation(ctx, "{{.TableName}}", "get")
+	defer span.End()
+
+	return a.get(ctx, a.querier, id)
+}
+
+// get reads a single {{.ModelName}} through q, so writes can read back rows in their transaction
+func (a *{{.ServiceName}}Adapter) get(ctx context.Context, q db.Querier, id any) (any, error) {
 	var {{.PrimaryKey.Name}}Val int64
 
 	switch v := id.(type) {
@@ -252,7 +624,7 @@ func (a *{{.ServiceName}}Adapter) Get(ctx context.Context, id any) (any, error)
 		// Try to parse as int64 first
 		parsed, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
-			return nil, fmt.Errorf("string id not supported for {{.TableName}}: %s", v)
+			return nil, core.BadRequest("invalid id for {{.TableName}}: %s", v)
 		}
 		{{.PrimaryKey.Name}}Val = parsed
 	case int:
@@ -260,90 +632,296 @@ func (a *{{.ServiceName}}Adapter) Get(ctx context.Context, id any) (any, error)
 	case int32:
 		{{.PrimaryKey.Name}}Val = int64(v)
 	default:
-		return nil, fmt.Errorf("unsupported id type for {{.TableName}}: %T", id)
+		return nil, core.BadRequest("unsupported id type for {{.TableName}}: %T", id)
 	}
 
-	result, err := a.querier.Get{{.Title}}_ar_gen(ctx, {{.PrimaryKey.Name}}Val)
+{{- if .OwnerColumn}}
+
+	// Restrict to the caller's own rows
+	if owner, scoped, err := a.ownerScope(ctx); err != nil {
+		return nil, err
+	} else if scoped {
+		var params db.Get{{.Title}}ForOwner_ar_genParams
+		if err := a.bindParams(map[string]any{"{{.PrimaryKey.Name}}": {{.PrimaryKey.Name}}Val, "{{.OwnerColumn}}": owner}, &params); err != nil {
+			return nil, err
+		}
+		result, err := q.Get{{.Title}}ForOwner_ar_gen(ctx, params)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, core.NotFound("{{.TableName}} with id %v not found", id)
+			}
+			return nil, a.queryFailed(ctx, "get", err)
+		}
+{{- if .EncryptedList}}
+		if err := a.decryptResult(&result); err != nil {
+			return nil, err
+		}
+{{- end}}
+		return result, nil
+	}
+{{- end}}
+
+	result, err := q.Get{{.Title}}_ar_gen(ctx, {{.PrimaryKey.Name}}Val)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("{{.TableName}} with id %v not found", id)
+			return nil, core.NotFound("{{.TableName}} with id %v not found", id)
 		}
+		return nil, a.queryFailed(ctx, "get", err)
+	}
+{{- if .EncryptedList}}
+
+	if err := a.decryptResult(&result); err != nil {
 		return nil, err
 	}
+{{- end}}
 
 	return result, nil
 }
 
 // List retrieves multiple {{.TableName}} records with pagination
 func (a *{{.ServiceName}}Adapter) List(ctx context.Context, limit, offset int32) (any, error) {
+	ctx, span := telemetry.StartOperation(ctx, "{{.TableName}}", "list")
+	defer span.End()
+
+{{- if .OwnerColumn}}
+	// Restrict to the caller's own rows
+	if owner, scoped, err := a.ownerScope(ctx); err != nil {
+		return nil, err
+	} else if scoped {
+		var params db.List{{.Title}}ForOwner_ar_genParams
+		if err := a.bindParams(map[string]any{"{{.OwnerColumn}}": owner, "limit": limit, "offset": offset}, &params); err != nil {
+			return nil, err
+		}
+		rows, err := a.querier.List{{.Title}}ForOwner_ar_gen(ctx, params)
+		if err != nil {
+			return nil, a.queryFailed(ctx, "list", err)
+		}
+{{- if .EncryptedList}}
+		if err := a.decryptResult(&rows); err != nil {
+			return nil, err
+		}
+{{- end}}
+		return rows, nil
+	}
+
+{{- end}}
 	params := db.List{{.Title}}_ar_genParams{
 		Limit:  int64(limit),
 		Offset: int64(offset),
 	}
-	return a.querier.List{{.Title}}_ar_gen(ctx, params)
+	rows, err := a.querier.List{{.Title}}_ar_gen(ctx, params)
+	if err != nil {
+		return nil, a.queryFailed(ctx, "list", err)
+	}
+{{- if .EncryptedList}}
+	if err := a.decryptResult(&rows); err != nil {
+		return nil, err
+	}
+{{- end}}
+	return rows, nil
 }
 
 // Create creates a new {{.TableName}} record
 func (a *{{.ServiceName}}Adapter) Create(ctx context.Context, params any) (any, error) {
+	ctx, span := telemetry.StartOperation(ctx, "{{.TableName}}", "create")
+	defer span.End()
+
 	// Convert params map to JSON and unmarshal into sqlc params struct
 	paramsMap, ok := params.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid params type for {{.TableName}}: expected map[string]any, got %T", params)
 	}
+{{- if .UnwritableList}}
+
+	// Drop columns clients may not set
+	for _, column := range []string{ {{- .UnwritableList -}} } {
+		delete(paramsMap, column)
+	}
+{{- end}}
+{{- if .OwnerColumn}}
+
+	// New rows are always owned by a restricted caller
+	if owner, scoped, err := a.ownerScope(ctx); err != nil {
+		return nil, err
+	} else if scoped {
+		paramsMap["{{.OwnerColumn}}"] = owner
+	}
+{{- end}}
+{{- if .EncryptedList}}
+
+	// Encrypt sensitive columns before they are written; the new row has no key
+	// yet, so they are sealed again once it has one
+	writeValues, err := a.encryptParams(paramsMap, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(writeValues)
+{{- else}}
 
 	data, err := json.Marshal(paramsMap)
+{{- end}}
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal params for {{.TableName}}: %w", err)
 	}
 
 	var createParams db.Create{{.Title}}_ar_genParams
 	if err := json.Unmarshal(data, &createParams); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal params for {{.TableName}}: %w", err)
+		return nil, core.BadRequest("invalid fields for {{.TableName}}: %v", err).Wrap(err)
 	}
 
-	// Execute insert
-	if err := a.querier.Create{{.Title}}_ar_gen(ctx, createParams); err != nil {
-		return nil, fmt.Errorf("failed to create {{.TableName}}: %w", err)
+	var created any
+	err = a.inTx(ctx, "create", func(q db.Querier, conn db.DBTX) error {
+		// Execute insert and read back the key of the new row
+{{- if .InsertReturnsKey}}
+		{{.PrimaryKey.Name}}Val, err := q.Create{{.Title}}_ar_gen(ctx, createParams)
+		if err != nil {
+			return a.queryFailed(ctx, "create", fmt.Errorf("failed to create {{.TableName}}: %w", err))
+		}
+{{- else}}
+		result, err := q.Create{{.Title}}_ar_gen(ctx, createParams)
+		if err != nil {
+			return a.queryFailed(ctx, "create", fmt.Errorf("failed to create {{.TableName}}: %w", err))
+		}
+		{{.PrimaryKey.Name}}Val, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get the id of the created {{.TableName}}: %w", err)
+		}
+{{- end}}
+{{- if .EncryptedList}}
+
+		// Sealed in the same transaction, so a failure leaves no row behind
+		if err := a.sealCreated(ctx, conn, paramsMap, {{.PrimaryKey.Name}}Val); err != nil {
+			return err
+		}
+{{- end}}
+
+		// Return the stored row, with database defaults and without write-only values
+		created, err = a.get(ctx, q, {{.PrimaryKey.Name}}Val)
+		if err != nil {
+			return err
+		}
+		return a.recordAudit(ctx, conn, core.AuditCreate, {{.PrimaryKey.Name}}Val, nil, created)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Get the created record - need to fetch by querying for the last inserted
-	// For simplicity, we'll return the params as a map
-	return paramsMap, nil
+	a.invalidateCache(ctx)
+	return created, nil
 }
 
 // Update updates an existing {{.TableName}} record
 func (a *{{.ServiceName}}Adapter) Update(ctx context.Context, params any) (any, error) {
+	ctx, span := telemetry.StartOperation(ctx, "{{.TableName}}", "update")
+	defer span.End()
+
 	// Convert params map to JSON and unmarshal into sqlc params struct
 	paramsMap, ok := params.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid params type for {{.TableName}}: expected map[string]any, got %T", params)
 	}
+{{- if .UnwritableList}}
 
-	data, err := json.Marshal(paramsMap)
+	// Drop columns clients may not set
+	for _, column := range []string{ {{- .UnwritableList -}} } {
+		delete(paramsMap, column)
+	}
+{{- end}}
+
+{{- if .EncryptedList}}
+
+	// Encrypt sensitive columns before they are written
+	writeValues, err := a.encryptParams(paramsMap, paramsMap["{{.PrimaryKey.Name}}"])
+	if err != nil {
+		return nil, err
+	}
+{{- else}}
+
+	writeValues := paramsMap
+{{- end}}
+
+	data, err := json.Marshal(writeValues)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal params for {{.TableName}}: %w", err)
 	}
 
 	var updateParams db.Update{{.Title}}_ar_genParams
 	if err := json.Unmarshal(data, &updateParams); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal params for {{.TableName}}: %w", err)
+		return nil, core.BadRequest("invalid fields for {{.TableName}}: %v", err).Wrap(err)
 	}
 
-	// Execute update
-	if err := a.querier.Update{{.Title}}_ar_gen(ctx, updateParams); err != nil {
-		return nil, fmt.Errorf("failed to update {{.TableName}}: %w", err)
-	}
+	id, hasID := paramsMap["{{.PrimaryKey.Name}}"]
+	var updated any
+	err = a.inTx(ctx, "update", func(q db.Querier, conn db.DBTX) error {
+		// Capture the previous state for the audit log
+		var before any
+		if a.auditor != nil && hasID {
+			var err error
+			if before, err = a.get(ctx, q, id); err != nil {
+				return err
+			}
+		}
+
+		// Execute update
+{{- if .OwnerColumn}}
+		if owner, scoped, err := a.ownerScope(ctx); err != nil {
+			return err
+		} else if scoped {
+			// Only the caller's own rows can be updated, and ownership cannot change
+			writeValues["{{.OwnerColumn}}"] = owner
+			var ownerParams db.Update{{.Title}}ForOwner_ar_genParams
+			if err := a.bindParams(writeValues, &ownerParams); err != nil {
+				return err
+			}
+			affected, err := q.Update{{.Title}}ForOwner_ar_gen(ctx, ownerParams)
+			if err != nil {
+				return a.queryFailed(ctx, "update", fmt.Errorf("failed to update {{.TableName}}: %w", err))
+			}
+			if affected == 0 {
+				return core.NotFound("{{.TableName}} with id %v not found", id)
+			}
+		} else if err := q.Update{{.Title}}_ar_gen(ctx, updateParams); err != nil {
+			return a.queryFailed(ctx, "update", fmt.Errorf("failed to update {{.TableName}}: %w", err))
+		}
+{{- else}}
+		if err := q.Update{{.Title}}_ar_gen(ctx, updateParams); err != nil {
+			return a.queryFailed(ctx, "update", fmt.Errorf("failed to update {{.TableName}}: %w", err))
+		}
+{{- end}}
 
-	// Fetch the updated record
-	if id, ok := paramsMap["id"]; ok {
-		return a.Get(ctx, id)
+		// Fetch the updated record
+		if hasID {
+			after, err := a.get(ctx, q, id)
+			if err != nil {
+				return err
+			}
+			updated = after
+			return a.recordAudit(ctx, conn, core.AuditUpdate, id, before, after)
+		}
+{{- if .UnreadableList}}
+
+		for _, column := range []string{ {{- .UnreadableList -}} } {
+			delete(paramsMap, column)
+		}
+{{- end}}
+
+		updated = paramsMap
+		return a.recordAudit(ctx, conn, core.AuditUpdate, "", before, paramsMap)
+	})
+	if err != nil {
+		return nil, err
 	}
-	
-	return paramsMap, nil
+
+	a.invalidateCache(ctx)
+	return updated, nil
 }
 
 // Delete deletes a {{.TableName}} record by id
 func (a *{{.ServiceName}}Adapter) Delete(ctx context.Context, id any) error {
+	ctx, span := telemetry.StartOperation(ctx, "{{.TableName}}", "delete")
+	defer span.End()
+
 	var {{.PrimaryKey.Name}}Val int64
 
 	switch v := id.(type) {
@@ -352,7 +930,7 @@ func (a *{{.ServiceName}}Adapter) Delete(ctx context.Context, id any) error {
 	case string:
 		parsed, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
-			return fmt.Errorf("string id not supported for {{.TableName}}: %s", v)
+			return core.BadRequest("invalid id for {{.TableName}}: %s", v)
 		}
 		{{.PrimaryKey.Name}}Val = parsed
 	case int:
@@ -360,10 +938,124 @@ func (a *{{.ServiceName}}Adapter) Delete(ctx context.Context, id any) error {
 	case int32:
 		{{.PrimaryKey.Name}}Val = int64(v)
 	default:
-		return fmt.Errorf("unsupported id type for {{.TableName}}: %T", id)
+		return core.BadRequest("unsupported id type for {{.TableName}}: %T", id)
 	}
 
-	return a.querier.Delete{{.Title}}_ar_gen(ctx, {{.PrimaryKey.Name}}Val)
+	err := a.inTx(ctx, "delete", func(q db.Querier, conn db.DBTX) error {
+		// Capture the deleted state for the audit log
+		var before any
+		if a.auditor != nil {
+			var err error
+			if before, err = a.get(ctx, q, {{.PrimaryKey.Name}}Val); err != nil {
+				return err
+			}
+		}
+{{- if .OwnerColumn}}
+
+		if owner, scoped, err := a.ownerScope(ctx); err != nil {
+			return err
+		} else if scoped {
+			// Only the caller's own rows can be deleted
+			var params db.Delete{{.Title}}ForOwner_ar_genParams
+			if err := a.bindParams(map[string]any{"{{.PrimaryKey.Name}}": {{.PrimaryKey.Name}}Val, "{{.OwnerColumn}}": owner}, &params); err != nil {
+				return err
+			}
+			affected, err := q.Delete{{.Title}}ForOwner_ar_gen(ctx, params)
+			if err != nil {
+				return a.queryFailed(ctx, "delete", err)
+			}
+			if affected == 0 {
+				return core.NotFound("{{.TableName}} with id %v not found", id)
+			}
+		} else if err := q.Delete{{.Title}}_ar_gen(ctx, {{.PrimaryKey.Name}}Val); err != nil {
+			return a.queryFailed(ctx, "delete", err)
+		}
+{{- else}}
+
+		if err := q.Delete{{.Title}}_ar_gen(ctx, {{.PrimaryKey.Name}}Val); err != nil {
+			return a.queryFailed(ctx, "delete", err)
+		}
+{{- end}}
+
+		return a.recordAudit(ctx, conn, core.AuditDelete, {{.PrimaryKey.Name}}Val, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	a.invalidateCache(ctx)
+	return nil
+}
+
+// findSpec describes the readable columns of {{.TableName}}, scoped to the caller's rows if restricted
+func (a *{{.ServiceName}}Adapter) findSpec(ctx context.Context) (database.FindSpec, error) {
+	if a.conn == nil {
+		return database.FindSpec{}, fmt.Errorf("database connection not set for {{.TableName}}")
+	}
+	spec := database.FindSpec{
+		Table:      "{{.TableName}}",
+		Columns:    []string{ {{- .ReadableList -}} },
+		Filterable: []string{ {{- .FilterableList -}} },
+		OrderBy:    "{{.PrimaryKey.Name}}",
+	}
+{{- if .OwnerColumn}}
+	if owner, scoped, err := a.ownerScope(ctx); err != nil {
+		return spec, err
+	} else if scoped {
+		spec.Scope = map[string]any{"{{.OwnerColumn}}": owner}
+	}
+{{- end}}
+	return spec, nil
+}
+
+// Find lists {{.TableName}} records matching filter; GraphQL uses it for
+// filters and batched relationship loads
+func (a *{{.ServiceName}}Adapter) Find(ctx context.Context, filter core.Filter) ([]map[string]any, error) {
+	ctx, span := telemetry.StartOperation(ctx, "{{.TableName}}", "find")
+	defer span.End()
+
+	spec, err := a.findSpec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.Find(ctx, a.conn, a.dialect, spec, filter)
+	if err != nil {
+		if _, ok := core.AsAPIError(err); ok {
+			return nil, err
+		}
+		return nil, a.queryFailed(ctx, "find", err)
+	}
+{{- if .EncryptedList}}
+	if err := a.decryptResult(&rows); err != nil {
+		return nil, err
+	}
+{{- end}}
+	return rows, nil
+}
+
+// Stream writes {{.TableName}} records matching filter to w as they are read;
+// CSV and NDJSON exports use it
+func (a *{{.ServiceName}}Adapter) Stream(ctx context.Context, filter core.Filter, w core.RowWriter) error {
+	ctx, span := telemetry.StartOperation(ctx, "{{.TableName}}", "stream")
+	defer span.End()
+
+	spec, err := a.findSpec(ctx)
+	if err != nil {
+		return err
+	}
+{{- if .EncryptedList}}
+	if a.cipher == nil {
+		return fmt.Errorf("encryption keyring not configured for {{.TableName}}")
+	}
+	w = database.DecryptRows(w, a.cipher, "{{.TableName}}", "{{.PrimaryKey.Name}}", {{.EncryptedList}})
+{{- end}}
+	if err := database.Stream(ctx, a.conn, a.dialect, spec, filter, w); err != nil {
+		if _, ok := core.AsAPIError(err); ok {
+			return err
+		}
+		return a.queryFailed(ctx, "stream", err)
+	}
+	return nil
 }
 
 // TableName returns the table name for this adapter
@@ -371,11 +1063,60 @@ func (a *{{.ServiceName}}Adapter) TableName() string {
 	return "{{.TableName}}"
 }
 
-// Ensure {{.ServiceName}}Adapter implements ServiceInterface
-var _ ServiceInterface = (*{{.ServiceName}}Adapter)(nil)
+// Resource describes {{.TableName}} for the JSON:API, HAL and XML formats and bulk imports
+func (a *{{.ServiceName}}Adapter) Resource() core.Resource {
+	return core.Resource{
+		Type:        "{{.TableName}}",
+		PrimaryKey:  "{{.PrimaryKey.Name}}",
+		ColumnTypes: map[string]string{ {{- .ColumnTypeList -}} },
+		Element:     "{{.XMLElement}}",
+		Columns: []core.Column{
+{{- range .XMLColumns}}
+			{Name: "{{.Name}}", Type: "{{.Type}}"{{if .Nullable}}, Nullable: true{{end}}},
+{{- end}}
+		},
+{{- if .Relations}}
+		Relations: []core.Relation{
+{{- range .Relations}}
+			{Name: "{{.Name}}", Column: "{{.Column}}", Type: "{{.Table}}"},
+{{- end}}
+		},
+{{- end}}
+	}
+}
 
-// Ensure {{.ServiceName}}Adapter implements TableNamer interface
-var _ TableNamer = (*{{.ServiceName}}Adapter)(nil)
+// ToProto converts a {{.ModelName}} row (a sqlc model or column map) to its db.{{.ProtoModel.Name}} message
+func (a *{{.ServiceName}}Adapter) ToProto(row any) (proto.Message, error) {
+	return {{.ProtoVar}}.Model(row)
+}
+
+// ProtoRequest returns an empty request message of operation for decoding protobuf bodies
+func (a *{{.ServiceName}}Adapter) ProtoRequest(operation string) proto.Message {
+	return {{.ProtoVar}}.Request(operation)
+}
+
+// ProtoResponse wraps the result of operation in its protobuf response message
+func (a *{{.ServiceName}}Adapter) ProtoResponse(operation string, result any) (proto.Message, error) {
+	return {{.ProtoVar}}.Response(operation, result)
+}
+
+// Ensure {{.ServiceName}}Adapter implements server.ServiceInterface
+var _ server.ServiceInterface = (*{{.ServiceName}}Adapter)(nil)
+
+// Ensure {{.ServiceName}}Adapter implements core.Finder
+var _ core.Finder = (*{{.ServiceName}}Adapter)(nil)
+
+// Ensure {{.ServiceName}}Adapter implements core.Streamer
+var _ core.Streamer = (*{{.ServiceName}}Adapter)(nil)
+
+// Ensure {{.ServiceName}}Adapter implements core.ResourceDescriber
+var _ core.ResourceDescriber = (*{{.ServiceName}}Adapter)(nil)
+
+// Ensure {{.ServiceName}}Adapter implements core.ProtoConverter
+var _ core.ProtoConverter = (*{{.ServiceName}}Adapter)(nil)
+
+// Ensure {{.ServiceName}}Adapter names its table for route registration
+var _ interface{ TableName() string } = (*{{.ServiceName}}Adapter)(nil)
 `
 
 // Init template for registering all adapters
@@ -386,24 +1127,77 @@ package {{.PackageName}}
 
 import (
 	"fmt"
-
+{{- if .EncryptionEnabled}}
+	"os"
+{{- end}}
+{{- if .AuditEnabled}}
+	"time"
+{{- end}}
+{{if .AuditEnabled}}
+	"github.com/bata94/apiright/pkg/audit"
+{{- end}}
 	"github.com/bata94/apiright/pkg/core"
 	"github.com/bata94/apiright/pkg/database"
+{{- if .EncryptionEnabled}}
+	"github.com/bata94/apiright/pkg/encryption"
+{{- end}}
 	"github.com/bata94/apiright/pkg/server"
+	"github.com/bata94/apiright/pkg/telemetry"
 	db "{{.ModulePath}}/gen/go"
 )
 
 // Init registers all service adapters with the server
 func Init(srv *server.DualServer, dbConn *database.Database, logger core.Logger) error {
 	// Create querier from database connection
-	querier := db.New(dbConn.GetDB())
+	// Queries run in spans when telemetry is enabled
+	conn := telemetry.InstrumentDB(dbConn.GetDB(), dbConn.Dialect())
+	querier := db.New(conn)
+{{- if .AuditEnabled}}
+
+	// Create audit log recorder
+	auditLog := audit.NewRecorder(dbConn.GetDB(), dbConn.Dialect(), audit.Options{
+		Table:     "{{.AuditTable}}",
+		Retention: {{.AuditRetentionDays}} * 24 * time.Hour,
+		Roles:     []string{ {{- .AuditRoles -}} },
+	}, logger)
+	srv.SetAuditLog(auditLog)
+{{- end}}
+{{- if .EncryptionEnabled}}
+
+	// Load the keyring for encrypted columns (APIRIGHT_KEYRING_FILE overrides the configured path)
+	keyringFile := "{{.KeyringFile}}"
+	if path := os.Getenv("APIRIGHT_KEYRING_FILE"); path != "" {
+		keyringFile = path
+	}
+	keyring, err := encryption.LoadKeyring(keyringFile)
+	if err != nil {
+		return fmt.Errorf("failed to load encryption keyring: %w", err)
+	}
+{{- end}}
 
 	// Register all service adapters
 {{- range .Tables}}
-	if err := srv.RegisterService(New{{.ServiceName}}Adapter(querier, logger)); err != nil {
+	{{.VarName}} := New{{.ServiceName}}Adapter(querier, logger)
+	{{.VarName}}.SetDB(conn, dbConn.Dialect())
+{{- if .Audited}}
+	{{.VarName}}.SetAuditor(auditLog)
+{{- end}}
+{{- if .Encrypted}}
+	{{.VarName}}.SetCipher(keyring)
+{{- end}}
+	if err := srv.RegisterService({{.VarName}}); err != nil {
 		return fmt.Errorf("failed to register {{.TableName}} service: %w", err)
 	}
 {{- end }}
+{{- if .Versions}}
+
+	// Mount the API versions frozen in versions/
+{{- range .Versions}}
+	if err := srv.MountVersion({{.VarName}}); err != nil {
+		return fmt.Errorf("failed to mount API version {{.Name}}: %w", err)
+	}
+{{- end}}
+{{- end}}
 
 	return nil
 }
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/audit"
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// AuditGenerator generates the migration for the audit log table
+type AuditGenerator struct {
+	config  config.AuditConfig
+	dialect Dialect
+	logger  core.Logger
+}
+
+// NewAuditGenerator creates a new audit migration generator
+func NewAuditGenerator(cfg config.AuditConfig, dialect Dialect, logger core.Logger) *AuditGenerator {
+	return &AuditGenerator{
+		config:  cfg,
+		dialect: dialect,
+		logger:  logger,
+	}
+}
+
+// GenerateMigration writes the audit table migration if audit is enabled and
+// no existing migration already creates the table
+func (ag *AuditGenerator) GenerateMigration(ctx *core.GenerationContext) error {
+	if !ag.config.Enabled {
+		return nil
+	}
+
+	migrationsDir := ctx.Join(ctx.ProjectDir, "migrations")
+	files, err := os.ReadDir(migrationsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	maxVersion := 0
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".sql") {
+			continue
+		}
+
+		content, err := ctx.ReadFile(ctx.Join(migrationsDir, file.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read migration file %s: %w", file.Name(), err)
+		}
+		if strings.Contains(string(content), "CREATE TABLE IF NOT EXISTS "+ag.config.Table+" ") {
+			ag.logger.Debug("Audit migration already present", "file", file.Name())
+			return nil
+		}
+
+		if version, err := strconv.Atoi(strings.SplitN(file.Name(), "_", 2)[0]); err == nil && version > maxVersion {
+			maxVersion = version
+		}
+	}
+
+	filename := fmt.Sprintf("%03d_create_%s_table.sql", maxVersion+1, ag.config.Table)
+	content := fmt.Sprintf(`-- Audit log table for APIRight
+-- This file is auto-generated by APIRight because audit.enabled is set.
+
+%s`, audit.CreateTableSQL(string(ag.dialect), ag.config.Table))
+
+	outputPath := ctx.Join(migrationsDir, filename)
+	if err := ctx.WriteFile(outputPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write audit migration: %w", err)
+	}
+
+	ag.logger.Info("Generated audit migration", "path", outputPath)
+	return nil
+}
@@ -245,6 +245,20 @@ func (c *Cache) calculateSchemaHash(migrationDir string) (string, error) {
 		hash.Write([]byte(fileHash))
 	}
 
+	// Frozen API version schemas drive versioned adapters
+	snapshots, err := filepath.Glob(filepath.Join(filepath.Dir(migrationDir), "versions", "*", "schema.json"))
+	if err != nil {
+		return "", err
+	}
+	for _, snapshot := range snapshots {
+		fileHash, err := c.calculateFileHash(snapshot)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(filepath.Base(filepath.Dir(snapshot))))
+		hash.Write([]byte(fileHash))
+	}
+
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
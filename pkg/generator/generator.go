@@ -19,19 +19,24 @@ type Generator struct {
 	serviceGen        *ServiceGenerator
 	adapterGen        *AdapterGenerator
 	openapiGen        *OpenAPIGenerator
+	graphqlGen        *GraphQLGenerator
+	xmlGen            *XMLGenerator
+	auditGen          *AuditGenerator
 	cache             *Cache
 	plugins           *plugins.PluginRegistry
+	config            *config.Config
 	logger            core.Logger
 }
 
 // GenerateOptions controls generation behavior
 type GenerateOptions struct {
-	Force     bool // --force flag
-	SQLOnly   bool // --sql-only flag
-	GoOnly    bool // --go-only flag
-	ProtoOnly bool // --proto-only flag
-	Verbose   bool // --verbose flag
-	DryRun    bool // --dry-run flag
+	Force     bool   // --force flag
+	SQLOnly   bool   // --sql-only flag
+	GoOnly    bool   // --go-only flag
+	ProtoOnly bool   // --proto-only flag
+	Verbose   bool   // --verbose flag
+	DryRun    bool   // --dry-run flag
+	Freeze    string // --freeze flag: API version to snapshot the schema as
 }
 
 // NewGenerator creates a new generator instance
@@ -50,24 +55,17 @@ func NewGenerator(projectDir string, options GenerateOptions, logger core.Logger
 
 	parser := NewSchemaParser(cfg.Database.Type, logger)
 
-	// Convert database type to dialect
-	var dialect Dialect
-	switch cfg.Database.Type {
-	case "postgres", "postgresql":
-		dialect = DialectPostgres
-	case "mysql":
-		dialect = DialectMySQL
-	default:
-		dialect = DialectSQLite
-	}
-
+	dialect := DialectFor(cfg.Database.Type)
 	sqlGen := NewSQLGenerator(cfg.Generation.GenSuffix, dialect, logger)
 	sqlcRunner := NewSQLCRunner(projectDir, options.Verbose, logger)
 	protoGen := NewProtoGenerator(cfg.Generation.GenSuffix, logger)
 	protoExtProcessor := NewProtoExtensionProcessor(cfg.Generation.GenSuffix, logger)
 	serviceGen := NewServiceGenerator(cfg.Generation.GenSuffix, logger)
-	adapterGen := NewAdapterGenerator(cfg.Generation.GenSuffix, logger)
+	adapterGen := NewAdapterGenerator(cfg.Generation.GenSuffix, cfg, logger)
 	openapiGen := NewOpenAPIGenerator(cfg.Generation.GenSuffix, logger)
+	graphqlGen := NewGraphQLGenerator(logger)
+	xmlGen := NewXMLGenerator(logger)
+	auditGen := NewAuditGenerator(cfg.Audit, dialect, logger)
 
 	return &Generator{
 		parser:            parser,
@@ -78,8 +76,12 @@ func NewGenerator(projectDir string, options GenerateOptions, logger core.Logger
 		serviceGen:        serviceGen,
 		adapterGen:        adapterGen,
 		openapiGen:        openapiGen,
+		graphqlGen:        graphqlGen,
+		xmlGen:            xmlGen,
+		auditGen:          auditGen,
 		cache:             cache,
 		plugins:           pluginRegistry,
+		config:            cfg,
 		logger:            logger,
 	}, nil
 }
@@ -95,8 +97,8 @@ func (g *Generator) Generate(ctx *core.GenerationContext, options GenerateOption
 	spinner := core.NewSpinner("Generating code")
 	spinner.Start()
 
-	// 1. Check cache first (unless force regeneration)
-	if !options.Force {
+	// 1. Check cache first (unless force regeneration or freezing a version)
+	if !options.Force && options.Freeze == "" {
 		shouldRegen, err := g.cache.ShouldRegenerate(
 			ctx.Join(ctx.ProjectDir, "migrations"),
 			ctx.Join(ctx.ProjectDir, "sqlc.yaml"),
@@ -119,6 +121,13 @@ func (g *Generator) Generate(ctx *core.GenerationContext, options GenerateOption
 		}
 	}
 
+	// 2.5 Generate framework migrations (audit log)
+	if !options.DryRun {
+		if err := g.auditGen.GenerateMigration(ctx); err != nil {
+			return g.formatError("audit_generation", err, "")
+		}
+	}
+
 	// 3. Parse SQL schema
 	spinner.SetMessage("Parsing SQL migrations")
 	schema, err := g.parser.ParseMigrations(ctx.Join(ctx.ProjectDir, "migrations"))
@@ -126,6 +135,17 @@ func (g *Generator) Generate(ctx *core.GenerationContext, options GenerateOption
 		return g.formatError("schema_parsing", err, "migrations directory")
 	}
 
+	// 3.5 Apply table annotations from configuration (row ownership)
+	g.annotateSchema(schema)
+
+	// 3.6 Freeze the schema as an API version
+	if options.Freeze != "" && !options.DryRun {
+		if err := FreezeSchema(schema, ctx, options.Freeze); err != nil {
+			return g.formatError("version_freeze", err, "versions directory")
+		}
+		g.logger.Info("Froze schema as API version", "version", options.Freeze, "path", SnapshotPath(ctx, options.Freeze))
+	}
+
 	// Update context with schema
 	ctx.WithSchema(schema)
 
@@ -210,6 +230,22 @@ func (g *Generator) Generate(ctx *core.GenerationContext, options GenerateOption
 		g.logger.Info("Generated OpenAPI documentation")
 	}
 
+	// 11.5 Generate GraphQL schema (unless sql-only or go-only)
+	if !options.SQLOnly && !options.GoOnly {
+		spinner.SetMessage("Generating GraphQL schema")
+		if err := g.graphqlGen.Generate(schema, ctx); err != nil {
+			return g.formatError("graphql_generation", err, "")
+		}
+	}
+
+	// 11.6 Generate XML schemas (unless sql-only or go-only)
+	if !options.SQLOnly && !options.GoOnly {
+		spinner.SetMessage("Generating XML schemas")
+		if err := g.xmlGen.Generate(schema, ctx); err != nil {
+			return g.formatError("xml_generation", err, "")
+		}
+	}
+
 	// 12. Generate service implementations (unless sql-only or proto-only)
 	if !options.SQLOnly && !options.ProtoOnly {
 		spinner.SetMessage("Generating service implementations")
@@ -249,6 +285,45 @@ func (g *Generator) Generate(ctx *core.GenerationContext, options GenerateOption
 	return nil
 }
 
+// annotateSchema copies table-level settings from apiright.yaml onto the parsed schema
+func (g *Generator) annotateSchema(schema *core.Schema) {
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+
+		// Column settings from config override SQL annotations
+		if tableConfig, ok := g.config.Tables[table.Name]; ok {
+			for j := range table.Columns {
+				if visibility := tableConfig.ColumnVisibility(table.Columns[j].Name); visibility != "" {
+					table.Columns[j].Visibility = visibility
+				}
+				if tableConfig.IsEncrypted(table.Columns[j].Name) {
+					table.Columns[j].Encrypted = true
+				}
+			}
+		}
+
+		ownerColumn := g.config.Policies.OwnerColumn(table.Name)
+		if ownerColumn == "" {
+			continue
+		}
+		if !hasColumn(*table, ownerColumn) {
+			g.logger.Warn("Policy owner column not found, ignoring", "table", table.Name, "column", ownerColumn)
+			continue
+		}
+		table.OwnerColumn = ownerColumn
+	}
+}
+
+// hasColumn reports whether a table has a column with the given name
+func hasColumn(table core.Table, name string) bool {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // formatError creates a formatted error with context information
 func (g *Generator) formatError(errorType string, err error, context string) error {
 	// Map error types to user-friendly messages
@@ -258,8 +333,12 @@ func (g *Generator) formatError(errorType string, err error, context string) err
 		"sqlc_execution":          "sqlc code generation failed",
 		"protobuf_generation":     "Failed to generate protobuf definitions",
 		"openapi_generation":      "Failed to generate OpenAPI documentation",
+		"graphql_generation":      "Failed to generate GraphQL schema",
+		"xml_generation":          "Failed to generate XML schemas",
+		"version_freeze":          "Failed to freeze API version schema",
 		"service_generation":      "Failed to generate service implementations",
 		"adapter_generation":      "Failed to generate service adapters",
+		"audit_generation":        "Failed to generate audit log migration",
 		"before_generation_hooks": "Before-generation plugin hooks failed",
 		"before_sqlc_hooks":       "Before-sqlc plugin hooks failed",
 		"after_sqlc_hooks":        "After-sqlc plugin hooks failed",
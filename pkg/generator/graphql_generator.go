@@ -0,0 +1,270 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// graphqlPrelude declares the scalars and the directives the server uses to map
+// the schema back onto tables
+const graphqlPrelude = `# Code generated by APIRight. DO NOT EDIT.
+
+"""RFC 3339 date and time"""
+scalar Time
+
+"""Arbitrary JSON value"""
+scalar JSON
+
+"""Backs an object type with a table"""
+directive @table(name: String!) on OBJECT
+
+"""Resolves a root field with a CRUD operation (get, list, create, update, delete) on a table"""
+directive @crud(table: String!, op: String!) on FIELD_DEFINITION
+
+"""Loads the rows of table whose column equals the parent's via column"""
+directive @relation(table: String!, column: String!, via: String!) on FIELD_DEFINITION
+`
+
+// GraphQLGenerator generates a GraphQL schema from the parsed SQL schema
+type GraphQLGenerator struct {
+	logger core.Logger
+}
+
+// NewGraphQLGenerator creates a new GraphQL schema generator
+func NewGraphQLGenerator(logger core.Logger) *GraphQLGenerator {
+	return &GraphQLGenerator{logger: logger}
+}
+
+// Generate writes gen/graphql/schema.graphql
+func (g *GraphQLGenerator) Generate(schema *core.Schema, ctx *core.GenerationContext) error {
+	graphqlDir := ctx.Join(ctx.ProjectDir, "gen", "graphql")
+	if err := os.MkdirAll(graphqlDir, 0755); err != nil {
+		return fmt.Errorf("failed to create graphql directory: %w", err)
+	}
+
+	path := filepath.Join(graphqlDir, "schema.graphql")
+	if err := os.WriteFile(path, []byte(g.BuildSchema(schema)), 0644); err != nil {
+		return fmt.Errorf("failed to write GraphQL schema: %w", err)
+	}
+
+	g.logger.Info("Generated GraphQL schema", "path", path)
+	return nil
+}
+
+// BuildSchema renders the GraphQL schema: an object type, filter and input
+// types per table, get/list queries, create/update/delete mutations, and
+// relationship fields in both directions of every single-column foreign key
+func (g *GraphQLGenerator) BuildSchema(schema *core.Schema) string {
+	var b strings.Builder
+	b.WriteString(graphqlPrelude)
+
+	tables := make(map[string]core.Table, len(schema.Tables))
+	for _, table := range schema.Tables {
+		if len(table.PrimaryKey) == 1 {
+			tables[table.Name] = table
+		}
+	}
+
+	var queries, mutations []string
+	for _, table := range schema.Tables {
+		if _, ok := tables[table.Name]; !ok {
+			g.logger.Warn("Skipping table without a single-column primary key in GraphQL schema", "table", table.Name)
+			continue
+		}
+		typeName := graphqlTypeName(table.Name)
+		pk := table.PrimaryKey[0]
+
+		// Object type
+		fmt.Fprintf(&b, "\ntype %s @table(name: %q) {\n", typeName, table.Name)
+		fields := make(map[string]bool)
+		for _, col := range table.Columns {
+			if !col.IsReadable() {
+				continue
+			}
+			fields[col.Name] = true
+			// Every stored row has its primary key, even where the column allows NULL
+			fmt.Fprintf(&b, "  %s: %s\n", col.Name, graphqlColumnType(col, pk, !col.Nullable || col.Name == pk))
+		}
+		for _, rel := range g.relations(table, schema, tables) {
+			if fields[rel.name] {
+				rel.name += "_ref"
+			}
+			fields[rel.name] = true
+			fmt.Fprintf(&b, "  %s @relation(table: %q, column: %q, via: %q)\n", rel.signature(), rel.table, rel.column, rel.via)
+		}
+		b.WriteString("}\n")
+
+		// Filter on readable, unencrypted columns; encrypted values never match
+		fmt.Fprintf(&b, "\ninput %sFilter {\n", typeName)
+		for _, col := range table.Columns {
+			if !col.IsReadable() || col.Encrypted {
+				continue
+			}
+			scalar := graphqlColumnType(col, pk, false)
+			fmt.Fprintf(&b, "  %s: %s\n  %s_in: [%s!]\n", col.Name, scalar, col.Name, scalar)
+		}
+		b.WriteString("}\n")
+
+		// Create and update inputs take the writable columns
+		for _, input := range []string{"Create", "Update"} {
+			fmt.Fprintf(&b, "\ninput %s%sInput {\n", input, typeName)
+			for _, col := range table.Columns {
+				if !col.IsWritable() || col.Name == pk && col.AutoIncrement || input == "Update" && col.Name == pk {
+					continue
+				}
+				required := input == "Create" && !col.Nullable && col.Default == "" && !col.AutoIncrement
+				fmt.Fprintf(&b, "  %s: %s\n", col.Name, graphqlColumnType(col, pk, required))
+			}
+			b.WriteString("}\n")
+		}
+
+		single, plural := graphqlFieldNames(table.Name)
+		queries = append(queries,
+			fmt.Sprintf("  %s(id: ID!): %s @crud(table: %q, op: \"get\")", single, typeName, table.Name),
+			fmt.Sprintf("  %s(filter: %sFilter, limit: Int = 50, offset: Int = 0): [%s!]! @crud(table: %q, op: \"list\")", plural, typeName, typeName, table.Name),
+		)
+		mutations = append(mutations,
+			fmt.Sprintf("  create%s(input: Create%sInput!): %s @crud(table: %q, op: \"create\")", typeName, typeName, typeName, table.Name),
+			fmt.Sprintf("  update%s(id: ID!, input: Update%sInput!): %s @crud(table: %q, op: \"update\")", typeName, typeName, typeName, table.Name),
+			fmt.Sprintf("  delete%s(id: ID!): Boolean! @crud(table: %q, op: \"delete\")", typeName, table.Name),
+		)
+	}
+
+	if len(queries) == 0 {
+		// A schema needs a query type even without tables
+		queries = append(queries, "  _empty: Boolean")
+	}
+	fmt.Fprintf(&b, "\ntype Query {\n%s\n}\n", strings.Join(queries, "\n"))
+	if len(mutations) > 0 {
+		fmt.Fprintf(&b, "\ntype Mutation {\n%s\n}\n", strings.Join(mutations, "\n"))
+	}
+	return b.String()
+}
+
+// graphqlRelation is a relationship field derived from a foreign key
+type graphqlRelation struct {
+	name     string
+	typeName string
+	many     bool
+	table    string // Table the related rows are loaded from
+	column   string // Column of the related table matched against via
+	via      string // Column of the parent row
+}
+
+// signature renders the field name, arguments and type
+func (r graphqlRelation) signature() string {
+	if r.many {
+		return fmt.Sprintf("%s(limit: Int, offset: Int): [%s!]!", r.name, r.typeName)
+	}
+	return fmt.Sprintf("%s: %s", r.name, r.typeName)
+}
+
+// relations returns the fields for foreign keys of table (to one) and foreign
+// keys of other tables referencing it (to many)
+func (g *GraphQLGenerator) relations(table core.Table, schema *core.Schema, tables map[string]core.Table) []graphqlRelation {
+	var relations []graphqlRelation
+
+	for _, fk := range table.ForeignKeys {
+		if len(fk.Columns) != 1 || len(fk.RefColumns) != 1 {
+			continue
+		}
+		ref, ok := tables[fk.RefTable]
+		if !ok || !isReadableColumn(table, fk.Columns[0]) || !isReadableColumn(ref, fk.RefColumns[0]) {
+			continue
+		}
+		relations = append(relations, graphqlRelation{
+			name:     relationName(fk.Columns[0], fk.RefTable),
+			typeName: graphqlTypeName(fk.RefTable),
+			table:    fk.RefTable,
+			column:   fk.RefColumns[0],
+			via:      fk.Columns[0],
+		})
+	}
+
+	for _, other := range schema.Tables {
+		if _, ok := tables[other.Name]; !ok {
+			continue
+		}
+		var matching []core.ForeignKey
+		for _, fk := range other.ForeignKeys {
+			if fk.RefTable == table.Name && len(fk.Columns) == 1 && len(fk.RefColumns) == 1 &&
+				isReadableColumn(other, fk.Columns[0]) && isReadableColumn(table, fk.RefColumns[0]) {
+				matching = append(matching, fk)
+			}
+		}
+		for _, fk := range matching {
+			// Several keys into the same table are told apart by column
+			name := other.Name
+			if len(matching) > 1 {
+				name = other.Name + "_by_" + strings.TrimSuffix(fk.Columns[0], "_id")
+			}
+			relations = append(relations, graphqlRelation{
+				name:     name,
+				typeName: graphqlTypeName(other.Name),
+				many:     true,
+				table:    other.Name,
+				column:   fk.Columns[0],
+				via:      fk.RefColumns[0],
+			})
+		}
+	}
+	return relations
+}
+
+// relationName names the to-one relationship of a foreign key column
+// (author_id -> author), falling back to the referenced table (owner -> user)
+func relationName(column, refTable string) string {
+	name := strings.TrimSuffix(column, "_id")
+	if name == column {
+		return singularize(refTable)
+	}
+	return name
+}
+
+// isReadableColumn reports whether the table has the column and returns it to clients
+func isReadableColumn(table core.Table, name string) bool {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col.IsReadable()
+		}
+	}
+	return false
+}
+
+// graphqlColumnType returns the GraphQL type of a column; the primary key is an ID
+func graphqlColumnType(col core.Column, pk string, required bool) string {
+	scalar := core.SQLTypeToGraphQL(col.Type)
+	if col.Name == pk {
+		scalar = "ID"
+	}
+	if required {
+		return scalar + "!"
+	}
+	return scalar
+}
+
+// graphqlTypeName returns the object type name of a table (users -> User)
+func graphqlTypeName(table string) string {
+	return core.ToPascalCase(singularize(table))
+}
+
+// graphqlFieldNames returns the get and list query names of a table (blog_posts ->
+// blogPost, blogPosts); list gets a suffix when singular and plural are equal
+func graphqlFieldNames(table string) (string, string) {
+	lowerFirst := func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToLower(s[:1]) + s[1:]
+	}
+	single := lowerFirst(graphqlTypeName(table))
+	plural := lowerFirst(core.ToPascalCase(table))
+	if plural == single {
+		plural += "List"
+	}
+	return single, plural
+}
@@ -102,6 +102,23 @@ type OpenAPISchema struct {
 	Items      *OpenAPISchema           `yaml:"items,omitempty"`
 	Format     string                   `yaml:"format,omitempty"`
 	Example    any                      `yaml:"example,omitempty"`
+	ReadOnly   bool                     `yaml:"readOnly,omitempty"`
+
+	XML          *OpenAPIXML          `yaml:"xml,omitempty"`
+	ExternalDocs *OpenAPIExternalDocs `yaml:"externalDocs,omitempty"`
+}
+
+// OpenAPIXML names the XML element of a schema
+type OpenAPIXML struct {
+	Name      string `yaml:"name,omitempty"`
+	Namespace string `yaml:"namespace,omitempty"`
+	Wrapped   bool   `yaml:"wrapped,omitempty"`
+}
+
+// OpenAPIExternalDocs links a schema to its documentation (e.g. a table's XSD)
+type OpenAPIExternalDocs struct {
+	Description string `yaml:"description,omitempty"`
+	URL         string `yaml:"url"`
 }
 
 func (g *OpenAPIGenerator) Generate(schema *core.Schema, ctx *core.GenerationContext) error {
@@ -131,6 +148,10 @@ func (g *OpenAPIGenerator) Generate(schema *core.Schema, ctx *core.GenerationCon
 
 func (g *OpenAPIGenerator) buildSpec(schema *core.Schema, ctx *core.GenerationContext) *OpenAPISpec {
 	serverURL := fmt.Sprintf("http://%s:%d", ctx.ServerConfig.Host, ctx.ServerConfig.HTTPPort)
+	docsPath := ctx.ServerConfig.DocsPath
+	if docsPath == "" {
+		docsPath = "/docs"
+	}
 
 	spec := &OpenAPISpec{
 		OpenAPI: "3.0.3",
@@ -156,18 +177,19 @@ func (g *OpenAPIGenerator) buildSpec(schema *core.Schema, ctx *core.GenerationCo
 
 		// Add CRUD paths using config values
 		basePath := ctx.ServerConfig.BasePath + "/" + ctx.ServerConfig.APIVersion + "/" + tableName
+		xsdURL := docsPath + "/xml/" + table.Name + ".xsd"
 
 		// GET /{base_path}/{api_version}/{table} - List
-		listOp := g.buildListOperation(schemaName, table)
-		createOp := g.buildCreateOperation(schemaName, table)
+		listOp := g.buildListOperation(schemaName, table, xsdURL)
+		createOp := g.buildCreateOperation(schemaName, table, xsdURL)
 		spec.Paths[basePath] = OpenAPIPath{
 			Get:  listOp,
 			Post: createOp,
 		}
 
 		// GET/PUT/DELETE /{base_path}/{api_version}/{table}/{id}
-		getOp := g.buildGetOperation(schemaName, table)
-		updateOp := g.buildUpdateOperation(schemaName, table)
+		getOp := g.buildGetOperation(schemaName, table, xsdURL)
+		updateOp := g.buildUpdateOperation(schemaName, table, xsdURL)
 		deleteOp := g.buildDeleteOperation(schemaName, table)
 		spec.Paths[basePath+"/{id}"] = OpenAPIPath{
 			Get:    getOp,
@@ -186,8 +208,13 @@ func (g *OpenAPIGenerator) buildSchema(table core.Table) OpenAPISchema {
 	}
 
 	for _, col := range table.Columns {
+		// Hidden and write-only columns are never returned
+		if !col.IsReadable() {
+			continue
+		}
 		prop := OpenAPISchema{
-			Type: core.SQLTypeToOpenAPI(col.Type),
+			Type:     core.SQLTypeToOpenAPI(col.Type),
+			ReadOnly: col.Visibility == core.VisibilityReadOnly,
 		}
 		if col.Nullable {
 			prop.Type = "null, " + prop.Type
@@ -202,7 +229,7 @@ func (g *OpenAPIGenerator) buildSchema(table core.Table) OpenAPISchema {
 	return schema
 }
 
-func (g *OpenAPIGenerator) buildListOperation(schemaName string, table core.Table) *OpenAPIOperation {
+func (g *OpenAPIGenerator) buildListOperation(schemaName string, table core.Table, xsdURL string) *OpenAPIOperation {
 	return &OpenAPIOperation{
 		Summary:     fmt.Sprintf("List all %s", schemaName),
 		Description: fmt.Sprintf("Returns a paginated list of %s", schemaName),
@@ -216,7 +243,7 @@ func (g *OpenAPIGenerator) buildListOperation(schemaName string, table core.Tabl
 				Description: "Successful response",
 				Content: map[string]OpenAPIMediaType{
 					"application/json": {Schema: &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Type: "object"}}},
-					"application/xml":  {Schema: &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Type: "object"}}},
+					"application/xml":  {Schema: g.xmlSchema(table, xsdURL, &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Type: "object"}})},
 					"application/yaml": {Schema: &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Type: "object"}}},
 				},
 			},
@@ -224,7 +251,7 @@ func (g *OpenAPIGenerator) buildListOperation(schemaName string, table core.Tabl
 	}
 }
 
-func (g *OpenAPIGenerator) buildCreateOperation(schemaName string, table core.Table) *OpenAPIOperation {
+func (g *OpenAPIGenerator) buildCreateOperation(schemaName string, table core.Table, xsdURL string) *OpenAPIOperation {
 	return &OpenAPIOperation{
 		Summary:     fmt.Sprintf("Create a new %s", schemaName),
 		Description: fmt.Sprintf("Creates a new %s record", schemaName),
@@ -238,7 +265,7 @@ func (g *OpenAPIGenerator) buildCreateOperation(schemaName string, table core.Ta
 					Example: g.getCreateExample(table),
 				},
 				"application/xml": {
-					Schema: &OpenAPISchema{Type: "object", Properties: g.getInputProperties(table)},
+					Schema: g.xmlSchema(table, xsdURL, &OpenAPISchema{Type: "object", Properties: g.getInputProperties(table)}),
 				},
 				"application/yaml": {
 					Schema: &OpenAPISchema{Type: "object", Properties: g.getInputProperties(table)},
@@ -256,7 +283,7 @@ func (g *OpenAPIGenerator) buildCreateOperation(schemaName string, table core.Ta
 	}
 }
 
-func (g *OpenAPIGenerator) buildGetOperation(schemaName string, table core.Table) *OpenAPIOperation {
+func (g *OpenAPIGenerator) buildGetOperation(schemaName string, table core.Table, xsdURL string) *OpenAPIOperation {
 	return &OpenAPIOperation{
 		Summary:     fmt.Sprintf("Get %s by ID", schemaName),
 		Description: fmt.Sprintf("Returns a single %s by its ID", schemaName),
@@ -269,7 +296,7 @@ func (g *OpenAPIGenerator) buildGetOperation(schemaName string, table core.Table
 				Description: "Successful response",
 				Content: map[string]OpenAPIMediaType{
 					"application/json": {Schema: &OpenAPISchema{Type: "object"}},
-					"application/xml":  {Schema: &OpenAPISchema{Type: "object"}},
+					"application/xml":  {Schema: g.xmlSchema(table, xsdURL, &OpenAPISchema{Type: "object"})},
 					"application/yaml": {Schema: &OpenAPISchema{Type: "object"}},
 				},
 			},
@@ -278,7 +305,7 @@ func (g *OpenAPIGenerator) buildGetOperation(schemaName string, table core.Table
 	}
 }
 
-func (g *OpenAPIGenerator) buildUpdateOperation(schemaName string, table core.Table) *OpenAPIOperation {
+func (g *OpenAPIGenerator) buildUpdateOperation(schemaName string, table core.Table, xsdURL string) *OpenAPIOperation {
 	return &OpenAPIOperation{
 		Summary:     fmt.Sprintf("Update %s", schemaName),
 		Description: fmt.Sprintf("Updates an existing %s record", schemaName),
@@ -291,7 +318,7 @@ func (g *OpenAPIGenerator) buildUpdateOperation(schemaName string, table core.Ta
 			Description: "The " + schemaName + " data to update",
 			Content: map[string]OpenAPIMediaType{
 				"application/json": {Schema: &OpenAPISchema{Type: "object", Properties: g.getInputProperties(table)}},
-				"application/xml":  {Schema: &OpenAPISchema{Type: "object", Properties: g.getInputProperties(table)}},
+				"application/xml":  {Schema: g.xmlSchema(table, xsdURL, &OpenAPISchema{Type: "object", Properties: g.getInputProperties(table)})},
 				"application/yaml": {Schema: &OpenAPISchema{Type: "object", Properties: g.getInputProperties(table)}},
 			},
 		},
@@ -317,10 +344,23 @@ func (g *OpenAPIGenerator) buildDeleteOperation(schemaName string, table core.Ta
 	}
 }
 
+// xmlSchema names the elements of an XML body after gen/xml/<table>.xsd, which
+// it links to: rows are row elements, lists a wrapping collection element
+func (g *OpenAPIGenerator) xmlSchema(table core.Table, xsdURL string, schema *OpenAPISchema) *OpenAPISchema {
+	element := &OpenAPIXML{Name: xmlElementName(table.Name), Namespace: core.XMLNamespace(table.Name)}
+	if schema.Items != nil {
+		schema.Items.XML = element
+		element = &OpenAPIXML{Name: table.Name, Namespace: element.Namespace, Wrapped: true}
+	}
+	schema.XML = element
+	schema.ExternalDocs = &OpenAPIExternalDocs{Description: "XML schema", URL: xsdURL}
+	return schema
+}
+
 func (g *OpenAPIGenerator) getInputProperties(table core.Table) map[string]OpenAPISchema {
 	props := make(map[string]OpenAPISchema)
 	for _, col := range table.Columns {
-		if col.AutoIncrement {
+		if col.AutoIncrement || !col.IsWritable() {
 			continue
 		}
 		props[core.ToPascalCase(col.Name)] = OpenAPISchema{
@@ -333,7 +373,7 @@ func (g *OpenAPIGenerator) getInputProperties(table core.Table) map[string]OpenA
 func (g *OpenAPIGenerator) getCreateExample(table core.Table) map[string]any {
 	example := make(map[string]any)
 	for _, col := range table.Columns {
-		if col.AutoIncrement {
+		if col.AutoIncrement || !col.IsWritable() {
 			continue
 		}
 		example[core.ToPascalCase(col.Name)] = core.GetExampleValue(col.Type)
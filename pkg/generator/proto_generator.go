@@ -2,6 +2,7 @@ package generator
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -45,6 +46,7 @@ type ProtoService struct {
 
 // ProtoMethod represents a protobuf service method
 type ProtoMethod struct {
+	Operation     string // CRUD operation the method serves (get, list, create, update or delete)
 	Name          string
 	Request       string
 	Response      string
@@ -173,6 +175,10 @@ func (pg *ProtoGenerator) createMessageFromTable(table core.Table) ProtoMessage
 	}
 
 	for i, col := range table.Columns {
+		// Write-only and hidden columns never appear in responses; keep field numbers stable
+		if !col.IsReadable() && !pg.isPrimaryKeyField(table, col) {
+			continue
+		}
 		field := ProtoField{
 			Name:     pg.toProtoFieldName(col.Name),
 			Type:     core.SQLToProtoType(col.Type),
@@ -180,6 +186,7 @@ func (pg *ProtoGenerator) createMessageFromTable(table core.Table) ProtoMessage
 			GoName:   pg.toGoFieldName(col.Name),
 			GoType:   core.SQLToGoType(col.Type),
 			Optional: col.Nullable,
+			JSONName: col.Name,
 		}
 		message.Fields = append(message.Fields, field)
 	}
@@ -202,16 +209,18 @@ func (pg *ProtoGenerator) createServiceFromTable(table core.Table) ProtoService
 	// CRUD methods
 	methods := []ProtoMethod{
 		{
+			Operation:     "get",
 			Name:          "Get" + titleName,
 			Request:       "Get" + titleName + "Request",
 			Response:      "Get" + titleName + "Response",
 			GoName:        "Get" + titleName,
 			HTTPMethod:    "GET",
 			HTTPPath:      "/v1/" + pg.pluralize(tableName) + "/{id}",
-			RequestFields: pg.generatePrimaryKeyField(table.Columns),
-			ResponseType:  titleName,
+			RequestFields: pg.generatePrimaryKeyField(table),
+			ResponseType:  "db." + titleName,
 		},
 		{
+			Operation:     "list",
 			Name:          "List" + pg.pluralize(titleName),
 			Request:       "List" + pg.pluralize(titleName) + "Request",
 			Response:      "List" + pg.pluralize(titleName) + "Response",
@@ -219,36 +228,39 @@ func (pg *ProtoGenerator) createServiceFromTable(table core.Table) ProtoService
 			HTTPMethod:    "GET",
 			HTTPPath:      "/v1/" + pg.pluralize(tableName),
 			RequestFields: pg.generatePaginationFields(),
-			ResponseType:  "repeated " + titleName,
+			ResponseType:  "repeated db." + titleName,
 		},
 		{
+			Operation:     "create",
 			Name:          "Create" + titleName,
 			Request:       "Create" + titleName + "Request",
 			Response:      "Create" + titleName + "Response",
 			GoName:        "Create" + titleName,
 			HTTPMethod:    "POST",
 			HTTPPath:      "/v1/" + pg.pluralize(tableName),
-			RequestFields: pg.generateProtoFields(table.Columns, false),
-			ResponseType:  titleName,
+			RequestFields: pg.generateProtoFields(table, false),
+			ResponseType:  "db." + titleName,
 		},
 		{
+			Operation:     "update",
 			Name:          "Update" + titleName,
 			Request:       "Update" + titleName + "Request",
 			Response:      "Update" + titleName + "Response",
 			GoName:        "Update" + titleName,
 			HTTPMethod:    "PUT",
 			HTTPPath:      "/v1/" + pg.pluralize(tableName) + "/{id}",
-			RequestFields: pg.generateUpdateFields(table.Columns),
-			ResponseType:  titleName,
+			RequestFields: pg.generateUpdateFields(table),
+			ResponseType:  "db." + titleName,
 		},
 		{
+			Operation:     "delete",
 			Name:          "Delete" + titleName,
 			Request:       "Delete" + titleName + "Request",
 			Response:      "Delete" + titleName + "Response",
 			GoName:        "Delete" + titleName,
 			HTTPMethod:    "DELETE",
 			HTTPPath:      "/v1/" + pg.pluralize(tableName) + "/{id}",
-			RequestFields: pg.generatePrimaryKeyField(table.Columns),
+			RequestFields: pg.generatePrimaryKeyField(table),
 			ResponseType:  "bool", // Success indicator
 		},
 	}
@@ -316,11 +328,14 @@ func (pg *ProtoGenerator) pluralize(s string) string {
 }
 
 // generateProtoFields generates protobuf field definitions
-func (pg *ProtoGenerator) generateProtoFields(columns []core.Column, includePK bool) []ProtoField {
+func (pg *ProtoGenerator) generateProtoFields(table core.Table, includePK bool) []ProtoField {
 	var fields []ProtoField
-	for _, col := range columns {
+	for _, col := range table.Columns {
 		// Skip primary key if not requested
-		if !includePK && pg.isPrimaryKeyField(col) {
+		if !includePK && pg.isPrimaryKeyField(table, col) {
+			continue
+		}
+		if !col.IsWritable() && !pg.isPrimaryKeyField(table, col) {
 			continue
 		}
 
@@ -336,9 +351,9 @@ func (pg *ProtoGenerator) generateProtoFields(columns []core.Column, includePK b
 }
 
 // generatePrimaryKeyField returns the primary key field for request messages
-func (pg *ProtoGenerator) generatePrimaryKeyField(columns []core.Column) []ProtoField {
-	for _, col := range columns {
-		if pg.isPrimaryKeyField(col) {
+func (pg *ProtoGenerator) generatePrimaryKeyField(table core.Table) []ProtoField {
+	for _, col := range table.Columns {
+		if pg.isPrimaryKeyField(table, col) {
 			return []ProtoField{
 				{
 					Name:     pg.toCamelCase(col.Name),
@@ -353,11 +368,14 @@ func (pg *ProtoGenerator) generatePrimaryKeyField(columns []core.Column) []Proto
 }
 
 // generateUpdateFields returns primary key + non-PK fields for update requests
-func (pg *ProtoGenerator) generateUpdateFields(columns []core.Column) []ProtoField {
+func (pg *ProtoGenerator) generateUpdateFields(table core.Table) []ProtoField {
 	var fields []ProtoField
 	fieldNum := 1
 
-	for _, col := range columns {
+	for _, col := range table.Columns {
+		if !col.IsWritable() && !pg.isPrimaryKeyField(table, col) {
+			continue
+		}
 		field := ProtoField{
 			Name:     pg.toCamelCase(col.Name),
 			Type:     core.SQLToProtoType(col.Type),
@@ -389,8 +407,11 @@ func (pg *ProtoGenerator) generatePaginationFields() []ProtoField {
 }
 
 // isPrimaryKeyField checks if column is a primary key
-func (pg *ProtoGenerator) isPrimaryKeyField(col core.Column) bool {
-	// Simple heuristic: primary key is usually first column and has specific characteristics
+func (pg *ProtoGenerator) isPrimaryKeyField(table core.Table, col core.Column) bool {
+	if len(table.PrimaryKey) > 0 {
+		return slices.Contains(table.PrimaryKey, col.Name)
+	}
+	// Without a declared key, guess from the column's type and name
 	return strings.Contains(strings.ToUpper(col.Type), "INTEGER") &&
 		(strings.Contains(strings.ToUpper(col.Name), "ID") ||
 			strings.Contains(strings.ToUpper(col.Name), "UUID") ||
@@ -422,7 +443,7 @@ import "google/protobuf/timestamp.proto";
 {{range .Messages}}
 // {{.GoName}} represents the {{.TableName}} table
 message {{.Name}} {
-{{range .Fields}}  {{.Type}} {{.Name}} = {{.Number}};
+{{range .Fields}}  {{.Type}} {{.Name}} = {{.Number}} [json_name = "{{.JSONName}}"];
 {{end}}}
 {{end}}`
 
@@ -440,12 +461,12 @@ import "{{.ImportPath}}";
 // {{.GoName}} provides CRUD operations for {{.TableName}}
 service {{.Name}} {
 {{range .Methods}}  rpc {{.Name}}({{.Request}}) returns ({{.Response}});
-{{end}}
+{{end}}}
 
 {{range .Methods}}
 // Request message for {{.Name}}
 message {{.Request}} {
-{{range .RequestFields}}  {{.Type}} {{.Name}} = {{.Number}};
+{{range .RequestFields}}  {{.Type}} {{.Name}} = {{.Number}} [json_name = "{{.JSONName}}"];
 {{end}}}
 
 // Response message for {{.Name}}
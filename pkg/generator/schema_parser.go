@@ -58,6 +58,8 @@ func (sp *SchemaParser) ParseMigrations(migrationDir string) (*core.Schema, erro
 		schema.Tables = append(schema.Tables, tables...)
 	}
 
+	resolveReferencedKeys(schema)
+
 	sp.logger.Info("Parsed schema", "tables", len(schema.Tables), "migrations", len(files))
 
 	return schema, nil
@@ -85,7 +87,7 @@ func (sp *SchemaParser) parseSQLFile(sqlContent string) ([]core.Table, error) {
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse CREATE TABLE statement: %w", err)
 			}
-			if table != nil {
+			if table != nil && !core.IsInternalTable(table.Name) {
 				tables = append(tables, *table)
 			}
 		}
@@ -111,11 +113,22 @@ func (sp *SchemaParser) cleanSQL(sql string) string {
 	// Remove SQL comments
 	lines := strings.Split(sql, "\n")
 	var cleaned []string
+	var pending []string // Annotations from a comment line, applied to the next definition
 
 	for _, line := range lines {
-		// Remove inline comments
+		// Remove inline comments, keeping @apiright annotations
 		if idx := strings.Index(line, "--"); idx >= 0 {
+			annotations := extractAnnotations(line[idx+2:])
 			line = strings.TrimSpace(line[:idx])
+			if line == "" {
+				pending = append(pending, annotations...)
+				continue
+			}
+			line = insertAnnotations(line, append(pending, annotations...))
+			pending = nil
+		} else if len(pending) > 0 && strings.TrimSpace(line) != "" && !strings.HasSuffix(strings.TrimSpace(line), "(") {
+			line = insertAnnotations(strings.TrimSpace(line), pending)
+			pending = nil
 		}
 
 		// Remove multi-line comments (basic implementation)
@@ -135,6 +148,33 @@ func (sp *SchemaParser) cleanSQL(sql string) string {
 	return strings.Join(cleaned, " ")
 }
 
+// annotationPattern matches column annotations such as "@apiright:hidden" or "@writeonly"
+var annotationPattern = regexp.MustCompile(`@(?:apiright:)?(\w+)`)
+
+// extractAnnotations returns normalized annotations from an @apiright comment
+func extractAnnotations(comment string) []string {
+	if !strings.Contains(comment, "@apiright:") {
+		return nil
+	}
+	var annotations []string
+	for _, match := range annotationPattern.FindAllStringSubmatch(comment, -1) {
+		annotations = append(annotations, "@apiright:"+strings.ToLower(match[1]))
+	}
+	return annotations
+}
+
+// insertAnnotations appends annotations to a column definition, before any trailing comma
+func insertAnnotations(line string, annotations []string) string {
+	if len(annotations) == 0 {
+		return line
+	}
+	suffix := ""
+	if strings.HasSuffix(line, ",") {
+		line, suffix = strings.TrimSuffix(line, ","), ","
+	}
+	return line + " " + strings.Join(annotations, " ") + suffix
+}
+
 // splitStatements splits SQL content into individual statements
 func (sp *SchemaParser) splitStatements(sql string) []string {
 	var statements []string
@@ -191,9 +231,11 @@ func (sp *SchemaParser) parseCreateTable(stmt string) (*core.Table, error) {
 	columnDefs := sp.parseColumnDefinitions(matches[2])
 
 	for _, colDef := range columnDefs {
-		// Skip constraints that are not columns
+		// Skip constraints that are not columns; only foreign keys are kept
 		if sp.isConstraint(colDef) {
-			// This is handled at table level, skip for now
+			if fk, ok := parseForeignKey(colDef); ok {
+				table.ForeignKeys = append(table.ForeignKeys, fk)
+			}
 			continue
 		}
 
@@ -203,6 +245,9 @@ func (sp *SchemaParser) parseCreateTable(stmt string) (*core.Table, error) {
 		}
 
 		table.Columns = append(table.Columns, *column)
+		if fk, ok := parseColumnReference(column.Name, colDef); ok {
+			table.ForeignKeys = append(table.ForeignKeys, fk)
+		}
 	}
 
 	// Debug: check for primary key and handle column-level PRIMARY KEY
@@ -274,6 +319,83 @@ func (sp *SchemaParser) isConstraint(def string) bool {
 		strings.HasPrefix(defUpper, "CONSTRAINT")
 }
 
+var (
+	foreignKeyRe      = regexp.MustCompile(`(?i)^(?:CONSTRAINT\s+(\w+)\s+)?FOREIGN\s+KEY\s*\(([^)]+)\)\s*REFERENCES\s+(\w+)\s*(?:\(([^)]+)\))?(.*)$`)
+	columnReferenceRe = regexp.MustCompile(`(?i)\bREFERENCES\s+(\w+)\s*(?:\(([^)]+)\))?(.*)$`)
+	referentialRe     = regexp.MustCompile(`(?i)ON\s+(DELETE|UPDATE)\s+(SET\s+NULL|SET\s+DEFAULT|CASCADE|RESTRICT|NO\s+ACTION)`)
+)
+
+// parseForeignKey parses a table-level FOREIGN KEY constraint
+func parseForeignKey(def string) (core.ForeignKey, bool) {
+	matches := foreignKeyRe.FindStringSubmatch(strings.TrimSpace(def))
+	if matches == nil {
+		return core.ForeignKey{}, false
+	}
+	fk := core.ForeignKey{
+		Name:       matches[1],
+		Columns:    splitColumnList(matches[2]),
+		RefTable:   matches[3],
+		RefColumns: splitColumnList(matches[4]),
+	}
+	applyReferentialActions(&fk, matches[5])
+	return fk, true
+}
+
+// parseColumnReference parses an inline REFERENCES clause of a column definition
+func parseColumnReference(column, def string) (core.ForeignKey, bool) {
+	matches := columnReferenceRe.FindStringSubmatch(def)
+	if matches == nil {
+		return core.ForeignKey{}, false
+	}
+	fk := core.ForeignKey{
+		Columns:    []string{column},
+		RefTable:   matches[1],
+		RefColumns: splitColumnList(matches[2]),
+	}
+	applyReferentialActions(&fk, matches[3])
+	return fk, true
+}
+
+// applyReferentialActions sets the ON DELETE and ON UPDATE actions of a foreign key
+func applyReferentialActions(fk *core.ForeignKey, clause string) {
+	for _, action := range referentialRe.FindAllStringSubmatch(clause, -1) {
+		value := strings.ToUpper(strings.Join(strings.Fields(action[2]), " "))
+		if strings.EqualFold(action[1], "DELETE") {
+			fk.OnDelete = value
+		} else {
+			fk.OnUpdate = value
+		}
+	}
+}
+
+// splitColumnList splits a parenthesized column list such as "a, b"
+func splitColumnList(list string) []string {
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		if column = strings.Trim(strings.TrimSpace(column), "`\""); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// resolveReferencedKeys fills in the referenced columns of foreign keys that
+// omit them, which then reference the primary key
+func resolveReferencedKeys(schema *core.Schema) {
+	primaryKeys := make(map[string][]string, len(schema.Tables))
+	for _, table := range schema.Tables {
+		primaryKeys[table.Name] = table.PrimaryKey
+	}
+	for i := range schema.Tables {
+		for j := range schema.Tables[i].ForeignKeys {
+			fk := &schema.Tables[i].ForeignKeys[j]
+			if len(fk.RefColumns) == 0 {
+				fk.RefColumns = primaryKeys[fk.RefTable]
+			}
+		}
+	}
+}
+
 // parseColumnDefinition parses a single column definition
 func (sp *SchemaParser) parseColumnDefinition(def string) (*core.Column, error) {
 	// Basic regex for column: name type [constraints]
@@ -307,6 +429,11 @@ func (sp *SchemaParser) parseColumnConstraints(constraints string, column *core.
 		part := parts[i]
 		partUpper := strings.ToUpper(part)
 
+		if annotation, ok := strings.CutPrefix(part, "@apiright:"); ok {
+			sp.applyAnnotation(annotation, column)
+			continue
+		}
+
 		switch partUpper {
 		case "NOT":
 			if i+1 < len(parts) && strings.ToUpper(parts[i+1]) == "NULL" {
@@ -335,6 +462,18 @@ func (sp *SchemaParser) parseColumnConstraints(constraints string, column *core.
 	}
 }
 
+// applyAnnotation applies an @apiright column annotation
+func (sp *SchemaParser) applyAnnotation(annotation string, column *core.Column) {
+	switch annotation {
+	case core.VisibilityHidden, core.VisibilityWriteOnly, core.VisibilityReadOnly:
+		column.Visibility = annotation
+	case "encrypted":
+		column.Encrypted = true
+	default:
+		sp.logger.Warn("Unknown column annotation", "column", column.Name, "annotation", annotation)
+	}
+}
+
 // addIndexToTable adds an index to the appropriate table
 func (sp *SchemaParser) addIndexToTable(tables *[]core.Table, index *core.Index) {
 	for i, table := range *tables {
@@ -134,6 +134,7 @@ func (sg *ServiceGenerator) prepareServiceData(table core.Table, ctx *core.Gener
 		"database/sql",
 		"fmt",
 		"github.com/bata94/apiright/pkg/core",
+		"github.com/bata94/apiright/pkg/database",
 	}
 
 	// Check if we need time import
@@ -283,7 +284,7 @@ func (s *{{.Title}}Service) Get{{.Title}}(ctx context.Context, {{.PrimaryKey.Nam
 	result, err := s.querier.Get{{.Title}}_ar_gen(ctx, {{.PrimaryKey.Name}})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("{{.TableName}} with %s %v not found", "{{.PrimaryKey.Name}}", {{.PrimaryKey.Name}})
+			return nil, core.NotFound("{{.TableName}} with %s %v not found", "{{.PrimaryKey.Name}}", {{.PrimaryKey.Name}})
 		}
 		s.logger.Error("Failed to get {{.TableName}}", "{{.PrimaryKey.Name}}", {{.PrimaryKey.Name}}, "error", err)
 		return nil, fmt.Errorf("failed to get {{.TableName}}: %w", err)
@@ -315,7 +316,7 @@ func (s *{{.Title}}Service) Create{{.Title}}(ctx context.Context, params Create{
 	result, err := s.querier.Create{{.Title}}_ar_gen(ctx, params)
 	if err != nil {
 		s.logger.Error("Failed to create {{.TableName}}", "params", params, "error", err)
-		return nil, fmt.Errorf("failed to create {{.TableName}}: %w", err)
+		return nil, database.TranslateError(fmt.Errorf("failed to create {{.TableName}}: %w", err))
 	}
 	
 	// Get the last inserted ID and fetch the record
@@ -333,7 +334,7 @@ func (s *{{.Title}}Service) Update{{.Title}}(ctx context.Context, params Update{
 	_, err := s.querier.Update{{.Title}}_ar_gen(ctx, params)
 	if err != nil {
 		s.logger.Error("Failed to update {{.TableName}}", "params", params, "error", err)
-		return nil, fmt.Errorf("failed to update {{.TableName}}: %w", err)
+		return nil, database.TranslateError(fmt.Errorf("failed to update {{.TableName}}: %w", err))
 	}
 	
 	// Fetch the updated record
@@ -345,7 +346,7 @@ func (s *{{.Title}}Service) Delete{{.Title}}(ctx context.Context, {{.PrimaryKey.
 	err := s.querier.Delete{{.Title}}_ar_gen(ctx, {{.PrimaryKey.Name}})
 	if err != nil {
 		s.logger.Error("Failed to delete {{.TableName}}", "{{.PrimaryKey.Name}}", {{.PrimaryKey.Name}}, "error", err)
-		return fmt.Errorf("failed to delete {{.TableName}}: %w", err)
+		return database.TranslateError(fmt.Errorf("failed to delete {{.TableName}}: %w", err))
 	}
 
 	return nil
@@ -17,6 +17,18 @@ const (
 	DialectMySQL    Dialect = "mysql"
 )
 
+// DialectFor returns the SQL dialect of a database type from apiright.yaml
+func DialectFor(databaseType string) Dialect {
+	switch databaseType {
+	case "postgres", "postgresql":
+		return DialectPostgres
+	case "mysql":
+		return DialectMySQL
+	default:
+		return DialectSQLite
+	}
+}
+
 // SQLGenerator generates CRUD SQL queries using Go templates
 type SQLGenerator struct {
 	templates *template.Template
@@ -39,7 +51,9 @@ type TableData struct {
 	PrimaryKeyWhere string
 	OrderByClause   string // Add for LIST query ORDER BY
 	Dialect         Dialect
-	HasReturning    bool // True if dialect supports RETURNING clause
+	HasReturning    bool   // True if dialect supports RETURNING clause
+	OwnerColumn     string // Row ownership column from policies (empty = none)
+	OwnerUpdateSet  string // UPDATE SET clause without the owner column
 }
 
 // ColumnData represents column data for template generation
@@ -50,6 +64,8 @@ type ColumnData struct {
 	Default  string
 	IsPK     bool
 	GoType   string
+	Readable bool // Returned by SELECT and RETURNING
+	Writable bool // Set by INSERT and UPDATE
 }
 
 // NewSQLGenerator creates a new SQL generator
@@ -90,6 +106,11 @@ func (sg *SQLGenerator) parseTemplates() error {
 		"create": sg.getCreateQueryTemplate(),
 		"update": sg.getUpdateQueryTemplate(),
 		"delete": deleteQueryTemplate,
+
+		"get_for_owner":    getForOwnerQueryTemplate,
+		"list_for_owner":   listForOwnerQueryTemplate,
+		"update_for_owner": updateForOwnerQueryTemplate,
+		"delete_for_owner": deleteForOwnerQueryTemplate,
 	}
 
 	sg.templates = template.New("sql").Option("missingkey=error")
@@ -117,6 +138,13 @@ func (sg *SQLGenerator) generateTableQueries(table core.Table, ctx *core.Generat
 		"delete": sg.executeTemplate("delete", tableData),
 	}
 
+	// Owner-scoped variants for tables with a row ownership policy
+	if tableData.OwnerColumn != "" {
+		for _, name := range ownerQueryOrder {
+			queries[name] = sg.executeTemplate(name, tableData)
+		}
+	}
+
 	// Combine all queries into single file
 	fileContent := sg.combineQueries(queries, tableData)
 
@@ -147,10 +175,14 @@ func (sg *SQLGenerator) prepareTableData(table core.Table) TableData {
 			Default:  col.Default,
 			IsPK:     isPK,
 			GoType:   core.SQLToGoType(col.Type),
+			Readable: isPK || col.IsReadable(),
+			Writable: isPK || col.Name == table.OwnerColumn || col.IsWritable(),
 		}
 
 		columns = append(columns, colData)
-		columnNames = append(columnNames, col.Name)
+		if colData.Readable {
+			columnNames = append(columnNames, col.Name)
+		}
 
 		if colData.IsPK {
 			primaryKey = colData
@@ -170,6 +202,7 @@ func (sg *SQLGenerator) prepareTableData(table core.Table) TableData {
 		ColumnsList:     strings.Join(columnNames, ", "),
 		Dialect:         sg.dialect,
 		HasReturning:    hasReturning,
+		OwnerColumn:     table.OwnerColumn,
 	}
 
 	// Prepare additional template data
@@ -183,20 +216,24 @@ func (sg *SQLGenerator) prepareQuerySpecificData(data *TableData) {
 	var insertColumns []string
 	var insertValues []string
 	var updateSet []string
+	var ownerUpdateSet []string
 	var pkWhere []string
 
 	for _, col := range data.Columns {
 		// INSERT columns and values - skip only auto-increment PK for INSERT
 		if col.IsPK && col.GoType == "int64" {
 			// Skip auto-increment primary key for INSERT only
-		} else {
+		} else if col.Writable {
 			insertColumns = append(insertColumns, col.Name)
 			insertValues = append(insertValues, "?")
 		}
 
 		// UPDATE SET clause
-		if !col.IsPK {
+		if !col.IsPK && col.Writable {
 			updateSet = append(updateSet, fmt.Sprintf("%s = ?", col.Name))
+			if col.Name != data.OwnerColumn {
+				ownerUpdateSet = append(ownerUpdateSet, fmt.Sprintf("%s = ?", col.Name))
+			}
 		}
 
 		// WHERE clause for PK - include ALL PK columns
@@ -208,7 +245,16 @@ func (sg *SQLGenerator) prepareQuerySpecificData(data *TableData) {
 	data.InsertColumns = strings.Join(insertColumns, ", ")
 	data.InsertValues = strings.Join(insertValues, ", ")
 	data.UpdateSet = strings.Join(updateSet, ", ")
+	if data.UpdateSet == "" && len(data.PrimaryKeyNames) > 0 {
+		// No client-writable columns; keep the statement valid
+		data.UpdateSet = fmt.Sprintf("%s = %s", data.PrimaryKeyNames[0], data.PrimaryKeyNames[0])
+	}
 	data.PrimaryKeyWhere = strings.Join(pkWhere, " AND ")
+	data.OwnerUpdateSet = strings.Join(ownerUpdateSet, ", ")
+	if data.OwnerUpdateSet == "" && data.OwnerColumn != "" {
+		// Nothing but the owner is updatable; keep the statement valid
+		data.OwnerUpdateSet = fmt.Sprintf("%s = %s", data.OwnerColumn, data.OwnerColumn)
+	}
 
 	// Set OrderByClause - use primary keys if available, otherwise first column
 	if len(data.PrimaryKeyNames) > 0 {
@@ -252,10 +298,10 @@ func (sg *SQLGenerator) combineQueries(queries map[string]string, data TableData
 `, data.Name)
 
 	// Add each query in order
-	order := []string{"get", "list", "create", "update", "delete"}
+	order := append([]string{"get", "list", "create", "update", "delete"}, ownerQueryOrder...)
 	for _, queryType := range order {
 		if query, exists := queries[queryType]; exists && query != "" {
-			fmt.Fprintf(&result, "-- %s\n%s\n\n", toTitle(queryType), query)
+			fmt.Fprintf(&result, "-- %s\n%s\n\n", toTitle(strings.ReplaceAll(queryType, "_", " ")), query)
 		}
 	}
 
@@ -301,12 +347,12 @@ SELECT {{.ColumnsList}} FROM {{.Name}} WHERE {{.PrimaryKeyWhere}} LIMIT 1;`
 SELECT {{.ColumnsList}} FROM {{.Name}} ORDER BY {{.OrderByClause}} LIMIT ? OFFSET ?;`
 
 	createQueryTemplatePostgres = `-- name: Create{{.Title}}_ar_gen :one
-INSERT INTO {{.Name}} ({{.InsertColumns}}) VALUES ({{.InsertValues}}) RETURNING {{.ColumnsList}};`
+INSERT INTO {{.Name}} ({{.InsertColumns}}) VALUES ({{.InsertValues}}) RETURNING {{.PrimaryKey.Name}};`
 
-	createQueryTemplateSQLite = `-- name: Create{{.Title}}_ar_gen :exec
+	createQueryTemplateSQLite = `-- name: Create{{.Title}}_ar_gen :execresult
 INSERT INTO {{.Name}} ({{.InsertColumns}}) VALUES ({{.InsertValues}});`
 
-	createQueryTemplateMySQL = `-- name: Create{{.Title}}_ar_gen :exec
+	createQueryTemplateMySQL = `-- name: Create{{.Title}}_ar_gen :execresult
 INSERT INTO {{.Name}} ({{.InsertColumns}}) VALUES ({{.InsertValues}});`
 
 	updateQueryTemplatePostgres = `-- name: Update{{.Title}}_ar_gen :one
@@ -317,8 +363,25 @@ UPDATE {{.Name}} SET {{.UpdateSet}} WHERE {{.PrimaryKeyWhere}};`
 
 	deleteQueryTemplate = `-- name: Delete{{.Title}}_ar_gen :exec
 DELETE FROM {{.Name}} WHERE {{.PrimaryKeyWhere}};`
+
+	// Owner-scoped queries push row ownership policies down into SQL.
+	// Updates cannot change the owner column.
+	getForOwnerQueryTemplate = `-- name: Get{{.Title}}ForOwner_ar_gen :one
+SELECT {{.ColumnsList}} FROM {{.Name}} WHERE {{.PrimaryKeyWhere}} AND {{.OwnerColumn}} = ? LIMIT 1;`
+
+	listForOwnerQueryTemplate = `-- name: List{{.Title}}ForOwner_ar_gen :many
+SELECT {{.ColumnsList}} FROM {{.Name}} WHERE {{.OwnerColumn}} = ? ORDER BY {{.OrderByClause}} LIMIT ? OFFSET ?;`
+
+	updateForOwnerQueryTemplate = `-- name: Update{{.Title}}ForOwner_ar_gen :execrows
+UPDATE {{.Name}} SET {{.OwnerUpdateSet}} WHERE {{.PrimaryKeyWhere}} AND {{.OwnerColumn}} = ?;`
+
+	deleteForOwnerQueryTemplate = `-- name: Delete{{.Title}}ForOwner_ar_gen :execrows
+DELETE FROM {{.Name}} WHERE {{.PrimaryKeyWhere}} AND {{.OwnerColumn}} = ?;`
 )
 
+// ownerQueryOrder lists the owner-scoped query templates in output order
+var ownerQueryOrder = []string{"get_for_owner", "list_for_owner", "update_for_owner", "delete_for_owner"}
+
 // getCreateQueryTemplate returns the appropriate create query template for the dialect
 func (sg *SQLGenerator) getCreateQueryTemplate() string {
 	switch sg.dialect {
@@ -222,6 +222,7 @@ import (
 	"github.com/bata94/apiright/pkg/core"
 	"github.com/bata94/apiright/pkg/database"
 	"github.com/bata94/apiright/pkg/server"
+	"github.com/bata94/apiright/pkg/telemetry"
 	"{{.AdapterPath}}"
 )
 
@@ -245,6 +246,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), cfg.Telemetry, cfg.Project.Version)
+	if err != nil {
+		logger.Error("Failed to set up telemetry", core.Error(err))
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTelemetry(context.Background()); err != nil {
+			logger.Error("Failed to flush telemetry", core.Error(err))
+		}
+	}()
+
 	db, err := database.NewDatabase(&cfg.Database, logger)
 	if err != nil {
 		logger.Error("Failed to create database", core.Error(err))
@@ -264,6 +276,11 @@ func main() {
 
 	srv := server.NewServer(&cfg.Server, "{{.ProjectDir}}", db, logger)
 
+	if err := srv.ConfigureMiddleware(cfg); err != nil {
+		logger.Error("Failed to configure middleware", core.Error(err))
+		os.Exit(1)
+	}
+
 	// Initialize real service adapters
 	if err := adapters.Init(srv, db, logger); err != nil {
 		logger.Error("Failed to initialize service adapters", core.Error(err))
@@ -0,0 +1,199 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// VersionData represents an API version frozen in versions/<name>/schema.json
+type VersionData struct {
+	Name    string // Version name from apiright.yaml (e.g. "v1")
+	VarName string // Variable holding the server.APIVersion (e.g. "apiV1")
+	Tables  []VersionTableData
+}
+
+// VersionTableData is a table as frozen in an API version
+type VersionTableData struct {
+	AdapterData
+	WritableList string // Quoted columns clients may set in the version
+	ColumnMap    string // Frozen column names mapped to current ones, as map literal entries
+}
+
+// SnapshotPath returns the file holding the schema frozen as API version name
+func SnapshotPath(ctx *core.GenerationContext, name string) string {
+	return ctx.Join(ctx.ProjectDir, "versions", name, "schema.json")
+}
+
+// FreezeSchema writes schema to versions/<name>/schema.json; later generations
+// serve API version name in this shape on top of the current tables
+func FreezeSchema(schema *core.Schema, ctx *core.GenerationContext, name string) error {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	path := SnapshotPath(ctx, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create version directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadSnapshot reads the schema frozen as API version name
+func LoadSnapshot(ctx *core.GenerationContext, name string) (*core.Schema, error) {
+	data, err := os.ReadFile(SnapshotPath(ctx, name))
+	if err != nil {
+		return nil, err
+	}
+	var schema core.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema snapshot for API version %s: %w", name, err)
+	}
+	return &schema, nil
+}
+
+// generateVersions writes gen/go/adapters/version_<name>_ar_gen.go for every
+// configured API version with a schema snapshot. Frozen tables that no longer
+// exist are left out; frozen columns are mapped to the current ones.
+func (ag *AdapterGenerator) generateVersions(schema *core.Schema, ctx *core.GenerationContext) ([]VersionData, error) {
+	current := make(map[string]core.Table, len(schema.Tables))
+	for _, table := range schema.Tables {
+		current[table.Name] = table
+	}
+
+	var versions []VersionData
+	for _, version := range ag.config.Server.Versions {
+		snapshot, err := LoadSnapshot(ctx, version.Name)
+		if errors.Is(err, os.ErrNotExist) {
+			ag.logger.Warn("API version has no frozen schema, it serves the current one", "version", version.Name,
+				"hint", "run 'apiright gen --freeze "+version.Name+"' before changing the schema")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data := VersionData{Name: version.Name, VarName: "api" + core.ToPascalCase(version.Name)}
+		for _, table := range snapshot.Tables {
+			currentTable, ok := current[table.Name]
+			if !ok {
+				ag.logger.Warn("Frozen table no longer exists", "version", version.Name, "table", table.Name)
+				continue
+			}
+			tableData, err := ag.mapVersionTable(version.Name, table, currentTable, version.Renames[table.Name], ctx)
+			if err != nil {
+				return nil, err
+			}
+			data.Tables = append(data.Tables, tableData)
+		}
+
+		code := ag.executeTemplate("version", data)
+		outputPath := ctx.Join(ctx.ProjectDir, "gen", "go", "adapters", "version_"+version.Name+ag.genSuffix+".go")
+		if err := ctx.WriteFile(outputPath, []byte(code), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write version file: %w", err)
+		}
+		versions = append(versions, data)
+	}
+	return versions, nil
+}
+
+// mapVersionTable maps the columns of a frozen table to the current table.
+// Columns keep their name unless renames maps them to a new one; frozen columns
+// that no longer exist are left out of the mapping.
+func (ag *AdapterGenerator) mapVersionTable(version string, table, currentTable core.Table, renames map[string]string, ctx *core.GenerationContext) (VersionTableData, error) {
+	for frozen := range renames {
+		if !slices.ContainsFunc(table.Columns, func(c core.Column) bool { return c.Name == frozen }) {
+			return VersionTableData{}, fmt.Errorf("API version %s renames %s.%s, which is not a frozen column", version, table.Name, frozen)
+		}
+	}
+
+	var writable, mapping []string
+	mapped := make(map[string]bool, len(table.Columns))
+	for _, col := range table.Columns {
+		name := col.Name
+		renamed, isRenamed := renames[col.Name]
+		if isRenamed {
+			name = renamed
+		}
+		i := slices.IndexFunc(currentTable.Columns, func(c core.Column) bool { return c.Name == name })
+		if i < 0 {
+			if isRenamed {
+				return VersionTableData{}, fmt.Errorf("API version %s renames %s.%s to %s, which does not exist", version, table.Name, col.Name, name)
+			}
+			ag.logger.Warn("Frozen column no longer exists", "version", version, "table", table.Name, "column", col.Name,
+				"hint", "add it to renames if it was renamed")
+			continue
+		}
+		if !strings.EqualFold(currentTable.Columns[i].Type, col.Type) {
+			ag.logger.Warn("Frozen column changed type", "version", version, "table", table.Name, "column", col.Name,
+				"frozen", col.Type, "current", currentTable.Columns[i].Type)
+		}
+		mapped[name] = true
+		mapping = append(mapping, fmt.Sprintf("%q: %q", col.Name, name))
+		if col.IsWritable() {
+			writable = append(writable, fmt.Sprintf("%q", col.Name))
+		}
+	}
+
+	for _, col := range currentTable.Columns {
+		if !mapped[col.Name] && !col.Nullable && col.Default == "" && !col.AutoIncrement && col.IsWritable() {
+			ag.logger.Warn("Required column cannot be set through API version", "version", version, "table", table.Name, "column", col.Name)
+		}
+	}
+
+	return VersionTableData{
+		AdapterData:  ag.prepareAdapterData(table, ctx),
+		WritableList: strings.Join(writable, ", "),
+		ColumnMap:    strings.Join(mapping, ", "),
+	}, nil
+}
+
+// Version template; each frozen table gets the Resource its adapter had
+const versionTemplate = `// Code generated by APIRight. DO NOT EDIT.
+// API version {{.Name}}, frozen in versions/{{.Name}}/schema.json
+
+package adapters
+
+import (
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// {{.VarName}} serves the tables and columns of API version {{.Name}}
+var {{.VarName}} = server.APIVersion{
+	Name: "{{.Name}}",
+	Tables: []server.VersionedTable{
+{{- range .Tables}}
+		{
+			Resource: core.Resource{
+				Type:        "{{.TableName}}",
+				PrimaryKey:  "{{.PrimaryKey.Name}}",
+				ColumnTypes: map[string]string{ {{- .ColumnTypeList -}} },
+				Element:     "{{.XMLElement}}",
+				Columns: []core.Column{
+{{- range .XMLColumns}}
+					{Name: "{{.Name}}", Type: "{{.Type}}"{{if .Nullable}}, Nullable: true{{end}}},
+{{- end}}
+				},
+{{- if .Relations}}
+				Relations: []core.Relation{
+{{- range .Relations}}
+					{Name: "{{.Name}}", Column: "{{.Column}}", Type: "{{.Table}}"},
+{{- end}}
+				},
+{{- end}}
+			},
+			Readable: []string{ {{- .ReadableList -}} },
+			Writable: []string{ {{- .WritableList -}} },
+			Columns:  map[string]string{ {{- .ColumnMap -}} },
+		},
+{{- end}}
+	},
+}
+`
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// XMLGenerator generates an XML schema per table from the parsed SQL schema
+type XMLGenerator struct {
+	logger core.Logger
+}
+
+// NewXMLGenerator creates a new XML schema generator
+func NewXMLGenerator(logger core.Logger) *XMLGenerator {
+	return &XMLGenerator{logger: logger}
+}
+
+// Generate writes gen/xml/<table>.xsd for every table
+func (g *XMLGenerator) Generate(schema *core.Schema, ctx *core.GenerationContext) error {
+	xmlDir := ctx.Join(ctx.ProjectDir, "gen", "xml")
+	if err := os.MkdirAll(xmlDir, 0755); err != nil {
+		return fmt.Errorf("failed to create xml directory: %w", err)
+	}
+
+	for _, table := range schema.Tables {
+		path := filepath.Join(xmlDir, table.Name+".xsd")
+		if err := os.WriteFile(path, []byte(g.BuildSchema(table)), 0644); err != nil {
+			return fmt.Errorf("failed to write XML schema for %s: %w", table.Name, err)
+		}
+	}
+
+	g.logger.Info("Generated XML schemas", "path", xmlDir, "tables", len(schema.Tables))
+	return nil
+}
+
+// BuildSchema renders the XSD of a table: a complex type with an element per
+// API column in table order, a row element and a collection element of rows.
+// Elements are optional since responses omit write-only columns and request
+// bodies read-only ones; NULL columns are xsi:nil.
+func (g *XMLGenerator) BuildSchema(table core.Table) string {
+	namespace := core.XMLNamespace(table.Name)
+	element := xmlElementName(table.Name)
+	typeName := core.ToPascalCase(element)
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<!-- Code generated by APIRight. DO NOT EDIT. -->\n")
+	fmt.Fprintf(&b, "<xs:schema xmlns:xs=\"http://www.w3.org/2001/XMLSchema\" xmlns=%q targetNamespace=%q elementFormDefault=\"qualified\">\n", namespace, namespace)
+
+	fmt.Fprintf(&b, "  <xs:complexType name=%q>\n    <xs:sequence>\n", typeName)
+	for _, col := range xmlColumns(table) {
+		nillable := ""
+		if col.Nullable {
+			nillable = ` nillable="true"`
+		}
+		fmt.Fprintf(&b, "      <xs:element name=%q type=%q minOccurs=\"0\"%s/>\n", col.Name, core.SQLTypeToXSD(col.Type), nillable)
+	}
+	b.WriteString("    </xs:sequence>\n  </xs:complexType>\n")
+
+	fmt.Fprintf(&b, "  <xs:element name=%q type=%q/>\n", element, typeName)
+	fmt.Fprintf(&b, "  <xs:element name=%q>\n", table.Name)
+	b.WriteString("    <xs:complexType>\n      <xs:sequence>\n")
+	fmt.Fprintf(&b, "        <xs:element ref=%q minOccurs=\"0\" maxOccurs=\"unbounded\"/>\n", element)
+	b.WriteString("      </xs:sequence>\n    </xs:complexType>\n  </xs:element>\n")
+	b.WriteString("</xs:schema>\n")
+	return b.String()
+}
+
+// xmlElementName returns the row element of a table (e.g. "post" for posts);
+// tables whose singular is their own name get a "_row" suffix so rows and
+// lists stay distinct
+func xmlElementName(table string) string {
+	if element := singularize(table); element != table {
+		return element
+	}
+	return table + "_row"
+}
+
+// xmlColumns returns the columns clients read or write, in table order
+func xmlColumns(table core.Table) []core.Column {
+	var columns []core.Column
+	for _, col := range table.Columns {
+		if col.IsReadable() || col.IsWritable() {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
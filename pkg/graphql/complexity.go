@@ -0,0 +1,73 @@
+package graphql
+
+import (
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	// MaxDepth bounds how deeply object fields may be nested in an operation
+	MaxDepth = 10
+	// MaxCost bounds the estimated number of objects an operation can return;
+	// each list counts with its limit argument, or its default limit
+	MaxCost = 250000
+	// defaultListLimit is the page size of root list fields without a limit
+	defaultListLimit = 50
+)
+
+// checkComplexity rejects operations nested deeper than MaxDepth or estimated
+// to return more than MaxCost objects, before any of them is resolved.
+// Introspection is left out; it is bounded by the size of the schema.
+func checkComplexity(set ast.SelectionSet, vars map[string]any) *gqlerror.Error {
+	depth, cost := measure(set, vars, 1)
+	if depth > MaxDepth {
+		return gqlerror.Errorf("query depth %d exceeds the maximum of %d", depth, MaxDepth)
+	}
+	if cost > MaxCost {
+		return gqlerror.Errorf("query cost %d exceeds the maximum of %d; pass smaller limit arguments", cost, MaxCost)
+	}
+	return nil
+}
+
+// measure returns the object nesting depth of set and the number of objects it
+// can return when executed for multiplier parent objects
+func measure(set ast.SelectionSet, vars map[string]any, multiplier int64) (int, int64) {
+	depth, cost := 0, int64(0)
+	for _, selection := range set {
+		var childDepth int
+		var childCost int64
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if len(sel.SelectionSet) == 0 || sel.Name == "__schema" || sel.Name == "__type" {
+				continue
+			}
+			objects := min(multiplier*listSize(sel, vars), MaxCost+1)
+			childDepth, childCost = measure(sel.SelectionSet, vars, objects)
+			childDepth++
+			childCost += objects
+		case *ast.InlineFragment:
+			childDepth, childCost = measure(sel.SelectionSet, vars, multiplier)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				childDepth, childCost = measure(sel.Definition.SelectionSet, vars, multiplier)
+			}
+		}
+		depth = max(depth, childDepth)
+		cost = min(cost+childCost, MaxCost+1)
+	}
+	return depth, cost
+}
+
+// listSize returns the number of objects field returns per parent
+func listSize(field *ast.Field, vars map[string]any) int64 {
+	if field.Definition == nil || field.Definition.Type.Elem == nil {
+		return 1
+	}
+	if limit, ok := field.ArgumentMap(vars)["limit"].(int64); ok && limit > 0 {
+		return min(limit, MaxLimit)
+	}
+	if field.Definition.Directives.ForName("crud") != nil {
+		return defaultListLimit
+	}
+	return MaxLimit
+}
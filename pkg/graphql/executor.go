@@ -0,0 +1,479 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	// MaxLimit bounds the rows a list field returns per parent
+	MaxLimit = 1000
+	// batchSize bounds the values of one batched relationship query, keeping it
+	// below the bind variable limits of the databases
+	batchSize = 500
+)
+
+// node is an object whose selection set is still to be executed
+type node struct {
+	value any // Row, introspection value, or nil for the root
+	out   *object
+	path  ast.Path
+}
+
+// collectedField is a response key and the fields merged into it
+type collectedField struct {
+	key    string
+	fields []*ast.Field
+}
+
+// executor executes one operation. Selection sets are executed for all objects
+// of a level at once, so a relationship costs one Find per level rather than
+// one per parent row.
+type executor struct {
+	ctx    context.Context
+	schema *ast.Schema
+	doc    *ast.QueryDocument
+	vars   map[string]any
+	source DataSource
+	logger core.Logger
+	errors gqlerror.List
+}
+
+// executeObjects executes set on every node, which all have type def. Fields
+// run one after the other, which keeps mutations serial.
+func (e *executor) executeObjects(def *ast.Definition, nodes []*node, set ast.SelectionSet) {
+	for _, cf := range e.collectFields(def, set) {
+		field := cf.fields[0]
+		fieldType := field.Definition.Type
+
+		var values []any
+		var errs []error
+		if field.Name == "__typename" {
+			values = make([]any, len(nodes))
+			errs = make([]error, len(nodes))
+			for i := range nodes {
+				values[i] = def.Name
+			}
+		} else {
+			values, errs = e.resolve(def, field, nodes)
+		}
+
+		var pending []*node
+		for i, n := range nodes {
+			path := extendPath(n.path, ast.PathName(cf.key))
+			if errs[i] != nil {
+				e.addError(errs[i], path, field)
+				n.out.set(cf.key, nullOf(fieldType), fieldType.NonNull)
+				continue
+			}
+			n.out.set(cf.key, e.complete(fieldType, values[i], path, field, &pending), fieldType.NonNull)
+		}
+
+		if len(pending) > 0 {
+			var merged ast.SelectionSet
+			for _, f := range cf.fields {
+				merged = append(merged, f.SelectionSet...)
+			}
+			e.executeObjects(e.schema.Types[fieldType.Name()], pending, merged)
+		}
+	}
+}
+
+// complete converts a resolved value to the output of type t. Objects are
+// queued on pending and filled in when their level is executed.
+func (e *executor) complete(t *ast.Type, value any, path ast.Path, field *ast.Field, pending *[]*node) any {
+	if value == nil {
+		if t.NonNull {
+			e.addError(gqlerror.Errorf("Cannot return null for non-nullable field %s", field.Name), path, field)
+			return invalid
+		}
+		return nil
+	}
+
+	if t.Elem != nil {
+		items, ok := toSlice(value)
+		if !ok {
+			e.addError(fmt.Errorf("expected a list for %s, got %T", field.Name, value), path, field)
+			return nullOf(t)
+		}
+		out := &list{items: make([]any, len(items)), nonNull: t.Elem.NonNull}
+		for i, item := range items {
+			out.items[i] = e.complete(t.Elem, item, extendPath(path, ast.PathIndex(i)), field, pending)
+		}
+		return out
+	}
+
+	def := e.schema.Types[t.NamedType]
+	switch def.Kind {
+	case ast.Scalar:
+		out, err := serializeScalar(def.Name, value)
+		if err != nil {
+			e.addError(err, path, field)
+			return nullOf(t)
+		}
+		return out
+	case ast.Enum:
+		return fmt.Sprint(value)
+	case ast.Object:
+		out := newObject()
+		*pending = append(*pending, &node{value: value, out: out, path: path})
+		return out
+	default:
+		e.addError(fmt.Errorf("cannot resolve %s type %s", strings.ToLower(string(def.Kind)), def.Name), path, field)
+		return nullOf(t)
+	}
+}
+
+// resolve returns the value of field for every node, with an error per node
+func (e *executor) resolve(def *ast.Definition, field *ast.Field, nodes []*node) ([]any, []error) {
+	values := make([]any, len(nodes))
+	errs := make([]error, len(nodes))
+	args := field.ArgumentMap(e.vars)
+
+	switch {
+	case field.Name == "__schema":
+		values[0] = e.schemaValue()
+	case field.Name == "__type":
+		if t, ok := e.schema.Types[fmt.Sprint(args["name"])]; ok {
+			values[0] = e.typeValue(t)
+		}
+	case field.Definition.Directives.ForName("crud") != nil:
+		values[0], errs[0] = e.resolveCRUD(field, args)
+	case field.Definition.Directives.ForName("relation") != nil:
+		e.resolveRelation(field, args, nodes, values, errs)
+	default:
+		for i, n := range nodes {
+			row, _ := n.value.(map[string]any)
+			value := row[field.Name]
+			if lazy, ok := value.(resolver); ok {
+				value = lazy(args)
+			}
+			values[i] = value
+		}
+	}
+	return values, errs
+}
+
+// resolveCRUD runs the table operation of a root field
+func (e *executor) resolveCRUD(field *ast.Field, args map[string]any) (any, error) {
+	crud := directiveArguments(field.Definition.Directives.ForName("crud"))
+	table := crud["table"]
+	args = e.coerceArguments(field.Definition.Arguments, args)
+
+	switch crud["op"] {
+	case "get":
+		row, err := e.source.Get(e.ctx, table, args["id"])
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, nil
+		}
+		return rowOrNil(row), err
+	case "list":
+		filter, err := e.filter(field.Definition.Arguments.ForName("filter"), args["filter"])
+		if err != nil {
+			return nil, err
+		}
+		if filter.Limit, filter.Offset, err = pagination(args, defaultListLimit); err != nil {
+			return nil, err
+		}
+		return e.source.Find(e.ctx, table, filter)
+	case "create":
+		input, _ := args["input"].(map[string]any)
+		row, err := e.source.Create(e.ctx, table, input)
+		return rowOrNil(row), err
+	case "update":
+		input, _ := args["input"].(map[string]any)
+		if input == nil {
+			input = map[string]any{}
+		}
+		input[primaryKey(e.schema.Types[field.Definition.Type.Name()])] = args["id"]
+		row, err := e.source.Update(e.ctx, table, input)
+		return rowOrNil(row), err
+	case "delete":
+		if err := e.source.Delete(e.ctx, table, args["id"]); err != nil {
+			return nil, err
+		}
+		return true, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %s for %s", crud["op"], table)
+	}
+}
+
+// resolveRelation loads the related rows of all nodes with batched Find calls
+// and hands each node the rows matching its via column
+func (e *executor) resolveRelation(field *ast.Field, args map[string]any, nodes []*node, values []any, errs []error) {
+	relation := directiveArguments(field.Definition.Directives.ForName("relation"))
+	table, column, via := relation["table"], relation["column"], relation["via"]
+	many := field.Definition.Type.Elem != nil
+
+	limit, offset, err := pagination(e.coerceArguments(field.Definition.Arguments, args), MaxLimit)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return
+	}
+
+	var keys []any
+	seen := make(map[string]bool)
+	for _, n := range nodes {
+		row, _ := n.value.(map[string]any)
+		if value := row[via]; value != nil && !seen[fmt.Sprint(value)] {
+			seen[fmt.Sprint(value)] = true
+			keys = append(keys, value)
+		}
+	}
+
+	// The page of every parent is cut in SQL, so a parent with many related
+	// rows never loads more than its limit
+	if !many {
+		limit, offset = 1, 0
+	}
+	groups := make(map[string][]map[string]any)
+	for start := 0; start < len(keys); start += batchSize {
+		batch := keys[start:min(start+batchSize, len(keys))]
+		rows, err := e.source.Find(e.ctx, table, core.Filter{
+			Equal:  map[string][]any{column: batch},
+			Limit:  limit,
+			Offset: offset,
+			PerKey: column,
+		})
+		if err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+			return
+		}
+		for _, row := range rows {
+			key := fmt.Sprint(row[column])
+			groups[key] = append(groups[key], row)
+		}
+	}
+
+	for i, n := range nodes {
+		row, _ := n.value.(map[string]any)
+		var matched []map[string]any
+		if value := row[via]; value != nil {
+			matched = groups[fmt.Sprint(value)]
+		}
+		if !many {
+			if len(matched) > 0 {
+				values[i] = matched[0]
+			}
+			continue
+		}
+		values[i] = append([]map[string]any{}, matched...)
+	}
+}
+
+// filter converts a filter input to equality conditions; column_in fields match any of a list
+func (e *executor) filter(arg *ast.ArgumentDefinition, value any) (core.Filter, error) {
+	filter := core.Filter{Equal: map[string][]any{}}
+	fields, _ := value.(map[string]any)
+	if arg == nil || len(fields) == 0 {
+		return filter, nil
+	}
+
+	input := e.schema.Types[arg.Type.Name()]
+	for name, value := range fields {
+		def := input.Fields.ForName(name)
+		if def == nil || value == nil {
+			continue
+		}
+		column := name
+		values := []any{value}
+		if def.Type.Elem != nil {
+			column = strings.TrimSuffix(name, "_in")
+			values, _ = toSlice(value)
+		}
+		if _, ok := filter.Equal[column]; ok {
+			return filter, core.BadRequest("filter on %s takes either %s or %s_in", column, column, column)
+		}
+		filter.Equal[column] = values
+	}
+	return filter, nil
+}
+
+// pagination returns the limit and offset arguments; a missing limit is fallback
+func pagination(args map[string]any, fallback int32) (int32, int32, error) {
+	limit, offset := fallback, int32(0)
+	if value, ok := args["limit"].(int64); ok {
+		if value < 1 || value > MaxLimit {
+			return 0, 0, core.BadRequest("limit must be between 1 and %d", MaxLimit)
+		}
+		limit = int32(value)
+	}
+	if value, ok := args["offset"].(int64); ok {
+		if value < 0 || value > 1<<31-1 {
+			return 0, 0, core.BadRequest("offset must not be negative")
+		}
+		offset = int32(value)
+	}
+	return limit, offset, nil
+}
+
+// collectFields returns the fields of set that apply to def, merged by
+// response key in order of appearance, honoring fragments, @skip and @include
+func (e *executor) collectFields(def *ast.Definition, set ast.SelectionSet) []*collectedField {
+	var fields []*collectedField
+	byKey := make(map[string]*collectedField)
+	visited := make(map[string]bool)
+
+	var collect func(set ast.SelectionSet)
+	collect = func(set ast.SelectionSet) {
+		for _, selection := range set {
+			switch sel := selection.(type) {
+			case *ast.Field:
+				if e.skipped(sel.Directives) {
+					continue
+				}
+				key := sel.Alias
+				if key == "" {
+					key = sel.Name
+				}
+				if cf, ok := byKey[key]; ok {
+					cf.fields = append(cf.fields, sel)
+					continue
+				}
+				cf := &collectedField{key: key, fields: []*ast.Field{sel}}
+				byKey[key] = cf
+				fields = append(fields, cf)
+			case *ast.InlineFragment:
+				if e.skipped(sel.Directives) || !e.applies(def, sel.TypeCondition) {
+					continue
+				}
+				collect(sel.SelectionSet)
+			case *ast.FragmentSpread:
+				if e.skipped(sel.Directives) || visited[sel.Name] {
+					continue
+				}
+				visited[sel.Name] = true
+				fragment := e.doc.Fragments.ForName(sel.Name)
+				if fragment == nil || !e.applies(def, fragment.TypeCondition) {
+					continue
+				}
+				collect(fragment.SelectionSet)
+			}
+		}
+	}
+	collect(set)
+	return fields
+}
+
+// skipped reports whether @skip or @include leave a selection out
+func (e *executor) skipped(directives ast.DirectiveList) bool {
+	if skip := directives.ForName("skip"); skip != nil && skip.ArgumentMap(e.vars)["if"] == true {
+		return true
+	}
+	if include := directives.ForName("include"); include != nil && include.ArgumentMap(e.vars)["if"] == false {
+		return true
+	}
+	return false
+}
+
+// applies reports whether a fragment with typeCondition applies to def
+func (e *executor) applies(def *ast.Definition, typeCondition string) bool {
+	if typeCondition == "" || typeCondition == def.Name {
+		return true
+	}
+	for _, parent := range e.schema.GetImplements(def) {
+		if parent.Name == typeCondition {
+			return true
+		}
+	}
+	return false
+}
+
+// addError records a field error. Typed errors keep their message and code;
+// other errors are logged and reported as internal errors.
+func (e *executor) addError(err error, path ast.Path, field *ast.Field) {
+	var locations []gqlerror.Location
+	if field.Position != nil {
+		locations = []gqlerror.Location{{Line: field.Position.Line, Column: field.Position.Column}}
+	}
+
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) {
+		e.errors = append(e.errors, &gqlerror.Error{Message: gqlErr.Message, Path: path, Locations: locations, Extensions: gqlErr.Extensions})
+		return
+	}
+
+	typed, ok := core.AsAPIError(err)
+	if !ok {
+		e.logger.Error("GraphQL field failed", "path", path.String(), "error", err)
+		typed = core.ToAPIError(err)
+	}
+	message := typed.Message
+	if message == "" {
+		message = http.StatusText(typed.Code.HTTPStatus())
+	}
+	extensions := map[string]any{"code": typed.Code.String()}
+	if len(typed.Violations) > 0 {
+		extensions["violations"] = typed.Violations
+	}
+	e.errors = append(e.errors, &gqlerror.Error{Message: message, Path: path, Locations: locations, Extensions: extensions})
+}
+
+// directiveArguments returns the literal arguments of a schema directive
+func directiveArguments(directive *ast.Directive) map[string]string {
+	args := make(map[string]string, len(directive.Arguments))
+	for _, arg := range directive.Arguments {
+		args[arg.Name] = arg.Value.Raw
+	}
+	return args
+}
+
+// primaryKey returns the ID field of an object type
+func primaryKey(def *ast.Definition) string {
+	for _, field := range def.Fields {
+		if field.Type.NamedType == "ID" {
+			return field.Name
+		}
+	}
+	return "id"
+}
+
+// extendPath returns a copy of path with el appended
+func extendPath(path ast.Path, el ast.PathElement) ast.Path {
+	out := make(ast.Path, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, el)
+}
+
+// toSlice returns the elements of a slice value
+func toSlice(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case []map[string]any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
+
+// rowOrNil returns nil for a nil row so it completes as null
+func rowOrNil(row map[string]any) any {
+	if row == nil {
+		return nil
+	}
+	return row
+}
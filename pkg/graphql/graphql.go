@@ -0,0 +1,141 @@
+// Package graphql executes GraphQL requests against the schema generated from
+// the SQL migrations, resolving tables through a DataSource.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// DataSource reads and writes the rows of the tables behind the schema. Rows
+// are maps keyed by column name.
+type DataSource interface {
+	Get(ctx context.Context, table string, id any) (map[string]any, error)
+	Find(ctx context.Context, table string, filter core.Filter) ([]map[string]any, error)
+	Create(ctx context.Context, table string, input map[string]any) (map[string]any, error)
+	// Update receives the input with the primary key set
+	Update(ctx context.Context, table string, input map[string]any) (map[string]any, error)
+	Delete(ctx context.Context, table string, id any) error
+}
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request; Data is absent when the request failed
+// before execution
+type Response struct {
+	Data   json.RawMessage `json:"data,omitempty"`
+	Errors gqlerror.List   `json:"errors,omitempty"`
+}
+
+// Schema is a parsed GraphQL schema that can execute requests
+type Schema struct {
+	schema *ast.Schema
+	logger core.Logger
+}
+
+// ParseSchema parses and validates a schema in the SDL
+func ParseSchema(source string, logger core.Logger) (*Schema, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: source})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GraphQL schema: %w", err)
+	}
+	return &Schema{schema: schema, logger: logger}, nil
+}
+
+// LoadSchema reads and parses a schema file such as gen/graphql/schema.graphql
+func LoadSchema(path string, logger core.Logger) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GraphQL schema: %w", err)
+	}
+	return ParseSchema(string(data), logger)
+}
+
+// Execute runs a query or mutation against source
+func (s *Schema) Execute(ctx context.Context, source DataSource, req Request) *Response {
+	return s.execute(ctx, source, req, false)
+}
+
+// execute runs a request; readOnly rejects mutations, which GET requests may not carry
+func (s *Schema) execute(ctx context.Context, source DataSource, req Request, readOnly bool) *Response {
+	doc, errs := gqlparser.LoadQuery(s.schema, req.Query)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	op, opErr := selectOperation(doc, req.OperationName)
+	if opErr != nil {
+		return &Response{Errors: gqlerror.List{opErr}}
+	}
+
+	var root *ast.Definition
+	switch op.Operation {
+	case ast.Query:
+		root = s.schema.Query
+	case ast.Mutation:
+		if readOnly {
+			return &Response{Errors: gqlerror.List{gqlerror.Errorf("mutations are not allowed in GET requests")}}
+		}
+		root = s.schema.Mutation
+	default:
+		return &Response{Errors: gqlerror.List{gqlerror.Errorf("%s operations are not supported", op.Operation)}}
+	}
+
+	vars, err := validator.VariableValues(s.schema, op, req.Variables)
+	if err != nil {
+		return &Response{Errors: gqlerror.List{gqlerror.WrapIfUnwrapped(err)}}
+	}
+	if err := checkComplexity(op.SelectionSet, vars); err != nil {
+		return &Response{Errors: gqlerror.List{err}}
+	}
+
+	e := &executor{
+		ctx:    ctx,
+		schema: s.schema,
+		doc:    doc,
+		vars:   vars,
+		source: source,
+		logger: core.LoggerFromContext(ctx, s.logger),
+	}
+	data := newObject()
+	e.executeObjects(root, []*node{{out: data}}, op.SelectionSet)
+
+	// A non-null root field that failed nulls all of data
+	var result any = data
+	if settle(data) == invalid {
+		result = nil
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		e.logger.Error("Failed to encode GraphQL response", "error", err)
+		return &Response{Errors: gqlerror.List{gqlerror.Errorf("Internal server error")}}
+	}
+	return &Response{Data: encoded, Errors: e.errors}
+}
+
+// selectOperation picks the operation named name, or the only operation of doc
+func selectOperation(doc *ast.QueryDocument, name string) (*ast.OperationDefinition, *gqlerror.Error) {
+	if name != "" {
+		if op := doc.Operations.ForName(name); op != nil {
+			return op, nil
+		}
+		return nil, gqlerror.Errorf("unknown operation %q", name)
+	}
+	if len(doc.Operations) != 1 {
+		return nil, gqlerror.Errorf("operationName is required for documents with several operations")
+	}
+	return doc.Operations[0], nil
+}
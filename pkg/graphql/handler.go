@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Handler serves GraphQL over HTTP: POST with a JSON body, or GET with query,
+// operationName and variables parameters for queries
+func (s *Schema) Handler(source DataSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		readOnly := false
+
+		switch r.Method {
+		case http.MethodGet:
+			query := r.URL.Query()
+			req.Query = query.Get("query")
+			req.OperationName = query.Get("operationName")
+			if variables := query.Get("variables"); variables != "" {
+				if err := decodeJSON(bytes.NewReader([]byte(variables)), &req.Variables); err != nil {
+					writeResponse(w, http.StatusBadRequest, &Response{Errors: gqlerror.List{gqlerror.Errorf("invalid variables: %s", err)}})
+					return
+				}
+			}
+			readOnly = true
+		case http.MethodPost:
+			if err := decodeJSON(r.Body, &req); err != nil {
+				writeResponse(w, http.StatusBadRequest, &Response{Errors: gqlerror.List{gqlerror.Errorf("invalid request body: %s", err)}})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeResponse(w, http.StatusMethodNotAllowed, &Response{Errors: gqlerror.List{gqlerror.Errorf("method %s not allowed", r.Method)}})
+			return
+		}
+
+		if req.Query == "" {
+			writeResponse(w, http.StatusBadRequest, &Response{Errors: gqlerror.List{gqlerror.Errorf("query is required")}})
+			return
+		}
+		writeResponse(w, http.StatusOK, s.execute(r.Context(), source, req, readOnly))
+	})
+}
+
+// decodeJSON decodes r keeping numbers as json.Number, so large integer IDs survive
+func decodeJSON(r io.Reader, target any) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// writeResponse writes a GraphQL response as JSON
+func writeResponse(w http.ResponseWriter, status int, response *Response) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		status = http.StatusInternalServerError
+		data = []byte(`{"errors":[{"message":"Internal server error"}]}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
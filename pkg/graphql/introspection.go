@@ -0,0 +1,216 @@
+package graphql
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// schemaValue returns the __Schema of the schema. Values are maps keyed by
+// field name; absent keys resolve to null.
+func (e *executor) schemaValue() map[string]any {
+	value := map[string]any{
+		"types": resolver(func(map[string]any) any {
+			names := make([]string, 0, len(e.schema.Types))
+			for name := range e.schema.Types {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			types := make([]any, len(names))
+			for i, name := range names {
+				types[i] = e.typeValue(e.schema.Types[name])
+			}
+			return types
+		}),
+		"queryType": e.typeValue(e.schema.Query),
+		"directives": resolver(func(map[string]any) any {
+			names := make([]string, 0, len(e.schema.Directives))
+			for name := range e.schema.Directives {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			directives := make([]any, len(names))
+			for i, name := range names {
+				directives[i] = e.directiveValue(e.schema.Directives[name])
+			}
+			return directives
+		}),
+	}
+	if e.schema.Description != "" {
+		value["description"] = e.schema.Description
+	}
+	if e.schema.Mutation != nil {
+		value["mutationType"] = e.typeValue(e.schema.Mutation)
+	}
+	if e.schema.Subscription != nil {
+		value["subscriptionType"] = e.typeValue(e.schema.Subscription)
+	}
+	return value
+}
+
+// typeValue returns the __Type of a named type
+func (e *executor) typeValue(def *ast.Definition) map[string]any {
+	value := map[string]any{
+		"kind": string(def.Kind),
+		"name": def.Name,
+	}
+	if def.Description != "" {
+		value["description"] = def.Description
+	}
+
+	switch def.Kind {
+	case ast.Object, ast.Interface:
+		value["fields"] = resolver(func(args map[string]any) any {
+			fields := []any{}
+			for _, field := range def.Fields {
+				if strings.HasPrefix(field.Name, "__") || skipDeprecated(field.Directives, args) {
+					continue
+				}
+				fields = append(fields, e.fieldValue(field))
+			}
+			return fields
+		})
+		value["interfaces"] = resolver(func(map[string]any) any {
+			interfaces := []any{}
+			for _, name := range def.Interfaces {
+				interfaces = append(interfaces, e.typeValue(e.schema.Types[name]))
+			}
+			return interfaces
+		})
+	case ast.Enum:
+		value["enumValues"] = resolver(func(args map[string]any) any {
+			values := []any{}
+			for _, enumValue := range def.EnumValues {
+				if skipDeprecated(enumValue.Directives, args) {
+					continue
+				}
+				v := map[string]any{"name": enumValue.Name}
+				if enumValue.Description != "" {
+					v["description"] = enumValue.Description
+				}
+				setDeprecation(v, enumValue.Directives)
+				values = append(values, v)
+			}
+			return values
+		})
+	case ast.InputObject:
+		value["inputFields"] = resolver(func(args map[string]any) any {
+			fields := []any{}
+			for _, field := range def.Fields {
+				if skipDeprecated(field.Directives, args) {
+					continue
+				}
+				fields = append(fields, e.inputValue(field.Name, field.Description, field.Type, field.DefaultValue, field.Directives))
+			}
+			return fields
+		})
+		value["isOneOf"] = def.Directives.ForName("oneOf") != nil
+	}
+	if def.Kind == ast.Interface || def.Kind == ast.Union {
+		value["possibleTypes"] = resolver(func(map[string]any) any {
+			types := []any{}
+			for _, possible := range e.schema.GetPossibleTypes(def) {
+				types = append(types, e.typeValue(possible))
+			}
+			return types
+		})
+	}
+	return value
+}
+
+// typeRef returns the __Type of a possibly wrapped type reference
+func (e *executor) typeRef(t *ast.Type) map[string]any {
+	if t.NonNull {
+		inner := *t
+		inner.NonNull = false
+		return map[string]any{"kind": "NON_NULL", "ofType": e.typeRef(&inner)}
+	}
+	if t.Elem != nil {
+		return map[string]any{"kind": "LIST", "ofType": e.typeRef(t.Elem)}
+	}
+	return e.typeValue(e.schema.Types[t.NamedType])
+}
+
+// fieldValue returns the __Field of a field definition
+func (e *executor) fieldValue(field *ast.FieldDefinition) map[string]any {
+	value := map[string]any{
+		"name": field.Name,
+		"args": resolver(func(args map[string]any) any {
+			return e.argumentValues(field.Arguments, args)
+		}),
+		"type": e.typeRef(field.Type),
+	}
+	if field.Description != "" {
+		value["description"] = field.Description
+	}
+	setDeprecation(value, field.Directives)
+	return value
+}
+
+// directiveValue returns the __Directive of a directive definition
+func (e *executor) directiveValue(directive *ast.DirectiveDefinition) map[string]any {
+	locations := make([]any, len(directive.Locations))
+	for i, location := range directive.Locations {
+		locations[i] = string(location)
+	}
+	value := map[string]any{
+		"name":         directive.Name,
+		"isRepeatable": directive.IsRepeatable,
+		"locations":    locations,
+		"args": resolver(func(args map[string]any) any {
+			return e.argumentValues(directive.Arguments, args)
+		}),
+	}
+	if directive.Description != "" {
+		value["description"] = directive.Description
+	}
+	return value
+}
+
+// argumentValues returns the __InputValue of each argument definition
+func (e *executor) argumentValues(defs ast.ArgumentDefinitionList, args map[string]any) []any {
+	values := []any{}
+	for _, arg := range defs {
+		if skipDeprecated(arg.Directives, args) {
+			continue
+		}
+		values = append(values, e.inputValue(arg.Name, arg.Description, arg.Type, arg.DefaultValue, arg.Directives))
+	}
+	return values
+}
+
+// inputValue returns the __InputValue of an argument or input field
+func (e *executor) inputValue(name, description string, t *ast.Type, defaultValue *ast.Value, directives ast.DirectiveList) map[string]any {
+	value := map[string]any{
+		"name": name,
+		"type": e.typeRef(t),
+	}
+	if description != "" {
+		value["description"] = description
+	}
+	if defaultValue != nil {
+		value["defaultValue"] = defaultValue.String()
+	}
+	setDeprecation(value, directives)
+	return value
+}
+
+// setDeprecation sets isDeprecated and deprecationReason from @deprecated
+func setDeprecation(value map[string]any, directives ast.DirectiveList) {
+	deprecated := directives.ForName("deprecated")
+	value["isDeprecated"] = deprecated != nil
+	if deprecated == nil {
+		return
+	}
+	value["deprecationReason"] = "No longer supported"
+	if reason := deprecated.Arguments.ForName("reason"); reason != nil {
+		value["deprecationReason"] = reason.Value.Raw
+	}
+}
+
+// skipDeprecated reports whether a deprecated element is left out because
+// includeDeprecated is not set
+func skipDeprecated(directives ast.DirectiveList, args map[string]any) bool {
+	return directives.ForName("deprecated") != nil && args["includeDeprecated"] != true
+}
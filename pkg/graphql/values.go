@@ -0,0 +1,279 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// resolver is a value computed from the field's arguments when it is selected;
+// introspection uses it to build the type graph lazily
+type resolver func(args map[string]any) any
+
+// invalidValue marks a non-null field that resolved to null; settle replaces
+// the nearest nullable parent with null
+type invalidValue struct{}
+
+var invalid = invalidValue{}
+
+// nullOf returns the output of a failed field of type t
+func nullOf(t *ast.Type) any {
+	if t.NonNull {
+		return invalid
+	}
+	return nil
+}
+
+// object is a response object that keeps the order of the selection set
+type object struct {
+	keys    []string
+	values  map[string]any
+	nonNull map[string]bool
+}
+
+func newObject() *object {
+	return &object{values: map[string]any{}, nonNull: map[string]bool{}}
+}
+
+// set stores the value of a response key
+func (o *object) set(key string, value any, nonNull bool) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+	o.nonNull[key] = nonNull
+}
+
+// MarshalJSON writes the fields in selection order
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// list is a response list
+type list struct {
+	items   []any
+	nonNull bool // Items are non-null
+}
+
+func (l *list) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.items)
+}
+
+// settle propagates invalid values to the nearest nullable parent and returns
+// value, or invalid if value itself must be null but is not allowed to be
+func settle(value any) any {
+	switch v := value.(type) {
+	case *object:
+		for _, key := range v.keys {
+			child := settle(v.values[key])
+			if child == invalid {
+				if v.nonNull[key] {
+					return invalid
+				}
+				child = nil
+			}
+			v.values[key] = child
+		}
+	case *list:
+		for i, item := range v.items {
+			child := settle(item)
+			if child == invalid {
+				if v.nonNull {
+					return invalid
+				}
+				child = nil
+			}
+			v.items[i] = child
+		}
+	}
+	return value
+}
+
+// serializeScalar converts a row value to the output of a scalar type
+func serializeScalar(name string, value any) (any, error) {
+	switch name {
+	case "Int":
+		n, err := toInt(value)
+		if err != nil || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("cannot represent %v as Int", value)
+		}
+		return n, nil
+	case "Float":
+		return toFloat(value)
+	case "Boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+		// SQLite and MySQL store booleans as integers
+		n, err := toInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot represent %v as Boolean", value)
+		}
+		return n != 0, nil
+	case "Time":
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format(time.RFC3339Nano), nil
+		case string:
+			return v, nil
+		}
+		return nil, fmt.Errorf("cannot represent %v as Time", value)
+	case "JSON":
+		switch v := value.(type) {
+		case string:
+			if json.Valid([]byte(v)) {
+				return json.RawMessage(v), nil
+			}
+		case []byte:
+			if json.Valid(v) {
+				return json.RawMessage(v), nil
+			}
+			return string(v), nil
+		}
+		return value, nil
+	default:
+		// String, ID and custom scalars
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		case time.Time:
+			return v.Format(time.RFC3339Nano), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+		return fmt.Sprint(value), nil
+	}
+}
+
+// toInt converts a numeric value to int64
+func toInt(value any) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("%T is not an integer", value)
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	n, err := toInt(value)
+	return float64(n), err
+}
+
+// coerceArguments normalizes argument values, which come from literals or from
+// JSON variables, to the Go types the data source expects
+func (e *executor) coerceArguments(defs ast.ArgumentDefinitionList, args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for name, value := range args {
+		if def := defs.ForName(name); def != nil {
+			value = e.coerceInput(def.Type, value)
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// coerceInput normalizes an input value of type t. Numbers become int64 or
+// float64, and IDs that are integers become int64 to match integer keys.
+func (e *executor) coerceInput(t *ast.Type, value any) any {
+	if value == nil {
+		return nil
+	}
+	if t.Elem != nil {
+		items, ok := toSlice(value)
+		if !ok {
+			// A single value is accepted where a list is expected
+			return []any{e.coerceInput(t.Elem, value)}
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = e.coerceInput(t.Elem, item)
+		}
+		return out
+	}
+
+	def := e.schema.Types[t.NamedType]
+	if def != nil && def.Kind == ast.InputObject {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+		out := make(map[string]any, len(fields))
+		for name, field := range fields {
+			if fieldDef := def.Fields.ForName(name); fieldDef != nil {
+				field = e.coerceInput(fieldDef.Type, field)
+			}
+			out[name] = field
+		}
+		return out
+	}
+
+	switch t.NamedType {
+	case "Int":
+		if n, err := toInt(value); err == nil {
+			return n
+		}
+	case "Float":
+		if f, err := toFloat(value); err == nil {
+			return f
+		}
+	case "ID":
+		if n, err := toInt(value); err == nil {
+			return n
+		}
+		return fmt.Sprint(value)
+	}
+	if n, ok := value.(json.Number); ok {
+		return n.String()
+	}
+	return value
+}
@@ -0,0 +1,384 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Authenticator verifies credentials carried in request headers or gRPC metadata
+type Authenticator interface {
+	// Name returns the authentication method name
+	Name() string
+	// Challenge returns the WWW-Authenticate challenge, or "" if none
+	Challenge() string
+	// Authenticate returns (nil, nil) when the request carries no credentials
+	// of this kind, and an error when the credentials are invalid
+	Authenticate(ctx context.Context, header func(string) string) (*core.Principal, error)
+}
+
+// HashSecret returns the hex SHA-256 hash used to store API keys. A fast hash
+// is only safe for high-entropy keys; passwords use HashPassword.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPassword returns the bcrypt hash used to store basic auth passwords
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// unknownUserHash is compared against for unknown users, so that a lookup
+// miss takes as long as a wrong password
+const unknownUserHash = "$2a$10$olRnCmJW47dtDbpEUOoGY.U6zMwlCB8DVWWlibDAprZ82rP5v8sO."
+
+// APIKey holds a hashed API key and the roles it grants
+type APIKey struct {
+	Name  string
+	Hash  string
+	Roles []string
+}
+
+// APIKeyAuthenticator verifies API keys from a header against hashed keys
+type APIKeyAuthenticator struct {
+	header  string
+	keys    []APIKey
+	db      *sql.DB
+	query   string
+	logger  core.Logger
+	timeout time.Duration
+}
+
+// NewAPIKeyAuthenticator creates a new API key authenticator.
+// If db and table are set, keys are also looked up in that table, which must
+// have name, key_hash and roles (comma-separated) columns.
+func NewAPIKeyAuthenticator(header string, keys []APIKey, db *sql.DB, dialect, table string, logger core.Logger) *APIKeyAuthenticator {
+	if header == "" {
+		header = "X-API-Key"
+	}
+
+	ka := &APIKeyAuthenticator{
+		header:  header,
+		keys:    keys,
+		logger:  logger,
+		timeout: 5 * time.Second,
+	}
+
+	if db != nil && table != "" {
+		ka.db = db
+		ka.query = fmt.Sprintf("SELECT name, roles FROM %s WHERE key_hash = %s", table, database.Placeholder(dialect, 1))
+	}
+
+	return ka
+}
+
+// Name returns authenticator name
+func (ka *APIKeyAuthenticator) Name() string {
+	return "api_key"
+}
+
+// Challenge returns an empty challenge (API keys have no standard scheme)
+func (ka *APIKeyAuthenticator) Challenge() string {
+	return ""
+}
+
+// Authenticate verifies the API key header
+func (ka *APIKeyAuthenticator) Authenticate(ctx context.Context, header func(string) string) (*core.Principal, error) {
+	key := header(ka.header)
+	if key == "" {
+		return nil, nil
+	}
+
+	hash := HashSecret(key)
+	for _, k := range ka.keys {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(strings.ToLower(k.Hash))) == 1 {
+			return &core.Principal{Subject: k.Name, Method: ka.Name(), Roles: k.Roles}, nil
+		}
+	}
+
+	if ka.db != nil {
+		queryCtx, cancel := context.WithTimeout(ctx, ka.timeout)
+		defer cancel()
+
+		var name string
+		var roles sql.NullString
+		err := ka.db.QueryRowContext(queryCtx, ka.query, hash).Scan(&name, &roles)
+		if err == nil {
+			return &core.Principal{Subject: name, Method: ka.Name(), Roles: splitRoles(roles.String)}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			ka.logger.Error("API key lookup failed", "error", err)
+			return nil, errors.New("API key lookup failed")
+		}
+	}
+
+	return nil, errors.New("invalid API key")
+}
+
+// BasicUser holds a basic auth user with a bcrypt password hash
+type BasicUser struct {
+	Username     string
+	PasswordHash string
+	Roles        []string
+}
+
+// BasicAuthenticator verifies HTTP basic auth credentials
+type BasicAuthenticator struct {
+	realm string
+	users map[string]BasicUser
+}
+
+// NewBasicAuthenticator creates a new basic auth authenticator
+func NewBasicAuthenticator(realm string, users []BasicUser) *BasicAuthenticator {
+	if realm == "" {
+		realm = "apiright"
+	}
+
+	byName := make(map[string]BasicUser, len(users))
+	for _, u := range users {
+		byName[u.Username] = u
+	}
+
+	return &BasicAuthenticator{
+		realm: realm,
+		users: byName,
+	}
+}
+
+// Name returns authenticator name
+func (ba *BasicAuthenticator) Name() string {
+	return "basic"
+}
+
+// Challenge returns the basic auth challenge
+func (ba *BasicAuthenticator) Challenge() string {
+	return fmt.Sprintf("Basic realm=%q", ba.realm)
+}
+
+// Authenticate verifies the basic auth Authorization header
+func (ba *BasicAuthenticator) Authenticate(ctx context.Context, header func(string) string) (*core.Principal, error) {
+	authorization := header("Authorization")
+	if len(authorization) < 6 || !strings.EqualFold(authorization[:6], "Basic ") {
+		return nil, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(authorization[6:]))
+	if err != nil {
+		return nil, errors.New("malformed basic credentials")
+	}
+
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, errors.New("malformed basic credentials")
+	}
+
+	user, exists := ba.users[username]
+	hash := user.PasswordHash
+	if !exists {
+		hash = unknownUserHash
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil || !exists {
+		return nil, errors.New("invalid username or password")
+	}
+
+	return &core.Principal{Subject: user.Username, Method: ba.Name(), Roles: user.Roles}, nil
+}
+
+// AuthMiddleware authenticates HTTP requests and gRPC calls
+type AuthMiddleware struct {
+	authenticators []Authenticator
+	publicPaths    []string
+	contentNeg     *core.ContentNegotiatorImpl
+	logger         core.Logger
+}
+
+// NewAuthMiddleware creates a new authentication middleware.
+// Public paths are matched exactly, or as a prefix when they end in "*".
+func NewAuthMiddleware(authenticators []Authenticator, publicPaths []string, logger core.Logger) *AuthMiddleware {
+	return &AuthMiddleware{
+		authenticators: authenticators,
+		publicPaths:    publicPaths,
+		contentNeg:     core.NewContentNegotiator(),
+		logger:         logger,
+	}
+}
+
+// NewAuthMiddlewareFromConfig creates an authentication middleware from apiright.yaml settings.
+// db may be nil when API keys are not stored in a table.
+func NewAuthMiddlewareFromConfig(cfg config.AuthConfig, db *sql.DB, dialect string, logger core.Logger) (*AuthMiddleware, error) {
+	var authenticators []Authenticator
+
+	if cfg.JWT.Enabled {
+		jwtAuth, err := NewJWTAuthenticator(JWTOptions{
+			Algorithm:  cfg.JWT.Algorithm,
+			Secret:     cfg.JWT.Secret,
+			KeyFile:    cfg.JWT.KeyFile,
+			JWKSFile:   cfg.JWT.JWKSFile,
+			Issuer:     cfg.JWT.Issuer,
+			Audience:   cfg.JWT.Audience,
+			Leeway:     time.Duration(cfg.JWT.Leeway) * time.Second,
+			RolesClaim: cfg.RolesClaim,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure JWT authentication: %w", err)
+		}
+		authenticators = append(authenticators, jwtAuth)
+	}
+
+	if cfg.APIKeys.Enabled {
+		keys := make([]APIKey, 0, len(cfg.APIKeys.Keys))
+		for _, k := range cfg.APIKeys.Keys {
+			keys = append(keys, APIKey{Name: k.Name, Hash: k.Hash, Roles: k.Roles})
+		}
+		authenticators = append(authenticators,
+			NewAPIKeyAuthenticator(cfg.APIKeys.Header, keys, db, dialect, cfg.APIKeys.Table, logger))
+	}
+
+	if cfg.Basic.Enabled {
+		users := make([]BasicUser, 0, len(cfg.Basic.Users))
+		for _, u := range cfg.Basic.Users {
+			users = append(users, BasicUser{Username: u.Username, PasswordHash: u.PasswordHash, Roles: u.Roles})
+		}
+		authenticators = append(authenticators, NewBasicAuthenticator(cfg.Basic.Realm, users))
+	}
+
+	if len(authenticators) == 0 {
+		return nil, fmt.Errorf("no authentication method enabled")
+	}
+
+	return NewAuthMiddleware(authenticators, cfg.PublicPaths, logger), nil
+}
+
+// Name returns middleware name
+func (am *AuthMiddleware) Name() string {
+	return "auth"
+}
+
+// Priority returns middleware priority
+func (am *AuthMiddleware) Priority() int {
+	return 20 // After rate limiting and CORS, before validation
+}
+
+// Handler returns HTTP middleware handler
+func (am *AuthMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Public routes and CORS preflight requests skip authentication
+			if am.isPublic(r.URL.Path) || (r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := am.authenticate(r.Context(), r.Header.Get)
+			if err != nil {
+				core.LoggerFromContext(r.Context(), am.logger).Warn("HTTP authentication failed",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"error", err,
+				)
+				am.writeUnauthorized(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(core.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// GRPCInterceptor returns gRPC interceptor
+func (am *AuthMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if am.isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		header := func(name string) string {
+			if values := md.Get(name); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+
+		principal, err := am.authenticate(ctx, header)
+		if err != nil {
+			core.LoggerFromContext(ctx, am.logger).Warn("gRPC authentication failed",
+				"method", info.FullMethod,
+				"error", err,
+			)
+			return nil, core.Unauthorized("%s", err.Error())
+		}
+
+		return handler(core.WithPrincipal(ctx, principal), req)
+	}
+}
+
+// authenticate tries each authenticator in order until one accepts the request
+func (am *AuthMiddleware) authenticate(ctx context.Context, header func(string) string) (*core.Principal, error) {
+	for _, authenticator := range am.authenticators {
+		principal, err := authenticator.Authenticate(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+		if principal != nil {
+			return principal, nil
+		}
+	}
+	return nil, errors.New("authentication required")
+}
+
+// isPublic reports whether a path or gRPC method skips authentication
+func (am *AuthMiddleware) isPublic(path string) bool {
+	for _, public := range am.publicPaths {
+		if prefix, ok := strings.CutSuffix(public, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == public {
+			return true
+		}
+	}
+	return false
+}
+
+// writeUnauthorized writes a 401 response in the negotiated content type
+func (am *AuthMiddleware) writeUnauthorized(w http.ResponseWriter, r *http.Request, authErr error) {
+	for _, authenticator := range am.authenticators {
+		if challenge := authenticator.Challenge(); challenge != "" {
+			w.Header().Add("WWW-Authenticate", challenge)
+		}
+	}
+
+	core.WriteError(w, r, am.contentNeg, core.Unauthorized("%s", authErr.Error()))
+}
+
+// splitRoles splits a comma-separated roles column
+func splitRoles(roles string) []string {
+	var result []string
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			result = append(result, role)
+		}
+	}
+	return result
+}
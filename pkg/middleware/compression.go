@@ -0,0 +1,449 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+)
+
+// Encoding compresses and decompresses bodies for one Content-Encoding token
+type Encoding struct {
+	NewWriter func(w io.Writer, level int) (io.WriteCloser, error) // level 0 selects the default
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+const (
+	// zstdMaxWindow caps the zstd window at the 8 MiB that HTTP clients must support
+	zstdMaxWindow = 8 << 20
+	// DefaultMaxDecodedBytes caps decompressed request bodies, so a small
+	// compressed body cannot inflate without bound
+	DefaultMaxDecodedBytes = 10 << 20
+)
+
+var (
+	encodingsMu sync.RWMutex
+	encodings   = map[string]Encoding{
+		"zstd": {
+			NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+				options := []zstd.EOption{zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(zstdMaxWindow)}
+				if level != 0 {
+					if level < 1 || level > 22 {
+						return nil, fmt.Errorf("zstd level must be between 1 and 22")
+					}
+					options = append(options, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+				}
+				return zstd.NewWriter(w, options...)
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+				if err != nil {
+					return nil, err
+				}
+				return decoder.IOReadCloser(), nil
+			},
+		},
+		"gzip": {
+			NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+				if level == 0 {
+					level = gzip.DefaultCompression
+				}
+				return gzip.NewWriterLevel(w, level)
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		"deflate": {
+			NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+				if level == 0 {
+					level = flate.DefaultCompression
+				}
+				return flate.NewWriter(w, level)
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return flate.NewReader(r), nil
+			},
+		},
+	}
+)
+
+// RegisterEncoding adds or replaces a Content-Encoding
+func RegisterEncoding(name string, encoding Encoding) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	encodings[strings.ToLower(name)] = encoding
+}
+
+// lookupEncoding returns the registered encoding with the given name
+func lookupEncoding(name string) (Encoding, bool) {
+	encodingsMu.RLock()
+	defer encodingsMu.RUnlock()
+	encoding, ok := encodings[strings.ToLower(name)]
+	return encoding, ok
+}
+
+// DefaultCompressionContentTypes are the media types compressed when no allowlist is configured
+var DefaultCompressionContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/yaml",
+	"application/x-yaml",
+	"application/problem+json",
+	"text/*",
+}
+
+// CompressionOptions configures a CompressionMiddleware
+type CompressionOptions struct {
+	Encodings    []string // Server preference order; defaults to zstd, gzip, deflate
+	Level        int      // Compression level passed to the encoder, 0 = default
+	MinSize      int      // Responses smaller than this are sent uncompressed
+	ContentTypes []string // Media types to compress; a trailing /* matches a type prefix
+	MaxDecoded   int64    // Decompressed request bodies larger than this are rejected (default: 10 MiB)
+}
+
+// CompressionMiddleware compresses responses according to Accept-Encoding and
+// decodes request bodies sent with Content-Encoding. Decoded bodies are capped
+// at MaxDecoded; BodyLimitMiddleware runs inside it and may limit them further.
+type CompressionMiddleware struct {
+	options    CompressionOptions
+	encodings  map[string]Encoding
+	pools      map[string]*sync.Pool
+	contentNeg *core.ContentNegotiatorImpl
+	logger     core.Logger
+}
+
+// resettableWriter is implemented by encoders that can be reused from a pool
+type resettableWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// NewCompressionMiddleware creates a new compression middleware
+func NewCompressionMiddleware(options CompressionOptions, logger core.Logger) (*CompressionMiddleware, error) {
+	if len(options.Encodings) == 0 {
+		for _, name := range []string{"zstd", "gzip", "deflate"} {
+			if _, ok := lookupEncoding(name); ok {
+				options.Encodings = append(options.Encodings, name)
+			}
+		}
+	}
+	if len(options.ContentTypes) == 0 {
+		options.ContentTypes = DefaultCompressionContentTypes
+	}
+	if options.MaxDecoded <= 0 {
+		options.MaxDecoded = DefaultMaxDecodedBytes
+	}
+
+	cm := &CompressionMiddleware{
+		options:    options,
+		encodings:  make(map[string]Encoding, len(options.Encodings)),
+		pools:      make(map[string]*sync.Pool, len(options.Encodings)),
+		contentNeg: core.NewContentNegotiator(),
+		logger:     logger,
+	}
+
+	names := make([]string, 0, len(options.Encodings))
+	for _, name := range options.Encodings {
+		name = strings.ToLower(name)
+		encoding, ok := lookupEncoding(name)
+		if !ok {
+			return nil, fmt.Errorf("compression encoding %q is not registered", name)
+		}
+		// Creating a writer up front rejects invalid levels at startup
+		writer, err := encoding.NewWriter(io.Discard, options.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compression level %d for %s: %w", options.Level, name, err)
+		}
+		names = append(names, name)
+		cm.encodings[name] = encoding
+		cm.pools[name] = &sync.Pool{}
+		cm.putWriter(name, writer)
+	}
+	cm.options.Encodings = names
+
+	return cm, nil
+}
+
+// Name returns middleware name
+func (cm *CompressionMiddleware) Name() string {
+	return "compression"
+}
+
+// Priority returns middleware priority
+func (cm *CompressionMiddleware) Priority() int {
+	return 12 // Outside body_limit, so size limits apply to decoded request bodies
+}
+
+// Handler returns HTTP middleware handler
+func (cm *CompressionMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Content-Encoding") != "" {
+				if !cm.decodeRequest(w, r) {
+					return
+				}
+				// The server only closes the original body; decoders such as zstd hold goroutines
+				defer r.Body.Close()
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			name := cm.negotiate(r.Header.Get("Accept-Encoding"))
+			if name == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, middleware: cm, encoding: name}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// GRPCInterceptor returns gRPC interceptor (gRPC compression is negotiated by the
+// registered gzip compressor, not by an interceptor)
+func (cm *CompressionMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return nil
+}
+
+// decodeRequest replaces a compressed request body with a decoding reader that
+// fails once MaxDecoded bytes are read. It writes a 415 problem and returns
+// false for unsupported encodings.
+func (cm *CompressionMiddleware) decodeRequest(w http.ResponseWriter, r *http.Request) bool {
+	name := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if name == "identity" {
+		r.Header.Del("Content-Encoding")
+		return true
+	}
+
+	encoding, ok := cm.encodings[name]
+	if !ok {
+		core.WriteError(w, r, cm.contentNeg,
+			core.UnsupportedMediaType("Unsupported Content-Encoding %q (supported: %s)", name, strings.Join(cm.options.Encodings, ", ")))
+		return false
+	}
+
+	reader, err := encoding.NewReader(r.Body)
+	if err != nil {
+		core.WriteError(w, r, cm.contentNeg, core.BadRequest("Request body is not valid %s: %v", name, err))
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, reader, cm.options.MaxDecoded)
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	return true
+}
+
+// negotiate picks the encoding with the highest Accept-Encoding q-value,
+// breaking ties by server preference. It returns "" for identity.
+func (cm *CompressionMiddleware) negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		token = strings.ToLower(strings.TrimSpace(token))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if token == "*" {
+			wildcard = q
+		} else if token != "" {
+			weights[token] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range cm.options.Encodings {
+		q, ok := weights[name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// shouldCompress reports whether a response with the given headers is eligible for compression
+func (cm *CompressionMiddleware) shouldCompress(header http.Header, status, size int) bool {
+	if size < cm.options.MinSize || status < http.StatusOK ||
+		status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, allowed := range cm.options.ContentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// getWriter returns a pooled or new encoder writing to w
+func (cm *CompressionMiddleware) getWriter(name string, w io.Writer) (io.WriteCloser, error) {
+	if pooled, ok := cm.pools[name].Get().(resettableWriter); ok {
+		pooled.Reset(w)
+		return pooled, nil
+	}
+	return cm.encodings[name].NewWriter(w, cm.options.Level)
+}
+
+// putWriter returns an encoder to its pool if it can be reset
+func (cm *CompressionMiddleware) putWriter(name string, writer io.WriteCloser) {
+	if resettable, ok := writer.(resettableWriter); ok {
+		cm.pools[name].Put(resettable)
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// response is large enough and of an allowed type to be compressed
+type compressWriter struct {
+	http.ResponseWriter
+	middleware *CompressionMiddleware
+	encoding   string
+	status     int
+	buf        []byte
+	decided    bool
+	encoder    io.WriteCloser
+}
+
+// WriteHeader records the status; it is sent once the compression decision is made
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+// Write buffers data until MinSize is reached, then streams it
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.middleware.options.MinSize {
+			return len(p), nil
+		}
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data to the client
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide writes the headers and the buffered body, compressed if eligible
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	header := cw.Header()
+
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.middleware.shouldCompress(header, cw.status, len(cw.buf)) {
+		encoder, err := cw.middleware.getWriter(cw.encoding, cw.ResponseWriter)
+		if err != nil {
+			cw.middleware.logger.Warn("failed to create compression writer", "encoding", cw.encoding, "error", err)
+		} else {
+			cw.encoder = encoder
+			header.Set("Content-Encoding", cw.encoding)
+			header.Del("Content-Length")
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// close flushes any buffered data and finishes the compressed stream
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 {
+			// Nothing was written; let net/http send its implicit 200
+			return
+		}
+		if err := cw.decide(); err != nil {
+			cw.middleware.logger.Warn("failed to write response", "error", err)
+			return
+		}
+	}
+	if cw.encoder != nil {
+		if err := cw.encoder.Close(); err != nil {
+			cw.middleware.logger.Warn("failed to finish compressed response", "encoding", cw.encoding, "error", err)
+		}
+		cw.middleware.putWriter(cw.encoding, cw.encoder)
+	}
+}
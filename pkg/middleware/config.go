@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"google.golang.org/grpc"
+)
+
+// prioritized overrides the priority of a wrapped middleware
+type prioritized struct {
+	HTTPMiddleware
+	priority int
+}
+
+// WithPriority returns mw with its priority replaced
+func WithPriority(mw HTTPMiddleware, priority int) HTTPMiddleware {
+	return &prioritized{HTTPMiddleware: mw, priority: priority}
+}
+
+// Priority returns the overridden priority
+func (p *prioritized) Priority() int {
+	return p.priority
+}
+
+// GRPCInterceptor returns the wrapped middleware's gRPC interceptor, if any
+func (p *prioritized) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	if m, ok := p.HTTPMiddleware.(interface {
+		GRPCInterceptor() grpc.UnaryServerInterceptor
+	}); ok {
+		return m.GRPCInterceptor()
+	}
+	return nil
+}
+
+// NewFromConfig builds the middleware enabled under server.middleware in apiright.yaml.
+// db may be nil unless API keys, rate limit buckets or idempotent responses are stored in a table.
+func NewFromConfig(cfg *config.Config, db *sql.DB, dialect string, logger core.Logger) ([]HTTPMiddleware, error) {
+	mwCfg := cfg.Server.Middleware
+	var result []HTTPMiddleware
+
+	add := func(mw HTTPMiddleware, toggle config.MiddlewareToggle) {
+		if toggle.Priority != nil {
+			mw = WithPriority(mw, *toggle.Priority)
+		}
+		result = append(result, mw)
+	}
+
+	if mwCfg.Logging.IsEnabled(false) {
+		add(NewLoggingMiddleware(logger), mwCfg.Logging)
+	}
+
+	if mwCfg.Telemetry.IsEnabled(cfg.Telemetry.Enabled) {
+		add(NewTelemetryMiddleware(logger), mwCfg.Telemetry)
+	}
+
+	if mwCfg.CORS.IsEnabled(false) {
+		add(NewCORSMiddleware(corsConfig(mwCfg.CORS), logger), mwCfg.CORS.MiddlewareToggle)
+	}
+
+	if mwCfg.RateLimit.IsEnabled(false) {
+		rateLimit, err := newRateLimitFromConfig(cfg, db, dialect, logger)
+		if err != nil {
+			return nil, err
+		}
+		add(rateLimit, mwCfg.RateLimit.MiddlewareToggle)
+	}
+
+	if mwCfg.Auth.IsEnabled(cfg.Auth.Enabled) {
+		auth, err := NewAuthMiddlewareFromConfig(cfg.Auth, db, dialect, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure authentication: %w", err)
+		}
+		add(auth, mwCfg.Auth)
+	}
+
+	if mwCfg.Idempotency.IsEnabled(false) {
+		idempotency, err := newIdempotencyFromConfig(mwCfg.Idempotency, db, dialect, logger)
+		if err != nil {
+			return nil, err
+		}
+		add(idempotency, mwCfg.Idempotency.MiddlewareToggle)
+	}
+
+	if mwCfg.Compression.IsEnabled(false) {
+		compression, err := NewCompressionMiddleware(CompressionOptions{
+			Encodings:    mwCfg.Compression.Encodings,
+			Level:        mwCfg.Compression.Level,
+			MinSize:      mwCfg.Compression.MinSize,
+			ContentTypes: mwCfg.Compression.ContentTypes,
+			MaxDecoded:   mwCfg.Compression.MaxDecodedBytes,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure compression: %w", err)
+		}
+		add(compression, mwCfg.Compression.MiddlewareToggle)
+	}
+
+	if mwCfg.BodyLimit.IsEnabled(false) {
+		add(NewBodyLimitMiddleware(mwCfg.BodyLimit.MaxBytes, logger), mwCfg.BodyLimit.MiddlewareToggle)
+	}
+
+	return result, nil
+}
+
+// corsConfig merges configured CORS settings over DefaultCORSConfig
+func corsConfig(cfg config.CORSMiddlewareConfig) CORSConfig {
+	cors := DefaultCORSConfig()
+	if len(cfg.AllowOrigins) > 0 {
+		cors.AllowOrigins = cfg.AllowOrigins
+	}
+	if len(cfg.AllowMethods) > 0 {
+		cors.AllowMethods = cfg.AllowMethods
+	}
+	if len(cfg.AllowHeaders) > 0 {
+		cors.AllowHeaders = cfg.AllowHeaders
+	}
+	if len(cfg.ExposeHeaders) > 0 {
+		cors.ExposeHeaders = cfg.ExposeHeaders
+	}
+	if cfg.MaxAge > 0 {
+		cors.MaxAge = cfg.MaxAge
+	}
+	cors.AllowCredentials = cfg.AllowCredentials
+	return cors
+}
+
+// newRateLimitFromConfig creates a rate limiting middleware from server.middleware.rate_limit
+func newRateLimitFromConfig(cfg *config.Config, db *sql.DB, dialect string, logger core.Logger) (*RateLimitMiddleware, error) {
+	rlCfg := cfg.Server.Middleware.RateLimit
+
+	window, err := config.ParseDuration(rlCfg.Window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit window: %w", err)
+	}
+
+	trustedProxies, err := ParseTrustedProxies(rlCfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	options := RateLimitOptions{
+		Default:        RateLimit{Requests: rlCfg.Requests, Window: window},
+		APIKeyHeader:   rlCfg.APIKeyHeader,
+		TrustedProxies: trustedProxies,
+	}
+
+	// Rules refer to API keys by name, so reuse the keys configured for authentication
+	if cfg.Auth.APIKeys.Enabled {
+		if options.APIKeyHeader == "" {
+			options.APIKeyHeader = cfg.Auth.APIKeys.Header
+		}
+		options.APIKeys = make(map[string]string, len(cfg.Auth.APIKeys.Keys))
+		for _, k := range cfg.Auth.APIKeys.Keys {
+			options.APIKeys[k.Hash] = k.Name
+		}
+	}
+
+	for i, rule := range rlCfg.Rules {
+		ruleWindow := window
+		if rule.Window != "" {
+			if ruleWindow, err = config.ParseDuration(rule.Window); err != nil {
+				return nil, fmt.Errorf("invalid window in rate limit rule %d: %w", i+1, err)
+			}
+		}
+		options.Rules = append(options.Rules, RateLimitRule{
+			Path:   rule.Path,
+			Method: rule.Method,
+			APIKey: rule.APIKey,
+			Limit:  RateLimit{Requests: rule.Requests, Window: ruleWindow},
+		})
+	}
+
+	switch rlCfg.Store {
+	case "", "memory":
+	case "sql":
+		if db == nil {
+			return nil, fmt.Errorf("rate limit sql store requires a database")
+		}
+		store := NewSQLRateLimitStore(db, dialect, rlCfg.Table)
+		if err := store.CreateTable(context.Background()); err != nil {
+			return nil, err
+		}
+		options.Store = store
+	default:
+		return nil, fmt.Errorf("unknown rate limit store: %s", rlCfg.Store)
+	}
+
+	return NewRateLimitMiddlewareWithOptions(options, logger), nil
+}
+
+// newIdempotencyFromConfig creates an idempotency middleware from server.middleware.idempotency
+func newIdempotencyFromConfig(cfg config.IdempotencyConfig, db *sql.DB, dialect string, logger core.Logger) (*IdempotencyMiddleware, error) {
+	ttl, err := config.ParseDuration(cfg.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid idempotency ttl: %w", err)
+	}
+	lease, err := config.ParseDuration(cfg.Lease)
+	if err != nil {
+		return nil, fmt.Errorf("invalid idempotency lease: %w", err)
+	}
+	options := IdempotencyOptions{Header: cfg.Header, TTL: ttl, Lease: lease, Methods: cfg.Methods}
+
+	switch cfg.Store {
+	case "memory":
+	case "", "sql":
+		if db == nil {
+			return nil, fmt.Errorf("idempotency sql store requires a database")
+		}
+		store := NewSQLIdempotencyStore(db, dialect, cfg.Table)
+		if err := store.CreateTable(context.Background()); err != nil {
+			return nil, err
+		}
+		options.Store = store
+	default:
+		return nil, fmt.Errorf("unknown idempotency store: %s", cfg.Store)
+	}
+
+	return NewIdempotencyMiddleware(options, logger), nil
+}
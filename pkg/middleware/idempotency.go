@@ -0,0 +1,422 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// DefaultIdempotencyHeader carries the client's key; gRPC uses it lowercased as metadata
+	DefaultIdempotencyHeader = "Idempotency-Key"
+	// DefaultIdempotencyTTL is how long responses are kept for replay
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyLease is how long a key stays reserved by a request that never completes
+	DefaultIdempotencyLease = time.Minute
+	// maxIdempotencyKeyLength bounds client keys
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencySkipHeaders are per-request response headers that are not replayed.
+// Encoding headers are set by outer middleware for the body it writes, not the
+// uncompressed body stored here, so they are negotiated again on replay.
+var idempotencySkipHeaders = []string{
+	"Date", "Retry-After", "Traceparent", "Tracestate", "X-Request-Id",
+	"Content-Encoding", "Content-Length", "Vary",
+}
+
+// IdempotencyRecord is the stored outcome of the first request with a key
+type IdempotencyRecord struct {
+	Fingerprint string      // Hash of the method, path and body of the first request
+	Status      int         // HTTP status; 0 while the first request is in flight
+	Header      http.Header // Response headers (HTTP only)
+	Body        []byte      // Response body; gRPC stores a google.rpc.Status with the response as detail
+	ExpiresAt   time.Time   // End of the lease while in flight, of the replay window once completed
+}
+
+// Completed reports whether the response has been stored
+func (r *IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
+
+// IdempotencyStore keeps responses by idempotency key
+type IdempotencyStore interface {
+	// Reserve claims key for a new request. It returns nil if the key was
+	// free, or the record of the request that claimed it first. The reservation
+	// lapses after lease, so a request that died in flight does not block the key.
+	Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*IdempotencyRecord, error)
+
+	// Complete stores the response for a reserved key until record.ExpiresAt
+	Complete(ctx context.Context, key string, record IdempotencyRecord) error
+
+	// Release drops a reservation so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOptions configures an IdempotencyMiddleware
+type IdempotencyOptions struct {
+	Store   IdempotencyStore // Defaults to an in-memory store
+	Header  string           // Default: Idempotency-Key
+	TTL     time.Duration    // Default: 24h
+	Lease   time.Duration    // Default: 1m
+	Methods []string         // HTTP methods honoring the header (default: POST)
+}
+
+// MemoryIdempotencyStore keeps idempotency records in process memory
+type MemoryIdempotencyStore struct {
+	mu         sync.Mutex
+	records    map[string]*IdempotencyRecord
+	lastSweep  time.Time
+	timeSource func() time.Time
+}
+
+// NewMemoryIdempotencyStore creates a new in-memory idempotency store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records:    make(map[string]*IdempotencyRecord),
+		timeSource: time.Now,
+	}
+}
+
+// Reserve claims key for a new request
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*IdempotencyRecord, error) {
+	now := s.timeSource()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= time.Minute {
+		for k, record := range s.records {
+			if now.After(record.ExpiresAt) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if record, ok := s.records[key]; ok && !now.After(record.ExpiresAt) {
+		existing := *record
+		return &existing, nil
+	}
+	s.records[key] = &IdempotencyRecord{Fingerprint: fingerprint, ExpiresAt: now.Add(lease)}
+	return nil, nil
+}
+
+// Complete stores the response for a reserved key
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[key]; ok {
+		existing.Status = record.Status
+		existing.Header = record.Header
+		existing.Body = record.Body
+		existing.ExpiresAt = record.ExpiresAt
+	}
+	return nil
+}
+
+// Release drops a reservation
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// IdempotencyMiddleware replays the stored response when a client repeats a
+// request with the same Idempotency-Key, so retried POSTs do not create duplicates
+type IdempotencyMiddleware struct {
+	options    IdempotencyOptions
+	contentNeg *core.ContentNegotiatorImpl
+	logger     core.Logger
+}
+
+// NewIdempotencyMiddleware creates a new idempotency middleware
+func NewIdempotencyMiddleware(options IdempotencyOptions, logger core.Logger) *IdempotencyMiddleware {
+	if options.Store == nil {
+		options.Store = NewMemoryIdempotencyStore()
+	}
+	if options.Header == "" {
+		options.Header = DefaultIdempotencyHeader
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultIdempotencyTTL
+	}
+	if options.Lease <= 0 {
+		options.Lease = DefaultIdempotencyLease
+	}
+	if len(options.Methods) == 0 {
+		options.Methods = []string{http.MethodPost}
+	}
+	return &IdempotencyMiddleware{
+		options:    options,
+		contentNeg: core.NewContentNegotiator(),
+		logger:     logger,
+	}
+}
+
+// Name returns middleware name
+func (im *IdempotencyMiddleware) Name() string {
+	return "idempotency"
+}
+
+// Priority returns middleware priority
+func (im *IdempotencyMiddleware) Priority() int {
+	return 30 // After auth, so keys are scoped to the caller
+}
+
+// Handler returns HTTP middleware handler
+func (im *IdempotencyMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(im.options.Header)
+			if key == "" || !slices.Contains(im.options.Methods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				core.WriteError(w, r, im.contentNeg, core.BadRequest("%s must be at most %d characters", im.options.Header, maxIdempotencyKeyLength))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				core.WriteError(w, r, im.contentNeg, core.ReadBodyFailed(err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			logger := core.LoggerFromContext(r.Context(), im.logger)
+			storeKey := scopedIdempotencyKey(r.Context(), key)
+			fingerprint := idempotencyFingerprint(r.Method, r.URL.RequestURI(), body)
+
+			existing, err := im.options.Store.Reserve(r.Context(), storeKey, fingerprint, im.options.Lease)
+			if err != nil {
+				// Fail open: an unavailable store must not take the API down
+				logger.Error("Idempotency store failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if existing != nil {
+				im.replay(w, r, existing, fingerprint)
+				return
+			}
+
+			defer im.releaseOnPanic(r.Context(), storeKey, logger)
+
+			iw := &idempotencyWriter{statusWriter: statusWriter{ResponseWriter: w, status: http.StatusOK}}
+			next.ServeHTTP(iw, r)
+
+			// Server errors are not stored, so the client can retry them
+			if iw.status >= http.StatusInternalServerError {
+				im.release(r.Context(), storeKey, logger)
+				return
+			}
+
+			record := IdempotencyRecord{
+				Status:    iw.status,
+				Header:    replayableHeader(w.Header()),
+				Body:      iw.body.Bytes(),
+				ExpiresAt: time.Now().Add(im.options.TTL),
+			}
+			if err := im.options.Store.Complete(r.Context(), storeKey, record); err != nil {
+				logger.Error("Failed to store idempotent response", "error", err)
+			}
+		})
+	}
+}
+
+// release drops a reservation so the client can retry. It ignores cancellation
+// of ctx, as the client may have gone away.
+func (im *IdempotencyMiddleware) release(ctx context.Context, storeKey string, logger core.Logger) {
+	if err := im.options.Store.Release(context.WithoutCancel(ctx), storeKey); err != nil {
+		logger.Error("Failed to release idempotency key", "error", err)
+	}
+}
+
+// releaseOnPanic releases the reservation of a handler that panicked and
+// re-panics; it must be deferred
+func (im *IdempotencyMiddleware) releaseOnPanic(ctx context.Context, storeKey string, logger core.Logger) {
+	if p := recover(); p != nil {
+		im.release(ctx, storeKey, logger)
+		panic(p)
+	}
+}
+
+// replay writes the stored response, or a conflict if the key cannot be replayed
+func (im *IdempotencyMiddleware) replay(w http.ResponseWriter, r *http.Request, record *IdempotencyRecord, fingerprint string) {
+	if err := im.replayError(record, fingerprint); err != nil {
+		core.WriteError(w, r, im.contentNeg, err)
+		return
+	}
+
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	_, _ = w.Write(record.Body)
+}
+
+// replayError returns the conflict for a key reused with another payload or still in flight
+func (im *IdempotencyMiddleware) replayError(record *IdempotencyRecord, fingerprint string) *core.APIError {
+	if record.Fingerprint != fingerprint {
+		return core.Conflict("%s was already used for a different request", im.options.Header)
+	}
+	if !record.Completed() {
+		return core.Conflict("A request with this %s is still being processed", im.options.Header)
+	}
+	return nil
+}
+
+// GRPCInterceptor returns gRPC interceptor honoring the key sent as metadata
+func (im *IdempotencyMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(strings.ToLower(im.options.Header))
+		message, ok := req.(proto.Message)
+		if len(values) == 0 || values[0] == "" || !ok {
+			return handler(ctx, req)
+		}
+		if len(values[0]) > maxIdempotencyKeyLength {
+			return nil, core.BadRequest("%s must be at most %d characters", im.options.Header, maxIdempotencyKeyLength)
+		}
+
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		logger := core.LoggerFromContext(ctx, im.logger)
+		storeKey := scopedIdempotencyKey(ctx, values[0])
+		fingerprint := idempotencyFingerprint("GRPC", info.FullMethod, data)
+
+		existing, err := im.options.Store.Reserve(ctx, storeKey, fingerprint, im.options.Lease)
+		if err != nil {
+			logger.Error("Idempotency store failed", "error", err)
+			return handler(ctx, req)
+		}
+		if existing != nil {
+			if err := im.replayError(existing, fingerprint); err != nil {
+				return nil, err
+			}
+			_ = grpc.SetHeader(ctx, metadata.Pairs("idempotent-replayed", "true"))
+			return replayGRPC(existing.Body)
+		}
+
+		defer im.releaseOnPanic(ctx, storeKey, logger)
+
+		resp, err := handler(ctx, req)
+		if isGRPCServerError(status.Code(err)) {
+			im.release(ctx, storeKey, logger)
+			return resp, err
+		}
+
+		if body, marshalErr := marshalGRPCOutcome(resp, err); marshalErr != nil {
+			logger.Error("Failed to encode idempotent response", "error", marshalErr)
+			im.release(ctx, storeKey, logger)
+		} else if storeErr := im.options.Store.Complete(ctx, storeKey, IdempotencyRecord{
+			Status:    http.StatusOK,
+			Body:      body,
+			ExpiresAt: time.Now().Add(im.options.TTL),
+		}); storeErr != nil {
+			logger.Error("Failed to store idempotent response", "error", storeErr)
+		}
+		return resp, err
+	}
+}
+
+// marshalGRPCOutcome encodes a response or error as a google.rpc.Status; a
+// successful response travels as its only detail
+func marshalGRPCOutcome(resp any, err error) ([]byte, error) {
+	if err != nil {
+		return proto.Marshal(status.Convert(err).Proto())
+	}
+	message, ok := resp.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("response %T is not a protobuf message", resp)
+	}
+	detail, err := anypb.New(message)
+	if err != nil {
+		return nil, err
+	}
+	outcome := status.New(codes.OK, "").Proto()
+	outcome.Details = []*anypb.Any{detail}
+	return proto.Marshal(outcome)
+}
+
+// replayGRPC decodes an outcome stored by marshalGRPCOutcome
+func replayGRPC(body []byte) (any, error) {
+	outcome := &spb.Status{}
+	if err := proto.Unmarshal(body, outcome); err != nil {
+		return nil, status.Error(codes.Internal, "failed to decode stored response")
+	}
+	if err := status.FromProto(outcome).Err(); err != nil {
+		return nil, err
+	}
+	if len(outcome.Details) == 0 {
+		return nil, status.Error(codes.Internal, "stored response is empty")
+	}
+	return outcome.Details[0].UnmarshalNew()
+}
+
+// scopedIdempotencyKey hashes the client's key with the caller's identity and
+// auth method, so keys cannot collide between callers (a JWT subject and a
+// basic-auth user may share a name) and always fit the store's key column
+func scopedIdempotencyKey(ctx context.Context, key string) string {
+	identity := ""
+	if principal, ok := core.PrincipalFromContext(ctx); ok {
+		identity = principal.Method + "\x00" + principal.Subject
+	}
+	sum := sha256.Sum256([]byte(identity + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyFingerprint identifies a request by method, target and payload
+func idempotencyFingerprint(method, target string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + target + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replayableHeader copies the response headers except per-request ones
+func replayableHeader(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for name, values := range header {
+		if slices.Contains(idempotencySkipHeaders, name) || strings.HasPrefix(name, "Ratelimit-") {
+			continue
+		}
+		result[name] = slices.Clone(values)
+	}
+	return result
+}
+
+// idempotencyWriter records the status and body while writing them through
+type idempotencyWriter struct {
+	statusWriter
+	body bytes.Buffer
+}
+
+// Write writes and records the response body
+func (iw *idempotencyWriter) Write(b []byte) (int, error) {
+	iw.body.Write(b)
+	return iw.statusWriter.Write(b)
+}
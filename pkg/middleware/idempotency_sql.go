@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/database"
+)
+
+// DefaultIdempotencyTable is the default table used by SQLIdempotencyStore
+const DefaultIdempotencyTable = "apiright_idempotency"
+
+// SQLIdempotencyStore keeps idempotency records in a database table so that
+// retries reaching another server instance are replayed too
+type SQLIdempotencyStore struct {
+	db         *sql.DB
+	dialect    string
+	table      string
+	timeSource func() time.Time
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewSQLIdempotencyStore creates a new SQL-backed idempotency store
+func NewSQLIdempotencyStore(db *sql.DB, dialect, table string) *SQLIdempotencyStore {
+	if table == "" {
+		table = DefaultIdempotencyTable
+	}
+	return &SQLIdempotencyStore{
+		db:         db,
+		dialect:    dialect,
+		table:      table,
+		timeSource: time.Now,
+	}
+}
+
+// CreateTable creates the idempotency table if it does not exist
+func (s *SQLIdempotencyStore) CreateTable(ctx context.Context) error {
+	keyType, blobType := "TEXT", "BLOB"
+	switch s.dialect {
+	case "mysql":
+		keyType, blobType = "VARCHAR(64)", "LONGBLOB"
+	case "postgres":
+		blobType = "BYTEA"
+	}
+	query := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (idempotency_key %s PRIMARY KEY, fingerprint VARCHAR(64) NOT NULL, status INTEGER NOT NULL, headers TEXT NOT NULL, body %s, expires_at BIGINT NOT NULL)",
+		s.table, keyType, blobType,
+	)
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create idempotency table: %w", err)
+	}
+	return nil
+}
+
+// Reserve claims key for a new request
+func (s *SQLIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*IdempotencyRecord, error) {
+	now := s.timeSource()
+	s.pruneEvery(ctx, now)
+
+	// An expired record or lapsed reservation must not block the key
+	if _, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE idempotency_key = %s AND expires_at < %s", s.table, database.Placeholder(s.dialect, 1), database.Placeholder(s.dialect, 2)),
+		key, now.UnixMilli(),
+	); err != nil {
+		return nil, fmt.Errorf("failed to expire idempotency key: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.insertQuery(), key, fingerprint, 0, "{}", now.Add(lease).UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if inserted, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	} else if inserted > 0 {
+		return nil, nil
+	}
+
+	var record IdempotencyRecord
+	var headers string
+	var expires int64
+	err = s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT fingerprint, status, headers, body, expires_at FROM %s WHERE idempotency_key = %s", s.table, database.Placeholder(s.dialect, 1)),
+		key,
+	).Scan(&record.Fingerprint, &record.Status, &headers, &record.Body, &expires)
+	if err == sql.ErrNoRows {
+		// Released between the insert and the read; let the caller proceed without a reservation
+		return nil, fmt.Errorf("idempotency key was released concurrently")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency record: %w", err)
+	}
+	if err := json.Unmarshal([]byte(headers), &record.Header); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency headers: %w", err)
+	}
+	record.ExpiresAt = time.UnixMilli(expires)
+	return &record, nil
+}
+
+// Complete stores the response for a reserved key
+func (s *SQLIdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord) error {
+	header := record.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	headers, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency headers: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET status = %s, headers = %s, body = %s, expires_at = %s WHERE idempotency_key = %s",
+			s.table, database.Placeholder(s.dialect, 1), database.Placeholder(s.dialect, 2), database.Placeholder(s.dialect, 3), database.Placeholder(s.dialect, 4), database.Placeholder(s.dialect, 5)),
+		record.Status, string(headers), record.Body, record.ExpiresAt.UnixMilli(), key,
+	); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release drops a reservation
+func (s *SQLIdempotencyStore) Release(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE idempotency_key = %s", s.table, database.Placeholder(s.dialect, 1)),
+		key,
+	); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes expired records
+func (s *SQLIdempotencyStore) Prune(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE expires_at < %s", s.table, database.Placeholder(s.dialect, 1)),
+		s.timeSource().UnixMilli(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune idempotency records: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// pruneEvery prunes expired records at most once per minute
+func (s *SQLIdempotencyStore) pruneEvery(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	_, _ = s.Prune(ctx)
+}
+
+// insertQuery returns the dialect-specific insert that skips existing keys
+func (s *SQLIdempotencyStore) insertQuery() string {
+	if s.dialect == "mysql" {
+		return fmt.Sprintf(
+			"INSERT IGNORE INTO %s (idempotency_key, fingerprint, status, headers, expires_at) VALUES (?, ?, ?, ?, ?)",
+			s.table,
+		)
+	}
+	return fmt.Sprintf(
+		"INSERT INTO %s (idempotency_key, fingerprint, status, headers, expires_at) VALUES (%s, %s, %s, %s, %s) "+
+			"ON CONFLICT (idempotency_key) DO NOTHING",
+		s.table, database.Placeholder(s.dialect, 1), database.Placeholder(s.dialect, 2), database.Placeholder(s.dialect, 3), database.Placeholder(s.dialect, 4), database.Placeholder(s.dialect, 5),
+	)
+}
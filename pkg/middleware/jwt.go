@@ -0,0 +1,341 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// JWTOptions configures a JWTAuthenticator
+type JWTOptions struct {
+	Algorithm  string // HS256 or RS256
+	Secret     string // HS256 shared secret
+	KeyFile    string // HS256 secret file or RS256 PEM public key/certificate
+	JWKSFile   string // Local JWKS document
+	Issuer     string
+	Audience   string
+	Leeway     time.Duration
+	RolesClaim string
+}
+
+// JWTAuthenticator verifies HS256/RS256 bearer tokens
+type JWTAuthenticator struct {
+	options    JWTOptions
+	secret     []byte                    // Default HS256 key
+	publicKey  *rsa.PublicKey            // Default RS256 key
+	hmacKeys   map[string][]byte         // HS256 keys by kid
+	rsaKeys    map[string]*rsa.PublicKey // RS256 keys by kid
+	timeSource func() time.Time
+}
+
+// NewJWTAuthenticator creates a new JWT authenticator and loads its keys
+func NewJWTAuthenticator(options JWTOptions) (*JWTAuthenticator, error) {
+	if options.Algorithm == "" {
+		options.Algorithm = "HS256"
+	}
+	if options.RolesClaim == "" {
+		options.RolesClaim = "roles"
+	}
+	if options.Algorithm != "HS256" && options.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", options.Algorithm)
+	}
+
+	ja := &JWTAuthenticator{
+		options:    options,
+		hmacKeys:   make(map[string][]byte),
+		rsaKeys:    make(map[string]*rsa.PublicKey),
+		timeSource: time.Now,
+	}
+
+	if options.Secret != "" {
+		ja.secret = []byte(options.Secret)
+	}
+
+	if options.KeyFile != "" {
+		data, err := os.ReadFile(options.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT key file: %w", err)
+		}
+		if options.Algorithm == "HS256" {
+			ja.secret = []byte(strings.TrimSpace(string(data)))
+		} else {
+			key, err := parseRSAPublicKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse JWT key file %s: %w", options.KeyFile, err)
+			}
+			ja.publicKey = key
+		}
+	}
+
+	if options.JWKSFile != "" {
+		if err := ja.loadJWKS(options.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if ja.secret == nil && ja.publicKey == nil && len(ja.hmacKeys) == 0 && len(ja.rsaKeys) == 0 {
+		return nil, fmt.Errorf("no JWT verification key configured")
+	}
+
+	return ja, nil
+}
+
+// Name returns authenticator name
+func (ja *JWTAuthenticator) Name() string {
+	return "jwt"
+}
+
+// Challenge returns the WWW-Authenticate challenge for bearer tokens
+func (ja *JWTAuthenticator) Challenge() string {
+	return "Bearer"
+}
+
+// Authenticate verifies a bearer token from the Authorization header
+func (ja *JWTAuthenticator) Authenticate(ctx context.Context, header func(string) string) (*core.Principal, error) {
+	authorization := header("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return nil, nil
+	}
+
+	claims, err := ja.Verify(strings.TrimSpace(authorization[7:]))
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &core.Principal{
+		Subject: subject,
+		Method:  ja.Name(),
+		Roles:   claimStrings(claims[ja.options.RolesClaim]),
+		Claims:  claims,
+	}, nil
+}
+
+// Verify checks the token signature and registered claims and returns the claims
+func (ja *JWTAuthenticator) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	if header.Alg != ja.options.Algorithm {
+		return nil, fmt.Errorf("unexpected signing algorithm: %s", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %w", err)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := ja.verifySignature(header.Kid, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	if err := ja.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// verifySignature checks the signature against the key selected by kid
+func (ja *JWTAuthenticator) verifySignature(kid string, signed, signature []byte) error {
+	switch ja.options.Algorithm {
+	case "HS256":
+		key := ja.secret
+		if k, ok := ja.hmacKeys[kid]; ok {
+			key = k
+		}
+		if key == nil {
+			return fmt.Errorf("unknown signing key: %s", kid)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid token signature")
+		}
+	case "RS256":
+		key := ja.publicKey
+		if k, ok := ja.rsaKeys[kid]; ok {
+			key = k
+		}
+		if key == nil {
+			return fmt.Errorf("unknown signing key: %s", kid)
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+	}
+	return nil
+}
+
+// validateClaims checks exp, nbf, iss and aud
+func (ja *JWTAuthenticator) validateClaims(claims map[string]any) error {
+	now := ja.timeSource()
+
+	if exp, ok := claims["exp"].(float64); ok {
+		if now.After(time.Unix(int64(exp), 0).Add(ja.options.Leeway)) {
+			return errors.New("token expired")
+		}
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(ja.options.Leeway).Before(time.Unix(int64(nbf), 0)) {
+			return errors.New("token not yet valid")
+		}
+	}
+
+	if ja.options.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != ja.options.Issuer {
+			return fmt.Errorf("unexpected token issuer: %s", iss)
+		}
+	}
+
+	if ja.options.Audience != "" {
+		found := false
+		for _, aud := range claimStrings(claims["aud"]) {
+			if aud == ja.options.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("token audience mismatch")
+		}
+	}
+
+	return nil
+}
+
+// loadJWKS loads HS256 (oct) and RS256 (RSA) keys from a local JWKS file
+func (ja *JWTAuthenticator) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			K   string `json:"k"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return fmt.Errorf("failed to parse JWKS file %s: %w", path, err)
+	}
+
+	for _, key := range jwks.Keys {
+		switch key.Kty {
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.K, "="))
+			if err != nil {
+				return fmt.Errorf("invalid JWKS oct key %s: %w", key.Kid, err)
+			}
+			ja.hmacKeys[key.Kid] = secret
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.N, "="))
+			if err != nil {
+				return fmt.Errorf("invalid JWKS RSA modulus %s: %w", key.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.E, "="))
+			if err != nil {
+				return fmt.Errorf("invalid JWKS RSA exponent %s: %w", key.Kid, err)
+			}
+			ja.rsaKeys[key.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseRSAPublicKey parses a PEM encoded RSA public key or certificate
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("certificate does not contain an RSA key")
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+		return nil, errors.New("PEM block does not contain an RSA key")
+	}
+}
+
+// decodeSegment decodes a base64url JWT segment into v
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// claimStrings converts a string, space-separated string or array claim into a slice
+func claimStrings(v any) []string {
+	switch value := v.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsMiddleware records request rate, errors and duration per table,
+// operation, protocol and status, and serves them for Prometheus
+type MetricsMiddleware struct {
+	registry    *prometheus.Registry
+	requests    *prometheus.CounterVec
+	errors      *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	rateLimited *prometheus.CounterVec
+	logger      core.Logger
+}
+
+// requestLabels are the labels of all request metrics
+var requestLabels = []string{"table", "operation", "protocol", "status"}
+
+// NewMetricsMiddleware creates a new metrics middleware with its own registry
+func NewMetricsMiddleware(logger core.Logger) *MetricsMiddleware {
+	mm := &MetricsMiddleware{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "apiright_requests_total",
+			Help: "Number of handled requests.",
+		}, requestLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "apiright_request_errors_total",
+			Help: "Number of requests that failed with a server error.",
+		}, requestLabels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "apiright_request_duration_seconds",
+			Help:    "Duration of handled requests.",
+			Buckets: prometheus.DefBuckets,
+		}, requestLabels),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "apiright_rate_limit_rejections_total",
+			Help: "Number of requests rejected by the rate limiter.",
+		}, []string{"protocol"}),
+		logger: logger,
+	}
+
+	mm.registry.MustRegister(
+		mm.requests, mm.errors, mm.duration, mm.rateLimited,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return mm
+}
+
+// Name returns middleware name
+func (mm *MetricsMiddleware) Name() string {
+	return "metrics"
+}
+
+// Priority returns middleware priority
+func (mm *MetricsMiddleware) Priority() int {
+	return 2 // Outside rate_limit and auth, so rejections are counted
+}
+
+// Registry returns the registry served at the metrics endpoint
+func (mm *MetricsMiddleware) Registry() *prometheus.Registry {
+	return mm.registry
+}
+
+// MetricsHandler serves the registry in the Prometheus text exposition format
+func (mm *MetricsMiddleware) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(mm.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB reports the connection pool statistics of db
+func (mm *MetricsMiddleware) RegisterDB(db *sql.DB, name string) error {
+	return mm.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterMigrationVersion reports the applied migration version returned by current
+func (mm *MetricsMiddleware) RegisterMigrationVersion(current func() (int, error)) error {
+	return mm.registry.Register(&migrationCollector{
+		current: current,
+		desc: prometheus.NewDesc("apiright_migration_version",
+			"Highest applied database migration version.", nil, nil),
+		logger: mm.logger,
+	})
+}
+
+// Handler returns HTTP middleware handler
+func (mm *MetricsMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, route := core.WithRouteInfo(r.Context())
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			mm.observe("http", *route, strconv.Itoa(sw.status), sw.status >= http.StatusInternalServerError,
+				sw.status == http.StatusTooManyRequests, time.Since(start))
+		})
+	}
+}
+
+// GRPCInterceptor returns gRPC interceptor
+func (mm *MetricsMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, route := core.WithRouteInfo(ctx)
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		mm.observe("grpc", *route, code.String(), isGRPCServerError(code),
+			code == grpccodes.ResourceExhausted, time.Since(start))
+		return resp, err
+	}
+}
+
+// observe records a finished request
+func (mm *MetricsMiddleware) observe(protocol string, route core.RouteInfo, statusLabel string, failed, rateLimited bool, duration time.Duration) {
+	labels := prometheus.Labels{
+		"table":     route.Table,
+		"operation": route.Operation,
+		"protocol":  protocol,
+		"status":    statusLabel,
+	}
+
+	mm.requests.With(labels).Inc()
+	mm.duration.With(labels).Observe(duration.Seconds())
+	if failed {
+		mm.errors.With(labels).Inc()
+	}
+	if rateLimited {
+		mm.rateLimited.WithLabelValues(protocol).Inc()
+	}
+}
+
+// migrationCollector reports the migration version at scrape time
+type migrationCollector struct {
+	current func() (int, error)
+	desc    *prometheus.Desc
+	logger  core.Logger
+}
+
+// Describe sends the metric description
+func (mc *migrationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mc.desc
+}
+
+// Collect sends the current version, or nothing if it cannot be read
+func (mc *migrationCollector) Collect(ch chan<- prometheus.Metric) {
+	version, err := mc.current()
+	if err != nil {
+		mc.logger.Debug("Failed to read migration version for metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(mc.desc, prometheus.GaugeValue, float64(version))
+}
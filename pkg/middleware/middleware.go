@@ -42,7 +42,7 @@ func (lm *LoggingMiddleware) Name() string {
 
 // Priority returns middleware priority
 func (lm *LoggingMiddleware) Priority() int {
-	return 100 // Low priority (executed first)
+	return 100 // Low priority (executed last, closest to the handler)
 }
 
 // Handler returns HTTP middleware handler
@@ -50,8 +50,9 @@ func (lm *LoggingMiddleware) Handler() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			logger := core.LoggerFromContext(r.Context(), lm.logger)
 
-			lm.logger.Info("HTTP request",
+			logger.Info("HTTP request",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
@@ -62,7 +63,7 @@ func (lm *LoggingMiddleware) Handler() func(http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 
 			duration := time.Since(start)
-			lm.logger.Info("HTTP response",
+			logger.Info("HTTP response",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"duration", duration,
@@ -75,8 +76,9 @@ func (lm *LoggingMiddleware) Handler() func(http.Handler) http.Handler {
 func (lm *LoggingMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 		start := time.Now()
+		logger := core.LoggerFromContext(ctx, lm.logger)
 
-		lm.logger.Info("gRPC request",
+		logger.Info("gRPC request",
 			"method", info.FullMethod,
 			"request", fmt.Sprintf("%+v", req),
 		)
@@ -85,7 +87,7 @@ func (lm *LoggingMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
 		resp, err := handler(ctx, req)
 
 		duration := time.Since(start)
-		lm.logger.Info("gRPC response",
+		logger.Info("gRPC response",
 			"method", info.FullMethod,
 			"duration", duration,
 			"error", err,
@@ -188,164 +190,6 @@ func (cm *CORSMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
 	return nil // CORS doesn't apply to gRPC
 }
 
-// RateLimitMiddleware provides request rate limiting
-type RateLimitMiddleware struct {
-	limit    int
-	window   time.Duration
-	requests map[string][]time.Time
-	logger   core.Logger
-	mu       sync.Mutex
-}
-
-// NewRateLimitMiddleware creates a new rate limiting middleware
-func NewRateLimitMiddleware(limit int, window time.Duration, logger core.Logger) *RateLimitMiddleware {
-	return &RateLimitMiddleware{
-		limit:    limit,
-		window:   window,
-		requests: make(map[string][]time.Time),
-		logger:   logger,
-	}
-}
-
-// Name returns middleware name
-func (rm *RateLimitMiddleware) Name() string {
-	return "rate_limit"
-}
-
-// Priority returns middleware priority
-func (rm *RateLimitMiddleware) Priority() int {
-	return 5 // Very high priority (executed first)
-}
-
-// Handler returns HTTP middleware handler
-func (rm *RateLimitMiddleware) Handler() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			clientIP := rm.getClientIP(r)
-
-			rm.mu.Lock()
-			// Clean old requests
-			rm.cleanOldRequests(clientIP)
-
-			// Check current count
-			requestCount := len(rm.requests[clientIP])
-			if requestCount >= rm.limit {
-				rm.mu.Unlock()
-				rm.logger.Warn("Rate limit exceeded",
-					"ip", clientIP,
-					"count", requestCount,
-					"limit", rm.limit,
-				)
-
-				// Return appropriate content type based on Accept header
-				acceptHeader := r.Header.Get("Accept")
-				contentType := "application/json"
-				if acceptHeader != "" {
-					// Simple content type detection
-					if strings.Contains(acceptHeader, "application/json") {
-						contentType = "application/json"
-					} else if strings.Contains(acceptHeader, "text/plain") {
-						contentType = "text/plain"
-					}
-				}
-
-				w.Header().Set("Content-Type", contentType)
-				w.WriteHeader(http.StatusTooManyRequests)
-
-				response := fmt.Sprintf("Rate limit exceeded: %d requests per %v", rm.limit, rm.window)
-				if _, err := w.Write([]byte(response)); err != nil {
-					rm.logger.Warn("failed to write rate limit response", "error", err)
-				}
-				return
-			}
-
-			// Record current request
-			rm.requests[clientIP] = append(rm.requests[clientIP], time.Now())
-			rm.mu.Unlock()
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
-// GRPCInterceptor returns gRPC interceptor
-func (rm *RateLimitMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		// Extract client info from context (if available)
-		clientIP := rm.extractGRPCClientIP(ctx)
-
-		if clientIP != "" {
-			rm.mu.Lock()
-			// Clean old requests
-			rm.cleanOldRequests(clientIP)
-
-			// Check current count
-			requestCount := len(rm.requests[clientIP])
-			if requestCount >= rm.limit {
-				rm.mu.Unlock()
-				rm.logger.Warn("gRPC rate limit exceeded",
-					"ip", clientIP,
-					"count", requestCount,
-					"limit", rm.limit,
-				)
-				return nil, status.Error(codes.ResourceExhausted, "Rate limit exceeded")
-			}
-
-			// Record current request
-			rm.requests[clientIP] = append(rm.requests[clientIP], time.Now())
-			rm.mu.Unlock()
-		}
-
-		return handler(ctx, req)
-	}
-}
-
-// cleanOldRequests removes old request timestamps
-func (rm *RateLimitMiddleware) cleanOldRequests(clientIP string) {
-	if requests, exists := rm.requests[clientIP]; exists {
-		cutoff := time.Now().Add(-rm.window)
-		var validRequests []time.Time
-
-		for _, reqTime := range requests {
-			if reqTime.After(cutoff) {
-				validRequests = append(validRequests, reqTime)
-			}
-		}
-
-		rm.requests[clientIP] = validRequests
-	}
-}
-
-// getClientIP extracts client IP from HTTP request
-func (rm *RateLimitMiddleware) getClientIP(r *http.Request) string {
-	// Try X-Forwarded-For header first
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the list
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
-		}
-		return strings.TrimSpace(xff)
-	}
-
-	// Try X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return strings.TrimSpace(xri)
-	}
-
-	// Fall back to RemoteAddr
-	if idx := strings.LastIndex(r.RemoteAddr, ":"); idx != -1 {
-		return r.RemoteAddr[:idx]
-	}
-	return r.RemoteAddr
-}
-
-// extractGRPCClientIP extracts client IP from gRPC context
-func (rm *RateLimitMiddleware) extractGRPCClientIP(ctx context.Context) string {
-	// In a real implementation, this would extract from metadata
-	// For now, return empty string to disable rate limiting for gRPC
-	return ""
-}
-
 // ValidationMiddleware provides request validation
 type ValidationMiddleware struct {
 	validator core.Validator
@@ -387,10 +231,14 @@ func (vm *ValidationMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
 		// Basic validation
 		if vm.validator != nil {
 			if err := vm.validator.Validate(req); err != nil {
-				vm.logger.Warn("gRPC request validation failed",
+				core.LoggerFromContext(ctx, vm.logger).Warn("gRPC request validation failed",
 					"method", info.FullMethod,
 					"error", err,
 				)
+				// Field violations are returned as BadRequest details
+				if apiErr, ok := core.AsAPIError(err); ok {
+					return nil, apiErr
+				}
 				return nil, status.Error(codes.InvalidArgument, err.Error())
 			}
 		}
@@ -450,6 +298,16 @@ func (mr *MiddlewareRegistry) GetHTTPMiddleware() []func(http.Handler) http.Hand
 	return handlers
 }
 
+// Chain wraps handler in the registered middleware so the lowest priority
+// number runs outermost, matching the order of the gRPC interceptors
+func (mr *MiddlewareRegistry) Chain(handler http.Handler) http.Handler {
+	handlers := mr.GetHTTPMiddleware()
+	for i := len(handlers) - 1; i >= 0; i-- {
+		handler = handlers[i](handler)
+	}
+	return handler
+}
+
 // GetGRPCInterceptors returns all gRPC interceptors
 func (mr *MiddlewareRegistry) GetGRPCInterceptors() []grpc.UnaryServerInterceptor {
 	mr.mu.RLock()
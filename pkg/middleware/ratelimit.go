@@ -0,0 +1,448 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RateLimit allows Requests per Window, refilled continuously (token bucket).
+// A negative Requests value disables limiting.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitResult describes the state of a bucket after taking a token
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until the next token is available (0 if allowed)
+}
+
+// RateLimitStore keeps token buckets. Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	// Take consumes one token from the bucket identified by key
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// RateLimitRule applies a limit to matching requests. Empty fields match everything.
+type RateLimitRule struct {
+	Path   string // HTTP path or gRPC method; trailing * matches a prefix
+	Method string // HTTP method
+	APIKey string // API key name
+	Limit  RateLimit
+}
+
+// RateLimitOptions configures a RateLimitMiddleware
+type RateLimitOptions struct {
+	Store        RateLimitStore    // Defaults to an in-memory store
+	Default      RateLimit         // Applied when no rule matches
+	Rules        []RateLimitRule   // First matching rule wins
+	APIKeyHeader string            // Clients sending a known key in this header are limited per key instead of per IP
+	APIKeys      map[string]string // Key hash (see HashSecret) to name, used to match rules
+
+	// TrustedProxies are the peers whose X-Forwarded-For and X-Real-IP headers
+	// are honoured; requests from any other peer are limited by their address
+	TrustedProxies []*net.IPNet
+}
+
+// bucketState is the persisted state of a token bucket
+type bucketState struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket up to now and tries to consume one token
+func (b *bucketState) take(now time.Time, limit RateLimit) RateLimitResult {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds() // Tokens per second
+
+	if b.updated.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	}
+	b.updated = now
+
+	result := RateLimitResult{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else if rate > 0 {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	} else {
+		result.RetryAfter = limit.Window
+	}
+
+	result.Remaining = int(b.tokens)
+	if rate > 0 {
+		result.Reset = time.Duration((capacity - b.tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+// memoryShardCount is the number of independently locked shards in MemoryRateLimitStore
+const memoryShardCount = 32
+
+// memoryShard holds a subset of buckets under its own lock
+type memoryShard struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// memoryBucket is a bucket plus the time after which it is full and can be evicted
+type memoryBucket struct {
+	bucketState
+	expires time.Time
+}
+
+// MemoryRateLimitStore is a sharded in-memory RateLimitStore. Idle buckets are
+// evicted once they would have refilled completely.
+type MemoryRateLimitStore struct {
+	shards        [memoryShardCount]*memoryShard
+	sweepInterval time.Duration
+	timeSource    func() time.Time
+}
+
+// NewMemoryRateLimitStore creates a new in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		sweepInterval: time.Minute,
+		timeSource:    time.Now,
+	}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{buckets: make(map[string]*memoryBucket)}
+	}
+	return s
+}
+
+// Take consumes one token from the bucket identified by key
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	shard := s.shards[hash.Sum32()%memoryShardCount]
+
+	now := s.timeSource()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) >= s.sweepInterval {
+		for k, b := range shard.buckets {
+			if now.After(b.expires) {
+				delete(shard.buckets, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	bucket, ok := shard.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{}
+		shard.buckets[key] = bucket
+	}
+	result := bucket.take(now, limit)
+	bucket.expires = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// Len returns the number of buckets currently held
+func (s *MemoryRateLimitStore) Len() int {
+	total := 0
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		total += len(shard.buckets)
+		shard.mu.Unlock()
+	}
+	return total
+}
+
+// RateLimitMiddleware provides request rate limiting
+type RateLimitMiddleware struct {
+	options    RateLimitOptions
+	contentNeg *core.ContentNegotiatorImpl
+	logger     core.Logger
+}
+
+// NewRateLimitMiddleware creates a new rate limiting middleware allowing limit
+// requests per window for each client IP
+func NewRateLimitMiddleware(limit int, window time.Duration, logger core.Logger) *RateLimitMiddleware {
+	return NewRateLimitMiddlewareWithOptions(RateLimitOptions{
+		Default: RateLimit{Requests: limit, Window: window},
+	}, logger)
+}
+
+// NewRateLimitMiddlewareWithOptions creates a rate limiting middleware with
+// per-route, per-method and per-API-key rules
+func NewRateLimitMiddlewareWithOptions(options RateLimitOptions, logger core.Logger) *RateLimitMiddleware {
+	if options.Store == nil {
+		options.Store = NewMemoryRateLimitStore()
+	}
+	return &RateLimitMiddleware{
+		options:    options,
+		contentNeg: core.NewContentNegotiator(),
+		logger:     logger,
+	}
+}
+
+// Name returns middleware name
+func (rm *RateLimitMiddleware) Name() string {
+	return "rate_limit"
+}
+
+// Priority returns middleware priority
+func (rm *RateLimitMiddleware) Priority() int {
+	return 5 // Very high priority (executed first)
+}
+
+// Handler returns HTTP middleware handler
+func (rm *RateLimitMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, keyName := rm.clientIdentity(r.Header.Get, rm.clientIP(r))
+
+			result, limited, err := rm.take(r.Context(), r.URL.Path, r.Method, client, keyName)
+			if err != nil {
+				// Fail open: an unavailable store must not take the API down
+				core.LoggerFromContext(r.Context(), rm.logger).Error("Rate limit store failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setRateLimitHeaders(w.Header(), result)
+
+			if !result.Allowed {
+				core.LoggerFromContext(r.Context(), rm.logger).Warn("Rate limit exceeded",
+					"client", client,
+					"path", r.URL.Path,
+					"limit", result.Limit,
+				)
+				rm.writeTooManyRequests(w, r, result)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GRPCInterceptor returns gRPC interceptor
+func (rm *RateLimitMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		header := func(name string) string {
+			if values := md.Get(name); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+
+		client, keyName := rm.clientIdentity(header, extractGRPCClientIP(ctx))
+		if client == "" {
+			return handler(ctx, req)
+		}
+
+		result, limited, err := rm.take(ctx, info.FullMethod, "", client, keyName)
+		if err != nil {
+			core.LoggerFromContext(ctx, rm.logger).Error("Rate limit store failed", "error", err)
+			return handler(ctx, req)
+		}
+
+		if limited && !result.Allowed {
+			core.LoggerFromContext(ctx, rm.logger).Warn("gRPC rate limit exceeded",
+				"client", client,
+				"method", info.FullMethod,
+				"limit", result.Limit,
+			)
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfterSeconds(result.RetryAfter)))
+			return nil, rateLimitError(result)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// take finds the limit for a request and consumes a token. limited is false
+// when the matching limit is disabled.
+func (rm *RateLimitMiddleware) take(ctx context.Context, path, method, client, keyName string) (RateLimitResult, bool, error) {
+	bucket := "default"
+	limit := rm.options.Default
+
+	for i, rule := range rm.options.Rules {
+		if rule.matches(path, method, keyName) {
+			bucket = "rule" + strconv.Itoa(i)
+			limit = rule.Limit
+			break
+		}
+	}
+
+	if limit.Requests < 0 {
+		return RateLimitResult{}, false, nil
+	}
+	if limit.Window <= 0 {
+		limit.Window = time.Minute
+	}
+
+	result, err := rm.options.Store.Take(ctx, bucket+"|"+client, limit)
+	if err != nil {
+		return RateLimitResult{}, false, err
+	}
+	return result, true, nil
+}
+
+// clientIdentity returns the bucket identity for a caller and, if it presented
+// a known API key, the key's name. Unknown keys are limited by IP; rate limiting
+// runs before authentication, so rotating made-up keys must not buy fresh buckets.
+func (rm *RateLimitMiddleware) clientIdentity(header func(string) string, ip string) (string, string) {
+	if rm.options.APIKeyHeader != "" {
+		if key := header(rm.options.APIKeyHeader); key != "" {
+			hash := HashSecret(key)
+			if name, ok := rm.options.APIKeys[hash]; ok {
+				return "key:" + hash, name
+			}
+		}
+	}
+	if ip == "" {
+		return "", ""
+	}
+	return "ip:" + ip, ""
+}
+
+// writeTooManyRequests writes a 429 response in the negotiated content type
+func (rm *RateLimitMiddleware) writeTooManyRequests(w http.ResponseWriter, r *http.Request, result RateLimitResult) {
+	// WriteError also sets the Retry-After header
+	core.WriteError(w, r, rm.contentNeg, rateLimitError(result))
+}
+
+// rateLimitError describes a rejected request for both transports
+func rateLimitError(result RateLimitResult) *core.APIError {
+	return core.RateLimited(result.RetryAfter, "Rate limit exceeded: %d requests allowed, retry in %ss", result.Limit, retryAfterSeconds(result.RetryAfter))
+}
+
+// matches reports whether the rule applies to a request
+func (rule RateLimitRule) matches(path, method, keyName string) bool {
+	if rule.Path != "" {
+		if prefix, ok := strings.CutSuffix(rule.Path, "*"); ok {
+			if !strings.HasPrefix(path, prefix) {
+				return false
+			}
+		} else if path != rule.Path {
+			return false
+		}
+	}
+	if rule.Method != "" && !strings.EqualFold(rule.Method, method) {
+		return false
+	}
+	if rule.APIKey != "" && rule.APIKey != keyName {
+		return false
+	}
+	return true
+}
+
+// setRateLimitHeaders sets the RateLimit-* response headers
+func setRateLimitHeaders(h http.Header, result RateLimitResult) {
+	h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+}
+
+// retryAfterSeconds formats a duration as whole seconds, rounded up
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// ParseTrustedProxies parses IP addresses and CIDR ranges for RateLimitOptions.TrustedProxies
+func ParseTrustedProxies(entries []string) ([]*net.IPNet, error) {
+	proxies := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		// A single address is a range of one
+		cidr := entry
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// clientIP returns the address requests are limited by. Forwarding headers
+// are only honoured when the peer is a trusted proxy; X-Forwarded-For is then
+// read from the right, skipping trusted proxies, as clients control its start.
+func (rm *RateLimitMiddleware) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !rm.trustedProxy(remote) {
+		return remote
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if hop := strings.TrimSpace(hops[i]); hop != "" && !rm.trustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+	return remote
+}
+
+// trustedProxy reports whether addr is one of the configured trusted proxies
+func (rm *RateLimitMiddleware) trustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range rm.options.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractGRPCClientIP extracts client IP from the gRPC peer
+func extractGRPCClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
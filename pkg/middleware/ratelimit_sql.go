@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/database"
+)
+
+// DefaultRateLimitTable is the default table used by SQLRateLimitStore
+const DefaultRateLimitTable = "apiright_rate_limits"
+
+// SQLRateLimitStore keeps token buckets in a database table so that several
+// server instances share the same limits
+type SQLRateLimitStore struct {
+	db         *sql.DB
+	dialect    string
+	table      string
+	timeSource func() time.Time
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewSQLRateLimitStore creates a new SQL-backed rate limit store
+func NewSQLRateLimitStore(db *sql.DB, dialect, table string) *SQLRateLimitStore {
+	if table == "" {
+		table = DefaultRateLimitTable
+	}
+	return &SQLRateLimitStore{
+		db:         db,
+		dialect:    dialect,
+		table:      table,
+		timeSource: time.Now,
+	}
+}
+
+// CreateTable creates the bucket table if it does not exist
+func (s *SQLRateLimitStore) CreateTable(ctx context.Context) error {
+	keyType := "TEXT"
+	if s.dialect == "mysql" {
+		keyType = "VARCHAR(255)"
+	}
+	query := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (bucket_key %s PRIMARY KEY, tokens DOUBLE PRECISION NOT NULL, updated_at BIGINT NOT NULL, expires_at BIGINT NOT NULL)",
+		s.table, keyType,
+	)
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create rate limit table: %w", err)
+	}
+	return nil
+}
+
+// Take consumes one token from the bucket identified by key
+func (s *SQLRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to begin rate limit transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Lock the row so concurrent instances see each other's updates
+	lock := ""
+	if s.dialect == "postgres" || s.dialect == "mysql" {
+		lock = " FOR UPDATE"
+	}
+
+	var state bucketState
+	var updated int64
+	err = tx.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT tokens, updated_at FROM %s WHERE bucket_key = %s%s", s.table, database.Placeholder(s.dialect, 1), lock),
+		key,
+	).Scan(&state.tokens, &updated)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return RateLimitResult{}, fmt.Errorf("failed to read rate limit bucket: %w", err)
+	default:
+		state.updated = time.UnixMilli(updated)
+	}
+
+	now := s.timeSource()
+	result := state.take(now, limit)
+
+	if _, err := tx.ExecContext(ctx, s.upsertQuery(), key, state.tokens, now.UnixMilli(), now.Add(result.Reset).UnixMilli()); err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to commit rate limit bucket: %w", err)
+	}
+	s.pruneEvery(ctx, now)
+
+	return result, nil
+}
+
+// Prune deletes buckets that have refilled completely
+func (s *SQLRateLimitStore) Prune(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE expires_at < %s", s.table, database.Placeholder(s.dialect, 1)),
+		s.timeSource().UnixMilli(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune rate limit buckets: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// pruneEvery prunes expired buckets at most once per minute
+func (s *SQLRateLimitStore) pruneEvery(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	_, _ = s.Prune(ctx)
+}
+
+// upsertQuery returns the dialect-specific insert-or-update statement
+func (s *SQLRateLimitStore) upsertQuery() string {
+	if s.dialect == "mysql" {
+		return fmt.Sprintf(
+			"INSERT INTO %s (bucket_key, tokens, updated_at, expires_at) VALUES (?, ?, ?, ?) "+
+				"ON DUPLICATE KEY UPDATE tokens = VALUES(tokens), updated_at = VALUES(updated_at), expires_at = VALUES(expires_at)",
+			s.table,
+		)
+	}
+	return fmt.Sprintf(
+		"INSERT INTO %s (bucket_key, tokens, updated_at, expires_at) VALUES (%s, %s, %s, %s) "+
+			"ON CONFLICT (bucket_key) DO UPDATE SET tokens = excluded.tokens, updated_at = excluded.updated_at, expires_at = excluded.expires_at",
+		s.table, database.Placeholder(s.dialect, 1), database.Placeholder(s.dialect, 2), database.Placeholder(s.dialect, 3), database.Placeholder(s.dialect, 4),
+	)
+}
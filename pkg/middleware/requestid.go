@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bata94/apiright/pkg/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultRequestIDHeader is the header used to read and echo request IDs
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs so they cannot bloat logs
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware reads or creates a request ID and W3C trace context,
+// stores them in the request context together with a logger that includes
+// them, and echoes them in response headers and gRPC metadata
+type RequestIDMiddleware struct {
+	header string
+	logger core.Logger
+}
+
+// NewRequestIDMiddleware creates a new request ID middleware
+func NewRequestIDMiddleware(header string, logger core.Logger) *RequestIDMiddleware {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	return &RequestIDMiddleware{
+		header: header,
+		logger: logger,
+	}
+}
+
+// Name returns middleware name
+func (rm *RequestIDMiddleware) Name() string {
+	return "request_id"
+}
+
+// Priority returns middleware priority
+func (rm *RequestIDMiddleware) Priority() int {
+	return 0 // DualServer installs it outside all registered middleware
+}
+
+// Handler returns HTTP middleware handler
+func (rm *RequestIDMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, requestID, tc := rm.begin(r.Context(), r.Header.Get(rm.header), r.Header.Get("traceparent"))
+
+			w.Header().Set(rm.header, requestID)
+			w.Header().Set("traceparent", tc.Traceparent())
+			if state := r.Header.Get("tracestate"); state != "" {
+				w.Header().Set("tracestate", state)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GRPCInterceptor returns gRPC interceptor
+func (rm *RequestIDMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		first := func(name string) string {
+			if values := md.Get(name); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+
+		ctx, requestID, tc := rm.begin(ctx, first(rm.header), first("traceparent"))
+
+		if err := grpc.SetHeader(ctx, metadata.Pairs(rm.header, requestID, "traceparent", tc.Traceparent())); err != nil {
+			core.LoggerFromContext(ctx, rm.logger).Debug("failed to set request ID metadata", "error", err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// begin resolves the request ID and trace context and stores them in ctx
+func (rm *RequestIDMiddleware) begin(ctx context.Context, requestID, traceparent string) (context.Context, string, core.TraceContext) {
+	if !validRequestID(requestID) {
+		requestID = core.NewRequestID()
+	}
+
+	// Continue the caller's trace with a new span, or start a new trace
+	tc := core.NewTraceContext()
+	if parent, err := core.ParseTraceparent(traceparent); err == nil {
+		tc = parent.Child()
+	}
+
+	logger := rm.logger.With("request_id", requestID, "trace_id", tc.TraceID, "span_id", tc.SpanID)
+
+	ctx = core.WithRequestID(ctx, requestID)
+	ctx = core.WithTraceContext(ctx, tc)
+	ctx = core.WithLogger(ctx, logger)
+	return ctx, requestID, tc
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to reuse
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') &&
+			c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
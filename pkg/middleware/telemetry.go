@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TelemetryMiddleware starts a server span for every request and records
+// request count, duration and error metrics per table and operation
+type TelemetryMiddleware struct {
+	logger core.Logger
+}
+
+// NewTelemetryMiddleware creates a new telemetry middleware. Spans and metrics
+// go to the global providers installed by telemetry.Setup.
+func NewTelemetryMiddleware(logger core.Logger) *TelemetryMiddleware {
+	return &TelemetryMiddleware{
+		logger: logger,
+	}
+}
+
+// Name returns middleware name
+func (tm *TelemetryMiddleware) Name() string {
+	return "telemetry"
+}
+
+// Priority returns middleware priority
+func (tm *TelemetryMiddleware) Priority() int {
+	return 1 // Outermost registered middleware, so rejected requests are measured too
+}
+
+// Handler returns HTTP middleware handler
+func (tm *TelemetryMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, route := core.WithRouteInfo(remoteParent(r.Context(), propagation.HeaderCarrier(r.Header)))
+			ctx, span := telemetry.Tracer().Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+				))
+			defer span.End()
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			if route.Table != "" {
+				span.SetName(r.Method + " " + route.Table + "." + route.Operation)
+				span.SetAttributes(
+					attribute.String("apiright.table", route.Table),
+					attribute.String("apiright.operation", route.Operation),
+				)
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+			failed := sw.status >= http.StatusInternalServerError
+			if failed {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+
+			telemetry.RecordRequest(ctx, "http", *route, strconv.Itoa(sw.status), failed, time.Since(start))
+		})
+	}
+}
+
+// GRPCInterceptor returns gRPC interceptor
+func (tm *TelemetryMiddleware) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx, route := core.WithRouteInfo(remoteParent(ctx, metadataCarrier(md)))
+		ctx, span := telemetry.Tracer().Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.method", info.FullMethod),
+			))
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		if route.Table != "" {
+			span.SetAttributes(
+				attribute.String("apiright.table", route.Table),
+				attribute.String("apiright.operation", route.Operation),
+			)
+		}
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+		failed := isGRPCServerError(code)
+		if failed {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		telemetry.RecordRequest(ctx, "grpc", *route, code.String(), failed, time.Since(start))
+		return resp, err
+	}
+}
+
+// remoteParent sets the caller's span as the remote parent of ctx. It prefers
+// the trace context resolved by the request ID middleware and falls back to
+// the global propagator.
+func remoteParent(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	tc, ok := core.TraceContextFromContext(ctx)
+	if !ok {
+		return otel.GetTextMapPropagator().Extract(ctx, carrier)
+	}
+	if tc.ParentID == "" {
+		return ctx
+	}
+
+	traceID, err := trace.TraceIDFromHex(tc.TraceID)
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(tc.ParentID)
+	if err != nil {
+		return ctx
+	}
+	var flags trace.TraceFlags
+	if tc.Sampled() {
+		flags = trace.FlagsSampled
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+// isGRPCServerError reports whether code indicates a server-side failure
+func isGRPCServerError(code grpccodes.Code) bool {
+	switch code {
+	case grpccodes.Unknown, grpccodes.DeadlineExceeded, grpccodes.Unimplemented,
+		grpccodes.Internal, grpccodes.Unavailable, grpccodes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+// Get returns the first value for key
+func (mc metadataCarrier) Get(key string) string {
+	if values := metadata.MD(mc).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set sets the value for key
+func (mc metadataCarrier) Set(key, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+// Keys returns all metadata keys
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for key := range mc {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// statusWriter records the status code written by the handler
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wroteHeader {
+		sw.status = code
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the response body, implying a 200 status
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush flushes the underlying writer if it supports flushing
+func (sw *statusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -174,6 +174,48 @@ func (pr *PluginRegistry) GetMiddlewareProviders() []core.MiddlewareProvider {
 	return providers
 }
 
+// GetHealthCheckProviders returns all plugins that implement HealthCheckProvider interface
+func (pr *PluginRegistry) GetHealthCheckProviders() []core.HealthCheckProvider {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+
+	var providers []core.HealthCheckProvider
+	for _, plugin := range pr.plugins {
+		if hp, ok := plugin.(core.HealthCheckProvider); ok {
+			providers = append(providers, hp)
+		}
+	}
+	return providers
+}
+
+// GetShutdownHookProviders returns all plugins that implement ShutdownHookProvider interface
+func (pr *PluginRegistry) GetShutdownHookProviders() []core.ShutdownHookProvider {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+
+	var providers []core.ShutdownHookProvider
+	for _, plugin := range pr.plugins {
+		if sp, ok := plugin.(core.ShutdownHookProvider); ok {
+			providers = append(providers, sp)
+		}
+	}
+	return providers
+}
+
+// GetSerializerProviders returns all plugins that implement SerializerProvider interface
+func (pr *PluginRegistry) GetSerializerProviders() []core.SerializerProvider {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+
+	var providers []core.SerializerProvider
+	for _, plugin := range pr.plugins {
+		if sp, ok := plugin.(core.SerializerProvider); ok {
+			providers = append(providers, sp)
+		}
+	}
+	return providers
+}
+
 // PluginLoader handles loading plugins from various sources
 type PluginLoader struct {
 	registry *PluginRegistry
@@ -370,6 +412,21 @@ func GetMiddlewareProviders() []core.MiddlewareProvider {
 	return providers
 }
 
+// GetHealthCheckProviders returns all plugins that implement HealthCheckProvider interface
+func GetHealthCheckProviders() []core.HealthCheckProvider {
+	return globalRegistry.GetHealthCheckProviders()
+}
+
+// GetShutdownHookProviders returns all plugins that implement ShutdownHookProvider interface
+func GetShutdownHookProviders() []core.ShutdownHookProvider {
+	return globalRegistry.GetShutdownHookProviders()
+}
+
+// GetSerializerProviders returns all plugins that implement SerializerProvider interface
+func GetSerializerProviders() []core.SerializerProvider {
+	return globalRegistry.GetSerializerProviders()
+}
+
 // HasProtoExtensions checks if any registered plugin provides proto extensions
 func HasProtoExtensions() bool {
 	return len(GetProtoExtensions()) > 0
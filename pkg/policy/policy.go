@@ -0,0 +1,164 @@
+// Package policy enforces per-table, per-operation authorization rules.
+package policy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"google.golang.org/grpc"
+)
+
+// Operation identifies a CRUD operation on a table
+type Operation string
+
+const (
+	OpGet    Operation = "get"
+	OpList   Operation = "list"
+	OpCreate Operation = "create"
+	OpUpdate Operation = "update"
+	OpDelete Operation = "delete"
+)
+
+var (
+	// ErrUnauthenticated is returned when an operation requires an authenticated caller
+	ErrUnauthenticated error = core.Unauthorized("authentication required")
+	// ErrForbidden is returned when the caller lacks a required role
+	ErrForbidden error = core.Forbidden("permission denied")
+)
+
+// AnyRole allows any authenticated caller
+const AnyRole = "*"
+
+// Enforcer checks table policies against the principal in the request context
+type Enforcer struct {
+	policies config.PolicyConfig
+	logger   core.Logger
+}
+
+// NewEnforcer creates a new policy enforcer
+func NewEnforcer(policies config.PolicyConfig, logger core.Logger) *Enforcer {
+	return &Enforcer{
+		policies: policies,
+		logger:   logger,
+	}
+}
+
+// Authorize checks whether the caller may perform op on table. On success it
+// returns a context carrying the owner scope if the table has an ownership rule.
+func (e *Enforcer) Authorize(ctx context.Context, table string, op Operation) (context.Context, error) {
+	tablePolicy, ok := e.policies[table]
+	if !ok {
+		return ctx, nil
+	}
+
+	principal, authenticated := core.PrincipalFromContext(ctx)
+
+	if roles := tablePolicy.Roles(string(op)); len(roles) > 0 {
+		if !authenticated {
+			return ctx, ErrUnauthenticated
+		}
+		if !hasAnyRole(principal, roles) {
+			core.LoggerFromContext(ctx, e.logger).Warn("Policy denied operation",
+				"table", table,
+				"operation", op,
+				"subject", principal.Subject,
+			)
+			return ctx, ErrForbidden
+		}
+	}
+
+	if tablePolicy.OwnerColumn != "" {
+		if !authenticated {
+			return ctx, ErrUnauthenticated
+		}
+		if !hasAnyRole(principal, tablePolicy.OwnerBypass) {
+			ctx = core.WithOwnerScope(ctx, core.OwnerScope{
+				Table:  table,
+				Column: tablePolicy.OwnerColumn,
+				Owner:  principal.Subject,
+			})
+		}
+	}
+
+	return ctx, nil
+}
+
+// GRPCInterceptor returns a gRPC interceptor enforcing policies on generated services
+func (e *Enforcer) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		table, op, ok := e.resolveMethod(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		// Both errors are typed, so gRPC returns Unauthenticated or PermissionDenied with details
+		ctx, err := e.Authorize(ctx, table, op)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// resolveMethod maps a generated gRPC method such as "/api.UserService/ListUsers"
+// onto a policy table and operation
+func (e *Enforcer) resolveMethod(fullMethod string) (string, Operation, bool) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "", "", false
+	}
+	if idx := strings.LastIndex(service, "."); idx >= 0 {
+		service = service[idx+1:]
+	}
+	service = normalizeName(strings.TrimSuffix(service, "Service"))
+
+	var op Operation
+	for _, candidate := range []Operation{OpGet, OpList, OpCreate, OpUpdate, OpDelete} {
+		if strings.HasPrefix(strings.ToLower(method), string(candidate)) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return "", "", false
+	}
+
+	for table := range e.policies {
+		if normalizeName(table) == service || singular(normalizeName(table)) == singular(service) {
+			return table, op, true
+		}
+	}
+	return "", "", false
+}
+
+// hasAnyRole reports whether the principal carries one of the roles
+func hasAnyRole(principal *core.Principal, roles []string) bool {
+	for _, role := range roles {
+		if role == AnyRole || principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeName lowercases a name and strips separators
+func normalizeName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", " ", "", "-", "").Replace(name))
+}
+
+// singular strips common plural suffixes
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ses"), strings.HasSuffix(name, "xes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s"):
+		return name[:len(name)-1]
+	default:
+		return name
+	}
+}
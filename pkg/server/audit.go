@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bata94/apiright/pkg/audit"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/policy"
+)
+
+const (
+	// auditRetentionInterval controls how often expired audit entries are pruned
+	auditRetentionInterval = time.Hour
+	// auditScanPages bounds the pages one /_audit request reads while filtering
+	auditScanPages = 10
+)
+
+// SetAuditLog enables the read-only audit endpoint backed by the given recorder
+func (s *DualServer) SetAuditLog(recorder *audit.Recorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auditLog = recorder
+}
+
+// auditHandler serves GET /_audit?table=&id=&limit=&before= to callers with an
+// audit role. Entries of rows the table policies hide from the caller are left
+// out; next_before pages on to older entries.
+func (s *DualServer) auditHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := s.detectContentType(r)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	auditLog, policies := s.auditLog, s.policies
+	s.mu.RUnlock()
+
+	principal, ok := core.PrincipalFromContext(r.Context())
+	if !ok {
+		core.WriteError(w, r, s.contentNeg, policy.ErrUnauthenticated)
+		return
+	}
+	if !auditLog.CanRead(principal) {
+		core.WriteError(w, r, s.contentNeg, policy.ErrForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > audit.MaxHistoryLimit {
+			s.handleServiceError(w, r, core.BadRequest("limit must be between 1 and %d", audit.MaxHistoryLimit), contentType)
+			return
+		}
+		limit = parsed
+	}
+	var before int64
+	if beforeStr := query.Get("before"); beforeStr != "" {
+		parsed, err := strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil || parsed < 1 {
+			s.handleServiceError(w, r, core.BadRequest("before must be a positive entry ID"), contentType)
+			return
+		}
+		before = parsed
+	}
+
+	entries, next, err := s.visibleAuditEntries(r.Context(), auditLog, policies, query.Get("table"), query.Get("id"), before, limit)
+	if err != nil {
+		s.handleServiceError(w, r, err, contentType)
+		return
+	}
+
+	response := map[string]any{
+		"table":   query.Get("table"),
+		"id":      query.Get("id"),
+		"entries": entries,
+	}
+	if next > 0 {
+		response["next_before"] = next
+	}
+	s.serializeResponse(w, response, contentType)
+}
+
+// visibleAuditEntries returns up to limit entries older than before that the
+// caller may see, reading further pages while policies filter entries out. It
+// reads at most auditScanPages pages; next is the before cursor of the
+// following page, or 0 once the log is exhausted.
+func (s *DualServer) visibleAuditEntries(ctx context.Context, auditLog *audit.Recorder, policies *policy.Enforcer, table, id string, before int64, limit int) ([]core.AuditEntry, int64, error) {
+	type row struct{ table, id string }
+	readable := map[row]bool{}
+	visible := []core.AuditEntry{}
+
+	for page := 0; page < auditScanPages; page++ {
+		entries, err := auditLog.HistoryBefore(ctx, table, id, before, limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, entry := range entries {
+			before = entry.ID
+			key := row{entry.Table, entry.PrimaryKey}
+			allowed, checked := readable[key]
+			if !checked {
+				allowed = policies == nil || s.canReadAuditedRow(ctx, policies, entry.Table, entry.PrimaryKey)
+				readable[key] = allowed
+			}
+			if !allowed {
+				continue
+			}
+			visible = append(visible, entry)
+			if len(visible) == limit {
+				return visible, before, nil
+			}
+		}
+		if len(entries) < limit {
+			return visible, 0, nil
+		}
+	}
+	return visible, before, nil
+}
+
+// canReadAuditedRow checks the get policy of table for the caller. Under an
+// owner scope the row must still be readable through the table's service, so
+// the history of deleted owned rows is only visible to owner_bypass roles.
+func (s *DualServer) canReadAuditedRow(ctx context.Context, policies *policy.Enforcer, table, id string) bool {
+	ctx, err := policies.Authorize(ctx, table, policy.OpGet)
+	if err != nil {
+		return false
+	}
+	if _, scoped := core.OwnerScopeFromContext(ctx, table); !scoped {
+		return true
+	}
+	s.mu.RLock()
+	service, ok := s.services[table].(ServiceInterface)
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	_, err = service.Get(ctx, id)
+	return err == nil
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// streamList writes a List response as CSV or NDJSON straight from the
+// database when the service can stream; it reports whether it handled the
+// request. Streams are only limited when the client passes limit.
+func (s *DualServer) streamList(w http.ResponseWriter, r *http.Request, tableName string, service any, contentType string) bool {
+	streamer, ok := service.(core.Streamer)
+	if !ok || !s.contentNeg.IsStreamable(contentType) {
+		return false
+	}
+
+	var filter core.Filter
+	if limit, err := parseInt32(r.URL.Query().Get("limit")); err == nil {
+		filter.Limit = limit
+	}
+	if offset, err := parseInt32(r.URL.Query().Get("offset")); err == nil {
+		filter.Offset = offset
+	}
+
+	rows, err := s.contentNeg.NewRowWriter(contentType, w)
+	if err != nil {
+		s.handleServiceError(w, r, err, contentType)
+		return true
+	}
+	stream := &responseStream{RowWriter: rows, w: w, contentType: contentType}
+	if err := streamer.Stream(r.Context(), filter, stream); err != nil {
+		if !stream.started {
+			s.handleServiceError(w, r, err, contentType)
+			return true
+		}
+		// The status is already sent; abort so the client sees a truncated response
+		core.LoggerFromContext(r.Context(), s.logger).Error("Stream failed", "table", tableName, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	return true
+}
+
+// responseStream sends the response headers when the first line is written,
+// so errors before the query runs can still become problem documents
+type responseStream struct {
+	core.RowWriter
+	w           http.ResponseWriter
+	contentType string
+	started     bool
+}
+
+func (rs *responseStream) WriteHeader(columns []string) error {
+	rs.started = true
+	rs.w.Header().Set("Content-Type", rs.contentType)
+	rs.w.WriteHeader(http.StatusOK)
+	return rs.RowWriter.WriteHeader(columns)
+}
+
+// parseCells converts the text values of a CSV or XML row to the column
+// types; nested values and NULLs are kept
+func parseCells(row map[string]any, columnTypes map[string]string) {
+	for column, value := range row {
+		if cell, ok := value.(string); ok {
+			row[column] = core.ParseCell(cell, columnTypes[column])
+		}
+	}
+}
+
+// handleBulkImport creates a row for each record of a CSV, NDJSON or
+// MessagePack body, reading the body as it goes. It stops at the first failing
+// record; rows created before it are kept and counted in the error.
+func (s *DualServer) handleBulkImport(w http.ResponseWriter, r *http.Request, tableName string, service ServiceInterface, bodyType, contentType string) {
+	reader, err := s.contentNeg.NewRowReader(bodyType, r.Body)
+	if err != nil {
+		s.handleServiceError(w, r, core.BadRequest("%v", err), contentType)
+		return
+	}
+
+	// CSV cells are text; parse them with the column types where known
+	var columnTypes map[string]string
+	if describer, ok := service.(core.ResourceDescriber); ok {
+		columnTypes = describer.Resource().ColumnTypes
+	}
+
+	created := 0
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.handleServiceError(w, r, core.BadRequest("record %d: %v (%d created)", created+1, err, created).Wrap(err), contentType)
+			return
+		}
+		if bodyType == core.ContentTypeCSV {
+			parseCells(row, columnTypes)
+		}
+
+		if _, err := service.Create(r.Context(), row); err != nil {
+			apiErr := *core.ToAPIError(err)
+			apiErr.Message = fmt.Sprintf("record %d: %s (%d created)", created+1, apiErr.Message, created)
+			s.handleServiceError(w, r, &apiErr, contentType)
+			return
+		}
+		created++
+	}
+
+	s.serializeResponse(w, map[string]any{
+		"table":   tableName,
+		"created": created,
+	}, contentType)
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bata94/apiright/pkg/cache"
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// cacheAware is implemented by generated adapters, which invalidate cached responses on writes
+type cacheAware interface {
+	SetCache(invalidator core.CacheInvalidator)
+}
+
+// SetResponseCache enables caching of Get and List responses for tables with a
+// TTL; registered adapters invalidate their table's entries after writes
+func (s *DualServer) SetResponseCache(responseCache *cache.ResponseCache) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responseCache = responseCache
+	for _, service := range s.services {
+		s.attachCache(service)
+	}
+}
+
+// attachCache hands the response cache to a cache-aware service (caller must hold lock)
+func (s *DualServer) attachCache(service any) {
+	if s.responseCache == nil {
+		return
+	}
+	if aware, ok := service.(cacheAware); ok {
+		aware.SetCache(s.responseCache)
+	}
+}
+
+// serveCached writes the cached response for r if there is one. Otherwise it
+// returns the key to cache the response under, or "" if the table is not cached.
+// Keys cover the route, API version, query, negotiated content type and the
+// caller's identity.
+func (s *DualServer) serveCached(w http.ResponseWriter, r *http.Request, tableName, contentType string) (string, bool) {
+	if s.responseCache == nil {
+		return "", false
+	}
+	ttl, ok := s.responseCache.TTL(tableName)
+	if !ok {
+		return "", false
+	}
+
+	key := s.responseCache.Key(tableName, cacheScope(r), s.routeOf(r).version, r.URL.Path, r.URL.Query().Encode(), contentType)
+	if strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+		return key, false
+	}
+	cached, ok := s.responseCache.Get(r.Context(), key)
+	if !ok {
+		return key, false
+	}
+
+	// Age tells downstream caches how much of max-age is already used up
+	setCacheHeaders(w, r, ttl)
+	w.Header().Set("Age", strconv.Itoa(int(max(time.Since(cached.StoredAt), 0)/time.Second)))
+	w.Header().Set("X-Cache", "HIT")
+	w.Header().Set("Content-Type", cached.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(cached.Body); err != nil {
+		s.logger.Warn("failed to write cached response", "error", err)
+	}
+	return key, true
+}
+
+// serializeCachedResponse serializes and writes response like serializeResponse,
+// caching it under key unless key is empty
+func (s *DualServer) serializeCachedResponse(w http.ResponseWriter, r *http.Request, tableName, key string, response any, contentType string) {
+	if key == "" {
+		s.serializeResponse(w, response, contentType)
+		return
+	}
+
+	data, err := s.contentNeg.SerializeResponse(response, contentType)
+	if err != nil {
+		http.Error(w, "Serialization failed", http.StatusInternalServerError)
+		return
+	}
+	s.responseCache.Set(r.Context(), tableName, key, core.CachedResponse{
+		ContentType: contentType,
+		Body:        data,
+		StoredAt:    time.Now(),
+	})
+
+	ttl, _ := s.responseCache.TTL(tableName)
+	setCacheHeaders(w, r, ttl)
+	w.Header().Set("X-Cache", "MISS")
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		s.logger.Warn("failed to write response", "error", err)
+	}
+}
+
+// setCacheHeaders sets Cache-Control for ttl; responses for an
+// authenticated caller may only be kept by the client's own cache
+func setCacheHeaders(w http.ResponseWriter, r *http.Request, ttl time.Duration) {
+	visibility := "public"
+	if cacheScope(r) != "" {
+		visibility = "private"
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(ttl/time.Second)))
+	w.Header().Add("Vary", "Accept")
+}
+
+// cacheScope returns the authenticated identity responses are cached for, or
+// "" for anonymous callers. The auth method and roles are part of the scope,
+// since policies may filter rows by role and subjects are only unique per method.
+func cacheScope(r *http.Request) string {
+	principal, ok := core.PrincipalFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	roles := append([]string(nil), principal.Roles...)
+	sort.Strings(roles)
+	return principal.Method + "\x00" + principal.Subject + "\x00" + strings.Join(roles, ",")
+}
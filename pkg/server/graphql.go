@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/graphql"
+	"github.com/bata94/apiright/pkg/policy"
+)
+
+// graphqlHandler serves the generated GraphQL schema, or explains how to generate it
+func (s *DualServer) graphqlHandler() http.Handler {
+	schemaPath := filepath.Join(s.projectDir, "gen", "graphql", "schema.graphql")
+	schema, err := graphql.LoadSchema(schemaPath, s.logger)
+	if err != nil {
+		if _, statErr := os.Stat(schemaPath); !os.IsNotExist(statErr) {
+			s.logger.Error("Failed to load GraphQL schema", "path", schemaPath, "error", err)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "GraphQL schema not found. Run 'apiright gen' first.", http.StatusNotFound)
+		})
+	}
+
+	handler := schema.Handler(&graphqlSource{server: s})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		core.SetRoute(r.Context(), "graphql", "query")
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// graphqlSource resolves GraphQL fields with the registered table services,
+// enforcing the same policies as the REST routes
+type graphqlSource struct {
+	server *DualServer
+}
+
+// service authorizes op on table and returns its service with the authorized context
+func (g *graphqlSource) service(ctx context.Context, table string, op policy.Operation) (ServiceInterface, context.Context, error) {
+	g.server.mu.RLock()
+	service, exists := g.server.services[table]
+	policies := g.server.policies
+	g.server.mu.RUnlock()
+
+	serviceInterface, ok := service.(ServiceInterface)
+	if !exists || !ok {
+		return nil, ctx, fmt.Errorf("no service registered for %s", table)
+	}
+	if policies != nil {
+		var err error
+		if ctx, err = policies.Authorize(ctx, table, op); err != nil {
+			return nil, ctx, err
+		}
+	}
+	return serviceInterface, ctx, nil
+}
+
+func (g *graphqlSource) Get(ctx context.Context, table string, id any) (map[string]any, error) {
+	service, ctx, err := g.service(ctx, table, policy.OpGet)
+	if err != nil {
+		return nil, err
+	}
+	result, err := service.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toRow(result)
+}
+
+// Find uses the service's Finder; services without one can only list unfiltered pages
+func (g *graphqlSource) Find(ctx context.Context, table string, filter core.Filter) ([]map[string]any, error) {
+	service, ctx, err := g.service(ctx, table, policy.OpList)
+	if err != nil {
+		return nil, err
+	}
+	if finder, ok := service.(core.Finder); ok {
+		return finder.Find(ctx, filter)
+	}
+	if len(filter.Equal) > 0 {
+		return nil, core.BadRequest("filtering %s is not supported", table)
+	}
+
+	result, err := service.List(ctx, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	if err := convertRows(result, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (g *graphqlSource) Create(ctx context.Context, table string, input map[string]any) (map[string]any, error) {
+	service, ctx, err := g.service(ctx, table, policy.OpCreate)
+	if err != nil {
+		return nil, err
+	}
+	result, err := service.Create(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return toRow(result)
+}
+
+func (g *graphqlSource) Update(ctx context.Context, table string, input map[string]any) (map[string]any, error) {
+	service, ctx, err := g.service(ctx, table, policy.OpUpdate)
+	if err != nil {
+		return nil, err
+	}
+	result, err := service.Update(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return toRow(result)
+}
+
+func (g *graphqlSource) Delete(ctx context.Context, table string, id any) error {
+	service, ctx, err := g.service(ctx, table, policy.OpDelete)
+	if err != nil {
+		return err
+	}
+	return service.Delete(ctx, id)
+}
+
+// toRow converts a service result, usually a sqlc struct, to a column map
+func toRow(result any) (map[string]any, error) {
+	if result == nil {
+		return nil, nil
+	}
+	var row map[string]any
+	if err := convertRows(result, &row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// convertRows converts service results to column maps through their JSON form
+func convertRows(result any, target any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("failed to decode result: %w", err)
+	}
+
+	switch rows := target.(type) {
+	case *map[string]any:
+		normalizeRow(*rows)
+	case *[]map[string]any:
+		for _, row := range *rows {
+			normalizeRow(row)
+		}
+	}
+	return nil
+}
+
+// normalizeRow turns JSON numbers into int64 or float64 and unwraps
+// sql.Null* values, which encode as {"String": "x", "Valid": true}
+func normalizeRow(row map[string]any) {
+	for column, value := range row {
+		if nullable, ok := value.(map[string]any); ok && len(nullable) == 2 {
+			if valid, ok := nullable["Valid"].(bool); ok {
+				value = nil
+				for key, inner := range nullable {
+					if key != "Valid" && valid {
+						value = inner
+					}
+				}
+			}
+		}
+		if number, ok := value.(json.Number); ok {
+			if n, err := number.Int64(); err == nil {
+				value = n
+			} else if f, err := number.Float64(); err == nil {
+				value = f
+			}
+		}
+		row[column] = value
+	}
+}
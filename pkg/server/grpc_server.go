@@ -5,20 +5,39 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bata94/apiright/pkg/core"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // Lets clients send and receive gzip-compressed messages
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
 func (s *DualServer) initGRPCServer() error {
-	interceptors := s.middlewareRegistry.GetGRPCInterceptors()
+	var interceptors []grpc.UnaryServerInterceptor
+	if s.requestID != nil {
+		interceptors = append(interceptors, s.requestID.GRPCInterceptor())
+	}
+	interceptors = append(interceptors, s.middlewareRegistry.GetGRPCInterceptors()...)
+	if s.policies != nil {
+		interceptors = append(interceptors, s.policies.GRPCInterceptor())
+	}
 	interceptors = append(interceptors, s.unaryInterceptor)
 
-	s.grpcServer = grpc.NewServer(
+	opts := []grpc.ServerOption{
 		grpc.UnaryInterceptor(s.chainGRPCInterceptors(interceptors)),
-	)
+	}
+	// In single-port mode the HTTP server terminates TLS
+	if s.tlsConfig != nil && !s.config.SinglePort {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig.Clone())))
+	}
+	s.grpcServer = grpc.NewServer(opts...)
 
 	reflection.Register(s.grpcServer)
 
+	s.grpcHealth = newGRPCHealthServer()
+	healthpb.RegisterHealthServer(s.grpcServer, s.grpcHealth)
+
 	for _, service := range s.services {
 		if err := s.registerGRPCService(service); err != nil {
 			return fmt.Errorf("failed to register gRPC service %T: %w", service, err)
@@ -49,16 +68,22 @@ func (s *DualServer) chainGRPCInterceptors(interceptors []grpc.UnaryServerInterc
 
 func (s *DualServer) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	start := time.Now()
+	logger := core.LoggerFromContext(ctx, s.logger)
 
-	s.logger.Info("gRPC call",
+	logger.Info("gRPC call",
 		"method", info.FullMethod,
 		"request", fmt.Sprintf("%+v", req),
 	)
 
 	resp, err := handler(ctx, req)
 
+	// Typed errors become statuses with details; unwrapping keeps causes out of the message
+	if apiErr, ok := core.AsAPIError(err); ok {
+		err = apiErr
+	}
+
 	duration := time.Since(start)
-	s.logger.Info("gRPC call completed",
+	logger.Info("gRPC call completed",
 		"method", info.FullMethod,
 		"duration", duration,
 		"error", err,
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// grpcWebTrailers are the trailers the gRPC handler transport predeclares
+var grpcWebTrailers = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+
+// multiplexHandler serves native gRPC (HTTP/2, including h2c), gRPC-Web and
+// plain HTTP requests from one listener
+func (s *DualServer) multiplexHandler(httpHandler http.Handler) http.Handler {
+	grpcWeb := http.Handler(&grpcWebHandler{server: s.grpcServer})
+
+	// Browsers need CORS for gRPC-Web; reuse the configured CORS middleware
+	if cors, ok := s.middlewareRegistry.GetMiddlewareByName("cors"); ok {
+		grpcWeb = cors.Handler()(grpcWeb)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case isGRPCWebRequest(r):
+			grpcWeb.ServeHTTP(w, r)
+		case isGRPCRequest(r):
+			s.grpcServer.ServeHTTP(w, r)
+		default:
+			httpHandler.ServeHTTP(w, r)
+		}
+	})
+}
+
+// isGRPCRequest reports whether r is a native gRPC call
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// isGRPCWebRequest reports whether r is a gRPC-Web call or its CORS preflight
+func isGRPCWebRequest(r *http.Request) bool {
+	if r.Method == http.MethodOptions {
+		return strings.Contains(strings.ToLower(r.Header.Get("Access-Control-Request-Headers")), "x-grpc-web")
+	}
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc-web")
+}
+
+// grpcWebHandler translates gRPC-Web requests into gRPC calls on server. The
+// message framing is shared with gRPC; trailers travel in a final body frame.
+type grpcWebHandler struct {
+	server *grpc.Server
+}
+
+// ServeHTTP serves a gRPC-Web call
+func (h *grpcWebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, "application/grpc-web-text")
+
+	// application/grpc-web[-text][+codec] becomes application/grpc[+codec]
+	codec := ""
+	if i := strings.Index(contentType, "+"); i >= 0 {
+		codec = contentType[i:]
+	}
+
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2"
+	req.Header.Set("Content-Type", "application/grpc"+codec)
+	req.Header.Del("Content-Length")
+	if text {
+		req.Body = io.NopCloser(base64.NewDecoder(base64.StdEncoding, r.Body))
+	}
+
+	responseType := "application/grpc-web"
+	if text {
+		responseType = "application/grpc-web-text"
+	}
+	gw := &grpcWebWriter{
+		ResponseWriter: w,
+		header:         make(http.Header),
+		contentType:    responseType + codec,
+		text:           text,
+	}
+	h.server.ServeHTTP(gw, req)
+	gw.finish()
+}
+
+// grpcWebWriter collects the headers written by the gRPC handler transport
+// and re-encodes its trailers as a gRPC-Web trailer frame
+type grpcWebWriter struct {
+	http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+	wroteHeader bool
+}
+
+// Header returns the header map the gRPC transport writes to
+func (gw *grpcWebWriter) Header() http.Header {
+	return gw.header
+}
+
+// WriteHeader copies the response headers, leaving out trailers
+func (gw *grpcWebWriter) WriteHeader(code int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+
+	out := gw.ResponseWriter.Header()
+	for key, values := range gw.header {
+		if key == "Trailer" || strings.HasPrefix(key, http.TrailerPrefix) || isGRPCWebTrailer(key) {
+			continue
+		}
+		out[key] = values
+	}
+	out.Set("Content-Type", gw.contentType)
+	out.Del("Content-Length")
+	gw.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes message frames, base64 encoded for grpc-web-text
+func (gw *grpcWebWriter) Write(b []byte) (int, error) {
+	gw.WriteHeader(http.StatusOK)
+	if gw.text {
+		if _, err := gw.ResponseWriter.Write([]byte(base64.StdEncoding.EncodeToString(b))); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return gw.ResponseWriter.Write(b)
+}
+
+// Flush sends the headers and any buffered data
+func (gw *grpcWebWriter) Flush() {
+	gw.WriteHeader(http.StatusOK)
+	if f, ok := gw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailer frame: flag 0x80, 4-byte length, then HTTP/1 style header lines
+func (gw *grpcWebWriter) finish() {
+	gw.WriteHeader(http.StatusOK)
+
+	var lines []string
+	for key, values := range gw.header {
+		name, ok := strings.CutPrefix(key, http.TrailerPrefix)
+		if !ok && !isGRPCWebTrailer(key) {
+			continue
+		}
+		for _, value := range values {
+			lines = append(lines, strings.ToLower(name)+": "+value+"\r\n")
+		}
+	}
+	sort.Strings(lines)
+	payload := strings.Join(lines, "")
+
+	frame := make([]byte, 5+len(payload))
+	frame[0] = 0x80
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+	_, _ = gw.Write(frame)
+	gw.Flush()
+}
+
+// isGRPCWebTrailer reports whether key is a predeclared gRPC trailer
+func isGRPCWebTrailer(key string) bool {
+	for _, trailer := range grpcWebTrailers {
+		if http.CanonicalHeaderKey(key) == trailer {
+			return true
+		}
+	}
+	return false
+}
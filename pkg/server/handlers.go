@@ -4,22 +4,40 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/policy"
 )
 
 func (s *DualServer) handleListRoute(w http.ResponseWriter, r *http.Request, tableName string) {
 	contentType := s.detectContentType(r)
 
+	r, allowed := s.authorizeRequest(w, r, tableName, policy.OpList)
+	if !allowed {
+		return
+	}
+
 	// Get service directly from services map (adapters are stored here)
-	service, exists := s.services[tableName]
+	service, exists := s.routeService(r, tableName)
 
 	var response any
 	var err error
+	var cacheKey string
 
 	if exists {
 		if serviceInterface, ok := service.(ServiceInterface); ok {
+			if s.streamList(w, r, tableName, service, contentType) {
+				return
+			}
+			var served bool
+			if cacheKey, served = s.serveCached(w, r, tableName, contentType); served {
+				return
+			}
+
 			limit := int32(50)
 			offset := int32(0)
 
@@ -35,8 +53,14 @@ func (s *DualServer) handleListRoute(w http.ResponseWriter, r *http.Request, tab
 			}
 
 			response, err = serviceInterface.List(r.Context(), limit, offset)
+			if err == nil {
+				response, err = s.hypermedia(r, tableName, service, response, contentType, &core.Page{Limit: limit, Offset: offset})
+			}
+			if err == nil {
+				response, err = s.protoResponse(service, "list", response, contentType)
+			}
 			if err != nil {
-				s.handleServiceError(w, err, contentType)
+				s.handleServiceError(w, r, err, contentType)
 				return
 			}
 		} else {
@@ -48,29 +72,46 @@ func (s *DualServer) handleListRoute(w http.ResponseWriter, r *http.Request, tab
 		s.logger.Debug("No service found, using mock response", "table", tableName)
 	}
 
-	s.serializeResponse(w, response, contentType)
+	s.serializeCachedResponse(w, r, tableName, cacheKey, response, contentType)
 }
 
 func (s *DualServer) handleGetRoute(w http.ResponseWriter, r *http.Request, tableName string) {
 	contentType := s.detectContentType(r)
 
-	id := s.extractIDFromPath(r.URL.Path, 2)
+	r, allowed := s.authorizeRequest(w, r, tableName, policy.OpGet)
+	if !allowed {
+		return
+	}
+
+	id := s.extractIDFromPath(r.URL.Path, s.idPathIndex(r))
 	if id == "" {
-		s.handleServiceError(w, fmt.Errorf("missing ID in path"), contentType)
+		s.handleServiceError(w, r, core.BadRequest("missing ID in path"), contentType)
 		return
 	}
 
 	// Get service directly from services map (adapters are stored here)
-	service, exists := s.services[tableName]
+	service, exists := s.routeService(r, tableName)
 
 	var response any
 	var err error
+	var cacheKey string
 
 	if exists {
 		if serviceInterface, ok := service.(ServiceInterface); ok {
+			var served bool
+			if cacheKey, served = s.serveCached(w, r, tableName, contentType); served {
+				return
+			}
+
 			response, err = serviceInterface.Get(r.Context(), id)
+			if err == nil {
+				response, err = s.hypermedia(r, tableName, service, response, contentType, nil)
+			}
+			if err == nil {
+				response, err = s.protoResponse(service, "get", response, contentType)
+			}
 			if err != nil {
-				s.handleServiceError(w, err, contentType)
+				s.handleServiceError(w, r, err, contentType)
 				return
 			}
 		} else {
@@ -81,26 +122,42 @@ func (s *DualServer) handleGetRoute(w http.ResponseWriter, r *http.Request, tabl
 		s.logger.Debug("No service found, using mock response", "table", tableName, "id", id)
 	}
 
-	s.serializeResponse(w, response, contentType)
+	s.serializeCachedResponse(w, r, tableName, cacheKey, response, contentType)
 }
 
 func (s *DualServer) handleCreateRoute(w http.ResponseWriter, r *http.Request, tableName string) {
 	contentType := s.detectContentType(r)
 
+	r, allowed := s.authorizeRequest(w, r, tableName, policy.OpCreate)
+	if !allowed {
+		return
+	}
+
 	// Get service directly from services map (adapters are stored here)
-	service, exists := s.services[tableName]
+	service, exists := s.routeService(r, tableName)
 
 	var response any
 	var err error
 
 	if exists {
 		if serviceInterface, ok := service.(ServiceInterface); ok {
-			response, err = serviceInterface.Create(r.Context(), map[string]any{
-				"request_body": "would_be_deserialized",
-				"headers":      r.Header,
-			})
+			if bodyType := core.ParseContentHeader(r.Header.Get("Content-Type")).ContentType; s.contentNeg.IsBulkImport(bodyType) {
+				s.handleBulkImport(w, r, tableName, serviceInterface, bodyType, contentType)
+				return
+			}
+			var params map[string]any
+			params, err = s.decodeBody(r, service, "create")
+			if err == nil {
+				response, err = serviceInterface.Create(r.Context(), params)
+			}
+			if err == nil {
+				response, err = s.hypermedia(r, tableName, service, response, contentType, nil)
+			}
+			if err == nil {
+				response, err = s.protoResponse(service, "create", response, contentType)
+			}
 			if err != nil {
-				s.handleServiceError(w, err, contentType)
+				s.handleServiceError(w, r, err, contentType)
 				return
 			}
 		} else {
@@ -117,28 +174,39 @@ func (s *DualServer) handleCreateRoute(w http.ResponseWriter, r *http.Request, t
 func (s *DualServer) handleUpdateRoute(w http.ResponseWriter, r *http.Request, tableName string) {
 	contentType := s.detectContentType(r)
 
-	id := s.extractIDFromPath(r.URL.Path, 2)
+	r, allowed := s.authorizeRequest(w, r, tableName, policy.OpUpdate)
+	if !allowed {
+		return
+	}
+
+	id := s.extractIDFromPath(r.URL.Path, s.idPathIndex(r))
 	if id == "" {
-		s.handleServiceError(w, fmt.Errorf("missing ID in path"), contentType)
+		s.handleServiceError(w, r, core.BadRequest("missing ID in path"), contentType)
 		return
 	}
 
 	// Get service directly from services map (adapters are stored here)
-	service, exists := s.services[tableName]
+	service, exists := s.routeService(r, tableName)
 
 	var response any
 	var err error
 
 	if exists {
 		if serviceInterface, ok := service.(ServiceInterface); ok {
-			params := map[string]any{
-				"id":           id,
-				"request_body": "would_be_deserialized",
-				"headers":      r.Header,
+			var params map[string]any
+			params, err = s.decodeBody(r, service, "update")
+			if err == nil {
+				params[primaryKeyOf(service)] = typedID(id)
+				response, err = serviceInterface.Update(r.Context(), params)
+			}
+			if err == nil {
+				response, err = s.hypermedia(r, tableName, service, response, contentType, nil)
+			}
+			if err == nil {
+				response, err = s.protoResponse(service, "update", response, contentType)
 			}
-			response, err = serviceInterface.Update(r.Context(), params)
 			if err != nil {
-				s.handleServiceError(w, err, contentType)
+				s.handleServiceError(w, r, err, contentType)
 				return
 			}
 		} else {
@@ -155,20 +223,25 @@ func (s *DualServer) handleUpdateRoute(w http.ResponseWriter, r *http.Request, t
 func (s *DualServer) handleDeleteRoute(w http.ResponseWriter, r *http.Request, tableName string) {
 	contentType := s.detectContentType(r)
 
-	id := s.extractIDFromPath(r.URL.Path, 2)
+	r, allowed := s.authorizeRequest(w, r, tableName, policy.OpDelete)
+	if !allowed {
+		return
+	}
+
+	id := s.extractIDFromPath(r.URL.Path, s.idPathIndex(r))
 	if id == "" {
-		s.handleServiceError(w, fmt.Errorf("missing ID in path"), contentType)
+		s.handleServiceError(w, r, core.BadRequest("missing ID in path"), contentType)
 		return
 	}
 
 	// Get service directly from services map (adapters are stored here)
-	service, exists := s.services[tableName]
+	service, exists := s.routeService(r, tableName)
 
 	if exists {
 		if serviceInterface, ok := service.(ServiceInterface); ok {
 			err := serviceInterface.Delete(r.Context(), id)
 			if err != nil {
-				s.handleServiceError(w, err, contentType)
+				s.handleServiceError(w, r, err, contentType)
 				return
 			}
 		}
@@ -176,37 +249,35 @@ func (s *DualServer) handleDeleteRoute(w http.ResponseWriter, r *http.Request, t
 		s.logger.Debug("No service found, using mock response", "table", tableName, "id", id)
 	}
 
-	response := map[string]any{
+	var response any = map[string]any{
 		"message":   fmt.Sprintf("Delete %s successful", tableName),
 		"operation": "delete",
 		"id":        id,
 		"success":   true,
 	}
+	if s.usesProto(contentType) {
+		var err error
+		if response, err = s.protoResponse(service, "delete", true, contentType); err != nil {
+			s.handleServiceError(w, r, err, contentType)
+			return
+		}
+	}
 
 	s.serializeResponse(w, response, contentType)
 }
 
-func (s *DualServer) handleServiceError(w http.ResponseWriter, err error, contentType string) {
-	s.logger.Error("Service error", "error", err)
-
-	statusCode := http.StatusInternalServerError
-	errorMsg := "Internal server error"
-
-	if strings.Contains(err.Error(), "not found") {
-		statusCode = http.StatusNotFound
-		errorMsg = "Resource not found"
-	} else if strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "bad request") {
-		statusCode = http.StatusBadRequest
-		errorMsg = "Invalid request"
-	}
-
-	response := map[string]any{
-		"error":   errorMsg,
-		"message": err.Error(),
-		"status":  statusCode,
+// handleServiceError writes err as a problem document. Typed errors keep their
+// status and message; anything else becomes a 500 without internal details.
+func (s *DualServer) handleServiceError(w http.ResponseWriter, r *http.Request, err error, contentType string) {
+	apiErr := core.ToAPIError(err)
+	logger := core.LoggerFromContext(r.Context(), s.logger)
+	if apiErr.Code.HTTPStatus() >= http.StatusInternalServerError {
+		logger.Error("Service error", "error", err)
+	} else {
+		logger.Debug("Request failed", "status", apiErr.Code.HTTPStatus(), "error", err)
 	}
 
-	s.serializeResponseWithStatus(w, response, contentType, statusCode)
+	core.WriteError(w, r, s.contentNeg, apiErr)
 }
 
 func (s *DualServer) createMockResponse(operation, tableName, contentType string) any {
@@ -240,6 +311,35 @@ func (s *DualServer) extractIDFromPath(path string, index int) string {
 	return pathParts[index]
 }
 
+// idPathIndex returns the position of the ID among the path segments of
+// BasePath/version/table/id, or BasePath/table/id for header-selected versions
+func (s *DualServer) idPathIndex(r *http.Request) int {
+	prefix := strings.Trim(s.routeOf(r).prefix, "/")
+	if prefix == "" {
+		return 1
+	}
+	return len(strings.Split(prefix, "/")) + 1
+}
+
+// typedID returns a numeric path ID as int64 so it binds to integer key columns
+func typedID(id string) any {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return n
+	}
+	return id
+}
+
+// primaryKeyOf returns the primary key column of a service's table; services
+// that don't describe themselves are keyed by id
+func primaryKeyOf(service any) string {
+	if describer, ok := service.(core.ResourceDescriber); ok {
+		if pk := describer.Resource().PrimaryKey; pk != "" {
+			return pk
+		}
+	}
+	return "id"
+}
+
 func parseInt32(s string) (int32, error) {
 	val, err := strconv.ParseInt(s, 10, 32)
 	if err != nil {
@@ -310,6 +410,31 @@ func (s *DualServer) docsOpenAPIHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// docsXSDHandler serves the XML schemas of gen/xml referenced by the OpenAPI spec
+func (s *DualServer) docsXSDHandler(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	if !strings.HasSuffix(name, ".xsd") {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.projectDir, "gen", "xml", name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "XML schema not found. Run 'apiright gen' first.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to read XML schema", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", core.ContentTypeXML)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		s.logger.Warn("failed to write docs response", "error", err)
+	}
+}
+
 func (s *DualServer) docsCSSHandler(w http.ResponseWriter, r *http.Request) {
 	data, err := docsFS.ReadFile("static/docs/swagger-ui.css")
 	if err != nil {
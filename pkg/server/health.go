@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+	"github.com/bata94/apiright/pkg/plugins"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// healthCheckTimeout bounds each individual health check
+	healthCheckTimeout = 2 * time.Second
+
+	// grpcHealthInterval is how often the gRPC health service is refreshed
+	grpcHealthInterval = 10 * time.Second
+)
+
+// HealthReport is the combined result of all registered health checks
+type HealthReport struct {
+	Status   core.HealthStatus
+	Checks   map[string]core.HealthCheckResult
+	Services map[string]core.HealthStatus // Status of each service with checks of its own
+	shared   core.HealthStatus            // Status of the checks covering every service
+}
+
+// ServiceStatus returns the status of a gRPC service: the worst of the checks
+// covering it and those covering every service
+func (r HealthReport) ServiceStatus(service string) core.HealthStatus {
+	if status, ok := r.Services[service]; ok {
+		return status
+	}
+	return r.shared
+}
+
+// registeredCheck is a health check and the services it covers; none means all
+type registeredCheck struct {
+	checker  core.HealthChecker
+	services []string
+}
+
+// HealthRegistry runs the registered health checks for readiness probes
+type HealthRegistry struct {
+	mu      sync.RWMutex
+	checks  []registeredCheck
+	timeout time.Duration
+}
+
+// NewHealthRegistry creates a health registry that gives each check timeout to finish
+func NewHealthRegistry(timeout time.Duration) *HealthRegistry {
+	return &HealthRegistry{timeout: timeout}
+}
+
+// Register adds a health check covering the given gRPC services, or every
+// service if none are given, replacing any check with the same name
+func (hr *HealthRegistry) Register(checker core.HealthChecker, services ...string) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+
+	check := registeredCheck{checker: checker, services: services}
+	for i, existing := range hr.checks {
+		if existing.checker.Name() == checker.Name() {
+			hr.checks[i] = check
+			return
+		}
+	}
+	hr.checks = append(hr.checks, check)
+}
+
+// Check runs all checks concurrently. The report is down if any check is
+// down, degraded if any is degraded, and ok otherwise; each service is rated
+// the same way from the checks covering it.
+func (hr *HealthRegistry) Check(ctx context.Context) HealthReport {
+	hr.mu.RLock()
+	checks := append([]registeredCheck(nil), hr.checks...)
+	hr.mu.RUnlock()
+
+	results := make([]core.HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = hr.run(ctx, check.checker)
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{
+		Status:   core.HealthOK,
+		Checks:   make(map[string]core.HealthCheckResult, len(checks)),
+		Services: make(map[string]core.HealthStatus),
+		shared:   core.HealthOK,
+	}
+	for i, check := range checks {
+		report.Checks[check.checker.Name()] = results[i]
+		report.Status = worseHealth(report.Status, results[i].Status)
+		if len(check.services) == 0 {
+			report.shared = worseHealth(report.shared, results[i].Status)
+		}
+	}
+	for i, check := range checks {
+		for _, service := range check.services {
+			report.Services[service] = worseHealth(report.ServiceStatus(service), results[i].Status)
+		}
+	}
+	return report
+}
+
+// worseHealth returns the worse of two statuses: down, then degraded, then ok
+func worseHealth(a, b core.HealthStatus) core.HealthStatus {
+	switch {
+	case a == core.HealthDown || b == core.HealthDown:
+		return core.HealthDown
+	case a == core.HealthDegraded || b == core.HealthDegraded:
+		return core.HealthDegraded
+	}
+	return core.HealthOK
+}
+
+// run executes one check with the registry timeout, treating a timeout or panic as down
+func (hr *HealthRegistry) run(ctx context.Context, checker core.HealthChecker) (result core.HealthCheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, hr.timeout)
+	defer cancel()
+
+	done := make(chan core.HealthCheckResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- core.HealthCheckResult{Status: core.HealthDown, Message: fmt.Sprintf("check panicked: %v", r)}
+			}
+		}()
+		done <- checker.Check(ctx)
+	}()
+
+	select {
+	case result = <-done:
+		return result
+	case <-ctx.Done():
+		return core.HealthCheckResult{Status: core.HealthDown, Message: "check timed out"}
+	}
+}
+
+// DatabaseHealthCheck pings the database
+func DatabaseHealthCheck(db *database.Database) core.HealthChecker {
+	return core.NewHealthCheck("database", func(ctx context.Context) core.HealthCheckResult {
+		sqlDB := db.GetDB()
+		if sqlDB == nil {
+			return core.HealthCheckResult{Status: core.HealthDown, Message: "database not connected"}
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return core.HealthCheckResult{Status: core.HealthDown, Message: err.Error()}
+		}
+		return core.HealthCheckResult{Status: core.HealthOK}
+	})
+}
+
+// MigrationsHealthCheck reports down while migrations are pending
+func MigrationsHealthCheck(db *database.Database) core.HealthChecker {
+	return core.NewHealthCheck("migrations", func(ctx context.Context) core.HealthCheckResult {
+		if db.GetDB() == nil {
+			return core.HealthCheckResult{Status: core.HealthDown, Message: "database not connected"}
+		}
+		status, err := db.GetMigrationStatus()
+		if err != nil {
+			return core.HealthCheckResult{Status: core.HealthDown, Message: err.Error()}
+		}
+		if len(status.Pending) > 0 {
+			return core.HealthCheckResult{Status: core.HealthDown, Message: fmt.Sprintf("%d pending migrations", len(status.Pending))}
+		}
+		return core.HealthCheckResult{Status: core.HealthOK}
+	})
+}
+
+// RegisterHealthCheck adds a check to the readiness probe and gRPC health
+// service, rating the given gRPC services or, if none are given, all of them
+func (s *DualServer) RegisterHealthCheck(checker core.HealthChecker, services ...string) {
+	s.health.Register(checker, services...)
+}
+
+// initHealthChecks registers the built-in checks and those provided by plugins
+func (s *DualServer) initHealthChecks() {
+	if s.db != nil {
+		s.health.Register(DatabaseHealthCheck(s.db))
+		s.health.Register(MigrationsHealthCheck(s.db))
+	}
+	s.health.Register(core.NewHealthCheck("services", s.checkServices))
+
+	for _, provider := range plugins.GetHealthCheckProviders() {
+		for _, checker := range provider.HealthChecks() {
+			s.health.Register(checker)
+		}
+	}
+}
+
+// checkServices reports degraded while any table is served by a mock
+func (s *DualServer) checkServices(ctx context.Context) core.HealthCheckResult {
+	var mocked []string
+	s.mu.RLock()
+	for tableName, service := range s.services {
+		if _, ok := service.(*mockService); ok {
+			mocked = append(mocked, tableName)
+		}
+	}
+	s.mu.RUnlock()
+	if len(mocked) > 0 {
+		sort.Strings(mocked)
+		return core.HealthCheckResult{Status: core.HealthDegraded, Message: fmt.Sprintf("serving mock data for %v", mocked)}
+	}
+	return core.HealthCheckResult{Status: core.HealthOK}
+}
+
+// liveHandler reports that the process is running, without checking dependencies
+func (s *DualServer) liveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.writeHealth(w, r, http.StatusOK, map[string]any{
+		"status":    string(core.HealthOK),
+		"timestamp": time.Now().Format(time.RFC3339),
+		"version":   core.Version,
+	})
+}
+
+// readyHandler runs all health checks and answers 503 if any is down or the server is shutting down
+func (s *DualServer) readyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Report not-ready as soon as shutdown begins, so load balancers stop routing here
+	if s.shuttingDown.Load() {
+		s.writeHealth(w, r, http.StatusServiceUnavailable, map[string]any{
+			"status":    string(core.HealthDown),
+			"timestamp": time.Now().Format(time.RFC3339),
+			"version":   core.Version,
+			"message":   "shutting down",
+		})
+		return
+	}
+
+	report := s.health.Check(r.Context())
+
+	checks := make(map[string]any, len(report.Checks))
+	for name, result := range report.Checks {
+		check := map[string]any{"status": string(result.Status)}
+		if result.Message != "" {
+			check["message"] = result.Message
+		}
+		checks[name] = check
+	}
+
+	statusCode := http.StatusOK
+	if report.Status == core.HealthDown {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	s.writeHealth(w, r, statusCode, map[string]any{
+		"status":    string(report.Status),
+		"timestamp": time.Now().Format(time.RFC3339),
+		"version":   core.Version,
+		"checks":    checks,
+	})
+}
+
+// writeHealth serializes a health response in the negotiated content type
+func (s *DualServer) writeHealth(w http.ResponseWriter, r *http.Request, statusCode int, response map[string]any) {
+	contentType := s.detectContentType(r)
+
+	data, err := s.contentNeg.SerializeResponse(response, contentType)
+	if err != nil {
+		http.Error(w, "Serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(data); err != nil {
+		s.logger.Warn("failed to write response", "error", err)
+	}
+}
+
+// updateGRPCHealth sets the gRPC health status of the server from all health
+// checks, and of every registered service from the checks covering it
+func (s *DualServer) updateGRPCHealth(ctx context.Context) {
+	report := s.health.Check(ctx)
+
+	s.grpcHealth.SetServingStatus("", grpcServingStatus(report.Status))
+	for service := range s.grpcServer.GetServiceInfo() {
+		if service != healthpb.Health_ServiceDesc.ServiceName {
+			s.grpcHealth.SetServingStatus(service, grpcServingStatus(report.ServiceStatus(service)))
+		}
+	}
+}
+
+// grpcServingStatus maps a health status onto the gRPC health protocol, where degraded still serves
+func grpcServingStatus(status core.HealthStatus) healthpb.HealthCheckResponse_ServingStatus {
+	if status == core.HealthDown {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+// runGRPCHealth refreshes the gRPC health service until ctx is done
+func (s *DualServer) runGRPCHealth(ctx context.Context) {
+	s.updateGRPCHealth(ctx)
+
+	ticker := time.NewTicker(grpcHealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.updateGRPCHealth(ctx)
+		}
+	}
+}
+
+// newGRPCHealthServer creates the grpc.health.v1.Health service, not serving until the first check
+func newGRPCHealthServer() *health.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return hs
+}
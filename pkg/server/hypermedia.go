@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// hypermedia wraps a service result in a JSON:API or HAL document when one of
+// them was negotiated, and maps it onto the table's XML schema for XML; page is
+// set for list results. Other formats pass through.
+func (s *DualServer) hypermedia(r *http.Request, tableName string, service any, response any, contentType string, page *core.Page) (any, error) {
+	if contentType != core.ContentTypeJSONAPI && contentType != core.ContentTypeHAL && contentType != core.ContentTypeXML {
+		return response, nil
+	}
+
+	// Services that don't describe themselves get a keyed resource without relationships
+	resource := core.Resource{Type: tableName, PrimaryKey: "id"}
+	if describer, ok := service.(core.ResourceDescriber); ok {
+		resource = describer.Resource()
+	}
+	// Without columns, XML falls back to the generic encoding
+	if contentType == core.ContentTypeXML && len(resource.Columns) == 0 {
+		return response, nil
+	}
+
+	var data any
+	if page != nil {
+		rows := []map[string]any{}
+		if err := convertRows(response, &rows); err != nil {
+			return nil, err
+		}
+		data = rows
+	} else {
+		row, err := toRow(response)
+		if err != nil {
+			return nil, err
+		}
+		if row != nil {
+			data = row
+		}
+	}
+
+	if contentType == core.ContentTypeXML {
+		return core.XMLDocument(resource, data), nil
+	}
+
+	hm := core.Hypermedia{
+		BasePath: s.config.BasePath + "/" + s.routeOf(r).version,
+		Self:     r.URL.RequestURI(),
+		Page:     page,
+	}
+	if contentType == core.ContentTypeJSONAPI {
+		return core.JSONAPIDocument(resource, data, hm), nil
+	}
+	return core.HALDocument(resource, data, hm), nil
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"github.com/bata94/apiright/pkg/middleware"
+)
+
+// initMetrics installs the Prometheus metrics middleware and, if a database
+// is connected, reports its pool statistics and migration version
+func (s *DualServer) initMetrics() {
+	s.metrics = middleware.NewMetricsMiddleware(s.logger)
+	s.middlewareRegistry.RegisterMiddleware(s.metrics)
+
+	if s.db == nil || s.db.GetDB() == nil {
+		s.logger.Warn("Database not connected, metrics will not include pool statistics")
+		return
+	}
+	if err := s.metrics.RegisterDB(s.db.GetDB(), s.db.Dialect()); err != nil {
+		s.logger.Warn("Failed to register database metrics", "error", err)
+	}
+	if err := s.metrics.RegisterMigrationVersion(s.db.CurrentVersion); err != nil {
+		s.logger.Warn("Failed to register migration version metric", "error", err)
+	}
+}
+
+// metricsPath returns the path of the Prometheus endpoint
+func (s *DualServer) metricsPath() string {
+	if s.config.Metrics.Path == "" {
+		return "/metrics"
+	}
+	return s.config.Metrics.Path
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/policy"
+)
+
+// SetPolicyEnforcer enables per-table authorization policies for HTTP and gRPC
+func (s *DualServer) SetPolicyEnforcer(enforcer *policy.Enforcer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies = enforcer
+}
+
+// authorizeRequest checks the table policy for op and returns the request with
+// the owner scope applied. It writes an error response and returns false if denied.
+// It also records the table and operation for telemetry, so denied requests are attributed.
+func (s *DualServer) authorizeRequest(w http.ResponseWriter, r *http.Request, tableName string, op policy.Operation) (*http.Request, bool) {
+	core.SetRoute(r.Context(), tableName, string(op))
+
+	if s.policies == nil {
+		return r, true
+	}
+
+	ctx, err := s.policies.Authorize(r.Context(), tableName, op)
+	if err == nil {
+		return r.WithContext(ctx), true
+	}
+
+	core.WriteError(w, r, s.contentNeg, err)
+	return r, false
+}
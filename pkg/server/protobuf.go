@@ -0,0 +1,65 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// usesProto reports whether contentType is rendered from the generated
+// protobuf messages: always for protobuf, for JSON in protojson mapping mode
+func (s *DualServer) usesProto(contentType string) bool {
+	return contentType == core.ContentTypeProtobuf ||
+		contentType == core.ContentTypeJSON && s.config.JSONMapping == "protojson"
+}
+
+// protoResponse wraps a service result in its protobuf response message when
+// the negotiated format uses them. Other formats, and services without
+// generated messages, pass through.
+func (s *DualServer) protoResponse(service any, operation string, response any, contentType string) (any, error) {
+	converter, ok := service.(core.ProtoConverter)
+	if !ok || !s.usesProto(contentType) {
+		return response, nil
+	}
+	return converter.ProtoResponse(operation, response)
+}
+
+// decodeBody decodes the request body into column values for operation.
+// Protobuf bodies, and JSON in protojson mapping mode, are read into the
+// operation's request message first; other formats are decoded directly, with
+// XML elements typed by the table's columns.
+func (s *DualServer) decodeBody(r *http.Request, service any, operation string) (map[string]any, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, core.ReadBodyFailed(err)
+	}
+	bodyType := core.ParseContentHeader(r.Header.Get("Content-Type")).ContentType
+	if bodyType == "" {
+		bodyType = core.ContentTypeJSON
+	}
+
+	if converter, ok := service.(core.ProtoConverter); ok && s.usesProto(bodyType) {
+		msg := converter.ProtoRequest(operation)
+		if msg == nil {
+			return nil, core.BadRequest("%s bodies are not supported for %s", bodyType, operation)
+		}
+		if err := s.contentNeg.DeserializeRequest(data, bodyType, msg); err != nil {
+			return nil, core.BadRequest("invalid %s body: %v", bodyType, err).Wrap(err)
+		}
+		return core.ProtoToRow(msg), nil
+	}
+
+	params := map[string]any{}
+	if len(data) == 0 {
+		return params, nil
+	}
+	if err := s.contentNeg.DeserializeRequest(data, bodyType, &params); err != nil {
+		return nil, core.BadRequest("invalid %s body: %v", bodyType, err).Wrap(err)
+	}
+	// XML elements are text; parse them with the column types where known
+	if describer, ok := service.(core.ResourceDescriber); ok && bodyType == core.ContentTypeXML {
+		parseCells(params, describer.Resource().ColumnTypes)
+	}
+	return params, nil
+}
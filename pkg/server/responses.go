@@ -2,35 +2,23 @@ package server
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/plugins"
 )
 
-func (s *DualServer) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	contentType := s.detectContentType(r)
-
-	response := map[string]any{
-		"status":    "ok",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"version":   core.Version,
-	}
-
-	data, err := s.contentNeg.SerializeResponse(response, contentType)
-	if err != nil {
-		http.Error(w, "Serialization failed", http.StatusInternalServerError)
-		return
-	}
+// RegisterSerializer adds a response format to content negotiation, replacing
+// any serializer for the same media types. Call it before Start.
+func (s *DualServer) RegisterSerializer(serializer core.Serializer) {
+	s.contentNeg.Register(serializer)
+}
 
-	w.Header().Set("Content-Type", contentType)
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write(data); err != nil {
-		s.logger.Warn("failed to write response", "error", err)
+// initSerializers registers the serializers provided by plugins
+func (s *DualServer) initSerializers() {
+	for _, provider := range plugins.GetSerializerProviders() {
+		for _, serializer := range provider.Serializers() {
+			s.contentNeg.Register(serializer)
+		}
 	}
 }
 
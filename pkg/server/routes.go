@@ -14,34 +14,9 @@ func (s *DualServer) setupHTTPRoutes(mux *http.ServeMux) {
 	)
 
 	for tableName, service := range s.services {
-		serviceType := fmt.Sprintf("%T", service)
-
-		s.logger.Info("Registering routes for service", "service_type", serviceType, "table", tableName)
-
-		basePath := s.config.BasePath + "/" + s.config.APIVersion + "/" + tableName
-
-		mux.HandleFunc(basePath, func(w http.ResponseWriter, r *http.Request) {
-			switch r.Method {
-			case http.MethodGet:
-				s.handleListRoute(w, r, tableName)
-			case http.MethodPost:
-				s.handleCreateRoute(w, r, tableName)
-			}
-		})
-
-		mux.HandleFunc(basePath+"/", func(w http.ResponseWriter, r *http.Request) {
-			switch r.Method {
-			case http.MethodGet:
-				s.handleGetRoute(w, r, tableName)
-			case http.MethodPut:
-				s.handleUpdateRoute(w, r, tableName)
-			case http.MethodDelete:
-				s.handleDeleteRoute(w, r, tableName)
-			}
-		})
-
-		s.logger.Info("HTTP routes registered", "table", tableName, "base_path", basePath)
+		s.logger.Info("Registering routes for service", "service_type", fmt.Sprintf("%T", service), "table", tableName)
 	}
+	s.mountVersionRoutes(mux)
 
 	if len(s.services) == 0 {
 		s.logger.Warn("No services registered, only default routes will be available")
@@ -78,7 +53,3 @@ func (s *DualServer) toTableName(serviceName string) string {
 	}
 	return string(result)
 }
-
-func (s *DualServer) getSortedMiddleware() []func(http.Handler) http.Handler {
-	return s.middlewareRegistry.GetHTTPMiddleware()
-}
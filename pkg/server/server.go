@@ -3,6 +3,8 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,13 +14,19 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/bata94/apiright/pkg/audit"
+	"github.com/bata94/apiright/pkg/cache"
+	"github.com/bata94/apiright/pkg/certs"
 	"github.com/bata94/apiright/pkg/config"
 	"github.com/bata94/apiright/pkg/core"
 	"github.com/bata94/apiright/pkg/database"
 	"github.com/bata94/apiright/pkg/middleware"
+	"github.com/bata94/apiright/pkg/policy"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
 )
 
 // DualServer implements both HTTP and gRPC servers
@@ -29,17 +37,31 @@ type DualServer struct {
 	contentNeg         *core.ContentNegotiatorImpl
 	httpServer         *http.Server
 	grpcServer         *grpc.Server
+	tlsConfig          *tls.Config // Shared by both servers when TLS is enabled
 	logger             core.Logger
 	mu                 sync.RWMutex
 	started            bool
-	services           map[string]any // key is table name
+	shuttingDown       atomic.Bool
+	stopped            chan struct{}      // Closed once a shutdown completes
+	stopBackground     context.CancelFunc // Stops the audit retention and gRPC health loops
+	preShutdown        []core.ShutdownHook
+	postShutdown       []core.ShutdownHook
+	services           map[string]any        // key is table name
+	versions           map[string]APIVersion // Older API versions, by name
 	middlewareRegistry *middleware.MiddlewareRegistry
+	requestID          *middleware.RequestIDMiddleware
+	metrics            *middleware.MetricsMiddleware
+	health             *HealthRegistry
+	grpcHealth         *health.Server
 	serviceRegistry    *ServiceRegistry
+	auditLog           *audit.Recorder
+	policies           *policy.Enforcer
+	responseCache      *cache.ResponseCache
 }
 
 // NewServer creates a new dual HTTP/gRPC server
 func NewServer(cfg *config.ServerConfig, projectDir string, db *database.Database, logger core.Logger) *DualServer {
-	return &DualServer{
+	s := &DualServer{
 		config:             cfg,
 		projectDir:         projectDir,
 		db:                 db,
@@ -48,7 +70,18 @@ func NewServer(cfg *config.ServerConfig, projectDir string, db *database.Databas
 		services:           make(map[string]any),
 		middlewareRegistry: middleware.NewMiddlewareRegistry(logger),
 		serviceRegistry:    NewServiceRegistry(db, logger),
+		health:             NewHealthRegistry(healthCheckTimeout),
 	}
+	s.initHealthChecks()
+	s.initShutdownHooks()
+	s.initSerializers()
+	if cfg.Middleware.RequestID.Enabled == nil || *cfg.Middleware.RequestID.Enabled {
+		s.requestID = middleware.NewRequestIDMiddleware(cfg.Middleware.RequestID.Header, logger)
+	}
+	if cfg.Metrics.Enabled {
+		s.initMetrics()
+	}
+	return s
 }
 
 // RegisterGeneratedServices loads and registers all generated services
@@ -114,7 +147,7 @@ func (s *DualServer) discoverTables(projectDir string) ([]string, error) {
 
 		matches := createTableRe.FindAllStringSubmatch(string(content), -1)
 		for _, match := range matches {
-			if len(match) > 1 {
+			if len(match) > 1 && !core.IsInternalTable(match[1]) {
 				tableSet[match[1]] = true
 			}
 		}
@@ -129,7 +162,9 @@ func (s *DualServer) discoverTables(projectDir string) ([]string, error) {
 	return tables, nil
 }
 
-// Start starts HTTP and/or gRPC servers based on config
+// Start starts HTTP and/or gRPC servers based on config. It blocks until ctx
+// is cancelled, a server fails, or Stop is called, and shuts down gracefully
+// in the first two cases.
 func (s *DualServer) Start(ctx context.Context) error {
 	s.mu.Lock()
 
@@ -138,15 +173,17 @@ func (s *DualServer) Start(ctx context.Context) error {
 		return fmt.Errorf("server already started")
 	}
 
-	// Initialize HTTP server if enabled
-	if s.config.EnableHTTP {
-		if err := s.initHTTPServer(); err != nil {
+	// Load certificates once; both servers share the reloading configuration
+	if s.config.TLS.Enabled {
+		tlsConfig, err := certs.NewTLSConfig(s.config.TLS, s.logger)
+		if err != nil {
 			s.mu.Unlock()
-			return fmt.Errorf("failed to initialize HTTP server: %w", err)
+			return fmt.Errorf("failed to load TLS configuration: %w", err)
 		}
+		s.tlsConfig = tlsConfig
 	}
 
-	// Initialize gRPC server if enabled
+	// Initialize gRPC server if enabled; first, as single-port HTTP serves it
 	if s.config.EnableGRPC {
 		if err := s.initGRPCServer(); err != nil {
 			s.mu.Unlock()
@@ -154,117 +191,95 @@ func (s *DualServer) Start(ctx context.Context) error {
 		}
 	}
 
+	// Initialize HTTP server if enabled
+	if s.config.EnableHTTP {
+		if err := s.initHTTPServer(); err != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("failed to initialize HTTP server: %w", err)
+		}
+	}
+
 	// Mark server as started before releasing lock
 	s.started = true
+	s.shuttingDown.Store(false)
+	s.stopped = make(chan struct{})
+	stopped := s.stopped
+	auditLog := s.auditLog
+	background, stopBackground := context.WithCancel(ctx)
+	s.stopBackground = stopBackground
 	s.mu.Unlock()
 
+	// Prune expired audit entries in the background
+	if auditLog != nil {
+		go auditLog.RunRetention(background, auditRetentionInterval)
+	}
+
+	// Keep the gRPC health service in sync with the health checks
+	if s.config.EnableGRPC {
+		go s.runGRPCHealth(background)
+	}
+
+	errChan := make(chan error, 2)
+
 	// Start HTTP server in goroutine if enabled
-	httpErrChan := make(chan error, 1)
 	if s.config.EnableHTTP {
 		go func() {
 			httpAddr := s.config.GetHTTPAddress()
-			s.logger.Info("Starting HTTP server", "address", httpAddr)
-			if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				httpErrChan <- fmt.Errorf("HTTP server error: %w", err)
+			s.logger.Info("Starting HTTP server", "address", httpAddr, "tls", s.tlsConfig != nil)
+			var err error
+			if s.tlsConfig != nil {
+				// The certificate comes from TLSConfig.GetCertificate
+				err = s.httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = s.httpServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				errChan <- fmt.Errorf("HTTP server error: %w", err)
 			}
 		}()
+		s.logger.Info("HTTP server is running", "base_path", s.config.BasePath, "api_version", s.config.APIVersion)
 	}
 
-	// Start gRPC server in goroutine if enabled
-	grpcErrChan := make(chan error, 1)
-	if s.config.EnableGRPC {
+	// Start gRPC server in goroutine if enabled; in single-port mode the HTTP server serves it
+	if s.config.EnableGRPC && s.config.SinglePort {
+		s.logger.Info("gRPC and gRPC-Web share the HTTP port", "address", s.config.GetHTTPAddress())
+	} else if s.config.EnableGRPC {
 		go func() {
 			grpcAddr := s.config.GetGRPCAddress()
 			listener, err := net.Listen("tcp", grpcAddr)
 			if err != nil {
-				grpcErrChan <- fmt.Errorf("failed to listen on gRPC address %s: %w", grpcAddr, err)
+				errChan <- fmt.Errorf("failed to listen on gRPC address %s: %w", grpcAddr, err)
 				return
 			}
 
-			s.logger.Info("Starting gRPC server", "address", grpcAddr)
+			s.logger.Info("Starting gRPC server", "address", grpcAddr, "tls", s.tlsConfig != nil)
 			if err := s.grpcServer.Serve(listener); err != nil {
-				grpcErrChan <- fmt.Errorf("gRPC server error: %w", err)
+				errChan <- fmt.Errorf("gRPC server error: %w", err)
 			}
 		}()
+		s.logger.Info("gRPC server is running")
 	}
 
-	// Create a channel to track if any server is running
-	runningChan := make(chan struct{}, 1)
-
-	// Start goroutine to signal when servers are running
-	go func() {
-		if s.config.EnableHTTP {
-			s.logger.Info("HTTP server is running", "base_path", s.config.BasePath, "api_version", s.config.APIVersion)
-		}
-		if s.config.EnableGRPC {
-			s.logger.Info("gRPC server is running")
-		}
-		close(runningChan)
-	}()
-
-	// Wait for servers to start before setting up shutdown
-	<-runningChan
-
-	// Create dedicated shutdown channel
-	shutdownChan := make(chan struct{})
-
-	// Goroutine to translate context cancellation to shutdown signal
-	go func() {
-		<-ctx.Done()
-		close(shutdownChan)
-	}()
-
-	// Wait for shutdown signal or server errors
-	for {
-		select {
-		case <-shutdownChan:
-			s.logger.Info("Shutdown signal received")
-			return s.Stop()
-		case err := <-httpErrChan:
-			return fmt.Errorf("HTTP server failed: %w", err)
-		case err := <-grpcErrChan:
-			return fmt.Errorf("gRPC server failed: %w", err)
-		default:
-			time.Sleep(time.Millisecond)
+	// Wait for shutdown signal, server errors, or an external Stop
+	select {
+	case <-ctx.Done():
+		s.logger.Info("Shutdown signal received")
+		return s.Stop()
+	case err := <-errChan:
+		if stopErr := s.Stop(); stopErr != nil {
+			s.logger.Error("Failed to stop servers after error", "error", stopErr)
 		}
+		return err
+	case <-stopped:
+		return nil
 	}
 }
 
-// Stop gracefully stops enabled servers
+// Stop gracefully stops enabled servers within the configured shutdown timeout
 func (s *DualServer) Stop() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if !s.started {
-		return nil
-	}
-
-	s.logger.Info("Stopping servers")
-
-	var errors []error
-
-	// Stop HTTP server if enabled
-	if s.httpServer != nil && s.config.EnableHTTP {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		if err := s.httpServer.Shutdown(ctx); err != nil {
-			errors = append(errors, fmt.Errorf("HTTP server shutdown error: %w", err))
-		}
-	}
-
-	// Stop gRPC server if enabled
-	if s.grpcServer != nil && s.config.EnableGRPC {
-		s.grpcServer.GracefulStop()
-	}
-
-	s.started = false
-
-	if len(errors) > 0 {
-		return fmt.Errorf("shutdown errors: %v", errors)
-	}
-
-	s.logger.Info("Servers stopped successfully")
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+	return s.Shutdown(ctx)
 }
 
 // RegisterService registers a service with enabled HTTP and/or gRPC servers
@@ -295,6 +310,7 @@ func (s *DualServer) registerServiceInternal(tableName string, service any) erro
 	}
 
 	s.services[tableName] = service
+	s.attachCache(service)
 	serviceType := fmt.Sprintf("%T", service)
 
 	if s.httpServer != nil && s.config.EnableHTTP {
@@ -323,10 +339,23 @@ func (s *DualServer) initHTTPServer() error {
 	// Create HTTP handler
 	handler := s.createHTTPHandler()
 
-	// Apply middleware
-	for _, mw := range s.getSortedMiddleware() {
-		handler = mw(handler)
-		s.logger.Debug("Applied HTTP middleware")
+	// Apply middleware, lowest priority number outermost
+	handler = s.middlewareRegistry.Chain(handler)
+	s.logger.Debug("Applied HTTP middleware", "count", len(s.middlewareRegistry.ListMiddleware()))
+
+	// Request IDs wrap everything so all middleware logs carry them
+	if s.requestID != nil {
+		handler = s.requestID.Handler()(handler)
+	}
+
+	// gRPC calls bypass the HTTP middleware and run through the gRPC interceptors
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	if s.config.SinglePort {
+		handler = s.multiplexHandler(handler)
+		// With TLS, HTTP/2 is negotiated through ALPN instead of h2c
+		protocols.SetUnencryptedHTTP2(s.tlsConfig == nil)
 	}
 
 	// Create HTTP server
@@ -335,6 +364,10 @@ func (s *DualServer) initHTTPServer() error {
 		Handler:      handler,
 		ReadTimeout:  time.Duration(s.config.Timeout) * time.Second,
 		WriteTimeout: time.Duration(s.config.Timeout) * time.Second,
+		Protocols:    protocols,
+	}
+	if s.tlsConfig != nil {
+		s.httpServer.TLSConfig = s.tlsConfig.Clone()
 	}
 
 	return nil
@@ -344,20 +377,38 @@ func (s *DualServer) initHTTPServer() error {
 func (s *DualServer) createHTTPHandler() http.Handler {
 	mux := http.NewServeMux()
 
-	// Register health check endpoint
-	mux.HandleFunc("/health", s.healthCheckHandler)
+	// Register health check endpoints; /health is the readiness probe
+	mux.HandleFunc("/health", s.readyHandler)
+	mux.HandleFunc("/health/live", s.liveHandler)
+	mux.HandleFunc("/health/ready", s.readyHandler)
 
 	// Register OpenAPI docs endpoint if enabled
 	if s.config.EnableDocs != nil && *s.config.EnableDocs {
 		mux.HandleFunc(s.config.DocsPath, s.docsHandler)
 		mux.HandleFunc(s.config.DocsPath+"/", s.docsHandler)
 		mux.HandleFunc(s.config.DocsPath+"/openapi.json", s.docsOpenAPIHandler)
+		mux.HandleFunc(s.config.DocsPath+"/xml/", s.docsXSDHandler)
 		mux.HandleFunc(s.config.DocsPath+"/swagger-ui.css", s.docsCSSHandler)
 		mux.HandleFunc(s.config.DocsPath+"/swagger-ui-bundle.js", s.docsJSHandler)
 		mux.HandleFunc(s.config.DocsPath+"/swagger-ui-standalone-preset.js", s.docsStandalonePresetHandler)
 		mux.HandleFunc(s.config.DocsPath+".json", s.docsJSONRedirect)
 	}
 
+	// Register GraphQL endpoint if enabled
+	if s.config.EnableGraphQL != nil && *s.config.EnableGraphQL {
+		mux.Handle(s.config.GraphQLPath, s.graphqlHandler())
+	}
+
+	// Register Prometheus metrics endpoint if enabled
+	if s.metrics != nil {
+		mux.Handle(s.metricsPath(), s.metrics.MetricsHandler())
+	}
+
+	// Register audit log endpoint if enabled
+	if s.auditLog != nil {
+		mux.HandleFunc("/_audit", s.auditHandler)
+	}
+
 	// Set up routes for registered services
 	s.setupHTTPRoutes(mux)
 
@@ -383,6 +434,38 @@ func (s *DualServer) AddMiddleware(middleware middleware.HTTPMiddleware) {
 	s.logger.Info("Middleware added", "name", middleware.Name())
 }
 
+// ConfigureMiddleware adds the middleware enabled under server.middleware in apiright.yaml,
+// along with the table policies and response cache, so every entry point enforces them
+func (s *DualServer) ConfigureMiddleware(cfg *config.Config) error {
+	var sqlDB *sql.DB
+	var dialect string
+	if s.db != nil {
+		sqlDB = s.db.GetDB()
+		dialect = s.db.Dialect()
+	}
+
+	mws, err := middleware.NewFromConfig(cfg, sqlDB, dialect, s.logger)
+	if err != nil {
+		return fmt.Errorf("failed to configure middleware: %w", err)
+	}
+	for _, mw := range mws {
+		s.AddMiddleware(mw)
+	}
+
+	if len(cfg.Policies) > 0 {
+		s.SetPolicyEnforcer(policy.NewEnforcer(cfg.Policies, s.logger))
+	}
+
+	if cfg.Cache.Enabled {
+		responseCache, err := cache.NewFromConfig(cfg, s.logger)
+		if err != nil {
+			return fmt.Errorf("failed to configure response cache: %w", err)
+		}
+		s.SetResponseCache(responseCache)
+	}
+	return nil
+}
+
 // GetHTTPServer returns the underlying HTTP server
 func (s *DualServer) GetHTTPServer() *http.Server {
 	s.mu.RLock()
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/plugins"
+)
+
+const (
+	// defaultShutdownTimeout applies when server.shutdown.timeout is not set
+	defaultShutdownTimeout = 30 * time.Second
+
+	// postShutdownTimeout bounds the post-shutdown hooks, which run after the drain deadline
+	postShutdownTimeout = 10 * time.Second
+)
+
+// AddPreShutdownHook registers a hook that runs once readiness reports
+// not-ready, before in-flight requests are drained
+func (s *DualServer) AddPreShutdownHook(hook core.ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preShutdown = append(s.preShutdown, hook)
+}
+
+// AddPostShutdownHook registers a hook that runs after both servers have
+// stopped, such as flushing buffers or closing the database
+func (s *DualServer) AddPostShutdownHook(hook core.ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.postShutdown = append(s.postShutdown, hook)
+}
+
+// initShutdownHooks registers the hooks provided by plugins
+func (s *DualServer) initShutdownHooks() {
+	for _, provider := range plugins.GetShutdownHookProviders() {
+		s.preShutdown = append(s.preShutdown, provider.PreShutdown)
+		s.postShutdown = append(s.postShutdown, provider.PostShutdown)
+	}
+}
+
+// IsShuttingDown reports whether a graceful shutdown is in progress
+func (s *DualServer) IsShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
+// Shutdown stops the servers gracefully: readiness flips to not-ready, the
+// pre-shutdown hooks run, in-flight requests drain until ctx expires, gRPC is
+// then force-stopped, and finally the post-shutdown hooks run with their own
+// deadline. Concurrent calls wait for the shutdown already in progress.
+func (s *DualServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	stopped := s.stopped
+	if s.shuttingDown.Swap(true) {
+		s.mu.Unlock()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	httpServer, grpcServer, grpcHealth := s.httpServer, s.grpcServer, s.grpcHealth
+	stopBackground := s.stopBackground
+	preShutdown := append([]core.ShutdownHook(nil), s.preShutdown...)
+	postShutdown := append([]core.ShutdownHook(nil), s.postShutdown...)
+	s.mu.Unlock()
+
+	// The lock is not held while draining, so in-flight handlers can still use it
+	s.logger.Info("Stopping servers")
+	var errs []error
+
+	if stopBackground != nil {
+		stopBackground()
+	}
+	if grpcHealth != nil {
+		grpcHealth.Shutdown()
+	}
+	errs = append(errs, runShutdownHooks(ctx, "pre-shutdown", preShutdown)...)
+
+	// Keep serving briefly so load balancers observe the readiness change
+	if delay := s.drainDelay(); delay > 0 {
+		s.logger.Info("Waiting before draining connections", "delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	httpDone := make(chan error, 1)
+	if httpServer != nil && s.config.EnableHTTP {
+		go func() { httpDone <- httpServer.Shutdown(ctx) }()
+	} else {
+		httpDone <- nil
+	}
+
+	if grpcServer != nil && s.config.EnableGRPC {
+		grpcDone := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcDone)
+		}()
+		select {
+		case <-grpcDone:
+		case <-ctx.Done():
+			s.logger.Warn("gRPC drain deadline exceeded, closing remaining connections")
+			grpcServer.Stop()
+			<-grpcDone
+		}
+	}
+
+	if err := <-httpDone; err != nil {
+		errs = append(errs, fmt.Errorf("HTTP server shutdown error: %w", err))
+		if closeErr := httpServer.Close(); closeErr != nil {
+			errs = append(errs, fmt.Errorf("HTTP server close error: %w", closeErr))
+		}
+	}
+
+	// ctx may have expired while draining, so the hooks get a deadline of their own
+	hookCtx, cancel := context.WithTimeout(context.Background(), postShutdownTimeout)
+	errs = append(errs, runShutdownHooks(hookCtx, "post-shutdown", postShutdown)...)
+	cancel()
+
+	s.mu.Lock()
+	s.started = false
+	s.mu.Unlock()
+	close(stopped)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("shutdown errors: %w", errors.Join(errs...))
+	}
+
+	s.logger.Info("Servers stopped successfully")
+	return nil
+}
+
+// runShutdownHooks runs every hook, collecting their errors
+func runShutdownHooks(ctx context.Context, stage string, hooks []core.ShutdownHook) []error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s hook: %w", stage, err))
+		}
+	}
+	return errs
+}
+
+// shutdownTimeout returns the configured drain deadline
+func (s *DualServer) shutdownTimeout() time.Duration {
+	if s.config.Shutdown.Timeout == "" {
+		return defaultShutdownTimeout
+	}
+	timeout, err := config.ParseDuration(s.config.Shutdown.Timeout)
+	if err != nil {
+		s.logger.Warn("Invalid shutdown timeout, using default", "timeout", s.config.Shutdown.Timeout, "error", err)
+		return defaultShutdownTimeout
+	}
+	return timeout
+}
+
+// drainDelay returns how long to keep serving after readiness flips
+func (s *DualServer) drainDelay() time.Duration {
+	if s.config.Shutdown.DrainDelay == "" {
+		return 0
+	}
+	delay, err := config.ParseDuration(s.config.Shutdown.DrainDelay)
+	if err != nil {
+		s.logger.Warn("Invalid shutdown drain delay, ignoring", "drain_delay", s.config.Shutdown.DrainDelay, "error", err)
+		return 0
+	}
+	return delay
+}
@@ -0,0 +1,418 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+)
+
+// APIVersion is an older API version served next to the current one, with the
+// tables and columns of its frozen schema. Generated adapters mount one per
+// snapshot in versions/.
+type APIVersion struct {
+	Name   string
+	Tables []VersionedTable
+}
+
+// VersionedTable is a table as frozen in an API version. Readable and Writable
+// name frozen columns; Columns maps each to the current column it is stored in.
+type VersionedTable struct {
+	Resource core.Resource     // Shape of the table in the version
+	Readable []string          // Columns returned to clients
+	Writable []string          // Columns clients may set
+	Columns  map[string]string // Frozen column to current column; nil keeps the names
+}
+
+// MountVersion serves version under BasePath/<name> on top of the current
+// services. Call it after registering the services and before Start.
+func (s *DualServer) MountVersion(version APIVersion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if version.Name == s.config.APIVersion {
+		return fmt.Errorf("API version %s is the current version", version.Name)
+	}
+	if s.versions == nil {
+		s.versions = make(map[string]APIVersion)
+	}
+	s.versions[version.Name] = version
+	return nil
+}
+
+// apiRoute is the API version a request is served from
+type apiRoute struct {
+	version     string
+	prefix      string // Path before the table name
+	services    map[string]any
+	deprecation time.Time // Zero unless the version is deprecated
+	sunset      time.Time
+}
+
+type apiRouteKey struct{}
+
+// versionRoutes returns the current version and the older versions, each with
+// its services. Configured versions without a frozen schema serve the current
+// services; mounted versions serve them cut down to the frozen tables.
+func (s *DualServer) versionRoutes() []*apiRoute {
+	routes := []*apiRoute{{
+		version:  s.config.APIVersion,
+		prefix:   s.config.BasePath + "/" + s.config.APIVersion,
+		services: s.services,
+	}}
+
+	configured := make(map[string]config.VersionConfig, len(s.config.Versions))
+	names := make([]string, 0, len(s.config.Versions)+len(s.versions))
+	for _, version := range s.config.Versions {
+		configured[version.Name] = version
+		names = append(names, version.Name)
+	}
+	for name := range s.versions {
+		if _, ok := configured[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		route := &apiRoute{version: name, prefix: s.config.BasePath + "/" + name, services: s.services}
+		if version, ok := s.versions[name]; ok {
+			route.services = s.versionedServices(version)
+		} else {
+			s.logger.Warn("API version has no frozen schema, serving the current one", "version", name)
+		}
+		// Dates were validated with the configuration
+		if cfg, ok := configured[name]; ok {
+			if cfg.Deprecated != "" {
+				route.deprecation, _ = config.ParseDate(cfg.Deprecated)
+			}
+			if cfg.Sunset != "" {
+				route.sunset, _ = config.ParseDate(cfg.Sunset)
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// versionedServices wraps the current service of each frozen table; tables
+// that no longer exist are not served
+func (s *DualServer) versionedServices(version APIVersion) map[string]any {
+	services := make(map[string]any, len(version.Tables))
+	for _, table := range version.Tables {
+		current, ok := s.services[table.Resource.Type].(ServiceInterface)
+		if !ok {
+			s.logger.Warn("Table of API version has no current service", "version", version.Name, "table", table.Resource.Type)
+			continue
+		}
+		services[table.Resource.Type] = newVersionedService(current, table)
+	}
+	return services
+}
+
+// mountVersionRoutes registers the routes of every API version, and unversioned
+// routes under BasePath (e.g. /api/posts) that pick the version from the
+// request headers
+func (s *DualServer) mountVersionRoutes(mux *http.ServeMux) {
+	routes := s.versionRoutes()
+	byName := make(map[string]*apiRoute, len(routes))
+	tables := map[string]bool{}
+	for _, route := range routes {
+		byName[route.version] = route
+		for tableName := range route.services {
+			tables[tableName] = true
+			s.mountTableRoutes(mux, route.prefix+"/"+tableName, tableName, func(r *http.Request) (*apiRoute, error) {
+				return route, nil
+			})
+		}
+		s.logger.Info("HTTP routes registered", "version", route.version, "base_path", route.prefix, "tables", len(route.services))
+	}
+
+	// Without a base path, unversioned routes could shadow /health and friends
+	if strings.Trim(s.config.BasePath, "/") == "" {
+		return
+	}
+	for tableName := range tables {
+		s.mountTableRoutes(mux, s.config.BasePath+"/"+tableName, tableName, func(r *http.Request) (*apiRoute, error) {
+			route, err := s.selectVersion(r, byName)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := route.services[tableName]; !ok {
+				return nil, core.NotFound("table %s is not part of API version %s", tableName, route.version)
+			}
+			selected := *route
+			selected.prefix = s.config.BasePath
+			return &selected, nil
+		})
+	}
+}
+
+// mountTableRoutes registers the CRUD routes of a table under path; resolve
+// picks the API version of each request
+func (s *DualServer) mountTableRoutes(mux *http.ServeMux, path, tableName string, resolve func(r *http.Request) (*apiRoute, error)) {
+	serve := func(w http.ResponseWriter, r *http.Request, handle func(w http.ResponseWriter, r *http.Request, tableName string)) {
+		route, err := resolve(r)
+		if err != nil {
+			s.handleServiceError(w, r, err, s.detectContentType(r))
+			return
+		}
+		s.setVersionHeaders(w, route, tableName)
+		handle(w, r.WithContext(context.WithValue(r.Context(), apiRouteKey{}, route)), tableName)
+	}
+
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			serve(w, r, s.handleListRoute)
+		case http.MethodPost:
+			serve(w, r, s.handleCreateRoute)
+		}
+	})
+
+	mux.HandleFunc(path+"/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			serve(w, r, s.handleGetRoute)
+		case http.MethodPut:
+			serve(w, r, s.handleUpdateRoute)
+		case http.MethodDelete:
+			serve(w, r, s.handleDeleteRoute)
+		}
+	})
+}
+
+// selectVersion returns the version named by the Accept-Version header or the
+// version parameter of the Accept media type ("1" also selects "v1"), or the
+// current version when the request names none
+func (s *DualServer) selectVersion(r *http.Request, routes map[string]*apiRoute) (*apiRoute, error) {
+	name := strings.TrimSpace(r.Header.Get("Accept-Version"))
+	if name == "" {
+		for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+			if version := core.ParseContentHeader(accepted).Version; version != "" {
+				name = strings.Trim(version, `"`)
+				break
+			}
+		}
+	}
+	if name == "" {
+		return routes[s.config.APIVersion], nil
+	}
+	if route, ok := routes[name]; ok {
+		return route, nil
+	}
+	if route, ok := routes["v"+name]; ok {
+		return route, nil
+	}
+	return nil, core.BadRequest("unsupported API version %q", name)
+}
+
+// setVersionHeaders announces deprecated versions with the Deprecation (RFC
+// 9745) and Sunset (RFC 8594) headers and links to the current version.
+// Responses of header-selected versions vary by Accept-Version.
+func (s *DualServer) setVersionHeaders(w http.ResponseWriter, route *apiRoute, tableName string) {
+	if route.prefix == s.config.BasePath {
+		w.Header().Add("Vary", "Accept-Version")
+	}
+	if !route.deprecation.IsZero() {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", route.deprecation.Unix()))
+		w.Header().Add("Link", fmt.Sprintf("<%s/%s/%s>; rel=\"successor-version\"", s.config.BasePath, s.config.APIVersion, tableName))
+	}
+	if !route.sunset.IsZero() {
+		w.Header().Set("Sunset", route.sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// routeOf returns the API version r is served from; requests outside the
+// version routes are served from the current version
+func (s *DualServer) routeOf(r *http.Request) *apiRoute {
+	if route, ok := r.Context().Value(apiRouteKey{}).(*apiRoute); ok {
+		return route
+	}
+	return &apiRoute{
+		version:  s.config.APIVersion,
+		prefix:   s.config.BasePath + "/" + s.config.APIVersion,
+		services: s.services,
+	}
+}
+
+// routeService returns the service of tableName in the API version of r
+func (s *DualServer) routeService(r *http.Request, tableName string) (any, bool) {
+	service, ok := s.routeOf(r).services[tableName]
+	return service, ok
+}
+
+// versionedService serves a table in the shape of an older API version on top
+// of its current service: frozen columns are renamed to and from the current
+// ones, and rows and bodies are cut down to the version's columns
+type versionedService struct {
+	ServiceInterface
+	table    VersionedTable
+	key      string            // Current primary key column
+	readable map[string]string // Current column to the readable frozen column
+	writable map[string]string // Writable frozen column to the current column
+	frozen   map[string]string // Current column to the frozen column
+}
+
+func newVersionedService(current ServiceInterface, table VersionedTable) *versionedService {
+	v := &versionedService{
+		ServiceInterface: current,
+		table:            table,
+		readable:         make(map[string]string, len(table.Readable)),
+		writable:         make(map[string]string, len(table.Writable)+1),
+		frozen:           make(map[string]string, len(table.Readable)),
+	}
+	for _, column := range table.Readable {
+		if name, ok := v.currentName(column); ok {
+			v.readable[name] = column
+			v.frozen[name] = column
+		}
+	}
+	for _, column := range table.Writable {
+		if name, ok := v.currentName(column); ok {
+			v.writable[column] = name
+			v.frozen[name] = column
+		}
+	}
+	// Update bodies carry the path ID under the primary key
+	if name, ok := v.currentName(table.Resource.PrimaryKey); ok {
+		v.writable[table.Resource.PrimaryKey] = name
+		v.key = name
+	}
+	return v
+}
+
+// currentName returns the current column a frozen column is stored in
+func (v *versionedService) currentName(column string) (string, bool) {
+	if v.table.Columns == nil {
+		return column, true
+	}
+	name, ok := v.table.Columns[column]
+	return name, ok
+}
+
+func (v *versionedService) TableName() string { return v.table.Resource.Type }
+
+func (v *versionedService) Resource() core.Resource { return v.table.Resource }
+
+func (v *versionedService) Get(ctx context.Context, id any) (any, error) {
+	result, err := v.ServiceInterface.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return v.output(result)
+}
+
+func (v *versionedService) List(ctx context.Context, limit, offset int32) (any, error) {
+	result, err := v.ServiceInterface.List(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]any{}
+	if err := convertRows(result, &rows); err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i] = mapColumns(row, v.readable)
+	}
+	return rows, nil
+}
+
+func (v *versionedService) Create(ctx context.Context, params any) (any, error) {
+	result, err := v.ServiceInterface.Create(ctx, v.input(params))
+	if err != nil {
+		return nil, v.frozenError(err)
+	}
+	return v.output(result)
+}
+
+func (v *versionedService) Update(ctx context.Context, params any) (any, error) {
+	values, err := v.merge(ctx, v.input(params))
+	if err != nil {
+		return nil, err
+	}
+	result, err := v.ServiceInterface.Update(ctx, values)
+	if err != nil {
+		return nil, v.frozenError(err)
+	}
+	return v.output(result)
+}
+
+// merge lays an update body over the stored row. Adapters write every writable
+// column, so columns added after the version was frozen keep their values.
+func (v *versionedService) merge(ctx context.Context, params any) (any, error) {
+	values, ok := params.(map[string]any)
+	if !ok {
+		return params, nil
+	}
+	id, ok := values[v.key]
+	if !ok {
+		return values, nil
+	}
+	stored, err := v.ServiceInterface.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	row, err := toRow(stored)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		row = make(map[string]any, len(values))
+	}
+	for column, value := range values {
+		row[column] = value
+	}
+	return row, nil
+}
+
+// input renames body columns to the current ones, dropping those the version doesn't accept
+func (v *versionedService) input(params any) any {
+	if row, ok := params.(map[string]any); ok {
+		return mapColumns(row, v.writable)
+	}
+	return params
+}
+
+// output returns a result row with the version's readable columns under their frozen names
+func (v *versionedService) output(result any) (any, error) {
+	row, err := toRow(result)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return mapColumns(row, v.readable), nil
+}
+
+// frozenError reports validation violations under the frozen column names
+func (v *versionedService) frozenError(err error) error {
+	typed, ok := core.AsAPIError(err)
+	if !ok || len(typed.Violations) == 0 {
+		return err
+	}
+	renamed := *typed
+	renamed.Violations = make(core.ValidationErrors, len(typed.Violations))
+	for i, violation := range typed.Violations {
+		if column, ok := v.frozen[violation.Field]; ok {
+			violation.Field = column
+		}
+		renamed.Violations[i] = violation
+	}
+	return &renamed
+}
+
+// mapColumns returns row with each column in columns renamed to the name it maps
+// to; other columns are dropped
+func mapColumns(row map[string]any, columns map[string]string) map[string]any {
+	mapped := make(map[string]any, len(columns))
+	for from, to := range columns {
+		if value, ok := row[from]; ok {
+			mapped[to] = value
+		}
+	}
+	return mapped
+}
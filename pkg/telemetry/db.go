@@ -0,0 +1,209 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/database"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DB wraps a *sql.DB so every query runs in a client span. It satisfies the
+// DBTX interface of sqlc generated code.
+type DB struct {
+	db     *sql.DB
+	system string
+}
+
+// InstrumentDB wraps db for tracing and registers gauges for its connection
+// pool. dialect is the normalized database type (sqlite, postgres, mysql).
+func InstrumentDB(db *sql.DB, dialect string) *DB {
+	system := dialect
+	if system == "postgres" {
+		system = "postgresql"
+	}
+
+	registerPoolMetrics(db, system)
+	return &DB{db: db, system: system}
+}
+
+// ExecContext executes a statement in a span
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	result, err := d.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		RecordError(ctx, err)
+	}
+	return result, err
+}
+
+// PrepareContext prepares a statement in a span
+func (d *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	stmt, err := d.db.PrepareContext(ctx, query)
+	if err != nil {
+		RecordError(ctx, err)
+	}
+	return stmt, err
+}
+
+// QueryContext runs a query in a span. The span ends once the query returns,
+// not when the rows are closed.
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		RecordError(ctx, err)
+	}
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query in a span
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	row := d.db.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		RecordError(ctx, err)
+	}
+	return row
+}
+
+// Begin starts a transaction whose statements run in spans like the DB's
+func (d *DB) Begin(ctx context.Context) (database.Tx, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		RecordError(ctx, err)
+		return nil, err
+	}
+	return &Tx{tx: tx, system: d.system}, nil
+}
+
+// start starts a client span named after the sqlc query name
+func (d *DB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	return startQuery(ctx, d.system, query)
+}
+
+// Tx wraps a *sql.Tx so every statement runs in a client span. It satisfies
+// database.Tx and the DBTX interface of sqlc generated code.
+type Tx struct {
+	tx     *sql.Tx
+	system string
+}
+
+// ExecContext executes a statement in a span
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, t.system, query)
+	defer span.End()
+
+	result, err := t.tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		RecordError(ctx, err)
+	}
+	return result, err
+}
+
+// PrepareContext prepares a statement in a span
+func (t *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := startQuery(ctx, t.system, query)
+	defer span.End()
+
+	stmt, err := t.tx.PrepareContext(ctx, query)
+	if err != nil {
+		RecordError(ctx, err)
+	}
+	return stmt, err
+}
+
+// QueryContext runs a query in a span
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, t.system, query)
+	defer span.End()
+
+	rows, err := t.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		RecordError(ctx, err)
+	}
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query in a span
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, t.system, query)
+	defer span.End()
+
+	row := t.tx.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		RecordError(ctx, err)
+	}
+	return row
+}
+
+// Commit commits the transaction
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// startQuery starts a client span named after the sqlc query name
+func startQuery(ctx context.Context, system, query string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, queryName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", system),
+			attribute.String("db.query.text", query),
+		))
+}
+
+// queryName returns the name from a sqlc "-- name: X :one" header, or the
+// first keyword of the statement
+func queryName(query string) string {
+	query = strings.TrimSpace(query)
+	if rest, ok := strings.CutPrefix(query, "-- name:"); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "query"
+}
+
+// registerPoolMetrics reports the connection pool statistics of db as gauges
+func registerPoolMetrics(db *sql.DB, system string) {
+	meter := Meter()
+	open, _ := meter.Int64ObservableGauge("apiright.db.connections.open",
+		metric.WithDescription("Open database connections"), metric.WithUnit("{connection}"))
+	inUse, _ := meter.Int64ObservableGauge("apiright.db.connections.in_use",
+		metric.WithDescription("Database connections in use"), metric.WithUnit("{connection}"))
+	idle, _ := meter.Int64ObservableGauge("apiright.db.connections.idle",
+		metric.WithDescription("Idle database connections"), metric.WithUnit("{connection}"))
+	waits, _ := meter.Int64ObservableCounter("apiright.db.connections.waits",
+		metric.WithDescription("Total waits for a database connection"), metric.WithUnit("{wait}"))
+
+	attrs := metric.WithAttributes(attribute.String("db.system.name", system))
+	_, _ = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := db.Stats()
+		o.ObserveInt64(open, int64(stats.OpenConnections), attrs)
+		o.ObserveInt64(inUse, int64(stats.InUse), attrs)
+		o.ObserveInt64(idle, int64(stats.Idle), attrs)
+		o.ObserveInt64(waits, stats.WaitCount, attrs)
+		return nil
+	}, open, inUse, idle, waits)
+}
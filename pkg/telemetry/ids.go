@@ -0,0 +1,65 @@
+package telemetry
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"github.com/bata94/apiright/pkg/core"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// idGenerator gives a request's server span the trace and span IDs chosen by
+// the request ID middleware, so logs, response headers and exported spans
+// agree. All other spans get random IDs.
+type idGenerator struct{}
+
+// NewIDGenerator returns an IDGenerator that reuses the request's core.TraceContext
+func NewIDGenerator() sdktrace.IDGenerator {
+	return idGenerator{}
+}
+
+// NewIDs returns the IDs of the request's trace context for a root span
+func (idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if tc, ok := requestSpan(ctx); ok && !trace.SpanContextFromContext(ctx).IsValid() {
+		traceID, _ := trace.TraceIDFromHex(tc.TraceID)
+		spanID, _ := trace.SpanIDFromHex(tc.SpanID)
+		return traceID, spanID
+	}
+
+	var traceID trace.TraceID
+	for !traceID.IsValid() {
+		for i := range traceID {
+			traceID[i] = byte(rand.Uint32())
+		}
+	}
+	return traceID, randomSpanID()
+}
+
+// NewSpanID returns the request's span ID for the span continuing the caller's trace
+func (idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	if tc, ok := requestSpan(ctx); ok && tc.TraceID == traceID.String() {
+		if parent := trace.SpanContextFromContext(ctx); parent.IsRemote() && parent.SpanID().String() == tc.ParentID {
+			spanID, _ := trace.SpanIDFromHex(tc.SpanID)
+			return spanID
+		}
+	}
+	return randomSpanID()
+}
+
+// requestSpan returns the request's trace context if one was set
+func requestSpan(ctx context.Context) (core.TraceContext, bool) {
+	tc, ok := core.TraceContextFromContext(ctx)
+	return tc, ok && tc.IsValid()
+}
+
+// randomSpanID returns a non-zero random span ID
+func randomSpanID() trace.SpanID {
+	var spanID trace.SpanID
+	for !spanID.IsValid() {
+		for i := range spanID {
+			spanID[i] = byte(rand.Uint32())
+		}
+	}
+	return spanID
+}
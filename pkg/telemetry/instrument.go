@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestInstruments holds the request metrics shared by HTTP and gRPC
+type requestInstruments struct {
+	count    metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+var (
+	instrumentsOnce sync.Once
+	instruments     requestInstruments
+)
+
+// Tracer returns the tracer used for APIRight spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Meter returns the meter used for APIRight metrics
+func Meter() metric.Meter {
+	return otel.Meter(instrumentationName)
+}
+
+// getInstruments creates the request instruments on first use. The global
+// meter delegates to the provider installed by Setup, even if Setup runs later.
+func getInstruments() requestInstruments {
+	instrumentsOnce.Do(func() {
+		meter := Meter()
+		instruments.count, _ = meter.Int64Counter("apiright.requests",
+			metric.WithDescription("Number of handled requests"),
+			metric.WithUnit("{request}"))
+		instruments.errors, _ = meter.Int64Counter("apiright.errors",
+			metric.WithDescription("Number of requests that failed with a server error"),
+			metric.WithUnit("{request}"))
+		instruments.duration, _ = meter.Float64Histogram("apiright.request.duration",
+			metric.WithDescription("Duration of handled requests"),
+			metric.WithUnit("s"))
+	})
+	return instruments
+}
+
+// RecordRequest records the count, duration and failure of a finished request
+func RecordRequest(ctx context.Context, protocol string, route core.RouteInfo, status string, failed bool, duration time.Duration) {
+	inst := getInstruments()
+	attrs := metric.WithAttributes(
+		attribute.String("protocol", protocol),
+		attribute.String("table", route.Table),
+		attribute.String("operation", route.Operation),
+		attribute.String("status", status),
+	)
+
+	inst.count.Add(ctx, 1, attrs)
+	inst.duration.Record(ctx, duration.Seconds(), attrs)
+	if failed {
+		inst.errors.Add(ctx, 1, attrs)
+	}
+}
+
+// StartOperation starts a span for a table operation and records the
+// operation on the request's RouteInfo. Callers must end the returned span.
+func StartOperation(ctx context.Context, table, operation string) (context.Context, trace.Span) {
+	core.SetRoute(ctx, table, operation)
+	return Tracer().Start(ctx, table+"."+operation,
+		trace.WithAttributes(
+			attribute.String("apiright.table", table),
+			attribute.String("apiright.operation", operation),
+		))
+}
+
+// RecordError marks the active span in ctx as failed with err
+func RecordError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
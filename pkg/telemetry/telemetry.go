@@ -0,0 +1,149 @@
+// Package telemetry provides OpenTelemetry tracing and metrics for APIRight services.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bata94/apiright/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// instrumentationName is the scope name of all tracers and meters created here
+const instrumentationName = "github.com/bata94/apiright"
+
+// ShutdownFunc flushes pending telemetry and stops the exporters
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs global tracer and meter providers that export according to
+// cfg. It returns a no-op ShutdownFunc when telemetry is disabled.
+func Setup(ctx context.Context, cfg config.TelemetryConfig, version string) (ShutdownFunc, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	interval, err := config.ParseDuration(cfg.MetricsInterval)
+	if err != nil {
+		return nil, fmt.Errorf("telemetry metrics_interval: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+
+	spanExporter, metricExporter, err := newExporters(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := 1.0
+	if cfg.SampleRatio != nil {
+		ratio = *cfg.SampleRatio
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithIDGenerator(NewIDGenerator()),
+	)
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(interval))),
+		sdkmetric.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// newExporters creates the span and metric exporters selected in cfg
+func newExporters(ctx context.Context, cfg config.TelemetryConfig) (sdktrace.SpanExporter, sdkmetric.Exporter, error) {
+	if cfg.Exporter == "stdout" {
+		spanExporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout span exporter: %w", err)
+		}
+		metricExporter, err := stdoutmetric.New()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout metric exporter: %w", err)
+		}
+		return spanExporter, metricExporter, nil
+	}
+
+	insecure := cfg.Insecure == nil || *cfg.Insecure
+	endpoint := cfg.Endpoint
+	// Full URLs carry their own scheme and path; bare host:port uses the OTLP defaults
+	isURL := strings.Contains(endpoint, "://")
+
+	if cfg.Protocol == "grpc" {
+		if endpoint == "" {
+			endpoint = "localhost:4317"
+		}
+		traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint)}
+		if isURL {
+			traceOpts = []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(endpoint)}
+			metricOpts = []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpointURL(endpoint)}
+		}
+		if insecure {
+			traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+		}
+
+		spanExporter, err := otlptracegrpc.New(ctx, traceOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP gRPC span exporter: %w", err)
+		}
+		metricExporter, err := otlpmetricgrpc.New(ctx, metricOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP gRPC metric exporter: %w", err)
+		}
+		return spanExporter, metricExporter, nil
+	}
+
+	if endpoint == "" {
+		endpoint = "localhost:4318"
+	}
+	traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint)}
+	if isURL {
+		traceOpts = []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+		metricOpts = []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(endpoint)}
+	}
+	if insecure {
+		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+	}
+
+	spanExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP HTTP span exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP HTTP metric exporter: %w", err)
+	}
+	return spanExporter, metricExporter, nil
+}
@@ -0,0 +1,311 @@
+package apiright_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/audit"
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/middleware"
+	"github.com/bata94/apiright/pkg/policy"
+	"github.com/bata94/apiright/pkg/server"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newAuditRecorder(t *testing.T, options audit.Options) *audit.Recorder {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := db.Exec(audit.CreateTableSQL("sqlite", options.Table)); err != nil {
+		t.Fatalf("Failed to create audit table: %v", err)
+	}
+
+	return audit.NewRecorder(db, "sqlite", options, &mockLogger{})
+}
+
+func TestAuditDiff(t *testing.T) {
+	before := map[string]any{"id": 1, "name": "old", "email": "a@example.com"}
+	after := map[string]any{"id": 1, "name": "new", "email": "a@example.com"}
+
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d: %+v", len(changes), changes)
+	}
+	if changes[0].Field != "name" || changes[0].OldValue != "old" || changes[0].NewValue != "new" {
+		t.Errorf("Unexpected change: %+v", changes[0])
+	}
+}
+
+func TestAuditDiffCreateAndDelete(t *testing.T) {
+	record := struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}{ID: 7, Name: "todo"}
+
+	created, err := audit.Diff(nil, record)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(created) != 2 {
+		t.Errorf("Expected 2 changes for create, got %d", len(created))
+	}
+
+	deleted, err := audit.Diff(record, nil)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	for _, change := range deleted {
+		if change.NewValue != nil {
+			t.Errorf("Expected nil new value on delete, got %v for %s", change.NewValue, change.Field)
+		}
+	}
+}
+
+func TestAuditRecorder_RecordAndHistory(t *testing.T) {
+	recorder := newAuditRecorder(t, audit.Options{})
+
+	ctx := core.WithActor(context.Background(), "alice")
+	ctx = core.WithRequestID(ctx, "req-123")
+
+	err := recorder.Record(ctx, nil, core.AuditEvent{
+		Table:      "users",
+		PrimaryKey: "42",
+		Operation:  core.AuditUpdate,
+		Before:     map[string]any{"name": "Bob"},
+		After:      map[string]any{"name": "Robert"},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	entries, err := recorder.History(context.Background(), "users", "42", 10)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Actor != "alice" {
+		t.Errorf("Expected actor 'alice', got '%s'", entry.Actor)
+	}
+	if entry.RequestID != "req-123" {
+		t.Errorf("Expected request ID 'req-123', got '%s'", entry.RequestID)
+	}
+	if entry.Operation != core.AuditUpdate {
+		t.Errorf("Expected operation update, got %s", entry.Operation)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Field != "name" {
+		t.Errorf("Unexpected changes: %+v", entry.Changes)
+	}
+
+	other, err := recorder.History(context.Background(), "users", "43", 10)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(other) != 0 {
+		t.Errorf("Expected no entries for other id, got %d", len(other))
+	}
+}
+
+func TestAuditRecorder_RecordInTransaction(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	if _, err := db.Exec(audit.CreateTableSQL("sqlite", "")); err != nil {
+		t.Fatalf("Failed to create audit table: %v", err)
+	}
+	recorder := audit.NewRecorder(db, "sqlite", audit.Options{}, &mockLogger{})
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	err = recorder.Record(context.Background(), tx, core.AuditEvent{
+		Table:      "users",
+		PrimaryKey: "42",
+		Operation:  core.AuditDelete,
+		Before:     map[string]any{"name": "Bob"},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	// The entry is discarded along with the mutation it describes
+	entries, err := recorder.History(context.Background(), "users", "42", 10)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries after rollback, got %d", len(entries))
+	}
+}
+
+func TestAuditRecorder_TableFilter(t *testing.T) {
+	recorder := newAuditRecorder(t, audit.Options{Tables: []string{"users"}})
+
+	err := recorder.Record(context.Background(), nil, core.AuditEvent{
+		Table:     "posts",
+		Operation: core.AuditCreate,
+		After:     map[string]any{"title": "hello"},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	entries, err := recorder.History(context.Background(), "posts", "", 10)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected unaudited table to be skipped, got %d entries", len(entries))
+	}
+}
+
+func TestAuditConfig_IsAudited(t *testing.T) {
+	cfg := config.AuditConfig{Enabled: true, Tables: []string{"users"}}
+	if !cfg.IsAudited("users") {
+		t.Error("Expected users to be audited")
+	}
+	if cfg.IsAudited("posts") {
+		t.Error("Expected posts not to be audited")
+	}
+
+	cfg.Enabled = false
+	if cfg.IsAudited("users") {
+		t.Error("Expected nothing to be audited when disabled")
+	}
+}
+
+// ownedPosts is a posts service honouring the owner scope like generated adapters
+type ownedPosts struct {
+	graphqlTable
+}
+
+func (s *ownedPosts) Get(ctx context.Context, id any) (any, error) {
+	row, err := s.graphqlTable.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if scope, ok := core.OwnerScopeFromContext(ctx, "posts"); ok && row.(map[string]any)[scope.Column] != scope.Owner {
+		return nil, core.NotFound("posts with id %v not found", id)
+	}
+	return row, nil
+}
+
+func TestDualServer_AuditAccess(t *testing.T) {
+	recorder := newAuditRecorder(t, audit.Options{Roles: []string{"admin", "member"}})
+	for _, owner := range []string{"alice", "bob"} {
+		id := map[string]string{"alice": "1", "bob": "2"}[owner]
+		if err := recorder.Record(core.WithActor(context.Background(), owner), nil, core.AuditEvent{
+			Table:      "posts",
+			PrimaryKey: id,
+			Operation:  core.AuditCreate,
+			After:      map[string]any{"title": "by " + owner, "user_id": owner},
+		}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	posts := &ownedPosts{graphqlTable{name: "posts", rows: []map[string]any{
+		{"id": int64(1), "title": "by alice", "user_id": "alice"},
+		{"id": int64(2), "title": "by bob", "user_id": "bob"},
+	}}}
+	if err := srv.RegisterService(posts); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+	srv.SetAuditLog(recorder)
+	srv.SetPolicyEnforcer(policy.NewEnforcer(config.PolicyConfig{
+		"posts": {OwnerColumn: "user_id", OwnerBypass: []string{"admin"}},
+	}, &mockLogger{}))
+	srv.AddMiddleware(middleware.NewAuthMiddleware([]middleware.Authenticator{
+		middleware.NewAPIKeyAuthenticator("X-API-Key", []middleware.APIKey{
+			{Name: "alice", Hash: middleware.HashSecret("alice-key"), Roles: []string{"member"}},
+			{Name: "root", Hash: middleware.HashSecret("root-key"), Roles: []string{"admin"}},
+			{Name: "guest", Hash: middleware.HashSecret("guest-key"), Roles: []string{"viewer"}},
+		}, nil, "", "", &mockLogger{}),
+	}, nil, &mockLogger{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	history := func(key, params string) (int, []string) {
+		t.Helper()
+		for i := 0; ; i++ {
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/_audit?table=posts%s", cfg.HTTPPort, params), nil)
+			req.Header.Set("Accept", core.ContentTypeJSON)
+			if key != "" {
+				req.Header.Set("X-API-Key", key)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				if i == 100 {
+					t.Fatalf("GET /_audit error = %v", err)
+				}
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			defer resp.Body.Close()
+			var body struct {
+				Entries []core.AuditEntry `json:"entries"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&body)
+			var ids []string
+			for _, entry := range body.Entries {
+				ids = append(ids, entry.PrimaryKey)
+			}
+			return resp.StatusCode, ids
+		}
+	}
+
+	// Owner-scoped callers only see the history of their own rows
+	if status, ids := history("alice-key", ""); status != http.StatusOK || fmt.Sprint(ids) != "[1]" {
+		t.Errorf("Expected alice to see post 1 only, got %d %v", status, ids)
+	}
+	if status, ids := history("root-key", ""); status != http.StatusOK || fmt.Sprint(ids) != "[2 1]" {
+		t.Errorf("Expected admin to see every post, got %d %v", status, ids)
+	}
+	if status, _ := history("guest-key", ""); status != http.StatusForbidden {
+		t.Errorf("Expected callers without an audit role to be forbidden, got %d", status)
+	}
+
+	// Entries are filtered before the limit applies, so hidden rows don't shorten pages
+	if status, ids := history("alice-key", "&limit=1"); status != http.StatusOK || fmt.Sprint(ids) != "[1]" {
+		t.Errorf("Expected alice's page of one to hold post 1, got %d %v", status, ids)
+	}
+	if status, _ := history("root-key", "&limit=100000000"); status != http.StatusBadRequest {
+		t.Errorf("Expected an oversized limit to be rejected, got %d", status)
+	}
+	if status, _ := history("", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected anonymous callers to be rejected, got %d", status)
+	}
+}
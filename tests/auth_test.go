@@ -0,0 +1,297 @@
+package apiright_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func signTestJWT(t *testing.T, alg string, claims map[string]any, sign func([]byte) []byte) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to marshal claims: %v", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256Signer(secret string) func([]byte) []byte {
+	return func(data []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(data)
+		return mac.Sum(nil)
+	}
+}
+
+func newTestAuthMiddleware(t *testing.T) *middleware.AuthMiddleware {
+	t.Helper()
+
+	jwtAuth, err := middleware.NewJWTAuthenticator(middleware.JWTOptions{
+		Algorithm: "HS256",
+		Secret:    "test-secret",
+		Issuer:    "apiright-test",
+	})
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator() error = %v", err)
+	}
+
+	keyAuth := middleware.NewAPIKeyAuthenticator("X-API-Key", []middleware.APIKey{
+		{Name: "ci", Hash: middleware.HashSecret("key-123"), Roles: []string{"admin"}},
+	}, nil, "", "", &mockLogger{})
+
+	passwordHash, err := middleware.HashPassword("wonderland")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	basicAuth := middleware.NewBasicAuthenticator("test", []middleware.BasicUser{
+		{Username: "alice", PasswordHash: passwordHash},
+	})
+
+	return middleware.NewAuthMiddleware(
+		[]middleware.Authenticator{jwtAuth, keyAuth, basicAuth},
+		[]string{"/health", "/docs*"},
+		&mockLogger{},
+	)
+}
+
+func serveWithAuth(t *testing.T, am *middleware.AuthMiddleware, req *http.Request) (*httptest.ResponseRecorder, *core.Principal) {
+	t.Helper()
+
+	var principal *core.Principal
+	handler := am.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = core.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, principal
+}
+
+func TestAuthMiddleware_JWT(t *testing.T) {
+	am := newTestAuthMiddleware(t)
+
+	token := signTestJWT(t, "HS256", map[string]any{
+		"sub":   "user-1",
+		"iss":   "apiright-test",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"editor"},
+	}, hs256Signer("test-secret"))
+
+	req := httptest.NewRequest("GET", "/api/v0/users", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec, principal := serveWithAuth(t, am, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if principal == nil || principal.Subject != "user-1" || principal.Method != "jwt" {
+		t.Fatalf("Unexpected principal: %+v", principal)
+	}
+	if !principal.HasRole("editor") {
+		t.Errorf("Expected editor role, got %v", principal.Roles)
+	}
+}
+
+func TestAuthMiddleware_JWTRejected(t *testing.T) {
+	am := newTestAuthMiddleware(t)
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		secret string
+	}{
+		{"expired", map[string]any{"sub": "u", "iss": "apiright-test", "exp": time.Now().Add(-time.Hour).Unix()}, "test-secret"},
+		{"wrong issuer", map[string]any{"sub": "u", "iss": "other"}, "test-secret"},
+		{"bad signature", map[string]any{"sub": "u", "iss": "apiright-test"}, "wrong-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v0/users", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestJWT(t, "HS256", tt.claims, hs256Signer(tt.secret)))
+
+			rec, _ := serveWithAuth(t, am, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_RS256KeyFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	jwtAuth, err := middleware.NewJWTAuthenticator(middleware.JWTOptions{Algorithm: "RS256", KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator() error = %v", err)
+	}
+
+	token := signTestJWT(t, "RS256", map[string]any{"sub": "svc"}, func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return sig
+	})
+
+	claims, err := jwtAuth.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims["sub"] != "svc" {
+		t.Errorf("Expected sub 'svc', got %v", claims["sub"])
+	}
+}
+
+func TestAuthMiddleware_APIKeyAndBasic(t *testing.T) {
+	am := newTestAuthMiddleware(t)
+
+	req := httptest.NewRequest("GET", "/api/v0/users", nil)
+	req.Header.Set("X-API-Key", "key-123")
+	rec, principal := serveWithAuth(t, am, req)
+	if rec.Code != http.StatusOK || principal == nil || principal.Subject != "ci" || !principal.HasRole("admin") {
+		t.Errorf("API key auth failed: status=%d principal=%+v", rec.Code, principal)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v0/users", nil)
+	req.SetBasicAuth("alice", "wonderland")
+	rec, principal = serveWithAuth(t, am, req)
+	if rec.Code != http.StatusOK || principal == nil || principal.Subject != "alice" {
+		t.Errorf("Basic auth failed: status=%d principal=%+v", rec.Code, principal)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v0/users", nil)
+	req.SetBasicAuth("alice", "wrong")
+	rec, _ = serveWithAuth(t, am, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for wrong password, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v0/users", nil)
+	req.SetBasicAuth("bob", "wonderland")
+	rec, _ = serveWithAuth(t, am, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for unknown user, got %d", rec.Code)
+	}
+}
+
+func TestValidateConfig_BasicAuthPasswordHash(t *testing.T) {
+	cfg := loadMiddlewareConfig(t, `
+auth:
+  enabled: true
+  basic:
+    enabled: true
+    users:
+      - username: alice
+        password_hash: `+middleware.HashSecret("wonderland")+`
+`)
+	if err := config.ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "bcrypt") {
+		t.Errorf("Expected SHA-256 password hashes to be rejected, got %v", err)
+	}
+
+	hash, err := middleware.HashPassword("wonderland")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	cfg.Auth.Basic.Users[0].PasswordHash = hash
+	if err := config.ValidateConfig(cfg); err != nil {
+		t.Errorf("ValidateConfig() error = %v", err)
+	}
+}
+
+func TestAuthMiddleware_MissingCredentials(t *testing.T) {
+	am := newTestAuthMiddleware(t)
+
+	req := httptest.NewRequest("GET", "/api/v0/users", nil)
+	req.Header.Set("Accept", "application/xml")
+	rec, _ := serveWithAuth(t, am, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+xml" {
+		t.Errorf("Expected negotiated XML problem content type, got '%s'", ct)
+	}
+	challenges := strings.Join(rec.Header().Values("WWW-Authenticate"), ", ")
+	if !strings.Contains(challenges, "Bearer") || !strings.Contains(challenges, `Basic realm="test"`) {
+		t.Errorf("Unexpected WWW-Authenticate challenges: %s", challenges)
+	}
+}
+
+func TestAuthMiddleware_PublicPaths(t *testing.T) {
+	am := newTestAuthMiddleware(t)
+
+	for _, path := range []string{"/health", "/docs", "/docs/openapi.json"} {
+		rec, _ := serveWithAuth(t, am, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected public path %s to return 200, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestAuthMiddleware_GRPC(t *testing.T) {
+	am := newTestAuthMiddleware(t)
+	interceptor := am.GRPCInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.UserService/ListUsers"}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		principal, _ := core.PrincipalFromContext(ctx)
+		return principal, nil
+	}
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key-123"))
+	resp, err := interceptor(ctx, nil, info, handler)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if principal, ok := resp.(*core.Principal); !ok || principal.Subject != "ci" {
+		t.Errorf("Unexpected principal: %+v", resp)
+	}
+	if actor := core.ActorFromContext(core.WithPrincipal(context.Background(), resp.(*core.Principal))); actor != "ci" {
+		t.Errorf("Expected actor 'ci', got '%s'", actor)
+	}
+}
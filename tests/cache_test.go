@@ -0,0 +1,249 @@
+package apiright_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/cache"
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// cachedWidgets counts reads and invalidates on writes like a generated adapter
+type cachedWidgets struct {
+	reads int
+	cache core.CacheInvalidator
+}
+
+func (s *cachedWidgets) SetCache(cache core.CacheInvalidator) { s.cache = cache }
+func (s *cachedWidgets) TableName() string                    { return "widgets" }
+
+func (s *cachedWidgets) Get(ctx context.Context, id any) (any, error) {
+	s.reads++
+	return map[string]any{"id": id, "reads": s.reads}, nil
+}
+
+func (s *cachedWidgets) List(ctx context.Context, limit, offset int32) (any, error) {
+	s.reads++
+	return []map[string]any{{"id": 1, "reads": s.reads}}, nil
+}
+
+func (s *cachedWidgets) Create(ctx context.Context, params any) (any, error) {
+	s.cache.InvalidateTable(ctx, "widgets")
+	return params, nil
+}
+
+func (s *cachedWidgets) Update(ctx context.Context, params any) (any, error) {
+	s.cache.InvalidateTable(ctx, "widgets")
+	return params, nil
+}
+
+func (s *cachedWidgets) Delete(ctx context.Context, id any) error {
+	s.cache.InvalidateTable(ctx, "widgets")
+	return nil
+}
+
+func TestDualServer_ResponseCache(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	widgets := &cachedWidgets{}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	srv.SetResponseCache(cache.New(cache.Options{TTLs: map[string]time.Duration{"widgets": time.Minute}}, &mockLogger{}))
+	if err := srv.RegisterService(widgets); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d/api/v0/widgets", cfg.HTTPPort)
+	get := func(url, accept string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("Accept", accept)
+		for i := 0; ; i++ {
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				return resp
+			}
+			if i == 100 {
+				t.Fatalf("GET %s error = %v", url, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if resp := get(baseURL, "application/json"); resp.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("Expected first list to miss, got %q", resp.Header.Get("X-Cache"))
+	}
+	resp := get(baseURL, "application/json")
+	if resp.Header.Get("X-Cache") != "HIT" || widgets.reads != 1 {
+		t.Errorf("Expected cached list after one read, got %q after %d reads", resp.Header.Get("X-Cache"), widgets.reads)
+	}
+	if resp.Header.Get("Cache-Control") != "public, max-age=60" || resp.Header.Get("Vary") != "Accept" {
+		t.Errorf("Unexpected cache headers: %v", resp.Header)
+	}
+
+	// Other representations and queries are cached separately
+	get(baseURL, "application/xml")
+	get(baseURL+"?limit=5", "application/json")
+	if widgets.reads != 3 {
+		t.Errorf("Expected separate entries per content type and query, got %d reads", widgets.reads)
+	}
+
+	req, _ := http.NewRequest("DELETE", baseURL+"/1", nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("DELETE error = %v", err)
+	} else {
+		resp.Body.Close()
+	}
+	if resp := get(baseURL, "application/json"); resp.Header.Get("X-Cache") != "MISS" || widgets.reads != 4 {
+		t.Errorf("Expected writes to invalidate the table, got %q after %d reads", resp.Header.Get("X-Cache"), widgets.reads)
+	}
+}
+
+func TestLRU_Eviction(t *testing.T) {
+	ctx := context.Background()
+	store := cache.NewLRU(2)
+
+	_ = store.Set(ctx, "users\x00a", core.CachedResponse{Body: []byte("a")}, time.Minute)
+	_ = store.Set(ctx, "users\x00b", core.CachedResponse{Body: []byte("b")}, time.Minute)
+	_, _, _ = store.Get(ctx, "users\x00a")
+	_ = store.Set(ctx, "posts\x00c", core.CachedResponse{Body: []byte("c")}, time.Minute)
+
+	if _, ok, _ := store.Get(ctx, "users\x00b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	_ = store.DeletePrefix(ctx, "users\x00")
+	if _, ok, _ := store.Get(ctx, "users\x00a"); ok || store.Len() != 1 {
+		t.Errorf("Expected only posts to remain, have %d entries", store.Len())
+	}
+}
+
+func TestDualServer_ConfigureMiddleware_Cache(t *testing.T) {
+	cfg := loadMiddlewareConfig(t, `
+cache:
+  enabled: true
+tables:
+  widgets:
+    cache_ttl: 1m
+`)
+	cfg.Server.Host = "127.0.0.1"
+	cfg.Server.HTTPPort = freePort(t)
+	cfg.Server.EnableGRPC = false
+
+	widgets := &cachedWidgets{}
+	srv := server.NewServer(&cfg.Server, t.TempDir(), nil, &mockLogger{})
+	if err := srv.ConfigureMiddleware(cfg); err != nil {
+		t.Fatalf("ConfigureMiddleware() error = %v", err)
+	}
+	if err := srv.RegisterService(widgets); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d/api/v0/widgets", cfg.Server.HTTPPort)
+	get := func(url string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", url, nil)
+		for i := 0; ; i++ {
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				return resp
+			}
+			if i == 100 {
+				t.Fatalf("GET %s error = %v", url, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	get(baseURL)
+	if resp := get(baseURL); resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("Expected cache_ttl to cache the list, got %q", resp.Header.Get("X-Cache"))
+	}
+}
+
+// headerPrincipal authenticates callers from test headers
+type headerPrincipal struct{}
+
+func (headerPrincipal) Name() string  { return "auth" }
+func (headerPrincipal) Priority() int { return 20 }
+func (headerPrincipal) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := &core.Principal{
+				Subject: "alice",
+				Method:  r.Header.Get("X-Test-Method"),
+				Roles:   strings.Split(r.Header.Get("X-Test-Roles"), ","),
+			}
+			next.ServeHTTP(w, r.WithContext(core.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func TestDualServer_ResponseCache_PrincipalScope(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	widgets := &cachedWidgets{}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	srv.SetResponseCache(cache.New(cache.Options{TTLs: map[string]time.Duration{"widgets": time.Minute}}, &mockLogger{}))
+	srv.AddMiddleware(headerPrincipal{})
+	if err := srv.RegisterService(widgets); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d/api/v0/widgets", cfg.HTTPPort)
+	get := func(method, roles string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", baseURL, nil)
+		req.Header.Set("X-Test-Method", method)
+		req.Header.Set("X-Test-Roles", roles)
+		for i := 0; ; i++ {
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				return resp
+			}
+			if i == 100 {
+				t.Fatalf("GET %s error = %v", baseURL, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	get("jwt", "reader,admin")
+	if resp := get("jwt", "admin,reader"); resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("Expected role order not to matter, got %q", resp.Header.Get("X-Cache"))
+	}
+	if resp := get("basic", "reader,admin"); resp.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("Expected a separate entry per auth method, got %q", resp.Header.Get("X-Cache"))
+	}
+	if resp := get("jwt", "reader"); resp.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("Expected a separate entry per role set, got %q", resp.Header.Get("X-Cache"))
+	}
+	if widgets.reads != 3 {
+		t.Errorf("Expected 3 reads, got %d", widgets.reads)
+	}
+}
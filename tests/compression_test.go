@@ -0,0 +1,241 @@
+package apiright_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bata94/apiright/pkg/middleware"
+	"github.com/klauspost/compress/zstd"
+)
+
+func newCompressionHandler(t *testing.T, options middleware.CompressionOptions, contentType, body string) http.Handler {
+	t.Helper()
+
+	cm, err := middleware.NewCompressionMiddleware(options, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewCompressionMiddleware() error = %v", err)
+	}
+	return cm.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestCompressionMiddleware_Negotiation(t *testing.T) {
+	body := strings.Repeat(`{"id":1,"name":"item"},`, 100)
+	handler := newCompressionHandler(t, middleware.CompressionOptions{MinSize: 256}, "application/json", body)
+
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"gzip, deflate", "gzip"},
+		{"deflate;q=1.0, gzip;q=0.5", "deflate"},
+		{"*", "zstd"},
+		{"zstd, gzip", "zstd"},
+		{"gzip;q=0", ""},
+		{"br", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/v0/items", nil)
+		req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Encoding"); got != tt.want {
+			t.Errorf("Accept-Encoding %q: expected %q, got %q", tt.acceptEncoding, tt.want, got)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/v0/items", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	if string(decoded) != body {
+		t.Error("Decompressed body does not match original")
+	}
+}
+
+func TestCompressionMiddleware_SkipsSmallAndDisallowed(t *testing.T) {
+	large := strings.Repeat("x", 2048)
+
+	for name, handler := range map[string]http.Handler{
+		"small":      newCompressionHandler(t, middleware.CompressionOptions{MinSize: 1024}, "application/json", `{"id":1}`),
+		"disallowed": newCompressionHandler(t, middleware.CompressionOptions{MinSize: 1024}, "image/png", large),
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected uncompressed response", name)
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: expected Vary: Accept-Encoding, got %q", name, rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestCompressionMiddleware_RequestDecompression(t *testing.T) {
+	cm, err := middleware.NewCompressionMiddleware(middleware.CompressionOptions{}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewCompressionMiddleware() error = %v", err)
+	}
+
+	var received string
+	handler := cm.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(`{"name":"Ada"}`))
+	_ = gz.Close()
+
+	req := httptest.NewRequest("POST", "/api/v0/users", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated || received != `{"name":"Ada"}` {
+		t.Errorf("Expected decoded body, got status %d body %q", rec.Code, received)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v0/users", strings.NewReader("data"))
+	req.Header.Set("Content-Encoding", "compress")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for unsupported encoding, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected application/problem+json, got %q", ct)
+	}
+}
+
+func TestCompressionMiddleware_Zstd(t *testing.T) {
+	body := strings.Repeat(`{"id":1,"name":"item"},`, 100)
+	handler := newCompressionHandler(t, middleware.CompressionOptions{Encodings: []string{"zstd"}, Level: 19}, "application/json", body)
+
+	req := httptest.NewRequest("GET", "/api/v0/items", nil)
+	req.Header.Set("Accept-Encoding", "zstd")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "zstd" {
+		t.Fatalf("Expected zstd response, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	decoder, err := zstd.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("zstd.NewReader() error = %v", err)
+	}
+	defer decoder.Close()
+	if decoded, _ := io.ReadAll(decoder); string(decoded) != body {
+		t.Error("Decompressed body does not match original")
+	}
+
+	cm, err := middleware.NewCompressionMiddleware(middleware.CompressionOptions{}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewCompressionMiddleware() error = %v", err)
+	}
+	var received string
+	echo := cm.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+	}))
+
+	encoder, _ := zstd.NewWriter(nil)
+	req = httptest.NewRequest("POST", "/api/v0/users", bytes.NewReader(encoder.EncodeAll([]byte(`{"name":"Ada"}`), nil)))
+	req.Header.Set("Content-Encoding", "zstd")
+	echo.ServeHTTP(httptest.NewRecorder(), req)
+	if received != `{"name":"Ada"}` {
+		t.Errorf("Expected decoded zstd body, got %q", received)
+	}
+
+	if _, err := middleware.NewCompressionMiddleware(middleware.CompressionOptions{Encodings: []string{"zstd"}, Level: 23}, &mockLogger{}); err == nil {
+		t.Error("Expected invalid zstd level to be rejected")
+	}
+}
+
+func TestCompressionMiddleware_UnregisteredEncoding(t *testing.T) {
+	_, err := middleware.NewCompressionMiddleware(middleware.CompressionOptions{Encodings: []string{"zstd-test"}}, &mockLogger{})
+	if err == nil {
+		t.Fatal("Expected error for unregistered encoding")
+	}
+
+	middleware.RegisterEncoding("zstd-test", middleware.Encoding{
+		NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	})
+	if _, err := middleware.NewCompressionMiddleware(middleware.CompressionOptions{Encodings: []string{"zstd-test"}}, &mockLogger{}); err != nil {
+		t.Errorf("Expected registered encoding to be accepted, got %v", err)
+	}
+}
+
+// closeRecorder records whether a decoding reader was closed
+type closeRecorder struct {
+	io.Reader
+	closed *bool
+}
+
+func (c closeRecorder) Close() error {
+	*c.closed = true
+	return nil
+}
+
+func TestCompressionMiddleware_DecompressionLimit(t *testing.T) {
+	closed := false
+	middleware.RegisterEncoding("gzip-closed-test", middleware.Encoding{
+		NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			reader, err := gzip.NewReader(r)
+			return closeRecorder{Reader: reader, closed: &closed}, err
+		},
+	})
+	cm, err := middleware.NewCompressionMiddleware(middleware.CompressionOptions{
+		Encodings:  []string{"gzip-closed-test"},
+		MaxDecoded: 1 << 20,
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewCompressionMiddleware() error = %v", err)
+	}
+	im := middleware.NewIdempotencyMiddleware(middleware.IdempotencyOptions{}, &mockLogger{})
+	handler := cm.Handler()(im.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the handler not to run for an oversized body")
+	})))
+
+	// 16 MiB of zeros compress to a few KB
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write(make([]byte, 16<<20))
+	_ = gz.Close()
+
+	req := httptest.NewRequest("POST", "/api/v0/users", &buf)
+	req.Header.Set("Content-Encoding", "gzip-closed-test")
+	req.Header.Set("Idempotency-Key", "bomb")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a body inflating past the cap, got %d", rec.Code)
+	}
+	if !closed {
+		t.Error("Expected the decoder to be closed when the request ends")
+	}
+}
@@ -1,6 +1,10 @@
 package apiright_test
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/bata94/apiright/pkg/core"
@@ -220,6 +224,11 @@ func TestContentNegotiatorSupportedTypes(t *testing.T) {
 		"application/yaml",
 		"application/protobuf",
 		"text/plain",
+		"application/vnd.api+json",
+		"application/hal+json",
+		"text/csv",
+		"application/x-ndjson",
+		"application/msgpack",
 	}
 
 	if len(supported) != len(expected) {
@@ -326,3 +335,72 @@ func TestParseContentHeader(t *testing.T) {
 		})
 	}
 }
+
+// upperSerializer is a plugin-style format rendering values in upper case
+type upperSerializer struct{}
+
+func (upperSerializer) MediaTypes() []string { return []string{"text/x-upper"} }
+
+func (upperSerializer) Marshal(data any) ([]byte, error) {
+	return []byte(strings.ToUpper(fmt.Sprint(data))), nil
+}
+
+func (upperSerializer) Unmarshal(data []byte, target any) error {
+	*target.(*string) = strings.ToLower(string(data))
+	return nil
+}
+
+func TestSerializerRegistry(t *testing.T) {
+	cn := core.NewContentNegotiator()
+	cn.Register(upperSerializer{})
+
+	if got := cn.DetectContentType("text/x-upper"); got != "text/x-upper" {
+		t.Fatalf("Expected registered type to be negotiable, got %q", got)
+	}
+	data, err := cn.SerializeResponse("hello", "text/x-upper")
+	if err != nil || string(data) != "HELLO" {
+		t.Errorf("Unexpected registered serialization %q (%v)", data, err)
+	}
+	var text string
+	if err := cn.DeserializeRequest([]byte("HI"), "text/x-upper", &text); err != nil || text != "hi" {
+		t.Errorf("Unexpected registered deserialization %q (%v)", text, err)
+	}
+
+	if cn.IsStreamable(core.ContentTypeJSON) || !cn.IsStreamable(core.ContentTypeCSV) || !cn.IsBulkImport(core.ContentTypeMsgpack) {
+		t.Error("Expected only CSV and NDJSON to stream, and MessagePack to import")
+	}
+}
+
+func TestNegotiateMediaType(t *testing.T) {
+	cn := core.NewContentNegotiator()
+
+	tests := []struct {
+		header string
+		want   string
+		params map[string]string
+	}{
+		{"application/vnd.acme.post+json", "application/vnd.acme.post+json", map[string]string{}},
+		{"application/vnd.acme+xml;q=0.9, application/vnd.acme+cbor", "application/vnd.acme+xml", map[string]string{}},
+		{"application/json; charset=utf-8; version=2", "application/json", map[string]string{"charset": "utf-8", "version": "2"}},
+		{"application/yaml;version=1;q=0.5, application/xml;q=0.5", "application/yaml", map[string]string{"version": "1"}},
+	}
+	for _, tt := range tests {
+		got := cn.NegotiateMediaType(tt.header)
+		if got.Type != tt.want || fmt.Sprint(got.Params) != fmt.Sprint(tt.params) {
+			t.Errorf("NegotiateMediaType(%q) = %v %v, want %s %v", tt.header, got.Type, got.Params, tt.want, tt.params)
+		}
+	}
+
+	data, err := cn.SerializeResponse(map[string]any{"a": 1}, "application/vnd.acme.post+json")
+	if err != nil || string(data) != `{"a":1}` {
+		t.Errorf("Expected +json types to serialize as JSON, got %q (%v)", data, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v0/posts/1", nil)
+	req.Header.Set("Accept", "application/vnd.acme.post+json")
+	rec := httptest.NewRecorder()
+	core.WriteError(rec, req, cn, core.NotFound("missing"))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected +json errors as application/problem+json, got %q", ct)
+	}
+}
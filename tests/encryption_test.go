@@ -0,0 +1,201 @@
+package apiright_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bata94/apiright/pkg/encryption"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestKeyring(t *testing.T, primary string, ids ...string) *encryption.Keyring {
+	t.Helper()
+
+	keys := make(map[string][]byte)
+	for i, id := range ids {
+		keys[id] = []byte(strings.Repeat(string(rune('a'+i)), 32))
+	}
+	keyring, err := encryption.NewKeyring(primary, keys)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+func TestKeyringEncryptDecrypt(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", "k1")
+	binding := encryption.NewBinding("users", "phone", 1)
+
+	encrypted, err := keyring.Encrypt("+49 123 456", binding)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(encrypted, "enc:k1:") {
+		t.Errorf("Expected key ID prefix, got %q", encrypted)
+	}
+
+	decrypted, err := keyring.Decrypt(encrypted, binding)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if decrypted != "+49 123 456" {
+		t.Errorf("Expected round trip, got %q", decrypted)
+	}
+
+	// Plaintext written before encryption was enabled is returned unchanged
+	if plain, _ := keyring.Decrypt("legacy", binding); plain != "legacy" {
+		t.Errorf("Expected plaintext passthrough, got %q", plain)
+	}
+
+	other := newTestKeyring(t, "k2", "k2")
+	if _, err := other.Decrypt(encrypted, binding); !errors.Is(err, encryption.ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+
+	// Values are bound to their table, column and row
+	for _, moved := range []encryption.Binding{
+		encryption.NewBinding("users", "phone", 2),
+		encryption.NewBinding("users", "email", 1),
+		encryption.NewBinding("admins", "phone", 1),
+	} {
+		if _, err := keyring.Decrypt(encrypted, moved); err == nil {
+			t.Errorf("Expected value moved to %+v not to decrypt", moved)
+		}
+	}
+	if plain, err := keyring.Decrypt(encrypted, encryption.NewBinding("users", "phone", "1")); err != nil || plain != "+49 123 456" {
+		t.Errorf("Expected string and integer keys to bind alike, got %q, %v", plain, err)
+	}
+}
+
+func TestKeyringFields(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", "k1")
+
+	values := map[string]any{"name": "Ada", "phone": "555-0100"}
+	if err := keyring.EncryptFields(values, "users", float64(7), "phone"); err != nil {
+		t.Fatalf("EncryptFields() error = %v", err)
+	}
+	if values["name"] != "Ada" || !encryption.IsEncrypted(values["phone"].(string)) {
+		t.Fatalf("Unexpected values after encryption: %+v", values)
+	}
+
+	type row struct {
+		ID    int64          `json:"id"`
+		Name  string         `json:"name"`
+		Phone string         `json:"phone"`
+		TaxID sql.NullString `json:"tax_id"`
+	}
+	taxID, _ := keyring.Encrypt("DE123", encryption.NewBinding("users", "tax_id", 7))
+	rows := []row{{ID: 7, Name: "Ada", Phone: values["phone"].(string), TaxID: sql.NullString{String: taxID, Valid: true}}}
+
+	if err := keyring.DecryptFields(&rows, "users", "id", "phone", "tax_id"); err != nil {
+		t.Fatalf("DecryptFields() error = %v", err)
+	}
+	if rows[0].Phone != "555-0100" || rows[0].TaxID.String != "DE123" {
+		t.Errorf("Unexpected decrypted row: %+v", rows[0])
+	}
+
+	// Ciphertext copied into another row does not decrypt
+	copied := []map[string]any{{"id": int64(8), "phone": values["phone"]}}
+	if err := keyring.DecryptFields(&copied, "users", "id", "phone"); err == nil {
+		t.Errorf("Expected copied value not to decrypt, got %v", copied[0]["phone"])
+	}
+}
+
+func TestKeyringFields_ClientCiphertext(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", "k1")
+
+	// Request values are always encrypted, even ones that look encrypted
+	for _, value := range []string{"enc:x:garbage", "enc:k1:AAAA"} {
+		values := map[string]any{"id": int64(1), "phone": value}
+		if err := keyring.EncryptFields(values, "users", 1, "phone"); err != nil {
+			t.Fatalf("EncryptFields() error = %v", err)
+		}
+		if values["phone"] == value {
+			t.Fatalf("Expected %q to be encrypted", value)
+		}
+		if err := keyring.DecryptFields(&values, "users", "id", "phone"); err != nil || values["phone"] != value {
+			t.Errorf("Expected %q back, got %v, %v", value, values["phone"], err)
+		}
+	}
+
+	values := map[string]any{"phone": 5550100}
+	if err := keyring.EncryptFields(values, "users", 1, "phone"); err == nil {
+		t.Error("Expected non-string values of encrypted columns to be rejected")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keyring.yaml")
+	content := "primary: 2024-10\nkeys:\n  2024-10: " + key + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write keyring: %v", err)
+	}
+
+	keyring, err := encryption.LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if keyring.Primary() != "2024-10" {
+		t.Errorf("Expected primary 2024-10, got %q", keyring.Primary())
+	}
+}
+
+func TestReencryptTable(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, phone TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	oldKeyring := newTestKeyring(t, "k1", "k1")
+	old, _ := oldKeyring.Encrypt("555-0100", encryption.NewBinding("users", "phone", 1))
+	if _, err := db.Exec("INSERT INTO users (id, phone) VALUES (1, ?), (2, 'plain'), (3, NULL)", old); err != nil {
+		t.Fatalf("Failed to insert rows: %v", err)
+	}
+
+	keyring := newTestKeyring(t, "k2", "k1", "k2")
+	target := encryption.Target{Table: "users", PrimaryKey: "id", Columns: []string{"phone"}}
+
+	count, err := encryption.ReencryptTable(context.Background(), db, "sqlite", keyring, target, false)
+	if err != nil {
+		t.Fatalf("ReencryptTable() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 rewritten rows, got %d", count)
+	}
+
+	var phone string
+	if err := db.QueryRow("SELECT phone FROM users WHERE id = 1").Scan(&phone); err != nil {
+		t.Fatalf("Failed to read row: %v", err)
+	}
+	if encryption.KeyID(phone) != "k2" {
+		t.Errorf("Expected value under k2, got %q", phone)
+	}
+	if plain, _ := keyring.Decrypt(phone, encryption.NewBinding("users", "phone", 1)); plain != "555-0100" {
+		t.Errorf("Expected decrypted value 555-0100, got %q", plain)
+	}
+
+	// A second run has nothing left to do
+	count, err = encryption.ReencryptTable(context.Background(), db, "sqlite", keyring, target, false)
+	if err != nil {
+		t.Fatalf("ReencryptTable() error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 rewritten rows on second run, got %d", count)
+	}
+}
@@ -0,0 +1,95 @@
+package apiright_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWriteError_Problem(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v0/users", nil)
+	rec := httptest.NewRecorder()
+	core.WriteError(rec, req, core.NewContentNegotiator(), core.Validation(core.ValidationError{Field: "email", Message: "is required"}))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected application/problem+json, got %q", ct)
+	}
+	var problem core.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if problem.Code != "validation_failed" || problem.Instance != "/api/v0/users" || len(problem.Errors) != 1 || problem.Errors[0].Field != "email" {
+		t.Errorf("Unexpected problem %+v", problem)
+	}
+
+	// Untyped errors never leak their message
+	rec = httptest.NewRecorder()
+	core.WriteError(rec, req, core.NewContentNegotiator(), errors.New("dial tcp: connection refused"))
+	if rec.Code != http.StatusInternalServerError || json.Unmarshal(rec.Body.Bytes(), &problem) != nil || problem.Detail != "Internal server error" {
+		t.Errorf("Expected generic 500 problem, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPIError_GRPCStatus(t *testing.T) {
+	err := core.Validation(core.ValidationError{Field: "title", Message: "must be at most 80 characters"})
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %s", st.Code())
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			violations = badRequest.FieldViolations
+		}
+	}
+	if len(violations) != 1 || violations[0].Field != "title" {
+		t.Errorf("Expected a BadRequest field violation for title, got %v", st.Details())
+	}
+
+	if !errors.Is(core.NotFound("users with id 1 not found"), core.ErrNotFound) {
+		t.Error("Expected NotFound to match core.ErrNotFound")
+	}
+}
+
+func TestTranslateError_SQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE)`); err != nil {
+		t.Fatalf("create table error = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (email) VALUES ('ada@example.com')`); err != nil {
+		t.Fatalf("insert error = %v", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO users (email) VALUES ('ada@example.com')`)
+	if !errors.Is(database.TranslateError(err), core.ErrConflict) {
+		t.Errorf("Expected unique violation to become a conflict, got %v", database.TranslateError(err))
+	}
+
+	_, err = db.Exec(`INSERT INTO users (email) VALUES (NULL)`)
+	apiErr, ok := core.AsAPIError(database.TranslateError(err))
+	if !ok || apiErr.Code != core.StatusUnprocessableEntity || len(apiErr.Violations) != 1 || apiErr.Violations[0].Field != "email" {
+		t.Errorf("Expected not-null violation on email, got %v", apiErr)
+	}
+
+	err = db.QueryRow(`SELECT email FROM users WHERE id = 99`).Scan(new(string))
+	if !errors.Is(database.TranslateError(err), core.ErrNotFound) {
+		t.Errorf("Expected sql.ErrNoRows to become not found, got %v", err)
+	}
+}
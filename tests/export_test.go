@@ -0,0 +1,175 @@
+package apiright_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+	"github.com/bata94/apiright/pkg/server"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// streamingTable streams its rows and parses imported cells with column types
+type streamingTable struct {
+	*graphqlTable
+}
+
+func (s *streamingTable) Stream(ctx context.Context, filter core.Filter, w core.RowWriter) error {
+	columns := []string{"id", "title", "views"}
+	if err := w.WriteHeader(columns); err != nil {
+		return err
+	}
+	for _, row := range s.rows {
+		if err := w.WriteRow([]any{row["id"], row["title"], row["views"]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *streamingTable) Resource() core.Resource {
+	return core.Resource{Type: "posts", PrimaryKey: "id", ColumnTypes: map[string]string{"title": "string", "views": "integer"}}
+}
+
+func TestDualServer_StreamAndImport(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	posts := &streamingTable{&graphqlTable{name: "posts"}}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(posts); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/v0/posts", cfg.HTTPPort)
+	send := func(method, contentType string, body []byte) (int, string) {
+		t.Helper()
+		for i := 0; ; i++ {
+			req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+			req.Header.Set("Accept", contentType)
+			if body != nil {
+				req.Header.Set("Content-Type", contentType)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				defer resp.Body.Close()
+				data, _ := io.ReadAll(resp.Body)
+				return resp.StatusCode, string(data)
+			}
+			if i == 100 {
+				t.Fatalf("%s %s error = %v", method, url, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	status, body := send(http.MethodPost, core.ContentTypeCSV, []byte("title,views\n\"engines, vol. 1\",10\nnotes,\n"))
+	if status != http.StatusOK || !strings.Contains(body, "created") {
+		t.Fatalf("CSV import failed: %d %s", status, body)
+	}
+	if views := posts.rows[0]["views"]; views != int64(10) || posts.rows[1]["views"] != nil {
+		t.Errorf("Expected CSV cells parsed by column type, got %v", posts.rows)
+	}
+
+	ndjson := []byte(`{"title":"compilers","views":3}` + "\n" + `{"title":"linkers"}` + "\n")
+	if status, body := send(http.MethodPost, core.ContentTypeNDJSON, ndjson); status != http.StatusOK {
+		t.Fatalf("NDJSON import failed: %d %s", status, body)
+	}
+	packed, _ := msgpack.Marshal([]map[string]any{{"title": "loaders"}})
+	if status, body := send(http.MethodPost, core.ContentTypeMsgpack, packed); status != http.StatusOK {
+		t.Fatalf("MessagePack import failed: %d %s", status, body)
+	}
+	if len(posts.rows) != 5 {
+		t.Fatalf("Expected 5 imported rows, got %d", len(posts.rows))
+	}
+
+	status, body = send(http.MethodPost, core.ContentTypeNDJSON, []byte(`{"title":"ok"}`+"\n{oops\n"))
+	if status != http.StatusBadRequest || !strings.Contains(body, "record 2") || !strings.Contains(body, "(1 created)") {
+		t.Errorf("Expected the malformed record to be reported, got %d %s", status, body)
+	}
+
+	_, body = send(http.MethodGet, core.ContentTypeCSV, nil)
+	if !strings.HasPrefix(body, "id,title,views\n1,\"engines, vol. 1\",10\n2,notes,\n3,compilers,3\n") {
+		t.Errorf("Unexpected CSV export:\n%s", body)
+	}
+	_, body = send(http.MethodGet, core.ContentTypeNDJSON, nil)
+	if lines := strings.Split(strings.TrimSpace(body), "\n"); len(lines) != 6 || lines[0] != `{"id":1,"title":"engines, vol. 1","views":10}` {
+		t.Errorf("Unexpected NDJSON export:\n%s", body)
+	}
+}
+
+func TestDatabaseStream(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT, body TEXT);
+		INSERT INTO posts (title, body) VALUES ('a', NULL), ('b "quoted"', 'x')`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, _ := core.NewContentNegotiator().NewRowWriter(core.ContentTypeCSV, &buf)
+	spec := database.FindSpec{Table: "posts", Columns: []string{"id", "title", "body"}, OrderBy: "id"}
+	if err := database.Stream(context.Background(), db, "sqlite", spec, core.Filter{}, w); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if want := "id,title,body\n1,a,\n2,\"b \"\"quoted\"\"\",x\n"; buf.String() != want {
+		t.Errorf("Unexpected CSV:\n got %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestTabularSerialization(t *testing.T) {
+	cn := core.NewContentNegotiator()
+	type post struct {
+		ID    int64          `json:"id"`
+		Title string         `json:"title"`
+		Body  sql.NullString `json:"body"`
+	}
+	posts := []post{{ID: 2, Title: "b", Body: sql.NullString{String: "x", Valid: true}}, {ID: 1, Title: "a"}}
+
+	data, err := cn.SerializeResponse(posts, core.ContentTypeCSV)
+	if err != nil || string(data) != "id,title,body\n2,b,x\n1,a,\n" {
+		t.Errorf("Unexpected CSV %q (%v)", data, err)
+	}
+
+	data, err = cn.SerializeResponse(posts, core.ContentTypeNDJSON)
+	if err != nil || strings.Count(string(data), "\n") != 2 {
+		t.Errorf("Unexpected NDJSON %q (%v)", data, err)
+	}
+	var rows []map[string]any
+	if err := cn.DeserializeRequest(data, core.ContentTypeNDJSON, &rows); err != nil || len(rows) != 2 || rows[1]["title"] != "a" {
+		t.Errorf("Unexpected NDJSON rows %v (%v)", rows, err)
+	}
+
+	data, err = cn.SerializeResponse(posts, core.ContentTypeMsgpack)
+	if err != nil {
+		t.Fatalf("SerializeResponse(msgpack) error = %v", err)
+	}
+	var decoded []post
+	if err := cn.DeserializeRequest(data, core.ContentTypeMsgpack, &decoded); err != nil || len(decoded) != 2 || decoded[0].Body.String != "x" {
+		t.Errorf("Unexpected MessagePack round trip %v (%v)", decoded, err)
+	}
+	raw, _ := json.Marshal(decoded)
+	if want, _ := json.Marshal(posts); string(raw) != string(want) {
+		t.Errorf("MessagePack round trip changed values: %s", raw)
+	}
+}
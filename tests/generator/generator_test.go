@@ -1,8 +1,15 @@
 package generator_test
 
 import (
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/bata94/apiright/pkg/config"
 	"github.com/bata94/apiright/pkg/core"
 	"github.com/bata94/apiright/pkg/generator"
 )
@@ -66,6 +73,37 @@ func TestProtoGenerator_NewProtoGenerator(t *testing.T) {
 	}
 }
 
+func TestProtoGenerator_Generate(t *testing.T) {
+	schema := &core.Schema{Tables: []core.Table{{
+		Name:       "posts",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "author_id", Type: "INTEGER"},
+			{Name: "title", Type: "TEXT"},
+		},
+	}}}
+	ctx := core.NewGenerationContext(t.TempDir())
+	if err := generator.NewProtoGenerator("_ar_gen", &mockLogger{}).Generate(schema, ctx); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	api, err := os.ReadFile(filepath.Join(ctx.ProjectDir, "gen", "proto", "api_ar_gen.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"returns (DeletePostResponse);\n}",
+		"int64 authorId = 1 [json_name = \"author_id\"];", // Foreign keys are not primary keys
+		"db.Post data = 1;",
+		"repeated db.Post data = 1;",
+	} {
+		if !strings.Contains(string(api), want) {
+			t.Errorf("Expected api proto to contain %q:\n%s", want, api)
+		}
+	}
+}
+
 func TestServiceGenerator_NewServiceGenerator(t *testing.T) {
 	logger := &mockLogger{}
 	gen := generator.NewServiceGenerator("_ar_gen", logger)
@@ -75,6 +113,129 @@ func TestServiceGenerator_NewServiceGenerator(t *testing.T) {
 	}
 }
 
+func TestXMLGenerator_Generate(t *testing.T) {
+	schema := &core.Schema{Tables: []core.Table{{
+		Name:       "posts",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER", AutoIncrement: true},
+			{Name: "title", Type: "TEXT"},
+			{Name: "published_at", Type: "TIMESTAMP", Nullable: true},
+			{Name: "secret", Type: "TEXT", Visibility: core.VisibilityHidden},
+		},
+	}}}
+	ctx := core.NewGenerationContext(t.TempDir()).WithServerConfig(core.ServerConfig{APIVersion: "v0", DocsPath: "/docs"})
+	if err := generator.NewXMLGenerator(&mockLogger{}).Generate(schema, ctx); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	xsd, err := os.ReadFile(filepath.Join(ctx.ProjectDir, "gen", "xml", "posts.xsd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`targetNamespace="urn:apiright:posts" elementFormDefault="qualified"`,
+		`<xs:element name="id" type="xs:long" minOccurs="0"/>`,
+		`<xs:element name="published_at" type="xs:dateTime" minOccurs="0" nillable="true"/>`,
+		`<xs:element name="post" type="Post"/>`,
+		`<xs:element ref="post" minOccurs="0" maxOccurs="unbounded"/>`,
+	} {
+		if !strings.Contains(string(xsd), want) {
+			t.Errorf("Expected XSD to contain %q:\n%s", want, xsd)
+		}
+	}
+	if strings.Contains(string(xsd), "secret") {
+		t.Error("Expected hidden columns to be left out of the XSD")
+	}
+
+	// The OpenAPI spec names XML elements after the XSD and links to it
+	if err := generator.NewOpenAPIGenerator("_ar_gen", &mockLogger{}).Generate(schema, ctx); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	spec, err := os.ReadFile(filepath.Join(ctx.ProjectDir, "gen", "openapi", "openapi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"url: /docs/xml/posts.xsd", "name: posts", "wrapped: true", "namespace: urn:apiright:posts"} {
+		if !strings.Contains(string(spec), want) {
+			t.Errorf("Expected OpenAPI spec to contain %q", want)
+		}
+	}
+}
+
+func TestAdapterGenerator_FrozenVersions(t *testing.T) {
+	frozen := &core.Schema{Tables: []core.Table{{
+		Name:       "posts",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER", AutoIncrement: true},
+			{Name: "title", Type: "TEXT"},
+			{Name: "body", Type: "TEXT"},
+			{Name: "legacy", Type: "TEXT", Nullable: true},
+		},
+	}}}
+	schema := &core.Schema{Tables: []core.Table{{
+		Name:       "posts",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER", AutoIncrement: true},
+			{Name: "title", Type: "TEXT"},
+			{Name: "content", Type: "TEXT"},
+			{Name: "summary", Type: "TEXT", Nullable: true},
+		},
+	}}}
+
+	ctx := core.NewGenerationContext(t.TempDir()).WithModulePath("example.com/blog")
+	if err := generator.FreezeSchema(frozen, ctx, "v1"); err != nil {
+		t.Fatalf("FreezeSchema() error = %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Server.APIVersion = "v2"
+	cfg.Server.Versions = []config.VersionConfig{
+		{Name: "v1", Deprecated: "2026-01-01", Renames: map[string]map[string]string{"posts": {"body": "content"}}},
+		{Name: "v0"},
+	}
+	if err := generator.NewAdapterGenerator("_ar_gen", cfg, &mockLogger{}).GenerateAdapters(schema, ctx); err != nil {
+		t.Fatalf("GenerateAdapters() error = %v", err)
+	}
+
+	adapters := filepath.Join(ctx.ProjectDir, "gen", "go", "adapters")
+	version, err := os.ReadFile(filepath.Join(adapters, "version_v1_ar_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := format.Source(version); err != nil {
+		t.Fatalf("Generated version is not valid Go: %v\n%s", err, version)
+	}
+	for _, want := range []string{
+		"var apiV1 = server.APIVersion{",
+		`{Name: "legacy", Type: "TEXT", Nullable: true},`,
+		`Readable: []string{"id", "title", "body", "legacy"},`,
+		`Writable: []string{"id", "title", "body"},`,
+		// The renamed column maps to its current name; the dropped one is not mapped
+		`Columns:  map[string]string{"id": "id", "title": "title", "body": "content"},`,
+	} {
+		if !strings.Contains(string(version), want) {
+			t.Errorf("Expected version file to contain %q:\n%s", want, version)
+		}
+	}
+
+	// Versions without a snapshot serve the current schema and are not mounted
+	init, err := os.ReadFile(filepath.Join(adapters, "init_ar_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(init), "srv.MountVersion(apiV1)") || strings.Contains(string(init), "apiV0") {
+		t.Errorf("Expected only v1 to be mounted:\n%s", init)
+	}
+
+	// Renames must name a frozen column and an existing current one
+	cfg.Server.Versions[0].Renames = map[string]map[string]string{"posts": {"body": "text"}}
+	if err := generator.NewAdapterGenerator("_ar_gen", cfg, &mockLogger{}).GenerateAdapters(schema, ctx); err == nil {
+		t.Error("Expected a rename to a missing column to be rejected")
+	}
+}
+
 func TestOpenAPIGenerator_NewOpenAPIGenerator(t *testing.T) {
 	logger := &mockLogger{}
 	gen := generator.NewOpenAPIGenerator("_ar_gen", logger)
@@ -198,3 +359,537 @@ func TestGetExampleValue(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLGenerator_OwnerQueries(t *testing.T) {
+	dir := t.TempDir()
+	ctx := core.NewGenerationContext(dir)
+
+	schema := &core.Schema{Tables: []core.Table{{
+		Name:        "posts",
+		PrimaryKey:  []string{"id"},
+		OwnerColumn: "user_id",
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "user_id", Type: "INTEGER"},
+			{Name: "title", Type: "TEXT"},
+		},
+	}}}
+
+	gen := generator.NewSQLGenerator("_ar_gen", generator.DialectSQLite, &mockLogger{})
+	if err := gen.GenerateQueries(schema, ctx); err != nil {
+		t.Fatalf("GenerateQueries() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "gen", "sql", "posts_ar_gen.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read generated queries: %v", err)
+	}
+
+	expected := []string{
+		"SELECT id, user_id, title FROM posts WHERE id = ? AND user_id = ? LIMIT 1;",
+		"SELECT id, user_id, title FROM posts WHERE user_id = ? ORDER BY id LIMIT ? OFFSET ?;",
+		"UPDATE posts SET title = ? WHERE id = ? AND user_id = ?;",
+		"DELETE FROM posts WHERE id = ? AND user_id = ?;",
+	}
+	for _, query := range expected {
+		if !strings.Contains(string(content), query) {
+			t.Errorf("Expected generated SQL to contain %q", query)
+		}
+	}
+}
+
+func TestSchemaParser_VisibilityAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	migration := `CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL,
+    password_hash TEXT NOT NULL, -- @apiright:writeonly
+    -- @apiright:readonly
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    internal_note TEXT -- @apiright:hidden
+);`
+	if err := os.WriteFile(filepath.Join(dir, "001_users.sql"), []byte(migration), 0644); err != nil {
+		t.Fatalf("Failed to write migration: %v", err)
+	}
+
+	schema, err := generator.NewSchemaParser("sqlite", &mockLogger{}).ParseMigrations(dir)
+	if err != nil {
+		t.Fatalf("ParseMigrations() error = %v", err)
+	}
+	if len(schema.Tables) != 1 {
+		t.Fatalf("Expected 1 table, got %d", len(schema.Tables))
+	}
+
+	expected := map[string]string{
+		"id":            "",
+		"email":         "",
+		"password_hash": core.VisibilityWriteOnly,
+		"created_at":    core.VisibilityReadOnly,
+		"internal_note": core.VisibilityHidden,
+	}
+	columns := schema.Tables[0].Columns
+	if len(columns) != len(expected) {
+		t.Fatalf("Expected %d columns, got %d", len(expected), len(columns))
+	}
+	for _, col := range columns {
+		if col.Visibility != expected[col.Name] {
+			t.Errorf("Column %s: expected visibility %q, got %q", col.Name, expected[col.Name], col.Visibility)
+		}
+	}
+}
+
+func TestSchemaParser_ForeignKeys(t *testing.T) {
+	dir := t.TempDir()
+	migration := `CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL
+);
+CREATE TABLE posts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    author_id INTEGER NOT NULL,
+    editor_id INTEGER REFERENCES users ON DELETE SET NULL,
+    CONSTRAINT fk_author FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+	if err := os.WriteFile(filepath.Join(dir, "001_schema.sql"), []byte(migration), 0644); err != nil {
+		t.Fatalf("Failed to write migration: %v", err)
+	}
+
+	schema, err := generator.NewSchemaParser("sqlite", &mockLogger{}).ParseMigrations(dir)
+	if err != nil {
+		t.Fatalf("ParseMigrations() error = %v", err)
+	}
+
+	expected := []core.ForeignKey{
+		{Columns: []string{"editor_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "SET NULL"},
+		{Name: "fk_author", Columns: []string{"author_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "CASCADE"},
+	}
+	if got := schema.Tables[1].ForeignKeys; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected foreign keys %+v, got %+v", expected, got)
+	}
+}
+
+func TestSQLGenerator_ColumnVisibility(t *testing.T) {
+	dir := t.TempDir()
+	ctx := core.NewGenerationContext(dir)
+
+	schema := &core.Schema{Tables: []core.Table{{
+		Name:       "users",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "email", Type: "TEXT"},
+			{Name: "password_hash", Type: "TEXT", Visibility: core.VisibilityWriteOnly},
+			{Name: "created_at", Type: "TIMESTAMP", Visibility: core.VisibilityReadOnly},
+			{Name: "internal_note", Type: "TEXT", Visibility: core.VisibilityHidden},
+		},
+	}}}
+
+	gen := generator.NewSQLGenerator("_ar_gen", generator.DialectSQLite, &mockLogger{})
+	if err := gen.GenerateQueries(schema, ctx); err != nil {
+		t.Fatalf("GenerateQueries() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "gen", "sql", "users_ar_gen.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read generated queries: %v", err)
+	}
+
+	expected := []string{
+		"SELECT id, email, created_at FROM users WHERE id = ? LIMIT 1;",
+		"INSERT INTO users (email, password_hash) VALUES (?, ?);",
+		"UPDATE users SET email = ?, password_hash = ? WHERE id = ?;",
+	}
+	for _, query := range expected {
+		if !strings.Contains(string(content), query) {
+			t.Errorf("Expected generated SQL to contain %q", query)
+		}
+	}
+}
+
+// Stand-ins for sqlc's output and an app recording the audit entry of a create
+const (
+	stubQueries = `package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+type User struct {
+	ID   int64  ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type CreateUser_ar_genParams struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type UpdateUser_ar_genParams struct {
+	Name string ` + "`json:\"name\"`" + `
+	ID   int64  ` + "`json:\"id\"`" + `
+}
+
+type ListUser_ar_genParams struct {
+	Limit  int64 ` + "`json:\"limit\"`" + `
+	Offset int64 ` + "`json:\"offset\"`" + `
+}
+
+type Querier interface {
+	CreateUser_ar_gen(ctx context.Context, arg CreateUser_ar_genParams) (sql.Result, error)
+	DeleteUser_ar_gen(ctx context.Context, id int64) error
+	GetUser_ar_gen(ctx context.Context, id int64) (User, error)
+	ListUser_ar_gen(ctx context.Context, arg ListUser_ar_genParams) ([]User, error)
+	UpdateUser_ar_gen(ctx context.Context, arg UpdateUser_ar_genParams) error
+}
+// Queries runs the queries of a DBTX that implements them
+type Queries struct{ Querier }
+
+func New(db DBTX) *Queries {
+	q, _ := db.(Querier)
+	return &Queries{q}
+}
+`
+	stubMain = `package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"example.com/blog/gen/go"
+	"example.com/blog/gen/go/adapters"
+	"github.com/bata94/apiright/pkg/core"
+)
+
+type inserted int64
+
+func (i inserted) LastInsertId() (int64, error) { return int64(i), nil }
+func (i inserted) RowsAffected() (int64, error) { return 1, nil }
+
+type querier struct{ db.Querier }
+
+func (querier) CreateUser_ar_gen(ctx context.Context, arg db.CreateUser_ar_genParams) (sql.Result, error) {
+	return inserted(7), nil
+}
+
+func (querier) GetUser_ar_gen(ctx context.Context, id int64) (db.User, error) {
+	return db.User{ID: id, Name: "ada"}, nil
+}
+
+type auditor struct{ events []core.AuditEvent }
+
+func (a *auditor) Record(ctx context.Context, conn core.Execer, event core.AuditEvent) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func main() {
+	logger, _ := core.NewLogger(false)
+	audit := &auditor{}
+	adapter := adapters.NewUserServiceAdapter(querier{}, logger)
+	adapter.SetAuditor(audit)
+	created, err := adapter.Create(context.Background(), map[string]any{"name": "ada"})
+	if err != nil {
+		panic(err)
+	}
+	body, _ := json.Marshal(created)
+	fmt.Printf("%s %s %s\n", audit.events[0].Operation, audit.events[0].PrimaryKey, body)
+}
+`
+)
+
+// runGeneratedAdapter generates the adapters of schema and runs main against
+// them, with queries standing in for sqlc's output. It returns the output.
+func runGeneratedAdapter(t *testing.T, schema *core.Schema, queries, main string) string {
+	t.Helper()
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not available")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := core.NewGenerationContext(t.TempDir()).WithModulePath("example.com/blog")
+	if err := generator.NewAdapterGenerator("_ar_gen", config.DefaultConfig(), &mockLogger{}).GenerateAdapters(schema, ctx); err != nil {
+		t.Fatalf("GenerateAdapters() error = %v", err)
+	}
+
+	// Build the adapter alone against the stand-ins; Init needs the full sqlc output
+	if err := os.Remove(filepath.Join(ctx.ProjectDir, "gen", "go", "adapters", "init_ar_gen.go")); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod": "module example.com/blog\n\ngo 1.25.5\n\n" +
+			"require (\n\tgithub.com/bata94/apiright v0.0.0\n\tgoogle.golang.org/protobuf v1.36.9\n)\n\n" +
+			"replace github.com/bata94/apiright => " + root + "\n",
+		"go.sum":       string(sums),
+		"gen/go/db.go": queries,
+		"main.go":      main,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(ctx.ProjectDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "run", ".")
+	cmd.Dir = ctx.ProjectDir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off", "GOPROXY=off")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Generated adapter failed: %v\n%s", err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestAdapterGenerator_CreateAuditsInsertedKey(t *testing.T) {
+	schema := &core.Schema{Tables: []core.Table{{
+		Name:       "users",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER", AutoIncrement: true},
+			{Name: "name", Type: "TEXT"},
+		},
+	}}}
+
+	if got, want := runGeneratedAdapter(t, schema, stubQueries, stubMain), `create 7 {"id":7,"name":"ada"}`; got != want {
+		t.Errorf("Create = %q, want %q", got, want)
+	}
+}
+
+// Stand-ins for an accounts table keyed by account_id with an encrypted phone,
+// and an app that creates and then updates an account
+const (
+	stubAccountQueries = `package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+type Account struct {
+	AccountID int64  ` + "`json:\"account_id\"`" + `
+	Phone     string ` + "`json:\"phone\"`" + `
+}
+
+type CreateAccount_ar_genParams struct {
+	Phone string ` + "`json:\"phone\"`" + `
+}
+
+type UpdateAccount_ar_genParams struct {
+	Phone     string ` + "`json:\"phone\"`" + `
+	AccountID int64  ` + "`json:\"account_id\"`" + `
+}
+
+type ListAccount_ar_genParams struct {
+	Limit  int64 ` + "`json:\"limit\"`" + `
+	Offset int64 ` + "`json:\"offset\"`" + `
+}
+
+type Querier interface {
+	CreateAccount_ar_gen(ctx context.Context, arg CreateAccount_ar_genParams) (sql.Result, error)
+	DeleteAccount_ar_gen(ctx context.Context, accountID int64) error
+	GetAccount_ar_gen(ctx context.Context, accountID int64) (Account, error)
+	ListAccount_ar_gen(ctx context.Context, arg ListAccount_ar_genParams) ([]Account, error)
+	UpdateAccount_ar_gen(ctx context.Context, arg UpdateAccount_ar_genParams) error
+}
+// Queries runs the queries of a DBTX that implements them
+type Queries struct{ Querier }
+
+func New(db DBTX) *Queries {
+	q, _ := db.(Querier)
+	return &Queries{q}
+}
+`
+	stubAccountMain = `package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"example.com/blog/gen/go"
+	"example.com/blog/gen/go/adapters"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+	"github.com/bata94/apiright/pkg/encryption"
+)
+
+type inserted int64
+
+func (i inserted) LastInsertId() (int64, error) { return int64(i), nil }
+func (i inserted) RowsAffected() (int64, error) { return 1, nil }
+
+// accounts keeps encrypted rows
+type accounts struct {
+	db.Querier
+	db.DBTX
+	rows     map[int64]db.Account
+	failSeal bool
+}
+
+func (a *accounts) CreateAccount_ar_gen(ctx context.Context, arg db.CreateAccount_ar_genParams) (sql.Result, error) {
+	id := int64(7 + len(a.rows))
+	a.rows[id] = db.Account{AccountID: id, Phone: arg.Phone}
+	return inserted(id), nil
+}
+
+func (a *accounts) GetAccount_ar_gen(ctx context.Context, accountID int64) (db.Account, error) {
+	row, ok := a.rows[accountID]
+	if !ok {
+		return db.Account{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+func (a *accounts) DeleteAccount_ar_gen(ctx context.Context, accountID int64) error {
+	delete(a.rows, accountID)
+	return nil
+}
+
+func (a *accounts) UpdateAccount_ar_gen(ctx context.Context, arg db.UpdateAccount_ar_genParams) error {
+	if _, ok := a.rows[arg.AccountID]; !ok {
+		return fmt.Errorf("no account %d", arg.AccountID)
+	}
+	a.rows[arg.AccountID] = db.Account{AccountID: arg.AccountID, Phone: arg.Phone}
+	return nil
+}
+
+// ExecContext applies the UPDATE that seals a created row for its key
+func (a *accounts) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if a.failSeal {
+		return nil, fmt.Errorf("seal failed")
+	}
+	id := args[1].(int64)
+	a.rows[id] = db.Account{AccountID: id, Phone: args[0].(string)}
+	return inserted(id), nil
+}
+
+// store holds the committed rows; transactions work on a copy
+type store struct {
+	accounts
+	rolledBack int
+}
+
+func (s *store) Begin(ctx context.Context) (database.Tx, error) {
+	rows := make(map[int64]db.Account, len(s.rows))
+	for id, row := range s.rows {
+		rows[id] = row
+	}
+	return &tx{accounts: accounts{rows: rows, failSeal: s.failSeal}, store: s}, nil
+}
+
+type tx struct {
+	accounts
+	store *store
+}
+
+func (t *tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (t *tx) Commit() error {
+	t.store.rows = t.rows
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	t.store.rolledBack++
+	return nil
+}
+
+// auditor records the keys of entries written inside a transaction
+type auditor struct {
+	keys    []string
+	outside int
+	fail    bool
+}
+
+func (a *auditor) Record(ctx context.Context, conn core.Execer, event core.AuditEvent) error {
+	if a.fail {
+		return fmt.Errorf("audit log unavailable")
+	}
+	if _, ok := conn.(*tx); !ok {
+		a.outside++
+	}
+	a.keys = append(a.keys, event.PrimaryKey)
+	return nil
+}
+
+func main() {
+	// Only fatal entries, so the expected errors do not reach the output
+	logger, _ := core.NewLoggerWithLevel("fatal", false)
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		panic(err)
+	}
+	s := &store{accounts: accounts{rows: map[int64]db.Account{}}}
+	audit := &auditor{}
+	adapter := adapters.NewAccountServiceAdapter(s, logger)
+	adapter.SetCipher(keyring)
+	adapter.SetDB(s, "sqlite")
+	adapter.SetAuditor(audit)
+
+	ctx := context.Background()
+	if _, err := adapter.Create(ctx, map[string]any{"phone": "111"}); err != nil {
+		panic(err)
+	}
+	// The HTTP handler passes the path ID under the primary key
+	if _, err := adapter.Update(ctx, map[string]any{"account_id": int64(7), "phone": "222"}); err != nil {
+		panic(err)
+	}
+	got, err := adapter.Get(ctx, int64(7))
+	if err != nil {
+		panic(err)
+	}
+
+	// A failed seal rolls the insert back
+	s.failSeal = true
+	_, createErr := adapter.Create(ctx, map[string]any{"phone": "333"})
+
+	// A failed audit entry rolls the delete back
+	s.failSeal = false
+	audit.fail = true
+	deleteErr := adapter.Delete(ctx, int64(7))
+	_, kept := s.rows[7]
+
+	fmt.Printf("%s %t %v %d %t %d %d %t %t\n", got.(db.Account).Phone, encryption.IsEncrypted(s.rows[7].Phone), audit.keys, audit.outside,
+		createErr != nil, len(s.rows), s.rolledBack, deleteErr != nil, kept)
+}
+`
+)
+
+func TestAdapterGenerator_EncryptedUpdateRoundTrip(t *testing.T) {
+	schema := &core.Schema{Tables: []core.Table{{
+		Name:       "accounts",
+		PrimaryKey: []string{"account_id"},
+		Columns: []core.Column{
+			{Name: "account_id", Type: "INTEGER", AutoIncrement: true},
+			{Name: "phone", Type: "TEXT", Encrypted: true},
+		},
+	}}}
+
+	if got, want := runGeneratedAdapter(t, schema, stubAccountQueries, stubAccountMain), `222 true [7 7] 0 true 1 2 true true`; got != want {
+		t.Errorf("Update round trip = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,313 @@
+package apiright_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/database"
+	"github.com/bata94/apiright/pkg/generator"
+	"github.com/bata94/apiright/pkg/graphql"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// graphqlTestSchema has users with posts through posts.user_id
+var graphqlTestSchema = &core.Schema{Tables: []core.Table{
+	{
+		Name:       "users",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER", AutoIncrement: true},
+			{Name: "name", Type: "TEXT"},
+			{Name: "password", Type: "TEXT", Visibility: core.VisibilityWriteOnly},
+		},
+	},
+	{
+		Name:       "posts",
+		PrimaryKey: []string{"id"},
+		Columns: []core.Column{
+			{Name: "id", Type: "INTEGER", AutoIncrement: true},
+			{Name: "user_id", Type: "INTEGER"},
+			{Name: "title", Type: "TEXT"},
+			{Name: "published_at", Type: "TIMESTAMP", Nullable: true},
+		},
+		ForeignKeys: []core.ForeignKey{{Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}},
+	},
+}}
+
+// graphqlTable is an in-memory table service implementing core.Finder
+type graphqlTable struct {
+	name  string
+	rows  []map[string]any
+	finds int
+}
+
+func (s *graphqlTable) TableName() string { return s.name }
+
+func (s *graphqlTable) Get(ctx context.Context, id any) (any, error) {
+	for _, row := range s.rows {
+		if fmt.Sprint(row["id"]) == fmt.Sprint(id) {
+			return row, nil
+		}
+	}
+	return nil, core.NotFound("%s with id %v not found", s.name, id)
+}
+
+func (s *graphqlTable) List(ctx context.Context, limit, offset int32) (any, error) {
+	return s.rows, nil
+}
+
+func (s *graphqlTable) Create(ctx context.Context, params any) (any, error) {
+	row := params.(map[string]any)
+	row["id"] = int64(len(s.rows) + 1)
+	s.rows = append(s.rows, row)
+	return row, nil
+}
+
+// Update replaces the whole row, as generated adapters write every writable column
+func (s *graphqlTable) Update(ctx context.Context, params any) (any, error) {
+	row := params.(map[string]any)
+	for i, stored := range s.rows {
+		if fmt.Sprint(stored["id"]) == fmt.Sprint(row["id"]) {
+			s.rows[i] = row
+		}
+	}
+	return row, nil
+}
+
+func (s *graphqlTable) Delete(ctx context.Context, id any) error {
+	return nil
+}
+
+func (s *graphqlTable) Find(ctx context.Context, filter core.Filter) ([]map[string]any, error) {
+	s.finds++
+	rows := []map[string]any{}
+	perKey := map[string]int32{}
+	for _, row := range s.rows {
+		matches := true
+		for column, values := range filter.Equal {
+			found := false
+			for _, value := range values {
+				found = found || fmt.Sprint(row[column]) == fmt.Sprint(value)
+			}
+			matches = matches && found
+		}
+		if !matches {
+			continue
+		}
+		if filter.PerKey != "" {
+			key := fmt.Sprint(row[filter.PerKey])
+			perKey[key]++
+			if perKey[key] <= filter.Offset || (filter.Limit > 0 && perKey[key] > filter.Offset+filter.Limit) {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+	if filter.PerKey == "" && filter.Limit > 0 && int(filter.Limit) < len(rows) {
+		rows = rows[:filter.Limit]
+	}
+	return rows, nil
+}
+
+func TestGraphQLGenerator_BuildSchema(t *testing.T) {
+	sdl := generator.NewGraphQLGenerator(&mockLogger{}).BuildSchema(graphqlTestSchema)
+
+	if _, err := graphql.ParseSchema(sdl, &mockLogger{}); err != nil {
+		t.Fatalf("Generated schema is invalid: %v\n%s", err, sdl)
+	}
+	for _, want := range []string{
+		`type User @table(name: "users")`,
+		"posts(limit: Int, offset: Int): [Post!]! @relation",
+		`user: User @relation(table: "users", column: "id", via: "user_id")`,
+		"published_at: Time",
+		"user_id_in: [Int!]",
+		"createUser(input: CreateUserInput!): User",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("Expected schema to contain %q", want)
+		}
+	}
+	if strings.Contains(sdl, "  password: String!\n}\n\ninput UserFilter") {
+		t.Error("Expected write-only column to be left out of the User type")
+	}
+}
+
+func TestDualServer_GraphQL(t *testing.T) {
+	projectDir := t.TempDir()
+	schemaDir := filepath.Join(projectDir, "gen", "graphql")
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sdl := generator.NewGraphQLGenerator(&mockLogger{}).BuildSchema(graphqlTestSchema)
+	if err := os.WriteFile(filepath.Join(schemaDir, "schema.graphql"), []byte(sdl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	users := &graphqlTable{name: "users", rows: []map[string]any{
+		{"id": int64(1), "name": "ada"},
+		{"id": int64(2), "name": "grace"},
+	}}
+	posts := &graphqlTable{name: "posts", rows: []map[string]any{
+		{"id": int64(1), "user_id": int64(1), "title": "engines"},
+		{"id": int64(2), "user_id": int64(1), "title": "notes"},
+		{"id": int64(3), "user_id": int64(2), "title": "compilers"},
+	}}
+	srv := server.NewServer(&cfg, projectDir, nil, &mockLogger{})
+	for _, service := range []any{users, posts} {
+		if err := srv.RegisterService(service); err != nil {
+			t.Fatalf("RegisterService() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/graphql", cfg.HTTPPort)
+	execute := func(query string, variables map[string]any) map[string]any {
+		t.Helper()
+		body, _ := json.Marshal(graphql.Request{Query: query, Variables: variables})
+		for i := 0; ; i++ {
+			resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+			if err == nil {
+				defer resp.Body.Close()
+				var result map[string]any
+				if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				return result
+			}
+			if i == 100 {
+				t.Fatalf("POST %s error = %v", url, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Relationships are loaded with one Find per level, not one per parent
+	result := execute(`{ users { name posts { title user { name } } } }`, nil)
+	if result["errors"] != nil {
+		t.Fatalf("Unexpected errors: %v", result["errors"])
+	}
+	got, _ := json.Marshal(result["data"])
+	want := `{"users":[{"name":"ada","posts":[{"title":"engines","user":{"name":"ada"}},{"title":"notes","user":{"name":"ada"}}]},` +
+		`{"name":"grace","posts":[{"title":"compilers","user":{"name":"grace"}}]}]}`
+	if string(got) != want {
+		t.Errorf("Unexpected data:\n got %s\nwant %s", got, want)
+	}
+	if posts.finds != 1 || users.finds != 2 {
+		t.Errorf("Expected batched loads, got %d posts and %d users finds", posts.finds, users.finds)
+	}
+
+	// Relation pages apply per parent
+	result = execute(`{ users { posts(limit: 1) { title } } }`, nil)
+	if got, _ := json.Marshal(result["data"]); string(got) != `{"users":[{"posts":[{"title":"engines"}]},{"posts":[{"title":"compilers"}]}]}` {
+		t.Errorf("Unexpected paged relation data: %s", got)
+	}
+
+	// Deep or expensive operations are rejected before anything is resolved
+	finds := posts.finds
+	result = execute(`{ users { posts { user { posts { title } } } } }`, nil)
+	if !strings.Contains(fmt.Sprint(result["errors"]), "query cost") || posts.finds != finds {
+		t.Errorf("Expected the cost limit to reject the query, got %v", result["errors"])
+	}
+	deep := "title"
+	for i := 0; i < graphql.MaxDepth; i++ {
+		deep = "user { posts(limit: 1) { " + deep + " } }"
+	}
+	result = execute(`{ posts(limit: 1) { `+deep+` } }`, nil)
+	if !strings.Contains(fmt.Sprint(result["errors"]), "query depth") {
+		t.Errorf("Expected the depth limit to reject the query, got %v", result["errors"])
+	}
+
+	result = execute(`query($ids: [Int!]) { posts(filter: {user_id_in: $ids}, limit: 1) { id title } }`, map[string]any{"ids": []int{2}})
+	if got, _ := json.Marshal(result["data"]); string(got) != `{"posts":[{"id":"3","title":"compilers"}]}` {
+		t.Errorf("Unexpected filtered data: %s", got)
+	}
+
+	result = execute(`mutation { createUser(input: {name: "linus", password: "secret"}) { id name } }`, nil)
+	if got, _ := json.Marshal(result["data"]); string(got) != `{"createUser":{"id":"3","name":"linus"}}` {
+		t.Errorf("Unexpected mutation data: %s", got)
+	}
+
+	result = execute(`{ __schema { queryType { name } } __type(name: "Post") { fields { name type { kind ofType { name } } } } }`, nil)
+	if got, _ := json.Marshal(result["data"]); !strings.Contains(string(got), `"queryType":{"name":"Query"}`) ||
+		!strings.Contains(string(got), `{"name":"user","type":{"kind":"OBJECT","ofType":null}}`) {
+		t.Errorf("Unexpected introspection data: %s", got)
+	}
+
+	result = execute(`{ user(id: 9) { name } posts(limit: 0) { id } }`, nil)
+	fieldErrors, _ := result["errors"].([]any)
+	if len(fieldErrors) != 1 || !strings.Contains(fmt.Sprint(fieldErrors[0]), "bad_request") {
+		t.Errorf("Expected one bad_request error for the limit, got %v", result["errors"])
+	}
+	if got, _ := json.Marshal(result["data"]); string(got) != `null` {
+		t.Errorf("Expected the failed non-null list to null data, got %s", got)
+	}
+}
+
+func TestDatabaseFind(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, title TEXT, secret TEXT);
+		INSERT INTO posts (user_id, title, secret) VALUES (1, 'a', 'x'), (1, 'b', 'x'), (2, 'c', 'x'), (3, 'd', 'x')`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	spec := database.FindSpec{
+		Table:      "posts",
+		Columns:    []string{"id", "title"},
+		Filterable: []string{"id", "user_id", "title"},
+		OrderBy:    "id",
+		Scope:      map[string]any{"secret": "x"},
+	}
+	rows, err := database.Find(context.Background(), db, "sqlite", spec, core.Filter{
+		Equal:  map[string][]any{"user_id": {int64(1), int64(2)}},
+		Offset: 1,
+	})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(rows) != 2 || rows[0]["title"] != "b" || rows[1]["title"] != "c" {
+		t.Errorf("Unexpected rows: %v", rows)
+	}
+
+	// PerKey pages every user's posts on their own
+	rows, err = database.Find(context.Background(), db, "sqlite", spec, core.Filter{
+		Equal:  map[string][]any{"user_id": {int64(1), int64(2), int64(3)}},
+		Limit:  1,
+		Offset: 1,
+		PerKey: "user_id",
+	})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0]["title"] != "b" {
+		t.Errorf("Unexpected per-key rows: %v", rows)
+	}
+
+	_, err = database.Find(context.Background(), db, "sqlite", spec, core.Filter{Equal: map[string][]any{"secret": {"x"}}})
+	if apiErr, ok := core.AsAPIError(err); !ok || apiErr.Code != core.StatusBadRequest {
+		t.Errorf("Expected filtering on an unlisted column to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package apiright_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// echoWidgets records the params the HTTP handlers decode for it
+type echoWidgets struct {
+	created map[string]any
+	updated map[string]any
+}
+
+func (s *echoWidgets) TableName() string { return "widgets" }
+
+func (s *echoWidgets) Get(ctx context.Context, id any) (any, error) {
+	return map[string]any{"id": id}, nil
+}
+
+func (s *echoWidgets) List(ctx context.Context, limit, offset int32) (any, error) {
+	return []map[string]any{}, nil
+}
+
+func (s *echoWidgets) Create(ctx context.Context, params any) (any, error) {
+	s.created, _ = params.(map[string]any)
+	return params, nil
+}
+
+func (s *echoWidgets) Update(ctx context.Context, params any) (any, error) {
+	s.updated, _ = params.(map[string]any)
+	return params, nil
+}
+
+func (s *echoWidgets) Delete(ctx context.Context, id any) error { return nil }
+
+func TestDualServer_DecodesRequestBodies(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = port
+	cfg.EnableGRPC = false
+
+	widgets := &echoWidgets{}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(widgets); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d/api/v0/widgets", port)
+	send := func(method, url, contentType, body string) *http.Response {
+		t.Helper()
+		for i := 0; ; i++ {
+			req, _ := http.NewRequest(method, url, strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				return resp
+			}
+			if i == 100 {
+				t.Fatalf("%s %s error = %v", method, url, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if resp := send("POST", baseURL, "application/json", `{"name":"ada","size":3}`); resp.StatusCode >= 300 {
+		t.Fatalf("POST: expected success, got %d", resp.StatusCode)
+	}
+	if widgets.created["name"] != "ada" || widgets.created["size"] != float64(3) {
+		t.Errorf("Expected the decoded JSON body, got %v", widgets.created)
+	}
+
+	if resp := send("PUT", baseURL+"/7", "application/yaml", "name: grace\n"); resp.StatusCode >= 300 {
+		t.Fatalf("PUT: expected success, got %d", resp.StatusCode)
+	}
+	if widgets.updated["name"] != "grace" || widgets.updated["id"] == nil {
+		t.Errorf("Expected the decoded YAML body with the path ID, got %v", widgets.updated)
+	}
+
+	widgets.created = nil
+	if resp := send("POST", baseURL, "application/json", `{"name":`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Malformed body: expected 400, got %d", resp.StatusCode)
+	}
+	if widgets.created != nil {
+		t.Errorf("Expected malformed bodies not to reach the service, got %v", widgets.created)
+	}
+}
@@ -0,0 +1,150 @@
+package apiright_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func staticCheck(name string, status core.HealthStatus) core.HealthChecker {
+	return core.NewHealthCheck(name, func(ctx context.Context) core.HealthCheckResult {
+		return core.HealthCheckResult{Status: status}
+	})
+}
+
+func TestHealthRegistry_Aggregation(t *testing.T) {
+	registry := server.NewHealthRegistry(time.Second)
+	registry.Register(staticCheck("database", core.HealthOK))
+
+	if report := registry.Check(context.Background()); report.Status != core.HealthOK {
+		t.Errorf("Expected ok, got %s", report.Status)
+	}
+
+	registry.Register(staticCheck("services", core.HealthDegraded))
+	if report := registry.Check(context.Background()); report.Status != core.HealthDegraded {
+		t.Errorf("Expected degraded, got %s", report.Status)
+	}
+
+	// A check with the same name replaces the previous one
+	registry.Register(staticCheck("database", core.HealthDown))
+	report := registry.Check(context.Background())
+	if report.Status != core.HealthDown || len(report.Checks) != 2 {
+		t.Errorf("Expected down with two checks, got %s with %d", report.Status, len(report.Checks))
+	}
+}
+
+func TestHealthRegistry_Timeout(t *testing.T) {
+	registry := server.NewHealthRegistry(20 * time.Millisecond)
+	registry.Register(core.NewHealthCheck("slow", func(ctx context.Context) core.HealthCheckResult {
+		time.Sleep(time.Second)
+		return core.HealthCheckResult{Status: core.HealthOK}
+	}))
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+	if report.Status != core.HealthDown || report.Checks["slow"].Message != "check timed out" {
+		t.Errorf("Expected timed out check to be down, got %+v", report.Checks["slow"])
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Expected Check to return after the timeout")
+	}
+}
+
+func TestHealthRegistry_ServiceStatus(t *testing.T) {
+	registry := server.NewHealthRegistry(time.Second)
+	registry.Register(staticCheck("database", core.HealthOK))
+	registry.Register(staticCheck("search", core.HealthDown), "api.PostService")
+	registry.Register(staticCheck("avatars", core.HealthDegraded), "api.UserService", "api.PostService")
+
+	report := registry.Check(context.Background())
+	if report.Status != core.HealthDown {
+		t.Errorf("Expected aggregate down, got %s", report.Status)
+	}
+	for service, want := range map[string]core.HealthStatus{
+		"api.PostService":    core.HealthDown,
+		"api.UserService":    core.HealthDegraded,
+		"api.CommentService": core.HealthOK,
+	} {
+		if got := report.ServiceStatus(service); got != want {
+			t.Errorf("ServiceStatus(%q) = %s, want %s", service, got, want)
+		}
+	}
+
+	// Checks covering every service rate all of them
+	registry.Register(staticCheck("database", core.HealthDown))
+	if got := registry.Check(context.Background()).ServiceStatus("api.CommentService"); got != core.HealthDown {
+		t.Errorf("Expected shared check to take every service down, got %s", got)
+	}
+}
+
+func TestDualServer_GRPCHealthPerService(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.SinglePort = true
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	srv.RegisterHealthCheck(staticCheck("search", core.HealthDown), "grpc.reflection.v1alpha.ServerReflection")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTPPort)
+	for i := 0; ; i++ {
+		resp, err := http.Get(baseURL + "/health/live")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := grpc.NewClient(fmt.Sprintf("127.0.0.1:%d", cfg.HTTPPort), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	// Services are unknown until the first health refresh
+	for i := 0; ; i++ {
+		callCtx, callCancel := context.WithTimeout(ctx, 2*time.Second)
+		_, err := client.Check(callCtx, &healthpb.HealthCheckRequest{Service: "grpc.reflection.v1.ServerReflection"})
+		callCancel()
+		if err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("gRPC health was not refreshed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The down check only covers one service; the aggregate reflects every check
+	for service, want := range map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"": healthpb.HealthCheckResponse_NOT_SERVING,
+		"grpc.reflection.v1alpha.ServerReflection": healthpb.HealthCheckResponse_NOT_SERVING,
+		"grpc.reflection.v1.ServerReflection":      healthpb.HealthCheckResponse_SERVING,
+	} {
+		callCtx, callCancel := context.WithTimeout(ctx, 2*time.Second)
+		resp, err := client.Check(callCtx, &healthpb.HealthCheckRequest{Service: service})
+		callCancel()
+		if err != nil {
+			t.Errorf("Check(%q) error = %v", service, err)
+		} else if resp.Status != want {
+			t.Errorf("Check(%q) = %s, want %s", service, resp.Status, want)
+		}
+	}
+}
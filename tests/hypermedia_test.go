@@ -0,0 +1,182 @@
+package apiright_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// hypermediaTable describes posts with a user relationship
+type hypermediaTable struct {
+	*graphqlTable
+}
+
+func (s *hypermediaTable) Resource() core.Resource {
+	return core.Resource{
+		Type:       "posts",
+		PrimaryKey: "id",
+		Relations:  []core.Relation{{Name: "user", Column: "user_id", Type: "users"}},
+	}
+}
+
+func TestDualServer_Hypermedia(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	posts := &hypermediaTable{&graphqlTable{name: "posts", rows: []map[string]any{
+		{"id": int64(1), "user_id": int64(1), "title": "engines"},
+		{"id": int64(2), "user_id": nil, "title": "notes"},
+	}}}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(posts); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	base := fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTPPort)
+	get := func(path, accept string) (string, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, base+path, nil)
+		req.Header.Set("Accept", accept)
+		for i := 0; ; i++ {
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				defer resp.Body.Close()
+				body, _ := io.ReadAll(resp.Body)
+				var document map[string]any
+				if err := json.Unmarshal(body, &document); err != nil {
+					t.Fatalf("Failed to decode %s: %v", body, err)
+				}
+				return resp.Header.Get("Content-Type"), document
+			}
+			if i == 100 {
+				t.Fatalf("GET %s error = %v", path, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	contentType, document := get("/api/v0/posts?limit=2", core.ContentTypeJSONAPI)
+	if contentType != core.ContentTypeJSONAPI {
+		t.Errorf("Expected %s, got %q", core.ContentTypeJSONAPI, contentType)
+	}
+	got, _ := json.Marshal(document["data"])
+	for _, want := range []string{
+		`"attributes":{"title":"engines"},"id":"1","links":{"self":"/api/v0/posts/1"}`,
+		`"relationships":{"user":{"data":{"id":"1","type":"users"},"links":{"related":"/api/v0/users/1"}}}`,
+		`"relationships":{"user":{"data":null}}`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("Expected JSON:API data to contain %s, got %s", want, got)
+		}
+	}
+	links, _ := document["links"].(map[string]any)
+	if links["first"] != "/api/v0/posts?limit=2&offset=0" || links["next"] != "/api/v0/posts?limit=2&offset=2" || links["prev"] != nil {
+		t.Errorf("Unexpected JSON:API links %v", links)
+	}
+
+	contentType, document = get("/api/v0/posts/1", core.ContentTypeHAL)
+	got, _ = json.Marshal(document)
+	if contentType != core.ContentTypeHAL || string(got) !=
+		`{"_links":{"self":{"href":"/api/v0/posts/1"},"user":{"href":"/api/v0/users/1"}},"id":1,"title":"engines","user_id":1}` {
+		t.Errorf("Unexpected HAL resource %s: %s", contentType, got)
+	}
+
+	_, document = get("/api/v0/posts?offset=1", core.ContentTypeHAL)
+	links, _ = document["_links"].(map[string]any)
+	embedded, _ := document["_embedded"].(map[string]any)
+	if links["prev"] == nil || links["next"] != nil || len(embedded["posts"].([]any)) != 2 {
+		t.Errorf("Unexpected HAL collection %v", document)
+	}
+
+	contentType, document = get("/api/v0/posts/9", core.ContentTypeJSONAPI)
+	got, _ = json.Marshal(document)
+	if contentType != core.ContentTypeJSONAPI || !strings.Contains(string(got), `{"errors":[{"code":"not_found"`) {
+		t.Errorf("Expected a JSON:API error document, got %s: %s", contentType, got)
+	}
+}
+
+func TestDeserializeRequest_Hypermedia(t *testing.T) {
+	cn := core.NewContentNegotiator()
+
+	var attributes map[string]any
+	body := `{"data":{"type":"posts","attributes":{"title":"engines"}}}`
+	if err := cn.DeserializeRequest([]byte(body), core.ContentTypeJSONAPI, &attributes); err != nil || attributes["title"] != "engines" {
+		t.Errorf("Expected JSON:API attributes, got %v (%v)", attributes, err)
+	}
+
+	var resource map[string]any
+	body = `{"title":"engines","_links":{"self":{"href":"/api/v0/posts/1"}}}`
+	if err := cn.DeserializeRequest([]byte(body), core.ContentTypeHAL, &resource); err != nil || len(resource) != 1 {
+		t.Errorf("Expected HAL resource without links, got %v (%v)", resource, err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v0/posts", nil)
+	req.Header.Set("Accept", core.ContentTypeHAL)
+	core.WriteError(rec, req, cn, core.NotFound("missing"))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected HAL errors as application/problem+json, got %q", ct)
+	}
+}
+
+// accountsTable is keyed by account_id rather than id
+type accountsTable struct {
+	*graphqlTable
+}
+
+func (s *accountsTable) Resource() core.Resource {
+	return core.Resource{Type: "accounts", PrimaryKey: "account_id"}
+}
+
+func TestDualServer_UpdatePassesPrimaryKey(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(&accountsTable{&graphqlTable{name: "accounts"}}); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/v0/accounts/7", cfg.HTTPPort)
+	for i := 0; ; i++ {
+		req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader(`{"phone":"222"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			if i == 100 {
+				t.Fatalf("PUT %s error = %v", url, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		// The stand-in echoes the params it was given
+		if !strings.Contains(string(body), `"account_id":7`) || strings.Contains(string(body), `"id":`) {
+			t.Errorf("Expected the path ID under account_id, got %d %s", resp.StatusCode, body)
+		}
+		return
+	}
+}
@@ -0,0 +1,254 @@
+package apiright_test
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func serveIdempotent(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v0/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyMiddleware_SQLReplay(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	store := middleware.NewSQLIdempotencyStore(db, "sqlite", "")
+	if err := store.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+
+	created := 0
+	im := middleware.NewIdempotencyMiddleware(middleware.IdempotencyOptions{Store: store}, &mockLogger{})
+	handler := im.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		created++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":%d}`, created)
+	}))
+
+	first := serveIdempotent(handler, "key-1", `{"name":"ada"}`)
+	replayed := serveIdempotent(handler, "key-1", `{"name":"ada"}`)
+	if created != 1 {
+		t.Fatalf("Expected the handler to run once, ran %d times", created)
+	}
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != first.Body.String() {
+		t.Errorf("Expected replay of 201 %s, got %d %s", first.Body.String(), replayed.Code, replayed.Body.String())
+	}
+	if replayed.Header().Get("Idempotent-Replayed") != "true" || replayed.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected replay headers: %v", replayed.Header())
+	}
+
+	if rec := serveIdempotent(handler, "key-1", `{"name":"grace"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a different payload, got %d", rec.Code)
+	}
+	if serveIdempotent(handler, "key-2", `{"name":"ada"}`); created != 2 {
+		t.Errorf("Expected a new key to run the handler, ran %d times", created)
+	}
+}
+
+func TestIdempotencyMiddleware_GRPCReplay(t *testing.T) {
+	im := middleware.NewIdempotencyMiddleware(middleware.IdempotencyOptions{}, &mockLogger{})
+	interceptor := im.GRPCInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.v0.UserService/CreateUser"}
+
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return wrapperspb.String(fmt.Sprintf("user-%d", calls)), nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("idempotency-key", "key-1"))
+
+	first, err := interceptor(ctx, wrapperspb.String("ada"), info, handler)
+	if err != nil {
+		t.Fatalf("first call error = %v", err)
+	}
+	replayed, err := interceptor(ctx, wrapperspb.String("ada"), info, handler)
+	if err != nil {
+		t.Fatalf("replayed call error = %v", err)
+	}
+	if calls != 1 || replayed.(*wrapperspb.StringValue).GetValue() != first.(*wrapperspb.StringValue).GetValue() {
+		t.Errorf("Expected replay of %v after one call, got %v after %d calls", first, replayed, calls)
+	}
+
+	if _, err := interceptor(ctx, wrapperspb.String("grace"), info, handler); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists for a different payload, got %v", err)
+	}
+}
+
+func TestIdempotencyMiddleware_ReplayBehindCompression(t *testing.T) {
+	cm, err := middleware.NewCompressionMiddleware(middleware.CompressionOptions{MinSize: 16}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewCompressionMiddleware() error = %v", err)
+	}
+	im := middleware.NewIdempotencyMiddleware(middleware.IdempotencyOptions{}, &mockLogger{})
+	body := strings.Repeat(`{"id":1,"name":"item"},`, 10)
+	handler := cm.Handler()(im.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(body))
+	})))
+
+	serve := func(acceptEncoding string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v0/users", strings.NewReader(`{"name":"ada"}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("gzip"); rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected the first response to be compressed, got %v", rec.Header())
+	}
+
+	// A replay to a client without Accept-Encoding must be plain
+	replayed := serve("")
+	if replayed.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("Expected a replay, got %v", replayed.Header())
+	}
+	if enc := replayed.Header().Get("Content-Encoding"); enc != "" || replayed.Body.String() != body {
+		t.Errorf("Expected the plain body without Content-Encoding, got %q %q", enc, replayed.Body.String())
+	}
+
+	// A replay to a gzip client is compressed again
+	replayed = serve("gzip")
+	if replayed.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a compressed replay, got %v", replayed.Header())
+	}
+	reader, err := gzip.NewReader(replayed.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	if decoded, _ := io.ReadAll(reader); string(decoded) != body {
+		t.Errorf("Expected the replayed body to decode, got %q", decoded)
+	}
+}
+
+func TestIdempotencyMiddleware_ReleasesOnPanic(t *testing.T) {
+	im := middleware.NewIdempotencyMiddleware(middleware.IdempotencyOptions{}, &mockLogger{})
+	calls := 0
+	handler := im.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected the panic to propagate")
+			}
+		}()
+		serveIdempotent(handler, "key-1", `{"name":"ada"}`)
+	}()
+
+	if rec := serveIdempotent(handler, "key-1", `{"name":"ada"}`); rec.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("Expected the retry to run the handler, got %d after %d calls", rec.Code, calls)
+	}
+}
+
+func TestIdempotencyMiddleware_ScopedByAuthMethod(t *testing.T) {
+	im := middleware.NewIdempotencyMiddleware(middleware.IdempotencyOptions{}, &mockLogger{})
+	calls := 0
+	handler := im.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v0/users", strings.NewReader(`{"name":"ada"}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		req = req.WithContext(core.WithPrincipal(req.Context(), &core.Principal{Subject: "alice", Method: method}))
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	serve("jwt")
+	// A basic-auth user that shares the JWT subject must not see its response
+	if rec := serve("basic"); rec.Header().Get("Idempotent-Replayed") != "" || calls != 2 {
+		t.Errorf("Expected a separate key per auth method, got %d calls", calls)
+	}
+	if rec := serve("jwt"); rec.Header().Get("Idempotent-Replayed") != "true" || calls != 2 {
+		t.Errorf("Expected a replay for the same principal, got %d calls", calls)
+	}
+}
+
+func TestIdempotencyStores_LeaseLapses(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	sqlStore := middleware.NewSQLIdempotencyStore(db, "sqlite", "")
+	if err := sqlStore.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+
+	stores := map[string]middleware.IdempotencyStore{
+		"memory": middleware.NewMemoryIdempotencyStore(),
+		"sql":    sqlStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			lease := 20 * time.Millisecond
+
+			if existing, err := store.Reserve(ctx, "stale", "fp", lease); err != nil || existing != nil {
+				t.Fatalf("Reserve() = %v, %v", existing, err)
+			}
+			if existing, _ := store.Reserve(ctx, "stale", "fp", lease); existing == nil || existing.Completed() {
+				t.Fatalf("Expected the key to be in flight, got %v", existing)
+			}
+
+			// A completed response outlives the lease
+			if _, err := store.Reserve(ctx, "done", "fp", lease); err != nil {
+				t.Fatalf("Reserve() error = %v", err)
+			}
+			record := middleware.IdempotencyRecord{Status: http.StatusCreated, ExpiresAt: time.Now().Add(time.Hour)}
+			if err := store.Complete(ctx, "done", record); err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+
+			time.Sleep(2 * lease)
+
+			if existing, err := store.Reserve(ctx, "stale", "fp", lease); err != nil || existing != nil {
+				t.Errorf("Expected a lapsed reservation to be taken over, got %v, %v", existing, err)
+			}
+			if existing, _ := store.Reserve(ctx, "done", "fp", lease); existing == nil || existing.Status != http.StatusCreated {
+				t.Errorf("Expected the completed response to be kept, got %v", existing)
+			}
+		})
+	}
+}
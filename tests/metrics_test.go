@@ -0,0 +1,64 @@
+package apiright_test
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/middleware"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	mm := middleware.NewMetricsMiddleware(&mockLogger{})
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	if err := mm.RegisterDB(db, "sqlite"); err != nil {
+		t.Fatalf("RegisterDB() error = %v", err)
+	}
+	if err := mm.RegisterMigrationVersion(func() (int, error) { return 7, nil }); err != nil {
+		t.Fatalf("RegisterMigrationVersion() error = %v", err)
+	}
+
+	handler := mm.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/users":
+			core.SetRoute(r.Context(), "users", "list")
+			w.WriteHeader(http.StatusOK)
+		case "/api/v0/users/1":
+			core.SetRoute(r.Context(), "users", "get")
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+
+	for _, path := range []string{"/api/v0/users", "/api/v0/users", "/api/v0/users/1", "/limited"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	mm.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	text := string(body)
+
+	for _, want := range []string{
+		`apiright_requests_total{operation="list",protocol="http",status="200",table="users"} 2`,
+		`apiright_request_errors_total{operation="get",protocol="http",status="500",table="users"} 1`,
+		`apiright_request_duration_seconds_count{operation="list",protocol="http",status="200",table="users"} 2`,
+		`apiright_rate_limit_rejections_total{protocol="http"} 1`,
+		`apiright_migration_version 7`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected metrics output to contain %q", want)
+		}
+	}
+}
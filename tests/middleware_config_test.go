@@ -0,0 +1,188 @@
+package apiright_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/middleware"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+func loadMiddlewareConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "apiright.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	return cfg
+}
+
+func TestNewFromConfig(t *testing.T) {
+	cfg := loadMiddlewareConfig(t, `
+server:
+  middleware:
+    cors:
+      enabled: true
+      allow_origins: [https://app.example.com]
+    rate_limit:
+      enabled: true
+      requests: 10
+      window: 1s
+      priority: 30
+      rules:
+        - path: /api/v0/users*
+          method: POST
+          requests: 1
+    body_limit:
+      enabled: true
+      max_bytes: 8
+generation:
+  middleware: [logging]
+`)
+	if err := config.ValidateConfig(cfg); err != nil {
+		t.Fatalf("ValidateConfig() error = %v", err)
+	}
+
+	mws, err := middleware.NewFromConfig(cfg, nil, "", &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+
+	priorities := make(map[string]int)
+	for _, mw := range mws {
+		priorities[mw.Name()] = mw.Priority()
+	}
+	want := map[string]int{"logging": 100, "cors": 10, "rate_limit": 30, "body_limit": 15}
+	if len(priorities) != len(want) {
+		t.Fatalf("Expected middleware %v, got %v", want, priorities)
+	}
+	for name, priority := range want {
+		if priorities[name] != priority {
+			t.Errorf("Expected %s priority %d, got %d", name, priority, priorities[name])
+		}
+	}
+}
+
+func TestNewFromConfig_Disabled(t *testing.T) {
+	mws, err := middleware.NewFromConfig(config.DefaultConfig(), nil, "", &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+	if len(mws) != 0 {
+		t.Errorf("Expected no middleware by default, got %d", len(mws))
+	}
+}
+
+// orderMiddleware appends its name to X-Order before calling the next handler
+type orderMiddleware struct {
+	name     string
+	priority int
+}
+
+func (m *orderMiddleware) Name() string  { return m.name }
+func (m *orderMiddleware) Priority() int { return m.priority }
+func (m *orderMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Order", m.name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestDualServer_MiddlewareOrder(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	srv.AddMiddleware(&orderMiddleware{name: "body_limit", priority: 15})
+	srv.AddMiddleware(&orderMiddleware{name: "cors", priority: 5})
+	srv.AddMiddleware(&orderMiddleware{name: "compression", priority: 12})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	for i := 0; ; i++ {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health/live", cfg.HTTPPort))
+		if err != nil {
+			if i == 100 {
+				t.Fatalf("GET /health/live error = %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		resp.Body.Close()
+
+		// The lowest priority number runs first, as for gRPC interceptors
+		if got := strings.Join(resp.Header.Values("X-Order"), ","); got != "cors,compression,body_limit" {
+			t.Errorf("Expected middleware order cors,compression,body_limit, got %s", got)
+		}
+		return
+	}
+}
+
+func TestValidateConfig_Middleware(t *testing.T) {
+	cfg := loadMiddlewareConfig(t, `
+server:
+  middleware:
+    rate_limit:
+      enabled: true
+      window: soon
+`)
+	if err := config.ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "rate_limit window") {
+		t.Errorf("Expected rate_limit window error, got %v", err)
+	}
+
+	cfg = loadMiddlewareConfig(t, `
+server:
+  middleware:
+    cors:
+      enabled: true
+      allow_origins: ["*"]
+      allow_credentials: true
+`)
+	if err := config.ValidateConfig(cfg); err == nil {
+		t.Error("Expected error for credentials with wildcard origin")
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	handler := middleware.NewBodyLimitMiddleware(4, &mockLogger{}).Handler()(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader("ok")))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for small body, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader("too large")))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for large body, got %d", rec.Code)
+	}
+	var problem core.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem.Code != "payload_too_large" || problem.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected payload_too_large problem, got %s", rec.Body.String())
+	}
+}
@@ -351,9 +351,9 @@ func TestRateLimitMiddleware_AcceptHeader(t *testing.T) {
 		accept   string
 		expected string
 	}{
-		{"application/json", "application/json"},
+		{"application/json", "application/problem+json"},
 		{"text/plain", "text/plain"},
-		{"", "application/json"},
+		{"", "application/problem+json"},
 	}
 
 	for _, tc := range tests {
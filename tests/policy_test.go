@@ -0,0 +1,154 @@
+package apiright_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/policy"
+	"github.com/bata94/apiright/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestEnforcer() *policy.Enforcer {
+	return policy.NewEnforcer(config.PolicyConfig{
+		"users": {
+			List:   []string{"admin", "support"},
+			Delete: []string{"admin"},
+		},
+		"posts": {
+			Get:         []string{"*"},
+			OwnerColumn: "user_id",
+			OwnerBypass: []string{"admin"},
+		},
+	}, &mockLogger{})
+}
+
+func withRoles(subject string, roles ...string) context.Context {
+	return core.WithPrincipal(context.Background(), &core.Principal{Subject: subject, Method: "jwt", Roles: roles})
+}
+
+func TestPolicyEnforcer_Roles(t *testing.T) {
+	enforcer := newTestEnforcer()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		table   string
+		op      policy.Operation
+		wantErr error
+	}{
+		{"support can list", withRoles("s", "support"), "users", policy.OpList, nil},
+		{"support cannot delete", withRoles("s", "support"), "users", policy.OpDelete, policy.ErrForbidden},
+		{"anonymous cannot list", context.Background(), "users", policy.OpList, policy.ErrUnauthenticated},
+		{"unrestricted operation", context.Background(), "users", policy.OpGet, nil},
+		{"table without policy", context.Background(), "comments", policy.OpDelete, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := enforcer.Authorize(tt.ctx, tt.table, tt.op)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyEnforcer_OwnerScope(t *testing.T) {
+	enforcer := newTestEnforcer()
+
+	ctx, err := enforcer.Authorize(withRoles("42", "member"), "posts", policy.OpList)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	scope, ok := core.OwnerScopeFromContext(ctx, "posts")
+	if !ok {
+		t.Fatal("Expected owner scope for posts")
+	}
+	if scope.Column != "user_id" || scope.Owner != "42" {
+		t.Errorf("Unexpected owner scope: %+v", scope)
+	}
+	if _, ok := core.OwnerScopeFromContext(ctx, "users"); ok {
+		t.Error("Owner scope must not apply to other tables")
+	}
+
+	ctx, err = enforcer.Authorize(withRoles("1", "admin"), "posts", policy.OpList)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if _, ok := core.OwnerScopeFromContext(ctx, "posts"); ok {
+		t.Error("Expected admin to bypass owner scope")
+	}
+
+	if _, err := enforcer.Authorize(context.Background(), "posts", policy.OpCreate); !errors.Is(err, policy.ErrUnauthenticated) {
+		t.Errorf("Expected anonymous access to owned table to fail, got %v", err)
+	}
+}
+
+func TestPolicyEnforcer_GRPC(t *testing.T) {
+	interceptor := newTestEnforcer().GRPCInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	_, err := interceptor(withRoles("s", "support"), nil, &grpc.UnaryServerInfo{FullMethod: "/api.UserService/DeleteUser"}, handler)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied, got %v", err)
+	}
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api.UserService/ListUsers"}, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+
+	resp, err := interceptor(withRoles("s", "support"), nil, &grpc.UnaryServerInfo{FullMethod: "/api.UserService/ListUsers"}, handler)
+	if err != nil || resp != "ok" {
+		t.Errorf("Expected call to pass, got resp=%v err=%v", resp, err)
+	}
+}
+
+func TestDualServer_ConfigureMiddleware_Policies(t *testing.T) {
+	cfg := loadMiddlewareConfig(t, `
+policies:
+  widgets:
+    delete: [admin]
+`)
+	cfg.Server.Host = "127.0.0.1"
+	cfg.Server.HTTPPort = freePort(t)
+	cfg.Server.EnableGRPC = false
+
+	srv := server.NewServer(&cfg.Server, t.TempDir(), nil, &mockLogger{})
+	if err := srv.ConfigureMiddleware(cfg); err != nil {
+		t.Fatalf("ConfigureMiddleware() error = %v", err)
+	}
+	if err := srv.RegisterService(&cachedWidgets{}); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/v0/widgets/1", cfg.Server.HTTPPort)
+	req, _ := http.NewRequest("DELETE", url, nil)
+	for i := 0; ; i++ {
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+				t.Errorf("Expected the delete policy to reject anonymous callers, got %d", resp.StatusCode)
+			}
+			return
+		}
+		if i == 100 {
+			t.Fatalf("DELETE %s error = %v", url, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
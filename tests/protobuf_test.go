@@ -0,0 +1,193 @@
+package apiright_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/server"
+	"google.golang.org/protobuf/proto"
+)
+
+// postProto declares posts the way generated adapters do
+var postProto = core.MustProtoSchema(
+	core.ProtoMessageSpec{Name: "db.Post", Fields: []core.ProtoFieldSpec{
+		{Name: "id", Number: 1, Type: "int64", JSONName: "id"},
+		{Name: "user_id", Number: 2, Type: "int64", JSONName: "user_id"},
+		{Name: "title", Number: 3, Type: "string", JSONName: "title"},
+		{Name: "published_at", Number: 4, Type: "google.protobuf.Timestamp", JSONName: "published_at"},
+	}},
+	core.ProtoMethodSpec{
+		Operation: "get",
+		Request: core.ProtoMessageSpec{Name: "api.GetPostRequest", Fields: []core.ProtoFieldSpec{
+			{Name: "id", Number: 1, Type: "int64", JSONName: "id"},
+		}},
+		Response:     "api.GetPostResponse",
+		ResponseType: "db.Post",
+	},
+	core.ProtoMethodSpec{
+		Operation: "list",
+		Request: core.ProtoMessageSpec{Name: "api.ListPostsRequest", Fields: []core.ProtoFieldSpec{
+			{Name: "limit", Number: 1, Type: "int64", JSONName: "limit"},
+			{Name: "offset", Number: 2, Type: "int64", JSONName: "offset"},
+		}},
+		Response:     "api.ListPostsResponse",
+		ResponseType: "repeated db.Post",
+	},
+	core.ProtoMethodSpec{
+		Operation: "create",
+		Request: core.ProtoMessageSpec{Name: "api.CreatePostRequest", Fields: []core.ProtoFieldSpec{
+			{Name: "userId", Number: 1, Type: "int64", JSONName: "user_id"},
+			{Name: "title", Number: 2, Type: "string", JSONName: "title"},
+			{Name: "publishedAt", Number: 3, Type: "google.protobuf.Timestamp", JSONName: "published_at"},
+		}},
+		Response:     "api.CreatePostResponse",
+		ResponseType: "db.Post",
+	},
+)
+
+// protoTable is a posts service with generated protobuf messages
+type protoTable struct {
+	*graphqlTable
+}
+
+func (s *protoTable) ProtoRequest(operation string) proto.Message {
+	return postProto.Request(operation)
+}
+
+func (s *protoTable) ProtoResponse(operation string, result any) (proto.Message, error) {
+	return postProto.Response(operation, result)
+}
+
+// sqlcPost mirrors a sqlc model with nullable columns
+type sqlcPost struct {
+	ID          int64          `json:"id"`
+	UserID      sql.NullInt64  `json:"user_id"`
+	Title       sql.NullString `json:"title"`
+	PublishedAt sql.NullTime   `json:"published_at"`
+}
+
+func TestProtoSchema(t *testing.T) {
+	published := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	msg, err := postProto.Model(sqlcPost{
+		ID:          7,
+		Title:       sql.NullString{String: "engines", Valid: true},
+		PublishedAt: sql.NullTime{Time: published, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Model() error = %v", err)
+	}
+
+	row := core.ProtoToRow(msg)
+	want := map[string]any{"id": int64(7), "title": "engines", "published_at": published}
+	if fmt.Sprint(row) != fmt.Sprint(want) {
+		t.Errorf("Expected NULL columns to stay unset, got %v", row)
+	}
+
+	// Messages are wire compatible with their .proto declaration
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("proto.Marshal() error = %v", err)
+	}
+	// Field 1 (id) as a varint, field 3 (title) as 7 bytes
+	if !bytes.Contains(data, []byte{0x08, 7}) || !bytes.Contains(data, []byte("\x1a\x07engines")) {
+		t.Errorf("Unexpected wire encoding %x", data)
+	}
+
+	response, err := postProto.Response("list", []sqlcPost{{ID: 1}, {ID: 2}})
+	if err != nil {
+		t.Fatalf("Response() error = %v", err)
+	}
+	if rows := core.ProtoToRow(response)["data"].([]any); len(rows) != 2 {
+		t.Errorf("Expected 2 listed posts, got %v", rows)
+	}
+
+	if _, err := postProto.Model(map[string]any{"id": "seven"}); err == nil {
+		t.Error("Expected an error for a non-numeric id")
+	}
+}
+
+func TestDualServer_Protobuf(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+	cfg.JSONMapping = "protojson"
+
+	posts := &protoTable{&graphqlTable{name: "posts", rows: []map[string]any{
+		{"id": int64(1), "user_id": int64(1), "title": "engines"},
+	}}}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(posts); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	base := fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTPPort)
+	do := func(method, path, contentType, accept string, body []byte) []byte {
+		t.Helper()
+		for i := 0; ; i++ {
+			req, _ := http.NewRequest(method, base+path, bytes.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Accept", accept)
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				defer resp.Body.Close()
+				data, _ := io.ReadAll(resp.Body)
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("%s %s returned %d: %s", method, path, resp.StatusCode, data)
+				}
+				if got := resp.Header.Get("Content-Type"); got != accept {
+					t.Errorf("Expected %s, got %q", accept, got)
+				}
+				return data
+			}
+			if i == 100 {
+				t.Fatalf("%s %s error = %v", method, path, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Protobuf responses are the generated response messages
+	data := do(http.MethodGet, "/api/v0/posts/1", "", core.ContentTypeProtobuf, nil)
+	reply, _ := postProto.Response("get", nil)
+	if err := proto.Unmarshal(data, reply); err != nil {
+		t.Fatalf("Failed to decode GetPostResponse: %v", err)
+	}
+	if post := core.ProtoToRow(reply)["data"].(map[string]any); post["title"] != "engines" {
+		t.Errorf("Unexpected post %v", post)
+	}
+
+	// Protobuf bodies are decoded through the request message; JSON uses the protojson mapping
+	request := postProto.Request("create")
+	if err := core.RowToProto(map[string]any{"user_id": 1, "title": "notes"}, request); err != nil {
+		t.Fatalf("RowToProto() error = %v", err)
+	}
+	body, _ := proto.Marshal(request)
+	data = do(http.MethodPost, "/api/v0/posts", core.ContentTypeProtobuf, core.ContentTypeJSON, body)
+	var created map[string]map[string]any
+	if err := json.Unmarshal(data, &created); err != nil {
+		t.Fatalf("Failed to decode %s: %v", data, err)
+	}
+	if post := created["data"]; post["id"] != "2" || post["user_id"] != "1" || post["title"] != "notes" {
+		t.Errorf("Expected protojson with int64 as strings, got %s", data)
+	}
+
+	data = do(http.MethodGet, "/api/v0/posts", "", core.ContentTypeJSON, nil)
+	var listed map[string][]any
+	if err := json.Unmarshal(data, &listed); err != nil || len(listed["data"]) != 2 {
+		t.Errorf("Expected a ListPostsResponse with 2 posts, got %s", data)
+	}
+}
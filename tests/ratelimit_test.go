@@ -0,0 +1,235 @@
+package apiright_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/middleware"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func rateLimitHandler(rm *middleware.RateLimitMiddleware) http.Handler {
+	return rm.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func serveRateLimited(handler http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitMiddleware_Headers(t *testing.T) {
+	handler := rateLimitHandler(middleware.NewRateLimitMiddleware(2, time.Minute, &mockLogger{}))
+
+	rec := serveRateLimited(handler, "GET", "/", nil)
+	if rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("Unexpected RateLimit headers: %v", rec.Header())
+	}
+
+	serveRateLimited(handler, "GET", "/", nil)
+	rec = serveRateLimited(handler, "GET", "/", map[string]string{"Accept": "application/xml"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", rec.Header().Get("Retry-After"))
+	}
+	if rec.Header().Get("Content-Type") != "application/problem+xml" {
+		t.Errorf("Expected negotiated XML problem body, got %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestRateLimitMiddleware_Rules(t *testing.T) {
+	rm := middleware.NewRateLimitMiddlewareWithOptions(middleware.RateLimitOptions{
+		Default: middleware.RateLimit{Requests: 100, Window: time.Minute},
+		Rules: []middleware.RateLimitRule{
+			{APIKey: "partner", Limit: middleware.RateLimit{Requests: -1}},
+			{Path: "/api/v0/users*", Method: "POST", Limit: middleware.RateLimit{Requests: 1, Window: time.Minute}},
+		},
+		APIKeyHeader: "X-API-Key",
+		APIKeys:      map[string]string{middleware.HashSecret("partner-secret"): "partner"},
+	}, &mockLogger{})
+	handler := rateLimitHandler(rm)
+
+	if rec := serveRateLimited(handler, "POST", "/api/v0/users", nil); rec.Code != http.StatusOK {
+		t.Fatalf("First POST: expected 200, got %d", rec.Code)
+	}
+	if rec := serveRateLimited(handler, "POST", "/api/v0/users", nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Second POST: expected 429, got %d", rec.Code)
+	}
+
+	// Other methods and routes use the default limit
+	if rec := serveRateLimited(handler, "GET", "/api/v0/users", nil); rec.Code != http.StatusOK {
+		t.Errorf("GET: expected 200, got %d", rec.Code)
+	}
+
+	// The partner key is unlimited
+	for i := 0; i < 3; i++ {
+		rec := serveRateLimited(handler, "POST", "/api/v0/users", map[string]string{"X-API-Key": "partner-secret"})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Partner request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitMiddleware_UnknownKeysShareIPBucket(t *testing.T) {
+	rm := middleware.NewRateLimitMiddlewareWithOptions(middleware.RateLimitOptions{
+		Default:      middleware.RateLimit{Requests: 2, Window: time.Minute},
+		APIKeyHeader: "X-API-Key",
+		APIKeys:      map[string]string{middleware.HashSecret("partner-secret"): "partner"},
+	}, &mockLogger{})
+	handler := rateLimitHandler(rm)
+
+	for i := 0; i < 2; i++ {
+		rec := serveRateLimited(handler, "GET", "/", map[string]string{"X-API-Key": fmt.Sprintf("random-%d", i)})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	rec := serveRateLimited(handler, "GET", "/", map[string]string{"X-API-Key": "random-2"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Rotating unknown keys: expected 429, got %d", rec.Code)
+	}
+
+	// A known key still gets its own bucket
+	rec = serveRateLimited(handler, "GET", "/", map[string]string{"X-API-Key": "partner-secret"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("Known key: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddleware_ForwardedForSpoofing(t *testing.T) {
+	// Clients cannot escape their bucket by forging forwarding headers
+	handler := rateLimitHandler(middleware.NewRateLimitMiddleware(1, time.Minute, &mockLogger{}))
+	serveRateLimited(handler, "GET", "/", nil)
+	for _, header := range []string{"X-Forwarded-For", "X-Real-IP"} {
+		rec := serveRateLimited(handler, "GET", "/", map[string]string{header: "203.0.113.9"})
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("Spoofed %s: expected 429, got %d", header, rec.Code)
+		}
+	}
+
+	// Behind a trusted proxy, the address it appended identifies the client
+	proxies, err := middleware.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	handler = rateLimitHandler(middleware.NewRateLimitMiddlewareWithOptions(middleware.RateLimitOptions{
+		Default:        middleware.RateLimit{Requests: 1, Window: time.Minute},
+		TrustedProxies: proxies,
+	}, &mockLogger{}))
+	if rec := serveRateLimited(handler, "GET", "/", map[string]string{"X-Forwarded-For": "198.51.100.1"}); rec.Code != http.StatusOK {
+		t.Fatalf("First client: expected 200, got %d", rec.Code)
+	}
+	if rec := serveRateLimited(handler, "GET", "/", map[string]string{"X-Forwarded-For": "198.51.100.2"}); rec.Code != http.StatusOK {
+		t.Errorf("Second client: expected 200, got %d", rec.Code)
+	}
+	rec := serveRateLimited(handler, "GET", "/", map[string]string{"X-Forwarded-For": "203.0.113.9, 198.51.100.1"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Forged first hop: expected 429, got %d", rec.Code)
+	}
+
+	if _, err := middleware.ParseTrustedProxies([]string{"proxy.internal"}); err == nil {
+		t.Error("Expected an error for a host name")
+	}
+}
+
+func TestMemoryRateLimitStore_Refill(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore()
+	limit := middleware.RateLimit{Requests: 1, Window: time.Millisecond}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := store.Take(context.Background(), key, limit); err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+	}
+	if store.Len() != 3 {
+		t.Fatalf("Expected 3 buckets, got %d", store.Len())
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if result, _ := store.Take(context.Background(), "a", limit); !result.Allowed {
+		t.Error("Expected bucket to refill")
+	}
+}
+
+func TestSQLRateLimitStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	store := middleware.NewSQLRateLimitStore(db, "sqlite", "")
+	if err := store.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+
+	limit := middleware.RateLimit{Requests: 2, Window: time.Hour}
+	for i, allowed := range []bool{true, true, false} {
+		result, err := store.Take(context.Background(), "ip:10.0.0.1", limit)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if result.Allowed != allowed {
+			t.Errorf("Request %d: expected allowed=%v, got %v", i+1, allowed, result.Allowed)
+		}
+	}
+
+	// Buckets are independent per key
+	if result, _ := store.Take(context.Background(), "ip:10.0.0.2", limit); !result.Allowed {
+		t.Error("Expected separate bucket for a different key")
+	}
+}
+
+func TestSQLRateLimitStore_PrunesExpiredBuckets(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	store := middleware.NewSQLRateLimitStore(db, "sqlite", "")
+	if err := store.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO " + middleware.DefaultRateLimitTable + " (bucket_key, tokens, updated_at, expires_at) VALUES ('ip:stale', 0, 0, 0)"); err != nil {
+		t.Fatalf("Failed to insert bucket: %v", err)
+	}
+
+	limit := middleware.RateLimit{Requests: 2, Window: time.Hour}
+	if _, err := store.Take(context.Background(), "ip:10.0.0.1", limit); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	var keys []string
+	rows, err := db.Query("SELECT bucket_key FROM " + middleware.DefaultRateLimitTable)
+	if err != nil {
+		t.Fatalf("Failed to read buckets: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			t.Fatalf("Failed to scan bucket: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) != 1 || keys[0] != "ip:10.0.0.1" {
+		t.Errorf("Expected only the live bucket to remain, got %v", keys)
+	}
+}
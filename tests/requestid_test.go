@@ -0,0 +1,98 @@
+package apiright_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/middleware"
+)
+
+// fieldLogger records the fields attached through With
+type fieldLogger struct {
+	mockLogger
+	fields []any
+}
+
+func (l *fieldLogger) With(fields ...any) core.Logger {
+	return &fieldLogger{fields: append(append([]any{}, l.fields...), fields...)}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var ctxRequestID string
+	var ctxTrace core.TraceContext
+	var ctxLogger core.Logger
+	handler := middleware.NewRequestIDMiddleware("", &fieldLogger{}).Handler()(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctxRequestID = core.RequestIDFromContext(r.Context())
+			ctxTrace, _ = core.TraceContextFromContext(r.Context())
+			ctxLogger = core.LoggerFromContext(r.Context(), nil)
+		}))
+
+	// A new request gets a generated ID and a new trace
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if ctxRequestID == "" || rec.Header().Get("X-Request-ID") != ctxRequestID {
+		t.Errorf("Expected generated request ID echoed, got context %q header %q", ctxRequestID, rec.Header().Get("X-Request-ID"))
+	}
+	if rec.Header().Get("traceparent") != ctxTrace.Traceparent() || ctxTrace.ParentID != "" {
+		t.Errorf("Unexpected trace context: %+v, header %q", ctxTrace, rec.Header().Get("traceparent"))
+	}
+	logger, ok := ctxLogger.(*fieldLogger)
+	if !ok || len(logger.fields) != 6 || logger.fields[1] != ctxRequestID || logger.fields[3] != ctxTrace.TraceID {
+		t.Errorf("Expected request-scoped logger with IDs, got %+v", ctxLogger)
+	}
+
+	// An incoming ID and traceparent are continued
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if ctxRequestID != "abc-123" {
+		t.Errorf("Expected incoming request ID, got %q", ctxRequestID)
+	}
+	if ctxTrace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || ctxTrace.ParentID != "00f067aa0ba902b7" {
+		t.Errorf("Expected continued trace, got %+v", ctxTrace)
+	}
+	if ctxTrace.SpanID == "00f067aa0ba902b7" || !strings.HasPrefix(rec.Header().Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Errorf("Expected a new span in the caller's trace, got %q", rec.Header().Get("traceparent"))
+	}
+
+	// Unsafe IDs are replaced
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if ctxRequestID == "bad id\nwith newline" {
+		t.Error("Expected unsafe request ID to be replaced")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header  string
+		wantErr bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true},
+		{"garbage", true},
+	}
+
+	for _, tt := range tests {
+		tc, err := core.ParseTraceparent(tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTraceparent(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+		}
+		if err == nil && !tc.Sampled() {
+			t.Errorf("ParseTraceparent(%q) expected sampled flag", tt.header)
+		}
+	}
+}
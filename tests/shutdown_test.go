@@ -0,0 +1,169 @@
+package apiright_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// freePort returns a TCP port that is currently unused
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// slowMiddleware delays requests to /slow so they are in flight during shutdown
+type slowMiddleware struct{ delay time.Duration }
+
+func (m *slowMiddleware) Name() string  { return "slow" }
+func (m *slowMiddleware) Priority() int { return 1 }
+func (m *slowMiddleware) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(m.delay)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestDualServer_GracefulShutdown(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.GRPCPort = freePort(t)
+	cfg.Shutdown.Timeout = "2s"
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	srv.AddMiddleware(&slowMiddleware{delay: 200 * time.Millisecond})
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTPPort)
+	var events []string
+	srv.AddPreShutdownHook(func(ctx context.Context) error {
+		resp, err := http.Get(baseURL + "/health/ready")
+		if err == nil {
+			events = append(events, fmt.Sprintf("pre:%d", resp.StatusCode))
+			resp.Body.Close()
+		}
+		return nil
+	})
+	srv.AddPostShutdownHook(func(ctx context.Context) error {
+		events = append(events, "post")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	startErr := make(chan error, 1)
+	go func() { startErr <- srv.Start(ctx) }()
+
+	// Wait until the HTTP server accepts requests
+	for i := 0; ; i++ {
+		resp, err := http.Get(baseURL + "/health/live")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	slowStatus := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			slowStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		slowStatus <- resp.StatusCode
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if status := <-slowStatus; status != http.StatusNoContent {
+		t.Errorf("Expected in-flight request to drain, got status %d", status)
+	}
+	select {
+	case err := <-startErr:
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Start() did not return after shutdown")
+	}
+
+	if len(events) != 2 || events[0] != "pre:503" || events[1] != "post" {
+		t.Errorf("Expected not-ready pre hook then post hook, got %v", events)
+	}
+	if srv.IsStarted() {
+		t.Error("Expected server to be stopped")
+	}
+}
+
+func TestDualServer_PostShutdownHooksOutliveDrainDeadline(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	srv.AddMiddleware(&slowMiddleware{delay: 500 * time.Millisecond})
+
+	hookErr := make(chan error, 1)
+	srv.AddPostShutdownHook(func(ctx context.Context) error {
+		hookErr <- ctx.Err()
+		return nil
+	})
+
+	go func() { _ = srv.Start(context.Background()) }()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTPPort)
+	for i := 0; ; i++ {
+		resp, err := http.Get(baseURL + "/health/live")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	go func() {
+		if resp, err := http.Get(baseURL + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// The request outlasts the drain deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+
+	select {
+	case err := <-hookErr:
+		if err != nil {
+			t.Errorf("Expected a live context in the post-shutdown hook, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Post-shutdown hook did not run")
+	}
+}
@@ -0,0 +1,78 @@
+package apiright_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestDualServer_SinglePort(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.SinglePort = true
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTPPort)
+	for i := 0; ; i++ {
+		resp, err := http.Get(baseURL + "/health/live")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected HTTP/1.1 health check to succeed, got %d", resp.StatusCode)
+			}
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Native gRPC over h2c on the same port
+	conn, err := grpc.NewClient(fmt.Sprintf("127.0.0.1:%d", cfg.HTTPPort), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	defer conn.Close()
+
+	callCtx, callCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer callCancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(callCtx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("Expected gRPC health check over the HTTP port, got %v", err)
+	}
+
+	// gRPC-Web: an empty HealthCheckRequest frame
+	req, _ := http.NewRequest("POST", baseURL+"/grpc.health.v1.Health/Check", bytes.NewReader([]byte{0, 0, 0, 0, 0}))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("X-Grpc-Web", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("gRPC-Web request error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.Header.Get("Content-Type") != "application/grpc-web+proto" {
+		t.Errorf("Unexpected gRPC-Web content type %q", resp.Header.Get("Content-Type"))
+	}
+	trailerStart := bytes.LastIndexByte(body, 0x80)
+	if trailerStart < 0 || !strings.Contains(string(body[trailerStart+5:]), "grpc-status: 0") {
+		t.Errorf("Expected gRPC-Web trailer frame with grpc-status 0, got %q", body)
+	}
+}
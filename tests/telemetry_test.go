@@ -0,0 +1,103 @@
+package apiright_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/middleware"
+	"github.com/bata94/apiright/pkg/telemetry"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTelemetry_SpansAndMetrics(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(spans),
+		sdktrace.WithIDGenerator(telemetry.NewIDGenerator()),
+	))
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	instrumented := telemetry.InstrumentDB(db, "sqlite")
+
+	requestID := middleware.NewRequestIDMiddleware("", &mockLogger{})
+	tm := middleware.NewTelemetryMiddleware(&mockLogger{})
+	handler := requestID.Handler()(tm.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := telemetry.StartOperation(r.Context(), "users", "get")
+		defer span.End()
+		if _, err := instrumented.QueryContext(ctx, "-- name: GetUser :one\nSELECT missing FROM users"); err == nil {
+			t.Error("Expected query error")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})))
+
+	req := httptest.NewRequest("GET", "/api/v0/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ended := spans.Ended()
+	if len(ended) != 3 {
+		t.Fatalf("Expected query, operation and server spans, got %d", len(ended))
+	}
+	query, operation, server := ended[0], ended[1], ended[2]
+
+	if server.Name() != "GET users.get" || server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Unexpected server span %q with parent %s", server.Name(), server.Parent().SpanID())
+	}
+	want := "00-" + server.SpanContext().TraceID().String() + "-" + server.SpanContext().SpanID().String() + "-01"
+	if rec.Header().Get("traceparent") != want {
+		t.Errorf("Expected response traceparent %q to match server span %q", rec.Header().Get("traceparent"), want)
+	}
+	if operation.Name() != "users.get" || operation.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("Unexpected operation span %q", operation.Name())
+	}
+	if query.Name() != "GetUser" || query.Parent().SpanID() != operation.SpanContext().SpanID() || len(query.Events()) == 0 {
+		t.Errorf("Expected failed query span under the operation, got %q", query.Name())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	counts := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					if table, _ := dp.Attributes.Value(attribute.Key("table")); table.AsString() == "users" {
+						counts[m.Name] += dp.Value
+					}
+				}
+			}
+		}
+	}
+	if counts["apiright.requests"] != 1 || counts["apiright.errors"] != 1 {
+		t.Errorf("Expected one request and one error for users, got %v", counts)
+	}
+}
+
+func TestTelemetrySetup_Disabled(t *testing.T) {
+	shutdown, err := telemetry.Setup(context.Background(), config.TelemetryConfig{Enabled: false}, "test")
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
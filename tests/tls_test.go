@@ -0,0 +1,128 @@
+package apiright_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/certs"
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// clientTLS returns a client configuration trusting the dev CA, presenting the dev client certificate if withCert
+func clientTLS(t *testing.T, dir string, withCert bool) *tls.Config {
+	t.Helper()
+	caPEM, err := os.ReadFile(filepath.Join(dir, certs.CAFile))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caPEM)
+
+	cfg := &tls.Config{RootCAs: pool}
+	if withCert {
+		cert, err := tls.LoadX509KeyPair(filepath.Join(dir, certs.ClientFile), filepath.Join(dir, certs.ClientKeyFile))
+		if err != nil {
+			t.Fatalf("LoadX509KeyPair() error = %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg
+}
+
+func TestDualServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	if err := certs.GenerateDev(dir, nil); err != nil {
+		t.Fatalf("GenerateDev() error = %v", err)
+	}
+
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.GRPCPort = freePort(t)
+	cfg.TLS = config.TLSConfig{
+		Enabled:      true,
+		CertFile:     filepath.Join(dir, certs.ServerFile),
+		KeyFile:      filepath.Join(dir, certs.ServerKeyFile),
+		ClientCAFile: filepath.Join(dir, certs.CAFile),
+		MinVersion:   "1.3",
+	}
+
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS(t, dir, true)}}
+	baseURL := fmt.Sprintf("https://127.0.0.1:%d", cfg.HTTPPort)
+	for i := 0; ; i++ {
+		resp, err := client.Get(baseURL + "/health/live")
+		if err == nil {
+			resp.Body.Close()
+			if resp.TLS == nil || resp.TLS.Version != tls.VersionTLS13 {
+				t.Errorf("Expected a TLS 1.3 connection, got %+v", resp.TLS)
+			}
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Without a client certificate the handshake is rejected
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS(t, dir, false)}}
+	if resp, err := anonymous.Get(baseURL + "/health/live"); err == nil {
+		resp.Body.Close()
+		t.Error("Expected request without client certificate to fail")
+	}
+
+	conn, err := grpc.NewClient(fmt.Sprintf("127.0.0.1:%d", cfg.GRPCPort), grpc.WithTransportCredentials(credentials.NewTLS(clientTLS(t, dir, true))))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	defer conn.Close()
+
+	callCtx, callCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer callCancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(callCtx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("Expected gRPC health check over mTLS, got %v", err)
+	}
+}
+
+func TestReloader_PicksUpNewCertificate(t *testing.T) {
+	dir := t.TempDir()
+	if err := certs.GenerateDev(dir, nil); err != nil {
+		t.Fatalf("GenerateDev() error = %v", err)
+	}
+	certFile, keyFile := filepath.Join(dir, certs.ServerFile), filepath.Join(dir, certs.ServerKeyFile)
+
+	reloader, err := certs.NewReloader(certFile, keyFile, time.Millisecond, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	before, _ := reloader.GetCertificate(nil)
+
+	// Rewrite the pair and make sure the modification time moves forward
+	if err := certs.GenerateDev(dir, []string{"reloaded.local"}); err != nil {
+		t.Fatalf("GenerateDev() error = %v", err)
+	}
+	later := time.Now().Add(time.Second)
+	_ = os.Chtimes(certFile, later, later)
+	time.Sleep(5 * time.Millisecond)
+
+	after, _ := reloader.GetCertificate(nil)
+	if after == before || after.Leaf == nil || after.Leaf.Subject.CommonName != "reloaded.local" {
+		t.Errorf("Expected the rewritten certificate to be served, got %v", after.Leaf.Subject)
+	}
+}
@@ -0,0 +1,209 @@
+package apiright_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// postsV1 is posts as frozen before summary was added
+var postsV1 = server.APIVersion{
+	Name: "v1",
+	Tables: []server.VersionedTable{{
+		Resource: core.Resource{Type: "posts", PrimaryKey: "id"},
+		Readable: []string{"id", "title"},
+		Writable: []string{"title"},
+	}},
+}
+
+func TestDualServer_Versions(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+	cfg.APIVersion = "v2"
+	cfg.Versions = []config.VersionConfig{{Name: "v1", Deprecated: "2026-01-01", Sunset: "2026-07-01"}}
+
+	posts := &graphqlTable{name: "posts", rows: []map[string]any{
+		{"id": int64(1), "title": "engines", "summary": "how engines work"},
+	}}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(posts); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+	if err := srv.MountVersion(postsV1); err != nil {
+		t.Fatalf("MountVersion() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	base := fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTPPort)
+	do := func(method, path, version string, body any) (*http.Response, map[string]any) {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		for i := 0; ; i++ {
+			req, _ := http.NewRequest(method, base+path, bytes.NewReader(data))
+			req.Header.Set("Content-Type", core.ContentTypeJSON)
+			if version != "" {
+				req.Header.Set("Accept-Version", version)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				defer resp.Body.Close()
+				raw, _ := io.ReadAll(resp.Body)
+				var row map[string]any
+				_ = json.Unmarshal(raw, &row)
+				return resp, row
+			}
+			if i == 100 {
+				t.Fatalf("%s %s error = %v", method, path, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Each version has its own tree
+	resp, row := do(http.MethodGet, "/api/v2/posts/1", "", nil)
+	if resp.StatusCode != http.StatusOK || row["summary"] != "how engines work" || resp.Header.Get("Deprecation") != "" {
+		t.Errorf("Expected the current shape, got %d %v", resp.StatusCode, row)
+	}
+	resp, row = do(http.MethodGet, "/api/v1/posts/1", "", nil)
+	if _, ok := row["summary"]; ok || row["title"] != "engines" {
+		t.Errorf("Expected the v1 shape, got %v", row)
+	}
+	if got := resp.Header.Get("Deprecation"); got != "@1767225600" {
+		t.Errorf("Expected Deprecation @1767225600, got %q", got)
+	}
+	if got := resp.Header.Get("Sunset"); got != "Wed, 01 Jul 2026 00:00:00 GMT" {
+		t.Errorf("Unexpected Sunset %q", got)
+	}
+	if got := resp.Header.Get("Link"); got != `</api/v2/posts>; rel="successor-version"` {
+		t.Errorf("Unexpected Link %q", got)
+	}
+
+	// Unversioned routes pick the version from Accept-Version
+	resp, row = do(http.MethodGet, "/api/posts/1", "v1", nil)
+	if _, ok := row["summary"]; ok || resp.Header.Get("Deprecation") == "" || resp.Header.Get("Vary") == "" {
+		t.Errorf("Expected v1 by header, got %v %v", row, resp.Header)
+	}
+	if _, row = do(http.MethodGet, "/api/posts/1", "", nil); row["summary"] == nil {
+		t.Errorf("Expected the current version by default, got %v", row)
+	}
+	if resp, _ = do(http.MethodGet, "/api/posts/1", "v9", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown version, got %d", resp.StatusCode)
+	}
+
+	// Bodies are cut down to the columns the version accepts
+	resp, row = do(http.MethodPost, "/api/v1/posts", "", map[string]any{"title": "notes", "summary": "ignored"})
+	if resp.StatusCode != http.StatusOK || row["title"] != "notes" {
+		t.Fatalf("Create returned %d %v", resp.StatusCode, row)
+	}
+	if _, ok := posts.rows[1]["summary"]; ok {
+		t.Errorf("Expected summary to be dropped from a v1 body, got %v", posts.rows[1])
+	}
+
+	// Updates through v1 keep the columns added in v2
+	resp, row = do(http.MethodPut, "/api/v1/posts/1", "", map[string]any{"title": "motors", "summary": "ignored"})
+	if resp.StatusCode != http.StatusOK || row["title"] != "motors" {
+		t.Fatalf("Update returned %d %v", resp.StatusCode, row)
+	}
+	if posts.rows[0]["title"] != "motors" || posts.rows[0]["summary"] != "how engines work" {
+		t.Errorf("Expected a v1 update to keep summary, got %v", posts.rows[0])
+	}
+	if resp, _ = do(http.MethodPut, "/api/v1/posts/9", "", map[string]any{"title": "missing"}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 updating a missing row through v1, got %d", resp.StatusCode)
+	}
+}
+
+func TestDualServer_VersionRenamedColumn(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+	cfg.APIVersion = "v2"
+
+	// title was renamed to headline after v1 was frozen
+	posts := &graphqlTable{name: "posts", rows: []map[string]any{
+		{"id": int64(1), "headline": "engines"},
+	}}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(posts); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+	if err := srv.MountVersion(server.APIVersion{
+		Name: "v1",
+		Tables: []server.VersionedTable{{
+			Resource: core.Resource{Type: "posts", PrimaryKey: "id"},
+			Readable: []string{"id", "title"},
+			Writable: []string{"title"},
+			Columns:  map[string]string{"id": "id", "title": "headline"},
+		}},
+	}); err != nil {
+		t.Fatalf("MountVersion() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	do := func(method, path string, body any) (int, map[string]any) {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		for i := 0; ; i++ {
+			req, _ := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", cfg.HTTPPort, path), bytes.NewReader(data))
+			req.Header.Set("Content-Type", core.ContentTypeJSON)
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				defer resp.Body.Close()
+				var row map[string]any
+				_ = json.NewDecoder(resp.Body).Decode(&row)
+				return resp.StatusCode, row
+			}
+			if i == 100 {
+				t.Fatalf("%s %s error = %v", method, path, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if status, row := do(http.MethodGet, "/api/v1/posts/1", nil); status != http.StatusOK || row["title"] != "engines" || row["headline"] != nil {
+		t.Errorf("Expected headline under its v1 name, got %d %v", status, row)
+	}
+	if _, row := do(http.MethodGet, "/api/v2/posts/1", nil); row["headline"] != "engines" {
+		t.Errorf("Expected the current name in v2, got %v", row)
+	}
+
+	status, row := do(http.MethodPost, "/api/v1/posts", map[string]any{"title": "notes"})
+	if status != http.StatusOK || row["title"] != "notes" {
+		t.Fatalf("Create returned %d %v", status, row)
+	}
+	if posts.rows[1]["headline"] != "notes" {
+		t.Errorf("Expected a v1 title to be stored as headline, got %v", posts.rows[1])
+	}
+}
+
+func TestValidateConfig_Versions(t *testing.T) {
+	for name, versions := range map[string][]config.VersionConfig{
+		"current":   {{Name: "v0"}},
+		"duplicate": {{Name: "v1"}, {Name: "v1"}},
+		"path":      {{Name: "v1/beta"}},
+		"date":      {{Name: "v1", Sunset: "soon"}},
+	} {
+		cfg := config.DefaultConfig()
+		cfg.Server.Versions = versions
+		if err := config.ValidateConfig(cfg); err == nil {
+			t.Errorf("Expected %s versions to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package apiright_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bata94/apiright/pkg/config"
+	"github.com/bata94/apiright/pkg/core"
+	"github.com/bata94/apiright/pkg/server"
+)
+
+// xmlPosts describes posts the way generated adapters do
+var xmlPosts = core.Resource{
+	Type:        "posts",
+	PrimaryKey:  "id",
+	ColumnTypes: map[string]string{"title": "string", "body": "string", "views": "integer", "draft": "boolean"},
+	Element:     "post",
+	Columns: []core.Column{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "title", Type: "TEXT"},
+		{Name: "body", Type: "TEXT", Nullable: true},
+		{Name: "views", Type: "INTEGER"},
+		{Name: "draft", Type: "BOOLEAN"},
+	},
+}
+
+// xmlTable is a posts service mapped onto its XML schema
+type xmlTable struct {
+	*graphqlTable
+}
+
+func (s *xmlTable) Resource() core.Resource { return xmlPosts }
+
+func TestXMLDocument(t *testing.T) {
+	cn := core.NewContentNegotiator()
+
+	row := map[string]any{"views": int64(3), "title": "fish & chips", "body": nil, "id": int64(1)}
+	data, err := cn.SerializeResponse(core.XMLDocument(xmlPosts, row), core.ContentTypeXML)
+	if err != nil {
+		t.Fatalf("SerializeResponse() error = %v", err)
+	}
+	want := `<post xmlns="urn:apiright:posts" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<id>1</id><title>fish &amp; chips</title><body xsi:nil="true"></body><views>3</views></post>`
+	if string(data) != want {
+		t.Errorf("Expected columns in table order with NULLs as xsi:nil\n got: %s\nwant: %s", data, want)
+	}
+
+	var decoded map[string]any
+	if err := cn.DeserializeRequest(data, core.ContentTypeXML, &decoded); err != nil {
+		t.Fatalf("DeserializeRequest() error = %v", err)
+	}
+	if decoded["title"] != "fish & chips" || decoded["body"] != nil || decoded["views"] != "3" {
+		t.Errorf("Unexpected round trip %v", decoded)
+	}
+
+	data, err = cn.SerializeResponse(core.XMLDocument(xmlPosts, []map[string]any{row, row}), core.ContentTypeXML)
+	if err != nil {
+		t.Fatalf("SerializeResponse() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), `<posts xmlns="urn:apiright:posts"`) || strings.Count(string(data), "<post>") != 2 {
+		t.Errorf("Expected a <posts> collection of rows, got %s", data)
+	}
+
+	// Generic values without a schema still encode; slices get a <response> root
+	data, err = cn.SerializeResponse([]map[string]any{{"a": "<b>"}}, core.ContentTypeXML)
+	if err != nil || string(data) != "<response><item><a>&lt;b&gt;</a></item></response>" {
+		t.Errorf("Unexpected generic XML %s (%v)", data, err)
+	}
+}
+
+func TestDualServer_XML(t *testing.T) {
+	cfg := config.DefaultConfig().Server
+	cfg.Host = "127.0.0.1"
+	cfg.HTTPPort = freePort(t)
+	cfg.EnableGRPC = false
+
+	posts := &xmlTable{&graphqlTable{name: "posts"}}
+	srv := server.NewServer(&cfg, t.TempDir(), nil, &mockLogger{})
+	if err := srv.RegisterService(posts); err != nil {
+		t.Fatalf("RegisterService() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/v0/posts", cfg.HTTPPort)
+	do := func(method string, body string) string {
+		t.Helper()
+		for i := 0; ; i++ {
+			req, _ := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+			req.Header.Set("Content-Type", core.ContentTypeXML)
+			req.Header.Set("Accept", core.ContentTypeXML)
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				defer resp.Body.Close()
+				data, _ := io.ReadAll(resp.Body)
+				if resp.StatusCode >= 300 {
+					t.Fatalf("%s returned %d: %s", method, resp.StatusCode, data)
+				}
+				return string(data)
+			}
+			if i == 100 {
+				t.Fatalf("%s error = %v", method, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Create bodies are typed by the column types
+	created := do(http.MethodPost, `<post xmlns="urn:apiright:posts" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`+
+		`<title>engines</title><body xsi:nil="true"/><views>12</views><draft>true</draft></post>`)
+	row := posts.rows[0]
+	if row["title"] != "engines" || row["body"] != nil || row["views"] != int64(12) || row["draft"] != true {
+		t.Errorf("Expected typed column values, got %#v", row)
+	}
+	if !strings.Contains(created, `<draft>true</draft>`) {
+		t.Errorf("Unexpected created post %s", created)
+	}
+
+	listed := do(http.MethodGet, "")
+	if !strings.HasPrefix(listed, `<posts xmlns="urn:apiright:posts"`) || !strings.Contains(listed, `<post><id>1</id><title>engines</title>`) {
+		t.Errorf("Expected a schema-mapped list, got %s", listed)
+	}
+}